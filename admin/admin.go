@@ -0,0 +1,224 @@
+// Package admin serves an HTTP introspection endpoint over the generated
+// repositories, meant to be mounted on an internal port only.
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/l-vitaly/gormrepo"
+)
+
+// MaxRows bounds the rows returned by the query console.
+var MaxRows = 100
+
+var errNotReadOnly = errors.New("only single SELECT, WITH or EXPLAIN statements are allowed")
+
+// Entity describes a generated repository, as returned by its AdminEntity
+// method when generated with -admin.
+type Entity struct {
+	Name    string
+	Table   string
+	Columns []Column
+	Stats   func() gormrepo.Stats `json:"-"`
+}
+
+type Column struct {
+	Field      string
+	Name       string
+	Type       string
+	PrimaryKey bool
+}
+
+// EntityOf describes the entity with the model, a pointer to the entity type.
+func EntityOf(db *gorm.DB, model interface{}) Entity {
+	scope := db.NewScope(model)
+	entity := Entity{Name: scope.GetModelStruct().ModelType.Name(), Table: scope.TableName()}
+	for _, field := range scope.Fields() {
+		if !field.IsNormal {
+			continue
+		}
+		entity.Columns = append(entity.Columns, Column{
+			Field:      field.Name,
+			Name:       field.DBName,
+			Type:       field.Struct.Type.String(),
+			PrimaryKey: field.IsPrimaryKey,
+		})
+	}
+	table := entity.Table
+	entity.Stats = func() gormrepo.Stats { return gormrepo.TableStats(table) }
+	return entity
+}
+
+// SlowQuery is a query slower than the threshold given to TrackSlowQueries.
+type SlowQuery struct {
+	SQL      string
+	Vars     []interface{}
+	Duration time.Duration
+	At       time.Time
+}
+
+var slow = struct {
+	sync.Mutex
+	threshold time.Duration
+	size      int
+	queries   []SlowQuery
+}{size: 100}
+
+// TrackSlowQueries keeps the last hundred queries run on db slower than threshold.
+func TrackSlowQueries(db *gorm.DB, threshold time.Duration) {
+	slow.Lock()
+	slow.threshold = threshold
+	slow.Unlock()
+	callbacks := db.Callback()
+	if callbacks.Query().Get("gormrepo:admin_slow_end") != nil {
+		return
+	}
+	callbacks.Create().Before("gorm:begin_transaction").Register("gormrepo:admin_slow_start", slowStart)
+	callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("gormrepo:admin_slow_end", slowEnd)
+	callbacks.Update().Before("gorm:begin_transaction").Register("gormrepo:admin_slow_start", slowStart)
+	callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("gormrepo:admin_slow_end", slowEnd)
+	callbacks.Delete().Before("gorm:begin_transaction").Register("gormrepo:admin_slow_start", slowStart)
+	callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("gormrepo:admin_slow_end", slowEnd)
+	callbacks.Query().Before("gorm:query").Register("gormrepo:admin_slow_start", slowStart)
+	callbacks.Query().After("gorm:after_query").Register("gormrepo:admin_slow_end", slowEnd)
+	callbacks.RowQuery().Before("gorm:row_query").Register("gormrepo:admin_slow_start", slowStart)
+	callbacks.RowQuery().After("gorm:row_query").Register("gormrepo:admin_slow_end", slowEnd)
+}
+
+func slowStart(scope *gorm.Scope) {
+	scope.InstanceSet("gormrepo:admin_slow_start", time.Now())
+}
+
+func slowEnd(scope *gorm.Scope) {
+	start, ok := scope.InstanceGet("gormrepo:admin_slow_start")
+	if !ok {
+		return
+	}
+	duration := time.Since(start.(time.Time))
+	slow.Lock()
+	defer slow.Unlock()
+	if duration < slow.threshold {
+		return
+	}
+	slow.queries = append(slow.queries, SlowQuery{SQL: scope.SQL, Vars: scope.SQLVars, Duration: duration, At: time.Now()})
+	if len(slow.queries) > slow.size {
+		slow.queries = slow.queries[len(slow.queries)-slow.size:]
+	}
+}
+
+// New returns the admin mux over db and the entities:
+//
+//	GET /entities         entity metadata and statistics
+//	GET /slow             recent slow queries, see TrackSlowQueries
+//	GET /pool             connection pool statistics
+//	GET /query?q=SELECT…  read-only query console
+func New(db *gorm.DB, entities ...Entity) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/entities", func(w http.ResponseWriter, r *http.Request) {
+		type entityStats struct {
+			Entity
+			Stats gormrepo.Stats
+		}
+		result := make([]entityStats, 0, len(entities))
+		for _, entity := range entities {
+			s := entityStats{Entity: entity}
+			if entity.Stats != nil {
+				s.Stats = entity.Stats()
+			}
+			result = append(result, s)
+		}
+		writeJSON(w, result)
+	})
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		slow.Lock()
+		queries := append([]SlowQuery(nil), slow.queries...)
+		slow.Unlock()
+		writeJSON(w, queries)
+	})
+	mux.HandleFunc("/pool", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, db.DB().Stats())
+	})
+	mux.HandleFunc("/query", func(w http.ResponseWriter, r *http.Request) {
+		rows, err := Query(db, r.FormValue("q"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, rows)
+	})
+	return mux
+}
+
+// Query runs a single read-only statement in a read-only transaction that is
+// always rolled back, returning at most MaxRows rows.
+func Query(db *gorm.DB, query string) ([]map[string]interface{}, error) {
+	query = strings.TrimSuffix(strings.TrimSpace(query), ";")
+	if !isReadOnly(query) {
+		return nil, errNotReadOnly
+	}
+	tx := db.Begin()
+	if tx.Error != nil {
+		return nil, tx.Error
+	}
+	defer tx.Rollback()
+	switch tx.Dialect().GetName() {
+	case "postgres", "mysql":
+		if err := tx.Exec("SET TRANSACTION READ ONLY").Error; err != nil {
+			return nil, err
+		}
+	}
+	rows, err := tx.Raw(query).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	var result []map[string]interface{}
+	for rows.Next() && len(result) < MaxRows {
+		values := make([]interface{}, len(columns))
+		dest := make([]interface{}, len(columns))
+		for i := range values {
+			dest[i] = &values[i]
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+		row := make(map[string]interface{}, len(columns))
+		for i, column := range columns {
+			if b, ok := values[i].([]byte); ok {
+				values[i] = string(b)
+			}
+			row[column] = values[i]
+		}
+		result = append(result, row)
+	}
+	return result, rows.Err()
+}
+
+func isReadOnly(query string) bool {
+	if query == "" || strings.Contains(query, ";") {
+		return false
+	}
+	fields := strings.Fields(strings.ToUpper(query))
+	switch fields[0] {
+	case "SELECT", "WITH":
+		return true
+	case "EXPLAIN":
+		return len(fields) < 2 || fields[1] != "ANALYZE"
+	}
+	return false
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
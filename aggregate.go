@@ -0,0 +1,127 @@
+package gormrepo
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Bucket is a histogram bucket counting values in [Lower, Upper). The first
+// and last buckets of Histogram have infinite bounds and count the values
+// out of range, they are omitted when empty.
+type Bucket struct {
+	Lower float64
+	Upper float64
+	Count int64
+}
+
+// Percentiles returns the continuous percentiles ps, in [0, 1], of the non
+// null values of column in the query, NaN when it has no value. Postgres
+// computes them with percentile_cont, other dialects interpolate between the
+// two rows around each rank. Percentiles out of [0, 1] fail with
+// ErrInvalidPercentile.
+func Percentiles(db *gorm.DB, column string, ps ...float64) ([]float64, error) {
+	for _, p := range ps {
+		// NaN fails both comparisons.
+		if !(p >= 0 && p <= 1) {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidPercentile, p)
+		}
+	}
+	db = db.Where(column + " IS NOT NULL")
+	result := make([]float64, len(ps))
+	if db.Dialect().GetName() == "postgres" {
+		for i, p := range ps {
+			var v sql.NullFloat64
+			err := db.Select("percentile_cont(?) WITHIN GROUP (ORDER BY "+column+")", p).Row().Scan(&v)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = math.NaN()
+			if v.Valid {
+				result[i] = v.Float64
+			}
+		}
+		return result, nil
+	}
+
+	var n int64
+	if err := db.Count(&n).Error; err != nil {
+		return nil, err
+	}
+	for i, p := range ps {
+		if n == 0 {
+			result[i] = math.NaN()
+			continue
+		}
+		rank := p * float64(n-1)
+		var values []float64
+		err := db.Order(column, true).Offset(int64(rank)).Limit(2).Pluck(column, &values).Error
+		if err != nil {
+			return nil, err
+		}
+		// Rows deleted since the count leave none at the rank.
+		if len(values) == 0 {
+			result[i] = math.NaN()
+			continue
+		}
+		result[i] = values[0]
+		if len(values) == 2 {
+			result[i] += (values[1] - values[0]) * (rank - math.Floor(rank))
+		}
+	}
+	return result, nil
+}
+
+// Histogram counts the values of column in the query into buckets of equal
+// width between min and max.
+func Histogram(db *gorm.DB, column string, min, max float64, buckets int) ([]Bucket, error) {
+	if buckets <= 0 || max <= min {
+		return nil, ErrInvalidBuckets
+	}
+	n := strconv.Itoa(buckets)
+	lo, hi := strconv.FormatFloat(min, 'g', -1, 64), strconv.FormatFloat(max, 'g', -1, 64)
+	var expr string
+	switch db.Dialect().GetName() {
+	case "postgres":
+		expr = "width_bucket(" + column + ", " + lo + ", " + hi + ", " + n + ")"
+	default:
+		floor := "FLOOR((" + column + " - " + lo + ") * " + n + " / (" + hi + " - " + lo + "))"
+		if db.Dialect().GetName() == "sqlite3" {
+			// Values are above min here, truncation is the floor.
+			floor = "CAST((" + column + " - " + lo + ") * " + n + " / (" + hi + " - " + lo + ") AS INTEGER)"
+		}
+		expr = "CASE WHEN " + column + " < " + lo + " THEN 0 WHEN " + column + " >= " + hi + " THEN " + n + " + 1 ELSE " + floor + " + 1 END"
+	}
+	rows, err := db.Where(column + " IS NOT NULL").Select(expr + " AS bucket, COUNT(*) AS count").Group(expr).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	counts := make([]int64, buckets+2)
+	for rows.Next() {
+		var bucket, count int64
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, err
+		}
+		counts[bucket] += count
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	width := (max - min) / float64(buckets)
+	var result []Bucket
+	if counts[0] > 0 {
+		result = append(result, Bucket{Lower: math.Inf(-1), Upper: min, Count: counts[0]})
+	}
+	for i := 1; i <= buckets; i++ {
+		result = append(result, Bucket{Lower: min + float64(i-1)*width, Upper: min + float64(i)*width, Count: counts[i]})
+	}
+	if counts[buckets+1] > 0 {
+		result = append(result, Bucket{Lower: max, Upper: math.Inf(1), Count: counts[buckets+1]})
+	}
+	return result, nil
+}
@@ -0,0 +1,130 @@
+package gormrepo
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// allowListKey is the setting of the dbs of AllowListOnly.
+const allowListKey = "gormrepo:allow_list"
+
+// identifier matches the column names, qualified or not, and the field paths
+// of the generated columns and preloads.
+var identifier = regexp.MustCompile(`^\w+(\.\w+)*$`)
+
+// AllowListOnly returns db, and the transactions begun from it, accepting
+// only the criteria options free of raw SQL: the options naming their columns
+// as the generated <T>Columns do, with And, Or and Not given maps or structs
+// and Select given column names. The other options, the options without a
+// description, Joins, Raw and the joins of Query fail with
+// ErrRawSQLForbidden.
+func AllowListOnly(db *gorm.DB) *gorm.DB {
+	return db.Set(allowListKey, true)
+}
+
+// IsAllowListOnly reports whether db is in the mode of AllowListOnly.
+func IsAllowListOnly(db *gorm.DB) bool {
+	_, ok := db.Get(allowListKey)
+	return ok
+}
+
+// ApplyCriteria applies the criteria options to db, rejecting the options
+// without a description when db is in the mode of AllowListOnly. The
+// generated repositories apply their criteria with it.
+func ApplyCriteria(db *gorm.DB, criteria ...CriteriaOption) *gorm.DB {
+	allowList := IsAllowListOnly(db)
+	for _, opt := range criteria {
+		// Describe runs the options without a description once more, only
+		// checked in this mode.
+		if allowList {
+			if _, ok := Describe(opt); !ok {
+				return withError(db, fmt.Errorf("%w: criteria option without description", ErrRawSQLForbidden))
+			}
+		}
+		db = opt(db)
+	}
+	return db
+}
+
+// checkRawSQL fails with ErrRawSQLForbidden when the criterion holds SQL
+// written by hand.
+func (c Criterion) checkRawSQL() error {
+	if c.Column != "" && !identifier.MatchString(c.Column) {
+		return fmt.Errorf("%w: column %q of %s", ErrRawSQLForbidden, c.Column, c.Op)
+	}
+	var raw bool
+	switch c.Op {
+	case "and", "or", "not", "having", "preload_where":
+		raw = len(c.Args) == 0 || rawQuery(c.Args[0])
+	case "select":
+		raw = len(c.Args) != 1 || !columnNames(c.Args[0])
+	case "joins", "raw":
+		raw = true
+	case "group_by":
+		for _, column := range c.Args {
+			raw = raw || !columnNames(column)
+		}
+	case "order_by", "order":
+		raw = len(c.Args) == 0 || !direction(c.Args[0])
+	case "collate_order":
+		raw = len(c.Args) < 2 || !direction(c.Args[1])
+	case "ilike_any":
+		for i := 1; i < len(c.Args); i++ {
+			raw = raw || !columnNames(c.Args[i])
+		}
+	case "published":
+		unpublish, _ := argValue(c.Args, 0).(string)
+		raw = unpublish != "" && !identifier.MatchString(unpublish)
+	}
+	if raw {
+		return fmt.Errorf("%w: %s", ErrRawSQLForbidden, c)
+	}
+	return nil
+}
+
+// rawQuery reports whether the query of And, Or or Not is SQL, rather than
+// a map or a struct gorm turns into conditions on columns.
+func rawQuery(query interface{}) bool {
+	switch query.(type) {
+	case string, []string, []interface{}:
+		return true
+	}
+	return false
+}
+
+// columnNames reports whether the columns of Select are column names.
+func columnNames(columns interface{}) bool {
+	var names []string
+	switch columns := columns.(type) {
+	case string:
+		names = []string{columns}
+	case []string:
+		names = columns
+	case []interface{}:
+		for _, column := range columns {
+			name, ok := column.(string)
+			if !ok {
+				return false
+			}
+			names = append(names, name)
+		}
+	default:
+		return false
+	}
+	for _, name := range names {
+		if !identifier.MatchString(name) {
+			return false
+		}
+	}
+	return len(names) > 0
+}
+
+// direction reports whether arg is the name of a Direction, in any case, or
+// empty for the default of the database.
+func direction(arg interface{}) bool {
+	d, _ := arg.(string)
+	return d == "" || Direction(strings.ToUpper(d)).Valid()
+}
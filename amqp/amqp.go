@@ -0,0 +1,74 @@
+// Package amqp publishes entity change events to an AMQP 0.9.1 broker such
+// as RabbitMQ, on a topic exchange with the routing key <Entity>.<op>.
+package amqp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"github.com/l-vitaly/gormrepo"
+	amqpgo "github.com/rabbitmq/amqp091-go"
+)
+
+var ErrNacked = errors.New("message rejected by broker")
+
+// Publisher is a gormrepo.Publisher writing persistent messages to an
+// exchange, with publisher confirms.
+type Publisher struct {
+	Channel  *amqpgo.Channel
+	Exchange string
+	// RoutingKey returns the routing key of the event, <Entity>.<op> by default.
+	RoutingKey func(event gormrepo.Event) string
+}
+
+// NewPublisher returns a publisher to the exchange, putting ch in confirm
+// mode. The channel must not be shared with other publishers.
+func NewPublisher(ch *amqpgo.Channel, exchange string) (*Publisher, error) {
+	if err := ch.Confirm(false); err != nil {
+		return nil, err
+	}
+	return &Publisher{
+		Channel:  ch,
+		Exchange: exchange,
+		RoutingKey: func(event gormrepo.Event) string {
+			return event.Entity + "." + event.Op.String()
+		},
+	}, nil
+}
+
+// Publish sends the events as JSON and waits for the broker to confirm them.
+func (p *Publisher) Publish(ctx context.Context, events ...gormrepo.Event) error {
+	confirms := make([]*amqpgo.DeferredConfirmation, 0, len(events))
+	for _, event := range events {
+		body, err := json.Marshal(event.Payload)
+		if err != nil {
+			return err
+		}
+		confirm, err := p.Channel.PublishWithDeferredConfirmWithContext(ctx, p.Exchange, p.RoutingKey(event), false, false, amqpgo.Publishing{
+			ContentType:  "application/json",
+			DeliveryMode: amqpgo.Persistent,
+			Type:         event.Entity,
+			Headers: amqpgo.Table{
+				"entity": event.Entity,
+				"op":     event.Op.String(),
+				"key":    event.Key,
+			},
+			Body: body,
+		})
+		if err != nil {
+			return err
+		}
+		confirms = append(confirms, confirm)
+	}
+	for _, confirm := range confirms {
+		ok, err := confirm.WaitContext(ctx)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return ErrNacked
+		}
+	}
+	return nil
+}
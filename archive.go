@@ -0,0 +1,41 @@
+package gormrepo
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+// ArchiveBatchSize is the number of rows archived per transaction when the
+// batch size given to Archive is not positive.
+var ArchiveBatchSize = 1000
+
+// Sink receives the rows offloaded by the generated Archive method, for
+// example a JSONL or Parquet writer uploading to object storage. Write is
+// called for each row of a batch, Flush before the batch is deleted: rows are
+// only removed from the database once Flush succeeded.
+type Sink interface {
+	Write(row interface{}) error
+	Flush() error
+}
+
+// JSONLSink writes each row as a JSON line.
+type JSONLSink struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+// NewJSONLSink creates a sink writing JSON lines to w, such as the body of an
+// S3 multipart upload.
+func NewJSONLSink(w io.Writer) *JSONLSink {
+	bw := bufio.NewWriter(w)
+	return &JSONLSink{w: bw, enc: json.NewEncoder(bw)}
+}
+
+func (s *JSONLSink) Write(row interface{}) error {
+	return s.enc.Encode(row)
+}
+
+func (s *JSONLSink) Flush() error {
+	return s.w.Flush()
+}
@@ -0,0 +1,38 @@
+package gormrepo
+
+import (
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+// arrayOp compares a Postgres array column with the values as an array.
+func arrayOp(name, column, op string, values []interface{}) CriteriaOption {
+	return Described(Criterion{Op: name, Column: column, Args: values}, func(db *gorm.DB) *gorm.DB {
+		if db.Dialect().GetName() != "postgres" {
+			return withError(db, ErrUnsupported)
+		}
+		return db.Where(column+" "+op+" ?", pq.GenericArray{A: values})
+	})
+}
+
+// ArrayContains limits the query to rows whose Postgres array column holds
+// all the values. It fails with ErrUnsupported on other dialects.
+func ArrayContains(column string, values ...interface{}) CriteriaOption {
+	return arrayOp("array_contains", column, "@>", values)
+}
+
+// ArrayOverlaps limits the query to rows whose Postgres array column holds
+// any of the values.
+func ArrayOverlaps(column string, values ...interface{}) CriteriaOption {
+	return arrayOp("array_overlaps", column, "&&", values)
+}
+
+// Any limits the query to rows whose Postgres array column holds the value.
+func Any(column string, value interface{}) CriteriaOption {
+	return Described(Criterion{Op: "any", Column: column, Args: []interface{}{value}}, func(db *gorm.DB) *gorm.DB {
+		if db.Dialect().GetName() != "postgres" {
+			return withError(db, ErrUnsupported)
+		}
+		return db.Where("? = ANY("+column+")", value)
+	})
+}
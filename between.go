@@ -0,0 +1,36 @@
+package gormrepo
+
+import "github.com/jinzhu/gorm"
+
+// Between limits the query to rows whose column is between from and to,
+// both included, such as dates or amounts.
+func Between(column string, from, to interface{}) CriteriaOption {
+	return between("between", column, "BETWEEN", from, to)
+}
+
+// NotBetween limits the query to rows whose column is below from or above
+// to.
+func NotBetween(column string, from, to interface{}) CriteriaOption {
+	return between("not_between", column, "NOT BETWEEN", from, to)
+}
+
+func between(name, column, op string, from, to interface{}) CriteriaOption {
+	return Described(Criterion{Op: name, Column: column, Args: []interface{}{from, to}}, func(db *gorm.DB) *gorm.DB {
+		return db.Where(column+" "+op+" ? AND ?", from, to)
+	})
+}
+
+func init() {
+	for op, build := range map[string]func(column string, from, to interface{}) CriteriaOption{
+		"between":     Between,
+		"not_between": NotBetween,
+	} {
+		build := build
+		RegisterCriterion(op, func(c Criterion) (CriteriaOption, error) {
+			if len(c.Args) != 2 {
+				return nil, c.invalid()
+			}
+			return build(c.Column, c.Args[0], c.Args[1]), nil
+		})
+	}
+}
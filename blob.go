@@ -0,0 +1,177 @@
+package gormrepo
+
+import (
+	"database/sql"
+	"io"
+	"io/ioutil"
+
+	"github.com/jinzhu/gorm"
+)
+
+// BlobChunkSize is the number of bytes read or written by a single blob query.
+var BlobChunkSize = 1 << 20
+
+// BlobColumn describes a binary column streamed in chunks. Table, Column and
+// Key are quoted names, Key being the primary key column. LargeObject marks a
+// Postgres oid column referencing a large object instead of a bytea column.
+type BlobColumn struct {
+	Table       string
+	Column      string
+	Key         string
+	LargeObject bool
+}
+
+type blobReader struct {
+	db     *gorm.DB
+	column BlobColumn
+	id     interface{} // Row id, or large object oid.
+	offset int
+	buf    []byte
+	done   bool
+}
+
+// OpenBlob returns a reader streaming the column of the row with the id,
+// so the value is never loaded into memory at once.
+func OpenBlob(db *gorm.DB, column BlobColumn, id interface{}) (io.ReadCloser, error) {
+	isNull, err := blobIsNull(db, column, id)
+	if err != nil {
+		return nil, err
+	}
+	r := &blobReader{db: db, column: column, id: id}
+	if isNull {
+		r.done = true
+	} else if column.LargeObject {
+		var oid uint32
+		err := db.Raw("SELECT "+column.Column+" FROM "+column.Table+" WHERE "+column.Key+" = ?", id).Row().Scan(&oid)
+		if err != nil {
+			return nil, err
+		}
+		r.id = oid
+	}
+	return ioutil.NopCloser(r), nil
+}
+
+func blobIsNull(db *gorm.DB, column BlobColumn, id interface{}) (bool, error) {
+	var isNull int
+	query := "SELECT CASE WHEN " + column.Column + " IS NULL THEN 1 ELSE 0 END FROM " + column.Table + " WHERE " + column.Key + " = ?"
+	err := db.Raw(query, id).Row().Scan(&isNull)
+	if err == sql.ErrNoRows {
+		return false, gorm.ErrRecordNotFound
+	}
+	return isNull == 1, err
+}
+
+func (r *blobReader) Read(p []byte) (int, error) {
+	if len(r.buf) == 0 {
+		if r.done {
+			return 0, io.EOF
+		}
+		if err := r.fetch(); err != nil {
+			return 0, err
+		}
+		if len(r.buf) == 0 {
+			return 0, io.EOF
+		}
+	}
+	n := copy(p, r.buf)
+	r.buf = r.buf[n:]
+	return n, nil
+}
+
+func (r *blobReader) fetch() error {
+	var row *sql.Row
+	if r.column.LargeObject {
+		row = r.db.Raw("SELECT lo_get(?, ?, ?)", r.id, r.offset, BlobChunkSize).Row()
+	} else {
+		query := "SELECT SUBSTR(" + r.column.Column + ", ?, ?) FROM " + r.column.Table + " WHERE " + r.column.Key + " = ?"
+		row = r.db.Raw(query, r.offset+1, BlobChunkSize, r.id).Row()
+	}
+	var chunk []byte
+	if err := row.Scan(&chunk); err != nil {
+		return err
+	}
+	r.offset += len(chunk)
+	r.done = len(chunk) < BlobChunkSize
+	r.buf = chunk
+	return nil
+}
+
+// WriteBlob replaces the column of the row with the id by the content of src,
+// appending it in chunks inside a transaction. On dialects without binary
+// concatenation the content is buffered and written in a single update.
+func WriteBlob(db *gorm.DB, column BlobColumn, id interface{}, src io.Reader) error {
+	return Transaction(db, func(tx *gorm.DB) error {
+		if _, err := blobIsNull(tx, column, id); err != nil {
+			return err
+		}
+		if column.LargeObject {
+			return writeLargeObject(tx, column, id, src)
+		}
+
+		var appendExpr string
+		switch tx.Dialect().GetName() {
+		case "postgres":
+			appendExpr = column.Column + " || ?"
+		case "mysql":
+			appendExpr = "CONCAT(" + column.Column + ", ?)"
+		default:
+			data, err := ioutil.ReadAll(src)
+			if err != nil {
+				return err
+			}
+			return tx.Exec("UPDATE "+column.Table+" SET "+column.Column+" = ? WHERE "+column.Key+" = ?", data, id).Error
+		}
+
+		if err := tx.Exec("UPDATE "+column.Table+" SET "+column.Column+" = ? WHERE "+column.Key+" = ?", []byte{}, id).Error; err != nil {
+			return err
+		}
+		return copyChunks(src, func(chunk []byte) error {
+			return tx.Exec("UPDATE "+column.Table+" SET "+column.Column+" = "+appendExpr+" WHERE "+column.Key+" = ?", chunk, id).Error
+		})
+	})
+}
+
+func writeLargeObject(tx *gorm.DB, column BlobColumn, id interface{}, src io.Reader) error {
+	var oldOID sql.NullInt64
+	if err := tx.Raw("SELECT "+column.Column+" FROM "+column.Table+" WHERE "+column.Key+" = ?", id).Row().Scan(&oldOID); err != nil {
+		return err
+	}
+	var oid uint32
+	if err := tx.Raw("SELECT lo_create(0)").Row().Scan(&oid); err != nil {
+		return err
+	}
+	offset := 0
+	err := copyChunks(src, func(chunk []byte) error {
+		err := tx.Exec("SELECT lo_put(?, ?, ?)", oid, offset, chunk).Error
+		offset += len(chunk)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := tx.Exec("UPDATE "+column.Table+" SET "+column.Column+" = ? WHERE "+column.Key+" = ?", oid, id).Error; err != nil {
+		return err
+	}
+	if oldOID.Valid {
+		return tx.Exec("SELECT lo_unlink(?)", oldOID.Int64).Error
+	}
+	return nil
+}
+
+func copyChunks(src io.Reader, fn func(chunk []byte) error) error {
+	buf := make([]byte, BlobChunkSize)
+	for {
+		n, err := io.ReadFull(src, buf)
+		if n > 0 {
+			if err := fn(buf[:n]); err != nil {
+				return err
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
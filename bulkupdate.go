@@ -0,0 +1,148 @@
+package gormrepo
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// BulkUpdateByKey updates the rows of the model by primary key, each with its
+// fields keyed by column or field name, in one transaction. The keys sharing
+// the same set of columns are loaded into a temporary table, with COPY on
+// Postgres, and their rows updated by a single UPDATE joining it, where
+// thousands of UPDATE statements would be needed otherwise. UpdatedAt is set
+// to now unless given.
+//
+// Postgres, MySQL and SQLite 3.33 or later are supported, other dialects fail
+// with ErrUnsupported. Unknown columns fail with ErrUnknownColumn. It returns
+// the number of updated rows.
+func BulkUpdateByKey(db *gorm.DB, model interface{}, pairs map[uint]Fields) (int64, error) {
+	dialect := db.Dialect().GetName()
+	switch dialect {
+	case "postgres", "mysql", "sqlite3":
+	default:
+		return 0, fmt.Errorf("%w: bulk update on %s", ErrUnsupported, dialect)
+	}
+	scope := db.NewScope(model)
+	rows := make(map[uint]map[string]interface{}, len(pairs))
+	groups := map[string][]uint{}
+	for key, fields := range pairs {
+		row, err := bulkRow(scope, fields)
+		if err != nil {
+			return 0, err
+		}
+		columns := make([]string, 0, len(row))
+		for column := range row {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+		set := strings.Join(columns, ",")
+		rows[key] = row
+		groups[set] = append(groups[set], key)
+	}
+	sets := make([]string, 0, len(groups))
+	for set := range groups {
+		sets = append(sets, set)
+	}
+	sort.Strings(sets)
+	var updated int64
+	err := Transaction(db, func(tx *gorm.DB) error {
+		for i, set := range sets {
+			keys := groups[set]
+			sort.Slice(keys, func(a, b int) bool { return keys[a] < keys[b] })
+			n, err := bulkUpdate(tx, scope, fmt.Sprintf("gormrepo_bulk_%d", i), strings.Split(set, ","), keys, rows)
+			if err != nil {
+				return err
+			}
+			updated += n
+		}
+		return nil
+	})
+	return updated, err
+}
+
+// bulkRow returns the fields keyed by column.
+func bulkRow(scope *gorm.Scope, fields Fields) (map[string]interface{}, error) {
+	row := make(map[string]interface{}, len(fields))
+	for name, value := range fields {
+		field, ok := scope.FieldByName(name)
+		if !ok || !field.IsNormal || field.IsPrimaryKey {
+			return nil, fmt.Errorf("%w: %s of %s", ErrUnknownColumn, name, scope.TableName())
+		}
+		row[field.DBName] = value
+	}
+	return row, nil
+}
+
+// bulkUpdate loads the columns of the rows of the keys into the temporary
+// table temp and updates the rows of the model joining it. The temporary
+// table is dropped even when the update fails: a MySQL rollback keeps it on
+// the connection, failing the next bulk update there.
+func bulkUpdate(tx *gorm.DB, scope *gorm.Scope, temp string, columns []string, keys []uint, rows map[uint]map[string]interface{}) (updated int64, err error) {
+	dialect := tx.Dialect()
+	pk := scope.PrimaryKey()
+	tempColumns := append([]string{pk}, columns...)
+	quoted := make([]string, len(tempColumns))
+	for i, column := range tempColumns {
+		quoted[i] = dialect.Quote(column)
+	}
+	table := scope.QuotedTableName()
+	// The temporary table takes the column types of the table.
+	create := "CREATE TEMPORARY TABLE " + dialect.Quote(temp) + " AS SELECT " + strings.Join(quoted, ", ") + " FROM " + table + " LIMIT 0"
+	if err := tx.Exec(create).Error; err != nil {
+		return 0, err
+	}
+	drop := "DROP TABLE IF EXISTS " + dialect.Quote(temp)
+	if dialect.GetName() == "mysql" {
+		drop = "DROP TEMPORARY TABLE IF EXISTS " + dialect.Quote(temp)
+	}
+	defer func() {
+		if dropErr := tx.Exec(drop).Error; err == nil {
+			err = dropErr
+		}
+	}()
+	values := make([][]interface{}, len(keys))
+	for i, key := range keys {
+		values[i] = []interface{}{key}
+		for _, column := range columns {
+			values[i] = append(values[i], rows[key][column])
+		}
+	}
+	if dialect.GetName() == "postgres" {
+		err = copyIn(tx, temp, tempColumns, values)
+	} else {
+		err = insertBatches(tx, dialect.Quote(temp), tempColumns, values)
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	var args []interface{}
+	updatedAt, touch := scope.FieldByName("UpdatedAt")
+	if touch {
+		_, given := rows[keys[0]][updatedAt.DBName]
+		touch = !given
+	}
+	assign := func(target string) []string {
+		sets := make([]string, 0, len(columns)+1)
+		for _, column := range columns {
+			sets = append(sets, target+dialect.Quote(column)+" = "+dialect.Quote(temp)+"."+dialect.Quote(column))
+		}
+		if touch {
+			sets = append(sets, target+dialect.Quote(updatedAt.DBName)+" = ?")
+			args = append(args, gorm.NowFunc())
+		}
+		return sets
+	}
+	join := table + "." + dialect.Quote(pk) + " = " + dialect.Quote(temp) + "." + dialect.Quote(pk)
+	var update string
+	if dialect.GetName() == "mysql" {
+		update = "UPDATE " + table + " JOIN " + dialect.Quote(temp) + " ON " + join + " SET " + strings.Join(assign(table+"."), ", ")
+	} else {
+		update = "UPDATE " + table + " SET " + strings.Join(assign(""), ", ") + " FROM " + dialect.Quote(temp) + " WHERE " + join
+	}
+	result := tx.Exec(update, args...)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,66 @@
+package gormrepo
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+)
+
+type bulkEntry struct {
+	ID   uint
+	Name string
+}
+
+// sqliteDB opens an in-memory SQLite database on a single connection, the
+// temporary tables and every other connection being on their own.
+func sqliteDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.DB().SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// TestBulkUpdateDropsTempTableOnFailure runs bulkUpdate outside a
+// transaction, as MySQL keeps the temporary table on the connection after a
+// rollback.
+func TestBulkUpdateDropsTempTableOnFailure(t *testing.T) {
+	db := sqliteDB(t)
+	if err := db.AutoMigrate(&bulkEntry{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	entry := bulkEntry{Name: "a"}
+	if err := db.Create(&entry).Error; err != nil {
+		t.Fatal(err)
+	}
+	scope := db.NewScope(&bulkEntry{})
+	columns := []string{"name"}
+	keys := []uint{entry.ID}
+
+	// The driver cannot bind a struct, failing the insert into the temporary
+	// table.
+	rows := map[uint]map[string]interface{}{entry.ID: {"name": struct{}{}}}
+	if _, err := bulkUpdate(db, scope, "gormrepo_bulk_0", columns, keys, rows); err == nil {
+		t.Fatal("bulk update of an unsupported value succeeded")
+	}
+	var temps int
+	if err := db.Raw("SELECT COUNT(*) FROM sqlite_temp_master WHERE name = ?", "gormrepo_bulk_0").Row().Scan(&temps); err != nil {
+		t.Fatal(err)
+	}
+	if temps != 0 {
+		t.Error("failed bulk update left its temporary table")
+	}
+
+	rows = map[uint]map[string]interface{}{entry.ID: {"name": "b"}}
+	n, err := bulkUpdate(db, scope, "gormrepo_bulk_0", columns, keys, rows)
+	if err != nil {
+		t.Fatalf("bulk update after a failed one: %v", err)
+	}
+	if n != 1 {
+		t.Errorf("bulk update after a failed one updated %d rows, want 1", n)
+	}
+}
@@ -0,0 +1,56 @@
+package gormrepo
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// Checksum consumes rows and returns the hex SHA-256 over their values, in
+// order. Values are normalized before hashing, times in UTC and byte slices as
+// strings, so the same data read through different drivers hashes the same.
+func Checksum(rows *sql.Rows) (string, error) {
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	values := make([]interface{}, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	h := sha256.New()
+	var size [8]byte
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		for _, value := range values {
+			var s string
+			switch v := value.(type) {
+			case nil:
+				// Keeps NULL apart from the empty string.
+				h.Write([]byte{0})
+				continue
+			case []byte:
+				s = string(v)
+			case time.Time:
+				s = v.UTC().Format(time.RFC3339Nano)
+			default:
+				s = fmt.Sprint(v)
+			}
+			binary.BigEndian.PutUint64(size[:], uint64(len(s)))
+			h.Write([]byte{1})
+			h.Write(size[:])
+			h.Write([]byte(s))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/l-vitaly/gormrepo/gen"
+)
+
+// configName is the file of the flags of a package generated by a pattern,
+// such as
+//
+//	# Repositories of the billing models.
+//	-registry -enums -t=Invoice,Payment
+const configName = "gormrepogen.conf"
+
+// isPattern reports whether the argument is a package pattern such as ./...
+func isPattern(arg string) bool {
+	return arg == "..." || strings.HasSuffix(arg, "/...")
+}
+
+// isPatterns reports whether the arguments are package patterns. Patterns
+// are not mixed with directories and files.
+func isPatterns(args []string) bool {
+	patterns := 0
+	for _, arg := range args {
+		if isPattern(arg) {
+			patterns++
+		}
+	}
+	if patterns > 0 && patterns < len(args) {
+		log.Fatalf("package patterns cannot be mixed with directories and files")
+	}
+	return patterns > 0
+}
+
+// batch generates the packages under the patterns that have a config file,
+// each with the flags of the command line followed by the flags of its
+// config file, and reports whether every package was generated.
+func batch(patterns []string) bool {
+	var dirs []string
+	for _, pattern := range patterns {
+		root := strings.TrimSuffix(strings.TrimSuffix(pattern, "..."), "/")
+		if root == "" {
+			root = "."
+		}
+		err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if !info.IsDir() {
+				return nil
+			}
+			name := info.Name()
+			if path != root && (name == "vendor" || name == "testdata" || strings.HasPrefix(name, ".") || strings.HasPrefix(name, "_")) {
+				return filepath.SkipDir
+			}
+			if _, err := os.Stat(filepath.Join(path, configName)); err == nil {
+				dirs = append(dirs, path)
+			}
+			return nil
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	if len(dirs) == 0 {
+		log.Fatalf("no %s found in %s", configName, strings.Join(patterns, " "))
+	}
+
+	var failed []string
+	for _, dir := range dirs {
+		if !generatePackage(dir) {
+			failed = append(failed, dir)
+		}
+	}
+	if len(failed) > 0 {
+		log.Printf("generated %d of %d packages, failed: %s", len(dirs)-len(failed), len(dirs), strings.Join(failed, ", "))
+		return false
+	}
+	return true
+}
+
+// generatePackage generates the package of dir with the flags of its config
+// file.
+func generatePackage(dir string) bool {
+	name := filepath.Join(dir, configName)
+	args, err := readConfig(name)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	o, types, packageTags := opts, typeNames, buildTags
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	bindFlags(fs, &o, &types, &packageTags)
+	if err := fs.Parse(args); err != nil {
+		log.Printf("%s: %s", name, err)
+		return false
+	}
+	if fs.NArg() > 0 {
+		log.Printf("%s: unexpected arguments %s, the package is the directory of the file", name, strings.Join(fs.Args(), " "))
+		return false
+	}
+	if types == "" {
+		log.Printf("%s: no types, set -t", name)
+		return false
+	}
+	command := []string{opts.Command}
+	for _, arg := range args {
+		if strings.ContainsAny(arg, " \t") {
+			arg = strconv.Quote(arg)
+		}
+		command = append(command, arg)
+	}
+	o.Command = strings.Join(command, " ")
+
+	g, err := gen.Load(tags(packageTags), dir)
+	if err != nil {
+		log.Print(err)
+		return false
+	}
+	return g.Write(typeList(types), o)
+}
+
+// readConfig returns the flags of the config file, separated by spaces and
+// lines as in a shell, double quoted when they contain spaces, without the
+// comments starting with #.
+func readConfig(name string) ([]string, error) {
+	src, err := ioutil.ReadFile(name)
+	if err != nil {
+		return nil, err
+	}
+	var args []string
+	for i, line := range strings.Split(string(src), "\n") {
+		var arg strings.Builder
+		inArg, quoted := false, false
+		for _, r := range line {
+			if !quoted && r == '#' {
+				break
+			}
+			switch {
+			case r == '"':
+				quoted, inArg = !quoted, true
+			case !quoted && (r == ' ' || r == '\t' || r == '\r'):
+				if inArg {
+					args = append(args, arg.String())
+					arg.Reset()
+					inArg = false
+				}
+			default:
+				arg.WriteRune(r)
+				inArg = true
+			}
+		}
+		if quoted {
+			return nil, fmt.Errorf("%s:%d: unterminated quoted string", name, i+1)
+		}
+		if inArg {
+			args = append(args, arg.String())
+		}
+	}
+	return args, nil
+}
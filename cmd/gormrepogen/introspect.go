@@ -0,0 +1,53 @@
+package main
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	_ "github.com/jinzhu/gorm/dialects/mysql"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/l-vitaly/gormrepo/gen"
+)
+
+// introspect implements the introspect subcommand: it reads the tables of a
+// database and writes a model per table to the output package, if missing,
+// then generates the repositories of the tables keyed by an integer id
+// with the flags given before the subcommand.
+func introspect(args []string) {
+	fs := flag.NewFlagSet("introspect", flag.ExitOnError)
+	dialect := fs.String("dialect", "postgres", "database dialect: postgres, mysql or sqlite3")
+	dsn := fs.String("dsn", "", "data source name of the database; must be set")
+	tables := fs.String("tables", "", "comma-separated list of tables, all the tables of the schema by default")
+	out := fs.String("out", ".", "directory of the model package")
+	pkgName := fs.String("pkg", "", "name of the model package, the directory name by default")
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage of %s introspect:\n", os.Args[0])
+		fmt.Fprintf(os.Stderr, "\tgormrepogen [flags] introspect -dialect D -dsn DSN [-tables T] [-out directory]\n")
+		fmt.Fprintf(os.Stderr, "Flags:\n")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *dsn == "" {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	db, err := sql.Open(*dialect, *dsn)
+	if err != nil {
+		log.Fatalf("opening database: %s", err)
+	}
+	defer db.Close()
+	var names []string
+	if *tables != "" {
+		names = strings.Split(*tables, ",")
+	}
+	if !gen.Introspect(db, *dialect, names, *out, *pkgName, opts) {
+		db.Close()
+		os.Exit(1)
+	}
+}
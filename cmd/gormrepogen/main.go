@@ -6,12 +6,12 @@
 //
 // package model
 //
-// type User struct {
-//    gorm.Model
-//    FirstName string
-//    LastName string
-//    Birthday time.Time
-// }
+//	type User struct {
+//	   gorm.Model
+//	   FirstName string
+//	   LastName string
+//	   Birthday time.Time
+//	}
 //
 // running this command:
 //
@@ -19,343 +19,139 @@
 //
 // Typically this process would be run using go generate, like this:
 //
-//      //go:generate gormrepogen -t=User
-//
+//	//go:generate gormrepogen -t=User
 package main
 
 import (
-	"bytes"
 	"flag"
 	"fmt"
-	"go/ast"
-	"go/build"
-	"go/format"
-	"go/parser"
-	"go/token"
-	"io/ioutil"
 	"log"
 	"os"
-	"path/filepath"
 	"strings"
+
+	"github.com/l-vitaly/gormrepo/gen"
 )
 
 var (
-	typeNames = flag.String("t", "", "comma-separated list of type names; must be set")
+	typeNames, buildTags string
+
+	opts = gen.Options{TreeStrategies: "adjacency"}
 )
 
+func init() {
+	bindFlags(flag.CommandLine, &opts, &typeNames, &buildTags)
+}
+
+// bindFlags defines the generation flags on the flag set, defaulting to the
+// values of the options, types and build tags given.
+func bindFlags(fs *flag.FlagSet, o *gen.Options, typeNames, buildTags *string) {
+	fs.StringVar(typeNames, "t", *typeNames, "comma-separated list of type names; must be set")
+	fs.StringVar(buildTags, "tags", *buildTags, "comma-separated list of build tags to apply when parsing the package")
+	fs.BoolVar(&o.Tree, "tree", o.Tree, "generate tree methods for self-referential types with a ParentID field")
+	fs.StringVar(&o.TreeStrategies, "tree-strategy", o.TreeStrategies, "tree strategy: adjacency, path or closure, or a comma-separated list of Type=strategy")
+	fs.BoolVar(&o.DefaultOrder, "default-order", o.DefaultOrder, "order GetBy and GetAll by primary key after any OrderBy criteria, for stable pagination")
+	fs.BoolVar(&o.Admin, "admin", o.Admin, "generate AdminEntity describing the repository to the gormrepo/admin mux")
+	fs.BoolVar(&o.Check, "check", o.Check, "write nothing and fail when a generated file differs from the one on disk, to find stale generated code in CI")
+	fs.BoolVar(&o.Columns, "columns", o.Columns, "generate <T>Columns descriptors for the typed criteria, in <t>_columns.go built with go1.18, implied by -lang go1.18 and later")
+	fs.BoolVar(&o.Decorators, "decorators", o.Decorators, "generate the repository interface and a decorator skeleton delegating all of its methods")
+	fs.BoolVar(&o.DoubleWrite, "double-write", o.DoubleWrite, "generate a DoubleWrite decorator mirroring writes to a secondary repository")
+	fs.BoolVar(&o.Enums, "enums", o.Enums, "generate value sets and In criteria of the enum fields, and reject invalid values on Create and Update")
+	fs.StringVar(&o.ERD, "erd", o.ERD, "comma-separated diagram formats, mermaid or dot, of the entity-relationship diagram of the types; writes a data dictionary to schema.md and the dot diagram to schema.dot")
+	fs.BoolVar(&o.Export, "export", o.Export, "export the base repository as <T>BaseRepo and create a <t>_repo.go stub for custom methods once")
+	fs.StringVar(&o.Header, "header", o.Header, "file of the header of the generated files, a text/template of their license and DO NOT EDIT banner given the .Command, .Package and .File; lines not starting with // are commented")
+	fs.BoolVar(&o.JSON, "json", o.JSON, "generate <T>JSONColumn and <T>ColumnJSON translating between the JSON names of the fields and their columns")
+	fs.StringVar(&o.Lang, "lang", o.Lang, "Go version of the generated code, such as go1.21: any with go1.18 and later, along with the typed column descriptors, and %v instead of %w before go1.13; by default interface{} and %w")
+	fs.BoolVar(&o.Merge, "merge", o.Merge, "mark the generated code with gormrepogen:begin/end regions and keep the code outside them on regeneration")
+	fs.BoolVar(&o.Meta, "meta", o.Meta, "register the table, columns, relations and indexes of the types into gormrepo.Meta")
+	fs.BoolVar(&o.Notify, "notify", o.Notify, "generate a Notifying decorator sending Postgres NOTIFY on writes, and OnChanged for gormrepo/notify listeners")
+	fs.BoolVar(&o.Publish, "publish", o.Publish, "generate a Publishing decorator sending change events to a gormrepo.Publisher on writes")
+	fs.BoolVar(&o.Registry, "registry", o.Registry, "generate a Registry of the repositories of all the types, migrating them and their reference data with MigrateAll and validating their schema")
+	fs.BoolVar(&o.Strict, "strict", o.Strict, "fail before writing any file when a type is missing or a file of the package does not parse")
+	fs.BoolVar(&o.Rollout, "rollout", o.Rollout, "generate a Rollout decorator routing a percentage of operations to an alternative implementation")
+	fs.BoolVar(&o.Shadow, "shadow", o.Shadow, "generate a Shadow decorator comparing reads with a second implementation")
+	fs.StringVar(&o.OutTags, "out-tags", o.OutTags, "build constraint of the generated files, such as \"enterprise && !oss\"; defaults to the constraint of the file declaring the type")
+	fs.StringVar(&o.Variants, "variants", o.Variants, "comma-separated list of implementations generated side by side behind build tags: gormv1 and gormv2")
+	fs.StringVar(&o.Views, "view", o.Views, "comma-separated list of types backed by a view, Type=materialized for materialized views; write and migration methods are not generated")
+}
+
 func Usage() {
 	fmt.Fprintf(os.Stderr, "Usage of %s:\n", os.Args[0])
 	fmt.Fprintf(os.Stderr, "\tgormrepogen [flags] -t T [directory]\n")
 	fmt.Fprintf(os.Stderr, "\tgormrepogen [flags] -t T files... # Must be a single package\n")
+	fmt.Fprintf(os.Stderr, "\tgormrepogen [flags] -tree -t T [directory] # T must have a ParentID field\n")
+	fmt.Fprintf(os.Stderr, "\tgormrepogen [flags] ./... # Packages with a %s file\n", configName)
+	fmt.Fprintf(os.Stderr, "\tgormrepogen [flags] introspect -dialect D -dsn DSN [-out directory] # Models from a database\n")
 	fmt.Fprintf(os.Stderr, "Flags:\n")
 	flag.PrintDefaults()
 }
 
-func init() {
+func main() {
 	log.SetFlags(0)
 	log.SetPrefix("gormrepogen: ")
 	flag.Usage = Usage
 	flag.Parse()
-}
+	opts.Command = command()
 
-func main() {
-	if len(*typeNames) == 0 {
-		flag.Usage()
-		os.Exit(2)
+	if flag.Arg(0) == "introspect" {
+		introspect(flag.Args()[1:])
+		return
 	}
-
-	types := strings.Split(*typeNames, ",")
-
-	args := flag.Args()
-	if len(args) == 0 {
-		// Default: process whole package in current directory.
-		args = []string{"."}
-	}
-
-	var g Generator
-
-	if len(args) == 1 && isDirectory(args[0]) {
-		g.parsePackageDir(args[0])
-	} else {
-		g.parsePackageFiles(args)
+	if isPatterns(flag.Args()) {
+		if !batch(flag.Args()) {
+			os.Exit(1)
+		}
+		return
 	}
-
-	// Run generate for each type.
-	for _, typeName := range types {
-		g.generate(typeName)
+	if len(typeNames) == 0 {
+		flag.Usage()
+		os.Exit(2)
 	}
-}
 
-func isDirectory(name string) bool {
-	info, err := os.Stat(name)
+	g, err := gen.Load(tags(buildTags), flag.Args()...)
 	if err != nil {
 		log.Fatal(err)
 	}
-	return info.IsDir()
-}
-
-// prefixDirectory places the directory name on the beginning of each name in the list.
-func prefixDirectory(directory string, names []string) []string {
-	if directory == "." {
-		return names
-	}
-	ret := make([]string, len(names))
-	for i, name := range names {
-		ret[i] = filepath.Join(directory, name)
+	if !g.Write(typeList(typeNames), opts) {
+		os.Exit(1)
 	}
-	return ret
-}
-
-type File struct {
-	name string    // Name of the constant type.
-	file *ast.File // Parsed AST.
-}
-
-type Generator struct {
-	buf   bytes.Buffer // Accumulated output.
-	files []*File
 }
 
-func (g *Generator) Printf(format string, args ...interface{}) {
-	fmt.Fprintf(&g.buf, format, args...)
-}
-
-func (g *Generator) parsePackageDir(directory string) {
-	pkg, err := build.Default.ImportDir(directory, 0)
-	if err != nil {
-		log.Fatalf("cannot process directory %s: %s", directory, err)
-	}
-	var names []string
-	names = append(names, pkg.GoFiles...)
-	names = append(names, pkg.CgoFiles...)
-	names = append(names, pkg.SFiles...)
-	names = prefixDirectory(directory, names)
-
-	g.parsePackage(directory, names, nil)
-}
-
-func (g *Generator) parsePackageFiles(names []string) {
-	g.parsePackage(".", names, nil)
-}
-
-func (g *Generator) parsePackage(directory string, names []string, text interface{}) {
-	var files []*File
-	fs := token.NewFileSet()
-	for _, name := range names {
-		if !strings.HasSuffix(name, ".go") {
-			continue
-		}
-		parsedFile, err := parser.ParseFile(fs, name, text, 0)
-		if err != nil {
-			log.Fatalf("parsing package: %s: %s", name, err)
+// typeList returns the types of -t. A type given twice is generated once,
+// in the order of its first occurrence.
+func typeList(typeNames string) []string {
+	var types []string
+	seen := map[string]bool{}
+	for _, typeName := range strings.Split(typeNames, ",") {
+		if !seen[typeName] {
+			seen[typeName] = true
+			types = append(types, typeName)
 		}
-
-		files = append(files, &File{
-			file: parsedFile,
-			name: name,
-		})
 	}
-	if len(files) == 0 {
-		log.Fatalf("%s: no buildable Go files", directory)
-	}
-
-	g.files = files
+	return types
 }
 
-func (g *Generator) format() []byte {
-	src, err := format.Source(g.buf.Bytes())
-	if err != nil {
-		// Should never happen, but can arise when developing this code.
-		// The user can compile the output to see the error.
-		log.Printf("warning: internal error: invalid Go generated: %s", err)
-		log.Printf("warning: compile the package to analyze the error")
-		return g.buf.Bytes()
+// tags returns the build tags of -tags.
+func tags(buildTags string) []string {
+	if buildTags == "" {
+		return nil
 	}
-	return src
-}
-
-func (g *Generator) ucFirst(s string) string {
-	return strings.ToUpper(s[:1]) + s[1:]
+	return strings.Split(buildTags, ",")
 }
 
-func (g *Generator) lcFirst(s string) string {
-	return strings.ToLower(s[:1]) + s[1:]
-}
-
-func (g *Generator) getFileByTypeName(typeName string) *File {
-	for _, f := range g.files {
-		for _, d := range f.file.Decls {
-			if gd, ok := d.(*ast.GenDecl); ok {
-				for _, s := range gd.Specs {
-					if tp, ok := s.(*ast.TypeSpec); ok {
-						if tp.Name.Name == typeName {
-							return f
-						}
-					}
-				}
-			}
+// command returns the command line printed in the header of the generated
+// files, without -check which compares the files generated without it, and
+// without the package patterns, replaced by the flags of each package.
+func command() string {
+	var args []string
+	for _, arg := range os.Args[1:] {
+		switch strings.TrimLeft(arg, "-") {
+		case "check", "check=true", "check=1":
+			continue
 		}
-	}
-	return nil
-}
-
-// generate repository for the named type.
-func (g *Generator) generate(typeName string) {
-	f := g.getFileByTypeName(typeName)
-	if f != nil {
-		repoName := g.lcFirst(typeName) + "BaseRepo"
-		repoNameRecv := "*" + repoName
-		typeNameWithPointer := "*" + typeName
-
-		// Print the header and package clause.
-		g.Printf("// Code generated by \"gormrepogen %s\"; DO NOT EDIT\n", strings.Join(os.Args[1:], " "))
-		g.Printf("\n")
-		g.Printf("package %s", f.file.Name.Name)
-		g.Printf("\n")
-		g.Printf("import (\n")
-		g.Printf("  \"github.com/l-vitaly/gormrepo\"\n")
-        g.Printf("  \"github.com/jinzhu/gorm\"\n")
-		g.Printf(")\n")
-
-		g.Printf(baseRepo, repoName)
-		g.Printf(repoApplyCriteria, repoNameRecv)
-        g.Printf(repoRelated, repoNameRecv, typeNameWithPointer)
-		g.Printf(repoGet, repoNameRecv, typeNameWithPointer, typeName)
-		g.Printf(repoGetAll, repoNameRecv, typeNameWithPointer)
-		g.Printf(repoGetBy, repoNameRecv, typeNameWithPointer)
-		g.Printf(repoGetByFirst, repoNameRecv, typeNameWithPointer, typeName)
-		g.Printf(repoGetByLast, repoNameRecv, typeNameWithPointer, typeName)
-		g.Printf(repoCreate, repoNameRecv, typeName, typeNameWithPointer)
-		g.Printf(repoUpdate, repoNameRecv, typeNameWithPointer)
-        g.Printf(repoDelete, repoNameRecv, typeNameWithPointer)
-		g.Printf(repoAutomigrate, repoNameRecv, typeName)
-        g.Printf(repoAddUniqueIndex, repoNameRecv, typeName)
-        g.Printf(repoAddForeignKey, repoNameRecv, typeName)
-        g.Printf(repoAddIndex, repoNameRecv, typeName)
-
-		//Format the output.
-		src := g.format()
-
-		absPath, _ := filepath.Abs(f.name)
-		dir := filepath.Dir(absPath)
-
-		baseName := fmt.Sprintf("%s_base_repo.go", typeName)
-		outputName := filepath.Join(dir, strings.ToLower(baseName))
-
-		err := ioutil.WriteFile(outputName, src, 0644)
-		if err != nil {
-			log.Fatalf("writing output: %s", err)
+		if isPattern(arg) {
+			continue
 		}
-
-		fmt.Printf("Type %s repository is generated: %s\n", typeName, outputName)
-
-		g.buf.Reset()
-	} else {
-		fmt.Printf("Type %s is not found\n", typeName)
+		args = append(args, arg)
 	}
+	return strings.Join(append([]string{"gormrepogen"}, args...), " ")
 }
-
-const baseRepo = `
-type %[1]s struct {
-    *gorm.DB
-}`
-
-const repoApplyCriteria = `
-func (r %[1]s) applyCriteria(criteria []gormrepo.CriteriaOption) *gorm.DB {
-	search := r.DB
-	for _, co := range criteria {
-		search = co(search)
-	}
-	return search
-}
-`
-
-const repoRelated = `
-func (r %[1]s) Related(claim %[2]s, related interface{}, criteria ...gormrepo.CriteriaOption) error {
-	return r.applyCriteria(criteria).Model(claim).Related(related).Error
-}
-`
-
-const repoGet = `
-func (r %[1]s) Get(id uint) (%[2]s, error) {
-    var entity %[3]s
-	err := r.DB.Where(map[string]interface{}{"id": id}).Find(&entity).Error
-	return &entity, err
-}`
-
-const repoGetAll = `
-func (r %[1]s) GetAll() ([]%[2]s, error) {
-    return r.GetBy()
-}
-`
-
-const repoGetBy = `
-func (r %[1]s) GetBy(criteria ...gormrepo.CriteriaOption) ([]%[2]s, error) {
-    var entities []%[2]s
-	err := r.applyCriteria(criteria).Find(&entities).Error
-	return entities, err
-}
-`
-
-const repoGetByFirst = `
-func (r %[1]s) GetByFirst(criteria ...gormrepo.CriteriaOption) (%[2]s, error) {
-    var entity %[3]s
-	err := r.applyCriteria(criteria).First(&entity).Error
-	return &entity, err
-}
-`
-
-const repoGetByLast = `
-func (r %[1]s) GetByLast(criteria ...gormrepo.CriteriaOption) (%[2]s, error) {
-    var entity %[3]s
-	err := r.applyCriteria(criteria).Last(&entity).Error
-	return &entity, err
-}
-`
-
-const repoCreate = `
-func (r %[1]s) Create(entity %[2]s) (%[3]s, error) {
-    if !r.DB.NewRecord(entity) {
-		return nil, gormrepo.ErrPrimaryNotBlank
-	}
-	err := r.DB.Create(&entity).Error
-	if err != nil {
-		return nil, err
-	}
-	return &entity, nil
-}
-`
-
-const repoUpdate = `
-func (r %[1]s) Update(entity %[2]s, fields gormrepo.Fields, criteria ...gormrepo.CriteriaOption) error {
-    return r.applyCriteria(criteria).Model(entity).Updates(fields).Error
-}
-`
-
-const repoDelete = `
-func (r %[1]s) Delete(entity %[2]s, criteria ...gormrepo.CriteriaOption) error {
-	return r.applyCriteria(criteria).Delete(entity).Error
-}
-`
-
-const repoAutomigrate = `
-func (r %[1]s) AutoMigrate() error {
-    return r.DB.AutoMigrate(&%[2]s{}).Error
-}
-`
-
-const repoAddUniqueIndex = `
-func (r %[1]s) AddUniqueIndex(name string, columns ...string) error {
-    return r.DB.Model(&%[2]s{}).AddUniqueIndex(name, columns...).Error
-}
-`
-const repoAddForeignKey = `
-func (r %[1]s) AddForeignKey(field string, dest string, onDelete string, onUpdate string) error {
-    return r.DB.Model(&%[2]s{}).AddForeignKey(field, dest, onDelete, onUpdate).Error
-}
-`
-const repoAddIndex = `
-func (r %[1]s) AddIndex(name string, columns ...string) error {
-    return r.DB.Model(&%[2]s{}).AddIndex(name, columns...).Error
-}
-`
@@ -0,0 +1,18 @@
+// Gormrepolint reports the database access bypassing the repositories
+// generated by gormrepogen, for CI:
+//
+//	gormrepolint ./...
+//
+// or as a vet tool:
+//
+//	go vet -vettool=$(which gormrepolint) ./...
+package main
+
+import (
+	"github.com/l-vitaly/gormrepo/lint"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(lint.Analyzer)
+}
@@ -0,0 +1,75 @@
+package gormrepo
+
+import (
+	"regexp"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	collationName = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+	// pgCollationName also matches the ICU and libc collation names of
+	// Postgres, such as "und-u-ks-level1" or "en_US.utf8".
+	pgCollationName = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+)
+
+// Collated is a column compared and sorted with an explicit collation, such as
+// "und-u-ks-level1" on Postgres or "utf8mb4_0900_ai_ci" on MySQL for case and
+// accent insensitive lookups.
+type Collated struct {
+	column    string
+	collation string
+}
+
+// Collate returns the column with the collation.
+func Collate(column, collation string) Collated {
+	return Collated{column: column, collation: collation}
+}
+
+// expr returns the column with its COLLATE clause for the dialect of db.
+// Postgres collation names are quoted identifiers, other dialects only accept
+// bare names. Names of other characters fail with ErrInvalidCollation.
+func (c Collated) expr(db *gorm.DB) (string, error) {
+	if db.Dialect().GetName() == "postgres" {
+		if !pgCollationName.MatchString(c.collation) {
+			return "", ErrInvalidCollation
+		}
+		return c.column + " COLLATE " + db.Dialect().Quote(c.collation), nil
+	}
+	if !collationName.MatchString(c.collation) {
+		return "", ErrInvalidCollation
+	}
+	return c.column + " COLLATE " + c.collation, nil
+}
+
+func (c Collated) where(name, op string, value interface{}) CriteriaOption {
+	return Described(Criterion{Op: name, Column: c.column, Args: []interface{}{c.collation, value}}, func(db *gorm.DB) *gorm.DB {
+		expr, err := c.expr(db)
+		if err != nil {
+			return withError(db, err)
+		}
+		return db.Where(expr+" "+op+" ?", value)
+	})
+}
+
+// Eq matches rows whose column equals value under the collation.
+func (c Collated) Eq(value interface{}) CriteriaOption {
+	return c.where("collate_eq", "=", value)
+}
+
+// Like matches rows whose column matches the LIKE pattern under the collation.
+// Postgres does not support LIKE with nondeterministic collations.
+func (c Collated) Like(pattern string) CriteriaOption {
+	return c.where("collate_like", "LIKE", pattern)
+}
+
+// Order sorts by the column under the collation.
+func (c Collated) Order(direction Direction) CriteriaOption {
+	return Described(Criterion{Op: "collate_order", Column: c.column, Args: []interface{}{c.collation, string(direction)}}, func(db *gorm.DB) *gorm.DB {
+		expr, err := c.expr(db)
+		if err != nil {
+			return withError(db, err)
+		}
+		return db.Order(expr + " " + string(direction))
+	})
+}
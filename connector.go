@@ -0,0 +1,161 @@
+package gormrepo
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+// dialNetworks numbers the networks of the dialers registered with the MySQL
+// driver.
+var dialNetworks int64
+
+// openConnector opens the database of a config with an auth token, a
+// credentials provider or a dialer, whose connections are opened by a
+// connector.
+func openConnector(c Config, dsn string) (*gorm.DB, error) {
+	conn := &connector{dialect: c.Dialect, dsn: dsn, authToken: c.AuthToken, dial: c.Dial}
+	if c.Credentials != nil {
+		refresh := c.CredentialsRefresh
+		if refresh <= 0 {
+			refresh = defaultCredentialsRefresh
+		}
+		conn.credentials = &rotatingCredentials{provider: c.Credentials, refresh: refresh}
+	}
+	switch c.Dialect {
+	case "postgres":
+		if _, err := pq.NewConnector(dsn); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+	case "mysql":
+		if _, err := mysql.ParseDSN(dsn); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+		if c.Dial != nil {
+			conn.network = fmt.Sprintf("gormrepo-dial-%d", atomic.AddInt64(&dialNetworks, 1))
+			mysql.RegisterDialContext(conn.network, func(ctx context.Context, addr string) (net.Conn, error) {
+				return c.Dial(ctx, "tcp", addr)
+			})
+		}
+	default:
+		return nil, ErrUnsupported
+	}
+	sqlDB := sql.OpenDB(conn)
+	db, err := gorm.Open(c.Dialect, sqlDB)
+	if err != nil {
+		sqlDB.Close()
+		return nil, err
+	}
+	return db, nil
+}
+
+// connector opens each connection with a fresh auth token as password or
+// the current credentials, and through the dialer, when set.
+type connector struct {
+	dialect     string
+	dsn         string
+	authToken   func(ctx context.Context) (string, error)
+	credentials *rotatingCredentials
+	dial        func(ctx context.Context, network, addr string) (net.Conn, error)
+	// network is the name of the dialer registered with the MySQL driver.
+	network string
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	var creds Credentials
+	generation := 0
+	if c.authToken != nil {
+		var err error
+		if creds.Password, err = c.authToken(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if c.credentials != nil {
+		var err error
+		if creds, generation, err = c.credentials.get(ctx); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := c.connect(ctx, creds)
+	if err != nil || c.credentials == nil {
+		return conn, err
+	}
+	return &rotatedConn{Conn: conn, credentials: c.credentials, generation: generation}, nil
+}
+
+// connect opens a connection with the credentials, those of the DSN when
+// empty.
+func (c *connector) connect(ctx context.Context, creds Credentials) (driver.Conn, error) {
+	password := c.authToken != nil || c.credentials != nil
+	if c.dialect == "mysql" {
+		cfg, err := mysql.ParseDSN(c.dsn)
+		if err != nil {
+			return nil, err
+		}
+		if creds.User != "" {
+			cfg.User = creds.User
+		}
+		if password {
+			cfg.Passwd = creds.Password
+		}
+		if c.authToken != nil {
+			// IAM tokens are sent as cleartext passwords, over TLS.
+			cfg.AllowCleartextPasswords = true
+		}
+		if c.network != "" {
+			cfg.Net = c.network
+		}
+		conn, err := mysql.NewConnector(cfg)
+		if err != nil {
+			return nil, err
+		}
+		return conn.Connect(ctx)
+	}
+	dsn := c.dsn
+	if creds.User != "" {
+		dsn = addDSNParams(dsn, [][2]string{{"user", creds.User}}, " ")
+	}
+	if password {
+		dsn = addDSNParams(dsn, [][2]string{{"password", creds.Password}}, " ")
+	}
+	conn, err := pq.NewConnector(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if c.dial != nil {
+		conn.Dialer(dialer(c.dial))
+	}
+	return conn.Connect(ctx)
+}
+
+func (c *connector) Driver() driver.Driver {
+	if c.dialect == "mysql" {
+		return mysql.MySQLDriver{}
+	}
+	return &pq.Driver{}
+}
+
+// dialer is a Postgres driver dialer of a dial function.
+type dialer func(ctx context.Context, network, addr string) (net.Conn, error)
+
+func (d dialer) Dial(network, addr string) (net.Conn, error) {
+	return d(context.Background(), network, addr)
+}
+
+func (d dialer) DialTimeout(network, addr string, timeout time.Duration) (net.Conn, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return d(ctx, network, addr)
+}
+
+func (d dialer) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	return d(ctx, network, addr)
+}
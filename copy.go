@@ -0,0 +1,134 @@
+package gormrepo
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/lib/pq"
+)
+
+// CopyBatchSize is the number of rows of the INSERT statements of CopyFrom
+// on the dialects without COPY.
+const CopyBatchSize = 1000
+
+// copyMaxParams are the numbers of bind parameters a statement takes, by
+// dialect, 65535 on the others.
+var copyMaxParams = map[string]int{
+	"sqlite3": 999,
+	"mssql":   2100,
+}
+
+// CopyFrom inserts the entities, a slice of pointers to a model, in bulk for
+// imports, in one transaction. Postgres streams the rows with COPY FROM
+// STDIN, the other dialects insert them with multi-row INSERT statements of
+// up to CopyBatchSize rows.
+//
+// Unlike Create, no callback, hook or association runs and the primary keys
+// are not read back. Blank primary keys are left to the database, the
+// entities with and without one being inserted by separate statements, and
+// blank CreatedAt and UpdatedAt fields are set to now.
+func CopyFrom(db *gorm.DB, entities interface{}) error {
+	rows := reflect.ValueOf(entities)
+	if rows.Len() == 0 {
+		return nil
+	}
+	// The rows are grouped by their columns, which leave out the blank
+	// primary keys, in the order of their first row.
+	var sets []string
+	columns := map[string][]string{}
+	values := map[string][][]interface{}{}
+	now := gorm.NowFunc()
+	for i := 0; i < rows.Len(); i++ {
+		s := db.NewScope(rows.Index(i).Interface())
+		var rowColumns []string
+		var row []interface{}
+		for _, field := range s.Fields() {
+			if !field.IsNormal || field.IsIgnored || (field.IsPrimaryKey && field.IsBlank) {
+				continue
+			}
+			value := field.Field.Interface()
+			if (field.Name == "CreatedAt" || field.Name == "UpdatedAt") && field.IsBlank {
+				value = now
+			}
+			rowColumns = append(rowColumns, field.DBName)
+			row = append(row, value)
+		}
+		set := strings.Join(rowColumns, ",")
+		if _, ok := columns[set]; !ok {
+			sets = append(sets, set)
+			columns[set] = rowColumns
+		}
+		values[set] = append(values[set], row)
+	}
+	scope := db.NewScope(rows.Index(0).Interface())
+	return Transaction(db, func(tx *gorm.DB) error {
+		for _, set := range sets {
+			var err error
+			if tx.Dialect().GetName() == "postgres" {
+				err = copyIn(tx, scope.TableName(), columns[set], values[set])
+			} else {
+				err = insertBatches(tx, scope.QuotedTableName(), columns[set], values[set])
+			}
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// copyIn streams the rows with the COPY protocol of lib/pq.
+func copyIn(tx *gorm.DB, table string, columns []string, values [][]interface{}) error {
+	stmt, err := tx.CommonDB().Prepare(pq.CopyIn(table, columns...))
+	if err != nil {
+		return err
+	}
+	for _, row := range values {
+		if _, err := stmt.Exec(row...); err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+	// The empty Exec flushes the buffered rows.
+	if _, err := stmt.Exec(); err != nil {
+		stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
+
+// insertBatches inserts the rows into the quoted table with multi-row INSERT
+// statements, within the bind parameter limit of the dialect.
+func insertBatches(tx *gorm.DB, table string, columns []string, values [][]interface{}) error {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = tx.Dialect().Quote(column)
+	}
+	maxParams, ok := copyMaxParams[tx.Dialect().GetName()]
+	if !ok {
+		maxParams = 65535
+	}
+	size := CopyBatchSize
+	if size*len(columns) > maxParams {
+		size = maxParams / len(columns)
+	}
+	insert := "INSERT INTO " + table + " (" + strings.Join(quoted, ", ") + ") VALUES "
+	row := "(" + strings.TrimSuffix(strings.Repeat("?, ", len(columns)), ", ") + ")"
+	for start := 0; start < len(values); start += size {
+		end := start + size
+		if end > len(values) {
+			end = len(values)
+		}
+		placeholders := make([]string, 0, end-start)
+		args := make([]interface{}, 0, (end-start)*len(columns))
+		for _, v := range values[start:end] {
+			placeholders = append(placeholders, row)
+			args = append(args, v...)
+		}
+		if err := tx.Exec(insert+strings.Join(placeholders, ", "), args...).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package gormrepo
+
+import "testing"
+
+type copyEntry struct {
+	ID   uint
+	Name string
+}
+
+func TestCopyFromMixedPrimaryKeys(t *testing.T) {
+	db := sqliteDB(t)
+	if err := db.AutoMigrate(&copyEntry{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	entries := []*copyEntry{{Name: "a"}, {ID: 10, Name: "b"}, {Name: "c"}, {ID: 20, Name: "d"}}
+	if err := CopyFrom(db, entries); err != nil {
+		t.Fatal(err)
+	}
+	var copied []copyEntry
+	if err := db.Order("name").Find(&copied).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(copied) != len(entries) {
+		t.Fatalf("copied %d entries, want %d", len(copied), len(entries))
+	}
+	ids := map[uint]bool{}
+	for i, entry := range copied {
+		if entry.Name != entries[i].Name {
+			t.Errorf("entry %d is %q, want %q", i, entry.Name, entries[i].Name)
+		}
+		if entries[i].ID != 0 && entry.ID != entries[i].ID {
+			t.Errorf("entry %q has id %d, want %d", entry.Name, entry.ID, entries[i].ID)
+		}
+		if entry.ID == 0 || ids[entry.ID] {
+			t.Errorf("entry %q has id %d, not assigned by the database", entry.Name, entry.ID)
+		}
+		ids[entry.ID] = true
+	}
+}
@@ -0,0 +1,151 @@
+package gormrepo
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"sync"
+	"time"
+)
+
+// Credentials are the user and password of the new connections of Open. An
+// empty user keeps the user of the DSN.
+type Credentials struct {
+	User     string
+	Password string
+}
+
+// CredentialsProvider returns the current credentials of the database, read
+// from Vault or a secrets manager for example, so rotated passwords are used
+// without restarting the service.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialsFunc is a CredentialsProvider function.
+type CredentialsFunc func(ctx context.Context) (Credentials, error)
+
+func (f CredentialsFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// defaultCredentialsRefresh is the interval at which the credentials are read
+// again when the config does not set one.
+const defaultCredentialsRefresh = time.Minute
+
+// rotatingCredentials caches the credentials of the provider, read again once
+// older than the refresh interval, and numbers each rotation.
+type rotatingCredentials struct {
+	provider CredentialsProvider
+	refresh  time.Duration
+
+	mu         sync.Mutex
+	current    Credentials
+	generation int
+	readAt     time.Time
+}
+
+// get returns the credentials and their generation. A provider failing after
+// a first read keeps the previous credentials, until the next refresh.
+func (r *rotatingCredentials) get(ctx context.Context) (Credentials, int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.generation > 0 && time.Since(r.readAt) < r.refresh {
+		return r.current, r.generation, nil
+	}
+	creds, err := r.provider.Credentials(ctx)
+	if err != nil {
+		if r.generation > 0 {
+			return r.current, r.generation, nil
+		}
+		return Credentials{}, 0, err
+	}
+	if r.generation == 0 || creds != r.current {
+		r.current = creds
+		r.generation++
+	}
+	r.readAt = time.Now()
+	return r.current, r.generation, nil
+}
+
+// generationChanged reports whether the credentials were rotated since the
+// generation, without reading them again.
+func (r *rotatingCredentials) generationChanged(generation int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.generation != generation
+}
+
+// rotatedConn is a connection opened with a generation of the credentials,
+// which the pool discards once the credentials are rotated instead of reusing
+// it. Its statements run to completion meanwhile.
+type rotatedConn struct {
+	driver.Conn
+	credentials *rotatingCredentials
+	generation  int
+}
+
+func (c *rotatedConn) ResetSession(ctx context.Context) error {
+	if _, generation, _ := c.credentials.get(ctx); generation != c.generation {
+		return driver.ErrBadConn
+	}
+	if resetter, ok := c.Conn.(driver.SessionResetter); ok {
+		return resetter.ResetSession(ctx)
+	}
+	return nil
+}
+
+func (c *rotatedConn) IsValid() bool {
+	if c.credentials.generationChanged(c.generation) {
+		return false
+	}
+	if validator, ok := c.Conn.(driver.Validator); ok {
+		return validator.IsValid()
+	}
+	return true
+}
+
+func (c *rotatedConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if beginner, ok := c.Conn.(driver.ConnBeginTx); ok {
+		return beginner.BeginTx(ctx, opts)
+	}
+	if opts.Isolation != driver.IsolationLevel(0) || opts.ReadOnly {
+		return nil, errors.New("driver does not support transaction options")
+	}
+	return c.Conn.Begin()
+}
+
+func (c *rotatedConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if preparer, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		return preparer.PrepareContext(ctx, query)
+	}
+	return c.Conn.Prepare(query)
+}
+
+func (c *rotatedConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if execer, ok := c.Conn.(driver.ExecerContext); ok {
+		return execer.ExecContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *rotatedConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if queryer, ok := c.Conn.(driver.QueryerContext); ok {
+		return queryer.QueryContext(ctx, query, args)
+	}
+	return nil, driver.ErrSkip
+}
+
+func (c *rotatedConn) Ping(ctx context.Context) error {
+	if pinger, ok := c.Conn.(driver.Pinger); ok {
+		return pinger.Ping(ctx)
+	}
+	return nil
+}
+
+func (c *rotatedConn) CheckNamedValue(v *driver.NamedValue) error {
+	if checker, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return checker.CheckNamedValue(v)
+	}
+	return driver.ErrSkip
+}
@@ -0,0 +1,397 @@
+package gormrepo
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Criterion describes a criteria option by its op, such as "and" or
+// "order_by", its column and its arguments, so the option can be logged,
+// hashed into a cache key and rebuilt with Option. Once decoded from JSON the
+// arguments are JSON values, which Option converts back.
+type Criterion struct {
+	Op     string        `json:"op"`
+	Column string        `json:"column,omitempty"`
+	Args   []interface{} `json:"args,omitempty"`
+}
+
+// describeKey is the setting of the probe db collecting the criteria of the
+// options applied to it.
+const describeKey = "gormrepo:describe"
+
+var (
+	probeOnce sync.Once
+	probe     *gorm.DB
+)
+
+// probeDB returns the db the options are applied to when described, which
+// never connects.
+func probeDB() *gorm.DB {
+	probeOnce.Do(func() {
+		db, err := gorm.Open("common", noConnection{})
+		if err != nil {
+			panic(err)
+		}
+		probe = db
+	})
+	return probe
+}
+
+// Described returns opt described by c. Criteria of other packages use it
+// for Describe and CriteriaKey to see through them, and RegisterCriterion to
+// rebuild them.
+func Described(c Criterion, opt CriteriaOption) CriteriaOption {
+	return func(db *gorm.DB) *gorm.DB {
+		if criteria, ok := db.Get(describeKey); ok {
+			*criteria.(*[]Criterion) = append(*criteria.(*[]Criterion), c)
+			return db
+		}
+		if _, ok := db.Get(allowListKey); ok {
+			if err := c.checkRawSQL(); err != nil {
+				return withError(db, err)
+			}
+		}
+		return opt(db)
+	}
+}
+
+// Describe returns the description of opt, and false when opt is a closure
+// not built by Described.
+func Describe(opt CriteriaOption) (Criterion, bool) {
+	var criteria []Criterion
+	opt(probeDB().Set(describeKey, &criteria))
+	if len(criteria) != 1 {
+		return Criterion{}, false
+	}
+	return criteria[0], true
+}
+
+// CriteriaKey returns a hash of the descriptions of the options, equal for
+// options of the same criteria, to key the cache of their results. It fails
+// with ErrOpaqueCriteria when an option has no description.
+func CriteriaKey(opts ...CriteriaOption) (string, error) {
+	criteria := make([]Criterion, len(opts))
+	for i, opt := range opts {
+		c, ok := Describe(opt)
+		if !ok {
+			return "", ErrOpaqueCriteria
+		}
+		criteria[i] = c
+	}
+	b, err := json.Marshal(criteria)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// compareOps are the ops of the descriptions of the comparisons, by SQL
+// operator.
+var compareOps = map[string]string{
+	"=":  "eq",
+	"<>": "ne",
+	">":  "gt",
+	">=": "gte",
+	"<":  "lt",
+	"<=": "lte",
+}
+
+var (
+	criteriaMu       sync.RWMutex
+	criteriaBuilders = map[string]func(Criterion) (CriteriaOption, error){}
+)
+
+// RegisterCriterion sets the builder of the options of op, which Option
+// calls with their description.
+func RegisterCriterion(op string, build func(Criterion) (CriteriaOption, error)) {
+	criteriaMu.Lock()
+	defer criteriaMu.Unlock()
+	criteriaBuilders[op] = build
+}
+
+// Option rebuilds the option described by c. It fails with
+// ErrInvalidCriterion when the op is not registered or the arguments do not
+// fit it.
+func (c Criterion) Option() (CriteriaOption, error) {
+	criteriaMu.RLock()
+	build, ok := criteriaBuilders[c.Op]
+	criteriaMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: unknown op %q", ErrInvalidCriterion, c.Op)
+	}
+	return build(c)
+}
+
+func init() {
+	for op, build := range map[string]func(Criterion) (CriteriaOption, error){
+		"and":    queryCriterion(And),
+		"or":     queryCriterion(Or),
+		"not":    queryCriterion(Not),
+		"select": queryCriterion(Select),
+		"having": queryCriterion(Having),
+		"group_by": func(c Criterion) (CriteriaOption, error) {
+			columns := make([]string, len(c.Args))
+			for i, arg := range c.Args {
+				column, ok := arg.(string)
+				if !ok {
+					return nil, c.invalid()
+				}
+				columns[i] = column
+			}
+			return GroupBy(columns...), nil
+		},
+		"joins": func(c Criterion) (CriteriaOption, error) {
+			var query string
+			if err := c.scan(&query); err != nil {
+				return nil, err
+			}
+			return Joins(query, c.Args[1:]...), nil
+		},
+		"raw": func(c Criterion) (CriteriaOption, error) {
+			var sql string
+			if err := c.scan(&sql); err != nil {
+				return nil, err
+			}
+			return Raw(sql, c.Args[1:]...), nil
+		},
+		"order_by": func(c Criterion) (CriteriaOption, error) {
+			var orientation string
+			var reorder bool
+			if err := c.scan(&orientation, &reorder); err != nil {
+				return nil, err
+			}
+			return OrderBy(c.Column, orientation, reorder), nil
+		},
+		"order": func(c Criterion) (CriteriaOption, error) {
+			var direction string
+			if err := c.scan(&direction); err != nil {
+				return nil, err
+			}
+			var o OrderOpts
+			for _, arg := range c.Args[1:] {
+				switch arg {
+				case "nulls_first":
+					o.NullsFirst = true
+				case "nulls_last":
+					o.NullsLast = true
+				case "case_insensitive":
+					o.CaseInsensitive = true
+				default:
+					return nil, c.invalid()
+				}
+			}
+			return Order(c.Column, Direction(direction), o), nil
+		},
+		"collate_eq": func(c Criterion) (CriteriaOption, error) {
+			var collation string
+			if err := c.scan(&collation); err != nil || len(c.Args) != 2 {
+				return nil, c.invalid()
+			}
+			return Collate(c.Column, collation).Eq(c.Args[1]), nil
+		},
+		"collate_like": func(c Criterion) (CriteriaOption, error) {
+			var collation, pattern string
+			if err := c.scan(&collation, &pattern); err != nil {
+				return nil, err
+			}
+			return Collate(c.Column, collation).Like(pattern), nil
+		},
+		"collate_order": func(c Criterion) (CriteriaOption, error) {
+			var collation, direction string
+			if err := c.scan(&collation, &direction); err != nil {
+				return nil, err
+			}
+			return Collate(c.Column, collation).Order(Direction(direction)), nil
+		},
+		"limit": func(c Criterion) (CriteriaOption, error) {
+			var limit int
+			if err := c.scan(&limit); err != nil {
+				return nil, err
+			}
+			return Limit(limit), nil
+		},
+		"offset": func(c Criterion) (CriteriaOption, error) {
+			var offset int
+			if err := c.scan(&offset); err != nil {
+				return nil, err
+			}
+			return Offset(offset), nil
+		},
+		"preload": func(c Criterion) (CriteriaOption, error) {
+			return Preload(c.Column), nil
+		},
+		"preload_where": func(c Criterion) (CriteriaOption, error) {
+			if len(c.Args) == 0 {
+				return nil, c.invalid()
+			}
+			return PreloadWhere(c.Column, c.Args[0], c.Args[1:]...), nil
+		},
+		"preload_all": func(c Criterion) (CriteriaOption, error) {
+			return PreloadAll(), nil
+		},
+		"lock_for_update": lockCriterion(LockForUpdate),
+		"lock_for_share":  lockCriterion(LockForShare),
+		"unscoped": func(c Criterion) (CriteriaOption, error) {
+			return Unscoped(), nil
+		},
+		"published": func(c Criterion) (CriteriaOption, error) {
+			var unpublish string
+			var now time.Time
+			if err := c.scan(&unpublish, &now); err != nil {
+				return nil, err
+			}
+			return Published(c.Column, unpublish, now), nil
+		},
+		"array_contains": func(c Criterion) (CriteriaOption, error) {
+			return ArrayContains(c.Column, c.Args...), nil
+		},
+		"array_overlaps": func(c Criterion) (CriteriaOption, error) {
+			return ArrayOverlaps(c.Column, c.Args...), nil
+		},
+		"any": func(c Criterion) (CriteriaOption, error) {
+			if len(c.Args) != 1 {
+				return nil, c.invalid()
+			}
+			return Any(c.Column, c.Args[0]), nil
+		},
+		"ilike_any": func(c Criterion) (CriteriaOption, error) {
+			if len(c.Args) == 0 {
+				return nil, c.invalid()
+			}
+			args := make([]string, len(c.Args))
+			for i, arg := range c.Args {
+				s, ok := arg.(string)
+				if !ok {
+					return nil, c.invalid()
+				}
+				args[i] = s
+			}
+			return ILikeAny(args[1:], args[0]), nil
+		},
+		"like":  likeCriterion(Like),
+		"ilike": likeCriterion(ILike),
+		"similar_to": func(c Criterion) (CriteriaOption, error) {
+			var term string
+			var threshold float64
+			if err := c.scan(&term, &threshold); err != nil {
+				return nil, err
+			}
+			return SimilarTo(c.Column, term, threshold), nil
+		},
+		"session_var": func(c Criterion) (CriteriaOption, error) {
+			var value string
+			if err := c.scan(&value); err != nil {
+				return nil, err
+			}
+			return WithSessionVar(c.Column, value), nil
+		},
+		"time_range": func(c Criterion) (CriteriaOption, error) {
+			var from, to time.Time
+			if err := c.scan(&from, &to); err != nil {
+				return nil, err
+			}
+			return TimeRange(c.Column, from, to, time.UTC), nil
+		},
+	} {
+		RegisterCriterion(op, build)
+	}
+}
+
+// queryCriterion builds the options of a query followed by its arguments.
+func queryCriterion(build func(query interface{}, args ...interface{}) CriteriaOption) func(Criterion) (CriteriaOption, error) {
+	return func(c Criterion) (CriteriaOption, error) {
+		if len(c.Args) == 0 {
+			return nil, c.invalid()
+		}
+		return build(c.Args[0], c.Args[1:]...), nil
+	}
+}
+
+// likeCriterion builds the options of Like and ILike.
+func likeCriterion(build func(column, text string, match ...LikeMatch) CriteriaOption) func(Criterion) (CriteriaOption, error) {
+	return func(c Criterion) (CriteriaOption, error) {
+		var text, match string
+		if err := c.scan(&text, &match); err != nil {
+			return nil, err
+		}
+		if _, ok := likePattern(text, LikeMatch(match)); !ok {
+			return nil, c.invalid()
+		}
+		return build(c.Column, text, LikeMatch(match)), nil
+	}
+}
+
+// lockCriterion builds the options of LockForUpdate and LockForShare.
+func lockCriterion(build func(wait ...LockWait) CriteriaOption) func(Criterion) (CriteriaOption, error) {
+	return func(c Criterion) (CriteriaOption, error) {
+		if len(c.Args) == 0 {
+			return build(), nil
+		}
+		var wait string
+		if err := c.scan(&wait); err != nil {
+			return nil, err
+		}
+		return build(LockWait(wait)), nil
+	}
+}
+
+func (c Criterion) invalid() error {
+	return fmt.Errorf("%w: arguments of %s", ErrInvalidCriterion, c.Op)
+}
+
+// scan stores the leading arguments into dest, pointers to string, bool, int,
+// float64 or time.Time, accepting the JSON values of the arguments.
+func (c Criterion) scan(dest ...interface{}) error {
+	if len(c.Args) < len(dest) {
+		return c.invalid()
+	}
+	for i, d := range dest {
+		arg := c.Args[i]
+		ok := true
+		switch d := d.(type) {
+		case *string:
+			*d, ok = arg.(string)
+		case *bool:
+			*d, ok = arg.(bool)
+		case *int:
+			switch v := arg.(type) {
+			case int:
+				*d = v
+			case float64:
+				*d, ok = int(v), v == float64(int(v))
+			default:
+				ok = false
+			}
+		case *float64:
+			switch v := arg.(type) {
+			case float64:
+				*d = v
+			case int:
+				*d = float64(v)
+			default:
+				ok = false
+			}
+		case *time.Time:
+			switch v := arg.(type) {
+			case time.Time:
+				*d = v
+			case string:
+				t, err := time.Parse(time.RFC3339Nano, v)
+				*d, ok = t, err == nil
+			default:
+				ok = false
+			}
+		}
+		if !ok {
+			return c.invalid()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package gormrepo
+
+import (
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// EntityDBs binds the entities of a generated Registry, by type name, to the
+// databases holding them, such as the users on one database and the
+// invoices on a billing one. The entities not listed use Default.
+type EntityDBs struct {
+	Default  *gorm.DB
+	Entities map[string]*gorm.DB
+}
+
+// DB returns the database of the entity.
+func (d EntityDBs) DB(entity string) *gorm.DB {
+	if db, ok := d.Entities[entity]; ok {
+		return db
+	}
+	return d.Default
+}
+
+// Check fails with ErrInvalidConfig when an entity listed is not one of the
+// entities, or one of the entities has no database.
+func (d EntityDBs) Check(entities ...string) error {
+	known := make(map[string]bool, len(entities))
+	for _, entity := range entities {
+		known[entity] = true
+		if d.DB(entity) == nil {
+			return fmt.Errorf("%w: no database for %s", ErrInvalidConfig, entity)
+		}
+	}
+	for entity := range d.Entities {
+		if !known[entity] {
+			return fmt.Errorf("%w: unknown entity %s", ErrInvalidConfig, entity)
+		}
+	}
+	return nil
+}
+
+// CrossDatabase returns a db failing with ErrCrossDatabaseTx, for the
+// repositories of the entities a transaction of another database would not
+// cover.
+func CrossDatabase(db *gorm.DB) *gorm.DB {
+	return withError(db, ErrCrossDatabaseTx)
+}
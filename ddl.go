@@ -0,0 +1,247 @@
+package gormrepo
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// noConnection is the database of DDL, which never connects.
+type noConnection struct{}
+
+var errNoConnection = errors.New("no database connection")
+
+func (noConnection) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return nil, errNoConnection
+}
+
+func (noConnection) Prepare(query string) (*sql.Stmt, error) {
+	return nil, errNoConnection
+}
+
+func (noConnection) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, errNoConnection
+}
+
+func (noConnection) QueryRow(query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+// ddlForeignKey is a foreign key constraint of the DDL.
+type ddlForeignKey struct {
+	table, refTable     string
+	columns, refColumns []string
+}
+
+var plainColumn = regexp.MustCompile(`^\w+$`)
+
+// DDL returns the CREATE TABLE and CREATE INDEX statements AutoMigrate runs
+// for the models on the dialect, "postgres", "mysql" or "sqlite3", with the
+// join tables of many to many relations and the foreign keys of the
+// relations between the models, without connecting to a database. Foreign
+// keys are declared in the tables on SQLite and added after them elsewhere.
+func DDL(dialect string, models ...interface{}) (string, error) {
+	if _, ok := gorm.GetDialect(dialect); !ok {
+		return "", ErrUnsupported
+	}
+	db, err := gorm.Open(dialect, noConnection{})
+	if err != nil {
+		return "", err
+	}
+	inline := dialect == "sqlite3"
+
+	tables := map[string]bool{}
+	for _, model := range models {
+		tables[db.NewScope(model).TableName()] = true
+	}
+	var creates, indexes []string
+	var foreignKeys []ddlForeignKey
+	var joins []string // Join tables, in order.
+	joinDefs := map[string][]string{}
+	for _, model := range models {
+		scope := db.NewScope(model)
+		for _, field := range scope.GetModelStruct().StructFields {
+			rel := field.Relationship
+			if rel == nil {
+				continue
+			}
+			refScope := db.NewScope(reflect.New(elemType(field.Struct.Type)).Interface())
+			switch rel.Kind {
+			case "belongs_to":
+				foreignKeys = append(foreignKeys, ddlForeignKey{scope.TableName(), refScope.TableName(), rel.ForeignDBNames, rel.AssociationForeignDBNames})
+			case "has_one", "has_many":
+				foreignKeys = append(foreignKeys, ddlForeignKey{refScope.TableName(), scope.TableName(), rel.ForeignDBNames, rel.AssociationForeignDBNames})
+			case "many_to_many":
+				join := rel.JoinTableHandler.Table(db)
+				if joinDefs[join] != nil || !tables[refScope.TableName()] {
+					continue
+				}
+				defs, keys := joinTable(scope, refScope, rel)
+				joins, joinDefs[join], tables[join] = append(joins, join), defs, true
+				foreignKeys = append(foreignKeys, keys...)
+			}
+		}
+	}
+	// Foreign keys are kept between the tables of the DDL only.
+	var kept []ddlForeignKey
+	seen := map[string]bool{}
+	for _, fk := range foreignKeys {
+		key := fk.table + "." + strings.Join(fk.columns, ",")
+		if tables[fk.table] && tables[fk.refTable] && !seen[key] {
+			seen[key] = true
+			kept = append(kept, fk)
+		}
+	}
+
+	quoteColumns := func(columns []string) string {
+		quoted := make([]string, len(columns))
+		for i, c := range columns {
+			if plainColumn.MatchString(c) {
+				c = db.Dialect().Quote(c)
+			}
+			quoted[i] = c
+		}
+		return strings.Join(quoted, ", ")
+	}
+	createTable := func(table string, defs []string) string {
+		if inline {
+			for _, fk := range kept {
+				if fk.table == table {
+					defs = append(defs, fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)", quoteColumns(fk.columns), db.Dialect().Quote(fk.refTable), quoteColumns(fk.refColumns)))
+				}
+			}
+		}
+		return fmt.Sprintf("CREATE TABLE %s (\n  %s\n)", db.Dialect().Quote(table), strings.Join(defs, ",\n  "))
+	}
+	for _, model := range models {
+		scope := db.NewScope(model)
+		var defs, primaryKeys []string
+		primaryInType := false
+		for _, field := range scope.GetModelStruct().StructFields {
+			if field.IsNormal {
+				sqlType := scope.Dialect().DataTypeOf(field)
+				primaryInType = primaryInType || strings.Contains(strings.ToLower(sqlType), "primary key")
+				defs = append(defs, scope.Quote(field.DBName)+" "+sqlType)
+			}
+			if field.IsPrimaryKey {
+				primaryKeys = append(primaryKeys, field.DBName)
+			}
+		}
+		if len(primaryKeys) > 0 && !primaryInType {
+			defs = append(defs, "PRIMARY KEY ("+quoteColumns(primaryKeys)+")")
+		}
+		creates = append(creates, createTable(scope.TableName(), defs))
+
+		for _, index := range modelIndexes(scope) {
+			create := "CREATE INDEX"
+			if index.unique {
+				create = "CREATE UNIQUE INDEX"
+			}
+			indexes = append(indexes, fmt.Sprintf("%s %s ON %s (%s)", create, index.name, scope.QuotedTableName(), quoteColumns(index.columns)))
+		}
+	}
+
+	for _, join := range joins {
+		creates = append(creates, createTable(join, joinDefs[join]))
+	}
+	statements := append(creates, indexes...)
+	if !inline {
+		for _, fk := range kept {
+			name := db.Dialect().BuildKeyName(fk.table, strings.Join(fk.columns, "_"), fk.refTable, "foreign")
+			statements = append(statements, fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s FOREIGN KEY (%s) REFERENCES %s (%s)",
+				db.Dialect().Quote(fk.table), db.Dialect().Quote(name), quoteColumns(fk.columns), db.Dialect().Quote(fk.refTable), quoteColumns(fk.refColumns)))
+		}
+	}
+	return strings.Join(statements, ";\n\n") + ";\n", nil
+}
+
+// elemType returns the struct type of a relation field.
+func elemType(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		t = t.Elem()
+	}
+	return t
+}
+
+// joinTable returns the column definitions of the join table of a many to
+// many relation, keyed by the foreign keys of both sides as AutoMigrate
+// creates it, and its foreign keys.
+func joinTable(scope, refScope *gorm.Scope, rel *gorm.Relationship) ([]string, []ddlForeignKey) {
+	table := rel.JoinTableHandler.Table(scope.NewDB())
+	var defs, primaryKeys []string
+	var keys []ddlForeignKey
+	sides := []struct {
+		scope             *gorm.Scope
+		fieldNames, names []string
+	}{
+		{scope, rel.ForeignFieldNames, rel.ForeignDBNames},
+		{refScope, rel.AssociationForeignFieldNames, rel.AssociationForeignDBNames},
+	}
+	for _, side := range sides {
+		fk := ddlForeignKey{table: table, refTable: side.scope.TableName()}
+		for i, fieldName := range side.fieldNames {
+			field, ok := side.scope.FieldByName(fieldName)
+			if !ok {
+				continue
+			}
+			column := &gorm.StructField{Name: field.Name, DBName: side.names[i], Struct: field.Struct, IsNormal: true}
+			defs = append(defs, scope.Quote(side.names[i])+" "+scope.Dialect().DataTypeOf(column))
+			primaryKeys = append(primaryKeys, scope.Quote(side.names[i]))
+			fk.columns = append(fk.columns, side.names[i])
+			fk.refColumns = append(fk.refColumns, field.DBName)
+		}
+		keys = append(keys, fk)
+	}
+	defs = append(defs, "PRIMARY KEY ("+strings.Join(primaryKeys, ", ")+")")
+	return defs, keys
+}
+
+// modelIndex is an index declared by the tags of a model.
+type modelIndex struct {
+	name    string
+	unique  bool
+	columns []string
+}
+
+// modelIndexes returns the indexes AutoMigrate creates for the index and
+// unique_index tags of the model, in name order.
+func modelIndexes(scope *gorm.Scope) []modelIndex {
+	byName := map[string]*modelIndex{}
+	var names []string
+	for _, field := range scope.GetStructFields() {
+		for _, tag := range []struct {
+			key, prefix string
+			unique      bool
+		}{{"INDEX", "idx", false}, {"UNIQUE_INDEX", "uix", true}} {
+			value, ok := field.TagSettingsGet(tag.key)
+			if !ok {
+				continue
+			}
+			for _, name := range strings.Split(value, ",") {
+				if name == tag.key || name == "" {
+					name = scope.Dialect().BuildKeyName(tag.prefix, scope.TableName(), field.DBName)
+				}
+				name, column := scope.Dialect().NormalizeIndexAndColumn(name, field.DBName)
+				index, ok := byName[name]
+				if !ok {
+					index = &modelIndex{name: name, unique: tag.unique}
+					byName[name] = index
+					names = append(names, name)
+				}
+				index.columns = append(index.columns, column)
+			}
+		}
+	}
+	sort.Strings(names)
+	result := make([]modelIndex, len(names))
+	for i, name := range names {
+		result[i] = *byName[name]
+	}
+	return result
+}
@@ -0,0 +1,70 @@
+package gormrepo
+
+import (
+	"errors"
+	"math/rand"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// IsDeadlock reports whether err, wrapped or not, is a deadlock or a
+// serialization failure, after which the transaction can be retried:
+// SQLSTATE 40P01 and 40001 on Postgres, errors 1205 and 3960 on SQL Server,
+// error 1213 on MySQL and a locked database on SQLite.
+func IsDeadlock(err error) bool {
+	if err == nil {
+		return false
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "40P01" || pqErr.Code == "40001"
+	}
+	var mssqlErr interface{ SQLErrorNumber() int32 }
+	if errors.As(err, &mssqlErr) {
+		n := mssqlErr.SQLErrorNumber()
+		return n == 1205 || n == 3960
+	}
+	// The MySQL and SQLite drivers are not dependencies, their messages are
+	// matched.
+	msg := err.Error()
+	return strings.Contains(msg, "Deadlock found when trying to get lock") ||
+		strings.Contains(msg, "database is locked") ||
+		strings.Contains(msg, "database table is locked")
+}
+
+// TxStats counts the deadlocks of the transactions run by Transaction.
+type TxStats struct {
+	Deadlocks int64 // Transactions failed with a deadlock, retried or not.
+	Retries   int64 // Transactions retried after a deadlock.
+}
+
+var txStats TxStats
+
+// TransactionStats returns the deadlock counters of Transaction since the
+// program started.
+func TransactionStats() TxStats {
+	return TxStats{
+		Deadlocks: atomic.LoadInt64(&txStats.Deadlocks),
+		Retries:   atomic.LoadInt64(&txStats.Retries),
+	}
+}
+
+// RetryDeadlocks runs the callback again, up to n times, when the
+// transaction fails with a deadlock, see IsDeadlock. The callback must be
+// safe to run again: its transaction was rolled back. Nested transactions do
+// not retry, the deadlock aborts the enclosing transaction, which retries
+// when it has the option.
+func RetryDeadlocks(n int) TxOption {
+	return func(o *txOptions) {
+		o.retries = n
+	}
+}
+
+// deadlockBackoff returns the pause before the retry after attempt, random
+// so that the transactions of the deadlock do not collide again.
+func deadlockBackoff(attempt int) time.Duration {
+	return time.Duration(rand.Int63n(int64(attempt+1) * int64(10*time.Millisecond)))
+}
@@ -0,0 +1,88 @@
+package gormrepo
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// DebeziumServerName is the logical server name reported in the source block
+// of Debezium envelopes, the topic prefix of Debezium connectors.
+var DebeziumServerName = "gormrepo"
+
+// DebeziumSource is the source block of a Debezium envelope.
+type DebeziumSource struct {
+	Version   string `json:"version"`
+	Connector string `json:"connector"`
+	Name      string `json:"name"`
+	TsMs      int64  `json:"ts_ms"`
+	Snapshot  string `json:"snapshot"`
+	Table     string `json:"table"`
+}
+
+// DebeziumEnvelope is a change event in the Debezium envelope format, as
+// produced with schemas disabled, so consumers written for Debezium topics
+// accept the application change events unchanged. Rows are keyed by column,
+// times are ISO-8601 strings in UTC like Debezium's ZonedTimestamp.
+type DebeziumEnvelope struct {
+	Before map[string]interface{} `json:"before"`
+	After  map[string]interface{} `json:"after"`
+	Source DebeziumSource         `json:"source"`
+	Op     string                 `json:"op"`
+	TsMs   int64                  `json:"ts_ms"`
+}
+
+var debeziumOps = map[Operation]string{OpCreate: "c", OpUpdate: "u", OpDelete: "d"}
+
+// NewDebeziumEnvelope builds the envelope of a change, old and new being nil
+// or pointers to the entity as in the generated <T>Changed events.
+func NewDebeziumEnvelope(db *gorm.DB, op Operation, old, new interface{}) DebeziumEnvelope {
+	now := time.Now().UnixNano() / int64(time.Millisecond)
+	entity := new
+	if isNilEntity(new) {
+		entity = old
+	}
+	var table string
+	if !isNilEntity(entity) {
+		table = db.NewScope(entity).TableName()
+	}
+	return DebeziumEnvelope{
+		Before: debeziumRow(db, old),
+		After:  debeziumRow(db, new),
+		Source: DebeziumSource{
+			Version:   "gormrepo",
+			Connector: db.Dialect().GetName(),
+			Name:      DebeziumServerName,
+			TsMs:      now,
+			Snapshot:  "false",
+			Table:     table,
+		},
+		Op:   debeziumOps[op],
+		TsMs: now,
+	}
+}
+
+func debeziumRow(db *gorm.DB, entity interface{}) map[string]interface{} {
+	if isNilEntity(entity) {
+		return nil
+	}
+	row := map[string]interface{}{}
+	for _, field := range db.NewScope(entity).Fields() {
+		if !field.IsNormal {
+			continue
+		}
+		value := field.Field.Interface()
+		switch v := value.(type) {
+		case time.Time:
+			value = v.UTC().Format(time.RFC3339Nano)
+		case *time.Time:
+			if v == nil {
+				value = nil
+			} else {
+				value = v.UTC().Format(time.RFC3339Nano)
+			}
+		}
+		row[field.DBName] = value
+	}
+	return row
+}
@@ -0,0 +1,69 @@
+package gormrepo
+
+import (
+	"reflect"
+
+	"github.com/jinzhu/gorm"
+)
+
+// DeleteReturning deletes the rows matching the criteria and scans them, as
+// they were before the delete, into dest, a pointer to a slice of the model,
+// for the events of the deletion. Models with a DeletedAt field are soft
+// deleted unless the criteria are unscoped.
+//
+// Postgres deletes and returns the rows in one statement with RETURNING *.
+// Other dialects select the rows and delete them by primary key, in a
+// transaction.
+func DeleteReturning(db *gorm.DB, dest interface{}, criteria ...CriteriaOption) error {
+	scope := db.NewScope(dest)
+	key := scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())
+	if db.Dialect().GetName() != "postgres" {
+		return Transaction(db, func(tx *gorm.DB) error {
+			return deleteFound(tx, dest, key, criteria)
+		})
+	}
+	query := ApplyCriteria(db, criteria...).Model(dest)
+	if query.Error != nil {
+		return query.Error
+	}
+	ids := query.Select(key).QueryExpr()
+	if deletedAt, ok := scope.FieldByName("DeletedAt"); ok && !query.NewScope(dest).Search.Unscoped {
+		return db.Raw("UPDATE "+scope.QuotedTableName()+" SET "+scope.Quote(deletedAt.DBName)+" = ? WHERE "+key+" IN (?) RETURNING *", gorm.NowFunc(), ids).Scan(dest).Error
+	}
+	return db.Raw("DELETE FROM "+scope.QuotedTableName()+" WHERE "+key+" IN (?) RETURNING *", ids).Scan(dest).Error
+}
+
+// deleteFound finds the rows matching the criteria into dest and deletes them
+// by the primary key column key.
+func deleteFound(tx *gorm.DB, dest interface{}, key string, criteria []CriteriaOption) error {
+	query := ApplyCriteria(tx, criteria...)
+	if err := query.Find(dest).Error; err != nil {
+		return err
+	}
+	rows := reflect.Indirect(reflect.ValueOf(dest))
+	if rows.Len() == 0 {
+		return nil
+	}
+	ids := make([]interface{}, rows.Len())
+	for i := range ids {
+		ids[i] = tx.NewScope(rows.Index(i).Interface()).PrimaryKeyValue()
+	}
+	model := rows.Type().Elem()
+	for model.Kind() == reflect.Ptr {
+		model = model.Elem()
+	}
+	del := tx.Where(key+" IN (?)", ids)
+	if query.NewScope(dest).Search.Unscoped {
+		del = del.Unscoped()
+	}
+	return del.Delete(reflect.New(model).Interface()).Error
+}
+
+// SoftDeletes reports whether deleting the model through db sets its
+// DeletedAt field instead of deleting the row, as gorm does unless db is
+// unscoped.
+func SoftDeletes(db *gorm.DB, model interface{}) bool {
+	scope := db.NewScope(model)
+	_, ok := scope.FieldByName("DeletedAt")
+	return ok && !scope.Search.Unscoped
+}
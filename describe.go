@@ -0,0 +1,407 @@
+package gormrepo
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DescribeCriteria returns the query the options compose, in SQL order, with
+// the arguments inlined, such as
+//
+//	WHERE (status = 'open') AND age > 18 ORDER BY created_at desc LIMIT 20
+//
+// for logs and error messages. Nothing is executed: the string is not meant
+// to be run, and the options without a description show as <opaque>.
+func DescribeCriteria(opts ...CriteriaOption) string {
+	var d queryDescription
+	for _, opt := range opts {
+		c, ok := Describe(opt)
+		if !ok {
+			d.other = append(d.other, "<opaque>")
+			continue
+		}
+		d.add(c)
+	}
+	return d.String()
+}
+
+// String returns the criterion as DescribeCriteria shows it.
+func (c Criterion) String() string {
+	var d queryDescription
+	d.add(c)
+	return d.String()
+}
+
+// queryDescription collects the clauses of the described criteria.
+type queryDescription struct {
+	isolation   string
+	sessionVars []string
+	raw         string
+	selects     []string
+	joins       []string
+	where       []string
+	or          []string
+	group       []string
+	having      []string
+	order       []string
+	limit       string
+	offset      string
+	lock        string
+	preload     []string
+	other       []string
+}
+
+func (d *queryDescription) add(c Criterion) {
+	arg := func(i int) string {
+		if i < len(c.Args) {
+			return describeArg(c.Args[i])
+		}
+		return "?"
+	}
+	switch c.Op {
+	case "and", "or", "not":
+		if len(c.Args) == 0 {
+			break
+		}
+		condition := "(" + describeQuery(c.Args[0], c.Args[1:]) + ")"
+		switch c.Op {
+		case "and":
+			d.where = append(d.where, condition)
+		case "or":
+			d.or = append(d.or, condition)
+		default:
+			d.where = append(d.where, "NOT "+condition)
+		}
+		return
+	case "select":
+		if len(c.Args) > 0 {
+			d.selects = append(d.selects, describeQuery(c.Args[0], c.Args[1:]))
+			return
+		}
+	case "raw":
+		if len(c.Args) > 0 {
+			d.raw = describeQuery(c.Args[0], c.Args[1:])
+			return
+		}
+	case "joins":
+		if len(c.Args) > 0 {
+			d.joins = append(d.joins, describeQuery(c.Args[0], c.Args[1:]))
+			return
+		}
+	case "group_by":
+		for i := range c.Args {
+			d.group = append(d.group, describeValue(c.Args, i))
+		}
+		return
+	case "having":
+		if len(c.Args) > 0 {
+			d.having = append(d.having, "("+describeQuery(c.Args[0], c.Args[1:])+")")
+			return
+		}
+	case "eq", "ne", "gt", "gte", "lt", "lte":
+		op := ""
+		for sqlOp, name := range compareOps {
+			if name == c.Op {
+				op = sqlOp
+			}
+		}
+		d.where = append(d.where, c.Column+" "+op+" "+arg(0))
+		return
+	case "eq_or_null":
+		if len(c.Args) > 0 && c.Args[0] == nil {
+			d.where = append(d.where, c.Column+" IS NULL")
+		} else {
+			d.where = append(d.where, c.Column+" = "+arg(0))
+		}
+		return
+	case "in", "not_in":
+		op := " IN ("
+		if c.Op == "not_in" {
+			op = " NOT IN ("
+		}
+		d.where = append(d.where, c.Column+op+describeArg(c.Args)+")")
+		return
+	case "between", "not_between":
+		op := " BETWEEN "
+		if c.Op == "not_between" {
+			op = " NOT BETWEEN "
+		}
+		d.where = append(d.where, c.Column+op+arg(0)+" AND "+arg(1))
+		return
+	case "collate_eq", "collate_like":
+		op := "="
+		if c.Op == "collate_like" {
+			op = "LIKE"
+		}
+		d.where = append(d.where, c.Column+" COLLATE "+describeValue(c.Args, 0)+" "+op+" "+arg(1))
+		return
+	case "time_range":
+		for i, op := range []string{">=", "<"} {
+			if t, ok := argTime(c.Args, i); ok && !t.IsZero() {
+				d.where = append(d.where, c.Column+" "+op+" "+describeArg(t))
+			}
+		}
+		return
+	case "published":
+		now := arg(1)
+		d.where = append(d.where, c.Column+" IS NOT NULL AND "+c.Column+" <= "+now)
+		if unpublish := describeValue(c.Args, 0); unpublish != "" {
+			d.where = append(d.where, "("+unpublish+" IS NULL OR "+unpublish+" > "+now+")")
+		}
+		return
+	case "array_contains", "array_overlaps":
+		op := "@>"
+		if c.Op == "array_overlaps" {
+			op = "&&"
+		}
+		d.where = append(d.where, c.Column+" "+op+" ARRAY["+describeArg(c.Args)+"]")
+		return
+	case "any":
+		d.where = append(d.where, arg(0)+" = ANY("+c.Column+")")
+		return
+	case "like", "ilike":
+		pattern, _ := likePattern(describeValue(c.Args, 0), LikeMatch(describeValue(c.Args, 1)))
+		d.where = append(d.where, c.Column+" "+strings.ToUpper(c.Op)+" "+describeArg(pattern))
+		return
+	case "ilike_any":
+		if len(c.Args) < 2 {
+			return
+		}
+		conditions := make([]string, len(c.Args)-1)
+		for i := range conditions {
+			conditions[i] = describeValue(c.Args, i+1) + " ILIKE " + describeArg("%"+describeValue(c.Args, 0)+"%")
+		}
+		d.where = append(d.where, "("+strings.Join(conditions, " OR ")+")")
+		return
+	case "similar_to":
+		similarity := "similarity(" + c.Column + ", " + arg(0) + ")"
+		d.where = append(d.where, similarity+" >= "+arg(1))
+		d.order = append(d.order, similarity+" DESC")
+		return
+	case "order_by":
+		if reorder, _ := argValue(c.Args, 1).(bool); reorder {
+			d.order = nil
+		}
+		d.order = append(d.order, c.Column+" "+describeValue(c.Args, 0))
+		return
+	case "order":
+		order := c.Column
+		var flags []string
+		for i := 1; i < len(c.Args); i++ {
+			switch flag := describeValue(c.Args, i); flag {
+			case "case_insensitive":
+				order = "LOWER(" + order + ")"
+			case "nulls_first", "nulls_last":
+				flags = append(flags, strings.ToUpper(strings.Replace(flag, "_", " ", 1)))
+			}
+		}
+		d.order = append(d.order, strings.Join(append([]string{order, describeValue(c.Args, 0)}, flags...), " "))
+		return
+	case "collate_order":
+		d.order = append(d.order, c.Column+" COLLATE "+describeValue(c.Args, 0)+" "+describeValue(c.Args, 1))
+		return
+	case "limit":
+		d.limit = describeValue(c.Args, 0)
+		return
+	case "offset":
+		d.offset = describeValue(c.Args, 0)
+		return
+	case "preload":
+		d.preload = append(d.preload, c.Column)
+		return
+	case "preload_where":
+		if len(c.Args) > 0 {
+			d.preload = append(d.preload, c.Column+" ("+describeQuery(c.Args[0], c.Args[1:])+")")
+			return
+		}
+	case "preload_all":
+		d.preload = append(d.preload, "*")
+		return
+	case "lock_for_update", "lock_for_share":
+		d.lock = "FOR UPDATE"
+		if c.Op == "lock_for_share" {
+			d.lock = "FOR SHARE"
+		}
+		if len(c.Args) > 0 {
+			d.lock += " " + strings.ToUpper(strings.Replace(describeValue(c.Args, 0), "_", " ", 1))
+		}
+		return
+	case "read_isolation":
+		var level int
+		if c.scan(&level) == nil {
+			d.isolation = isolationNames[sql.IsolationLevel(level)]
+		}
+		return
+	case "session_var":
+		d.sessionVars = append(d.sessionVars, c.Column+" = "+arg(0))
+		return
+	}
+	args := make([]string, len(c.Args))
+	for i, a := range c.Args {
+		args[i] = describeArg(a)
+	}
+	if c.Column != "" {
+		args = append([]string{c.Column}, args...)
+	}
+	d.other = append(d.other, c.Op+"("+strings.Join(args, ", ")+")")
+}
+
+func (d *queryDescription) String() string {
+	var clauses []string
+	if d.isolation != "" {
+		clauses = append(clauses, "SET TRANSACTION ISOLATION LEVEL "+d.isolation)
+	}
+	if len(d.sessionVars) > 0 {
+		clauses = append(clauses, "SET LOCAL "+strings.Join(d.sessionVars, ", "))
+	}
+	if d.raw != "" {
+		clauses = append(clauses, d.raw)
+	}
+	if len(d.selects) > 0 {
+		clauses = append(clauses, "SELECT "+strings.Join(d.selects, ", "))
+	}
+	if len(d.joins) > 0 {
+		clauses = append(clauses, d.joins...)
+	}
+	if len(d.where) > 0 || len(d.or) > 0 {
+		conditions := strings.Join(d.where, " AND ")
+		if len(d.or) > 0 {
+			if len(d.where) > 1 {
+				conditions = "(" + conditions + ")"
+			}
+			if conditions != "" {
+				conditions += " OR "
+			}
+			conditions += strings.Join(d.or, " OR ")
+		}
+		clauses = append(clauses, "WHERE "+conditions)
+	}
+	if len(d.group) > 0 {
+		clauses = append(clauses, "GROUP BY "+strings.Join(d.group, ", "))
+	}
+	if len(d.having) > 0 {
+		clauses = append(clauses, "HAVING "+strings.Join(d.having, " AND "))
+	}
+	if len(d.order) > 0 {
+		clauses = append(clauses, "ORDER BY "+strings.Join(d.order, ", "))
+	}
+	if d.limit != "" {
+		clauses = append(clauses, "LIMIT "+d.limit)
+	}
+	if d.offset != "" {
+		clauses = append(clauses, "OFFSET "+d.offset)
+	}
+	if d.lock != "" {
+		clauses = append(clauses, d.lock)
+	}
+	if len(d.preload) > 0 {
+		clauses = append(clauses, "PRELOAD "+strings.Join(d.preload, ", "))
+	}
+	return strings.Join(append(clauses, d.other...), " ")
+}
+
+// describeQuery returns the query with its ? placeholders replaced by the
+// arguments, and the conditions of a map or struct query.
+func describeQuery(query interface{}, args []interface{}) string {
+	s, ok := query.(string)
+	if !ok {
+		v := reflect.Indirect(reflect.ValueOf(query))
+		switch v.Kind() {
+		case reflect.Map:
+			keys := v.MapKeys()
+			sort.Slice(keys, func(i, j int) bool {
+				return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+			})
+			conditions := make([]string, len(keys))
+			for i, key := range keys {
+				conditions[i] = fmt.Sprint(key.Interface()) + " = " + describeArg(v.MapIndex(key).Interface())
+			}
+			return strings.Join(conditions, " AND ")
+		case reflect.Slice:
+			columns := make([]string, v.Len())
+			for i := range columns {
+				columns[i] = fmt.Sprint(v.Index(i).Interface())
+			}
+			return strings.Join(columns, ", ")
+		}
+		return fmt.Sprintf("%+v", query)
+	}
+	var b strings.Builder
+	for _, r := range s {
+		if r == '?' && len(args) > 0 {
+			b.WriteString(describeArg(args[0]))
+			args = args[1:]
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// describeArg returns the argument as an SQL literal, the values of a slice
+// separated by commas as gorm expands them.
+func describeArg(arg interface{}) string {
+	if valuer, ok := arg.(driver.Valuer); ok {
+		if value, err := valuer.Value(); err == nil {
+			arg = value
+		}
+	}
+	switch arg := arg.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.Replace(arg, "'", "''", -1) + "'"
+	case []byte:
+		return describeArg(string(arg))
+	case time.Time:
+		return describeArg(arg.Format(time.RFC3339Nano))
+	}
+	v := reflect.ValueOf(arg)
+	switch v.Kind() {
+	case reflect.Ptr:
+		if v.IsNil() {
+			return "NULL"
+		}
+		return describeArg(v.Elem().Interface())
+	case reflect.Slice, reflect.Array:
+		values := make([]string, v.Len())
+		for i := range values {
+			values[i] = describeArg(v.Index(i).Interface())
+		}
+		return strings.Join(values, ", ")
+	}
+	return fmt.Sprint(arg)
+}
+
+// argValue returns the argument i, nil when missing.
+func argValue(args []interface{}, i int) interface{} {
+	if i < len(args) {
+		return args[i]
+	}
+	return nil
+}
+
+// describeValue returns the argument i as text, "" when missing.
+func describeValue(args []interface{}, i int) string {
+	if i < len(args) {
+		return fmt.Sprint(args[i])
+	}
+	return ""
+}
+
+// argTime returns the argument i as a time, also once decoded from JSON.
+func argTime(args []interface{}, i int) (time.Time, bool) {
+	switch v := argValue(args, i).(type) {
+	case time.Time:
+		return v, true
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		return t, err == nil
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,140 @@
+package gormrepo
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Participant is one database taking part in a distributed transaction.
+type Participant struct {
+	DB *gorm.DB // Database the transaction was started on.
+	Tx *gorm.DB // Transaction running on DB.
+	// Prepared is set by the coordinator's Prepare once it ended Tx itself,
+	// as PREPARE TRANSACTION does, leaving its commit to the coordinator.
+	Prepared bool
+}
+
+// Coordinator is notified at each phase of a distributed transaction, so two
+// phase commit or saga frameworks can take part in the outcome.
+//
+// Prepare runs after the callback succeeded and before the local commits, an
+// error aborts the transaction. Commit runs once every participant not
+// marked Prepared committed, and commits the prepared ones. Rollback runs
+// when the transaction is aborted, with the cause.
+type Coordinator interface {
+	Prepare(participants []Participant) error
+	Commit(participants []Participant) error
+	Rollback(participants []Participant, cause error) error
+}
+
+// DistributedTransaction runs fn with a transaction on each of dbs, in the
+// same order, and commits them all when fn and the coordinator's Prepare
+// succeed. Without a coordinator the transactions are committed one after
+// another on a best effort basis.
+func DistributedTransaction(dbs []*gorm.DB, coordinator Coordinator, fn func(txs []*gorm.DB) error) error {
+	participants := make([]Participant, 0, len(dbs))
+	txs := make([]*gorm.DB, 0, len(dbs))
+	abort := func(cause error) error {
+		if coordinator != nil {
+			if err := coordinator.Rollback(participants, cause); err != nil {
+				cause = fmt.Errorf("%v (coordinator rollback: %v)", cause, err)
+			}
+		}
+		for _, p := range participants {
+			p.Tx.Rollback()
+		}
+		return cause
+	}
+
+	for _, db := range dbs {
+		tx := db.Begin()
+		if tx.Error != nil {
+			return abort(tx.Error)
+		}
+		participants = append(participants, Participant{DB: db, Tx: tx})
+		txs = append(txs, tx)
+	}
+	if err := fn(txs); err != nil {
+		return abort(err)
+	}
+	if coordinator != nil {
+		if err := coordinator.Prepare(participants); err != nil {
+			return abort(err)
+		}
+	}
+	for i, p := range participants {
+		if p.Prepared {
+			continue
+		}
+		if err := p.Tx.Commit().Error; err != nil {
+			// Earlier participants are committed already, the coordinator
+			// is responsible for compensating them.
+			for _, rest := range participants[i+1:] {
+				rest.Tx.Rollback()
+			}
+			if coordinator != nil {
+				coordinator.Rollback(participants, err)
+			}
+			return err
+		}
+	}
+	if coordinator != nil {
+		return coordinator.Commit(participants)
+	}
+	return nil
+}
+
+// PreparedTransactions is a Coordinator using Postgres two phase commit:
+// every participant is prepared with PREPARE TRANSACTION and committed with
+// COMMIT PREPARED once all of them are prepared. ID must be unique among the
+// in-flight distributed transactions, the server needs max_prepared_transactions > 0.
+//
+// PREPARE TRANSACTION dissociates the transaction from the session, so the
+// connection of a prepared participant is released right away. lib/pq
+// refuses to end a transaction it no longer sees and closes the connection.
+type PreparedTransactions struct {
+	ID       string
+	prepared int
+}
+
+// gid returns the quoted global transaction id of the i-th participant.
+func (c *PreparedTransactions) gid(i int) string {
+	return "'" + strings.Replace(fmt.Sprintf("%s_%d", c.ID, i), "'", "''", -1) + "'"
+}
+
+func (c *PreparedTransactions) Prepare(participants []Participant) error {
+	for i, p := range participants {
+		if err := p.Tx.Exec("PREPARE TRANSACTION " + c.gid(i)).Error; err != nil {
+			return err
+		}
+		c.prepared = i + 1
+		participants[i].Prepared = true
+		if tx, ok := p.Tx.CommonDB().(*sql.Tx); ok {
+			tx.Rollback()
+		}
+	}
+	return nil
+}
+
+func (c *PreparedTransactions) Commit(participants []Participant) error {
+	for i, p := range participants {
+		if err := p.DB.Exec("COMMIT PREPARED " + c.gid(i)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *PreparedTransactions) Rollback(participants []Participant, cause error) error {
+	var rbErr error
+	for i := 0; i < c.prepared && i < len(participants); i++ {
+		if err := participants[i].DB.Exec("ROLLBACK PREPARED " + c.gid(i)).Error; err != nil && rbErr == nil {
+			rbErr = err
+		}
+	}
+	c.prepared = 0
+	return rbErr
+}
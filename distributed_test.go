@@ -0,0 +1,77 @@
+package gormrepo
+
+import (
+	"os"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+)
+
+type distributedEntry struct {
+	ID   uint
+	Name string
+}
+
+// postgresDB opens the database of GORMREPO_POSTGRES_DSN, skipping the test
+// without it.
+func postgresDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("GORMREPO_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("GORMREPO_POSTGRES_DSN is not set")
+	}
+	db, err := gorm.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestPreparedTransactionsCommit(t *testing.T) {
+	db := postgresDB(t)
+	var setting string
+	if err := db.Raw("SHOW max_prepared_transactions").Row().Scan(&setting); err != nil {
+		t.Fatal(err)
+	}
+	if n, _ := strconv.Atoi(setting); n < 2 {
+		t.Skip("max_prepared_transactions is below 2")
+	}
+	if err := db.AutoMigrate(&distributedEntry{}).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.DropTable(&distributedEntry{}) })
+	// A second pool stands for the second database.
+	other := postgresDB(t)
+
+	coordinator := &PreparedTransactions{ID: "gormrepo_test_" + strconv.FormatInt(time.Now().UnixNano(), 36)}
+	err := DistributedTransaction([]*gorm.DB{db, other}, coordinator, func(txs []*gorm.DB) error {
+		for i, tx := range txs {
+			if err := tx.Create(&distributedEntry{Name: strconv.Itoa(i)}).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("DistributedTransaction: %v", err)
+	}
+
+	var count int
+	if err := db.Model(&distributedEntry{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Errorf("committed %d rows, want 2", count)
+	}
+	var pending int
+	if err := db.Raw("SELECT count(*) FROM pg_prepared_xacts WHERE gid LIKE ?", coordinator.ID+"%").Row().Scan(&pending); err != nil {
+		t.Fatal(err)
+	}
+	if pending != 0 {
+		t.Errorf("%d transactions left prepared", pending)
+	}
+}
@@ -0,0 +1,30 @@
+package gormrepo
+
+import (
+	"log"
+	"reflect"
+)
+
+// Divergence reports an operation of a double-write or shadow read decorator
+// whose secondary result differs from the primary one.
+type Divergence struct {
+	Entity    string // Entity type name.
+	Op        string // Repository method.
+	Primary   interface{}
+	Secondary interface{}
+	Err       error // Error of the secondary, nil for a mismatch.
+}
+
+// LogDivergence is the default divergence reporter, writing to the standard logger.
+func LogDivergence(d Divergence) {
+	if d.Err != nil {
+		log.Printf("gormrepo: %s.%s diverged: secondary failed: %v", d.Entity, d.Op, d.Err)
+		return
+	}
+	log.Printf("gormrepo: %s.%s diverged: primary %+v, secondary %+v", d.Entity, d.Op, d.Primary, d.Secondary)
+}
+
+// ShadowEqual compares the primary and shadow results of a shadow read.
+// Replace it to ignore differences expected between backends, such as time
+// locations.
+var ShadowEqual = reflect.DeepEqual
@@ -0,0 +1,70 @@
+package gormrepo
+
+import (
+	"reflect"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Operation is the kind of change carried by the generated <T>Changed events.
+type Operation int
+
+const (
+	OpCreate Operation = iota + 1
+	OpUpdate
+	OpDelete
+)
+
+func (o Operation) String() string {
+	switch o {
+	case OpCreate:
+		return "create"
+	case OpUpdate:
+		return "update"
+	case OpDelete:
+		return "delete"
+	}
+	return "unknown"
+}
+
+// ChangedFields returns the columns whose values differ between old and new,
+// pointers to the same entity type. When either is nil, as for creates and
+// deletes, every column of the other is returned.
+func ChangedFields(db *gorm.DB, old, new interface{}) []string {
+	oldNil, newNil := isNilEntity(old), isNilEntity(new)
+	if oldNil && newNil {
+		return nil
+	}
+	var columns []string
+	if oldNil || newNil {
+		entity := new
+		if newNil {
+			entity = old
+		}
+		for _, field := range db.NewScope(entity).Fields() {
+			if field.IsNormal {
+				columns = append(columns, field.DBName)
+			}
+		}
+		return columns
+	}
+	oldScope := db.NewScope(old)
+	for _, field := range db.NewScope(new).Fields() {
+		if !field.IsNormal {
+			continue
+		}
+		oldField, ok := oldScope.FieldByName(field.DBName)
+		if !ok || !reflect.DeepEqual(oldField.Field.Interface(), field.Field.Interface()) {
+			columns = append(columns, field.DBName)
+		}
+	}
+	return columns
+}
+
+func isNilEntity(entity interface{}) bool {
+	if entity == nil {
+		return true
+	}
+	v := reflect.ValueOf(entity)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
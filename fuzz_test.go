@@ -0,0 +1,360 @@
+//go:build go1.18
+
+package gormrepo
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+)
+
+type fuzzEntity struct {
+	ID    uint
+	Name  string
+	Email string
+}
+
+// queryRecorder is the database of the fuzz tests, recording the query of
+// the criteria instead of running it.
+type queryRecorder struct {
+	query string
+	args  []interface{}
+}
+
+var errQueryRecorded = errors.New("query recorded")
+
+func (r *queryRecorder) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return nil, errQueryRecorded
+}
+
+func (r *queryRecorder) Prepare(query string) (*sql.Stmt, error) {
+	return nil, errQueryRecorded
+}
+
+func (r *queryRecorder) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	r.query, r.args = query, args
+	return nil, errQueryRecorded
+}
+
+func (r *queryRecorder) QueryRow(query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+// fuzzQuery returns the query of the criteria on fuzzEntity for the dialect
+// and its variables, or the error the criteria fail with.
+func fuzzQuery(dialect string, allowList bool, criteria ...CriteriaOption) (string, []interface{}, error) {
+	rec := &queryRecorder{}
+	db, err := gorm.Open(dialect, rec)
+	if err != nil {
+		return "", nil, err
+	}
+	db.LogMode(false)
+	if allowList {
+		db = AllowListOnly(db)
+	}
+	var entities []fuzzEntity
+	err = ApplyCriteria(db.Model(&fuzzEntity{}), criteria...).Find(&entities).Error
+	if !errors.Is(err, errQueryRecorded) {
+		if err == nil {
+			err = errors.New("no query run")
+		}
+		return "", nil, err
+	}
+	return rec.query, rec.args, nil
+}
+
+var fuzzDialects = []string{"postgres", "mysql", "sqlite3"}
+
+func FuzzOrderBy(f *testing.F) {
+	f.Add("name", "asc")
+	f.Add("fuzz_entities.name", "DESC")
+	f.Add("name", "")
+	f.Add("name; DROP TABLE users --", "")
+	f.Add("name", "ASC, (SELECT 1)")
+	f.Add("CASE WHEN 1=1 THEN name END", "desc")
+	f.Fuzz(func(t *testing.T, column, orientation string) {
+		for _, dialect := range fuzzDialects {
+			query, _, err := fuzzQuery(dialect, false, OrderBy(column, orientation, false))
+			if err != nil {
+				if !errors.Is(err, ErrInvalidCriterion) {
+					t.Fatalf("OrderBy(%q, %q) on %s: %v", column, orientation, dialect, err)
+				}
+				continue
+			}
+			if !identifier.MatchString(column) || !direction(orientation) {
+				t.Errorf("OrderBy(%q, %q) on %s is not rejected: %s", column, orientation, dialect, query)
+			}
+		}
+	})
+}
+
+func FuzzOrder(f *testing.F) {
+	f.Add("name", "ASC", false, false, false)
+	f.Add("email", "desc", true, false, true)
+	f.Add("name", "DESC NULLS FIRST", false, true, false)
+	f.Add("name) --", "ASC", false, false, false)
+	f.Fuzz(func(t *testing.T, column, dir string, nullsFirst, nullsLast, caseInsensitive bool) {
+		opts := OrderOpts{NullsFirst: nullsFirst, NullsLast: nullsLast, CaseInsensitive: caseInsensitive}
+		for _, dialect := range fuzzDialects {
+			query, _, err := fuzzQuery(dialect, false, Order(column, Direction(dir), opts))
+			if err != nil {
+				if !errors.Is(err, ErrInvalidCriterion) {
+					t.Fatalf("Order(%q, %q) on %s: %v", column, dir, dialect, err)
+				}
+				continue
+			}
+			if !identifier.MatchString(column) || !Direction(dir).Valid() {
+				t.Errorf("Order(%q, %q) on %s is not rejected: %s", column, dir, dialect, query)
+			}
+		}
+	})
+}
+
+// FuzzIn fails when the values of In change the SQL rather than only its
+// variables.
+func FuzzIn(f *testing.F) {
+	f.Add("x", "y")
+	f.Add("') OR 1=1 --", "?")
+	f.Add("", "$1")
+	f.Fuzz(func(t *testing.T, a, b string) {
+		for _, dialect := range fuzzDialects {
+			want, _, err := fuzzQuery(dialect, false, In("name", "x", "y"), In("name", []string{"x", "y"}))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, args, err := fuzzQuery(dialect, false, In("name", a, b), In("name", []string{a, b}))
+			if err != nil {
+				t.Fatalf("In(%q, %q) on %s: %v", a, b, dialect, err)
+			}
+			if got != want {
+				t.Errorf("In(%q, %q) on %s is not bound:\n\twant: %s\n\tgot:  %s", a, b, dialect, want, got)
+			}
+			if !reflect.DeepEqual(args, []interface{}{a, b, a, b}) {
+				t.Errorf("In(%q, %q) on %s binds %q", a, b, dialect, args)
+			}
+		}
+	})
+}
+
+// FuzzLike fails when the text of Like and ILike is not bound or matches
+// other than literally.
+func FuzzLike(f *testing.F) {
+	f.Add("ann", string(Contains))
+	f.Add("50%_off!", string(StartsWith))
+	f.Add("' OR 1=1 --", string(EndsWith))
+	f.Add("x", "")
+	f.Add("x", "regex")
+	f.Fuzz(func(t *testing.T, text, match string) {
+		for _, dialect := range fuzzDialects {
+			for _, build := range []func(column, text string, match ...LikeMatch) CriteriaOption{Like, ILike} {
+				want, _, err := fuzzQuery(dialect, false, build("name", "x"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, args, err := fuzzQuery(dialect, false, build("name", text, LikeMatch(match)))
+				if err != nil {
+					if !errors.Is(err, ErrInvalidCriterion) {
+						t.Fatalf("like %q %q on %s: %v", text, match, dialect, err)
+					}
+					continue
+				}
+				if got != want {
+					t.Errorf("like %q on %s is not bound:\n\twant: %s\n\tgot:  %s", text, dialect, want, got)
+				}
+				pattern, _ := args[0].(string)
+				if unescaped, ok := unescapeLike(pattern, LikeMatch(match)); !ok || unescaped != text {
+					t.Errorf("like %q %q on %s binds the pattern %q", text, match, dialect, pattern)
+				}
+			}
+		}
+	})
+}
+
+// unescapeLike returns the text the LIKE pattern matches literally, and
+// false when the pattern has other wildcards than those of the match.
+func unescapeLike(pattern string, match LikeMatch) (string, bool) {
+	var ok bool
+	switch match {
+	case Contains:
+		pattern, ok = trimWildcard(pattern, true, true)
+	case StartsWith:
+		pattern, ok = trimWildcard(pattern, false, true)
+	case EndsWith:
+		pattern, ok = trimWildcard(pattern, true, false)
+	default:
+		ok = true
+	}
+	if !ok {
+		return "", false
+	}
+	var text strings.Builder
+	for i := 0; i < len(pattern); i++ {
+		switch pattern[i] {
+		case '!':
+			if i+1 == len(pattern) {
+				return "", false
+			}
+			i++
+		case '%', '_':
+			return "", false
+		}
+		text.WriteByte(pattern[i])
+	}
+	return text.String(), true
+}
+
+func trimWildcard(pattern string, prefix, suffix bool) (string, bool) {
+	if prefix {
+		if !strings.HasPrefix(pattern, "%") {
+			return "", false
+		}
+		pattern = pattern[1:]
+	}
+	if suffix {
+		if !strings.HasSuffix(pattern, "%") {
+			return "", false
+		}
+		pattern = pattern[:len(pattern)-1]
+	}
+	return pattern, true
+}
+
+// FuzzCollate fails when a collation name breaks out of the COLLATE clause
+// or the compared value is not bound.
+func FuzzCollate(f *testing.F) {
+	f.Add("und-u-ks-level1", "ann")
+	f.Add("en_US.utf8", "x")
+	f.Add(`C" = name OR "1`, "x")
+	f.Add("utf8mb4_0900_ai_ci; --", "' OR 1=1 --")
+	f.Fuzz(func(t *testing.T, collation, value string) {
+		const baseline = "c0llation"
+		for _, dialect := range fuzzDialects {
+			for _, build := range []func(c Collated) CriteriaOption{
+				func(c Collated) CriteriaOption { return c.Eq(value) },
+				func(c Collated) CriteriaOption { return c.Like(value) },
+				func(c Collated) CriteriaOption { return c.Order(Asc) },
+			} {
+				want, _, err := fuzzQuery(dialect, false, build(Collate("name", baseline)))
+				if err != nil {
+					t.Fatal(err)
+				}
+				got, _, err := fuzzQuery(dialect, false, build(Collate("name", collation)))
+				if err != nil {
+					if !errors.Is(err, ErrInvalidCollation) {
+						t.Fatalf("collation %q on %s: %v", collation, dialect, err)
+					}
+					continue
+				}
+				if want = strings.Replace(want, baseline, collation, 1); got != want {
+					t.Errorf("collation %q, value %q on %s:\n\twant: %s\n\tgot:  %s", collation, value, dialect, want, got)
+				}
+			}
+		}
+	})
+}
+
+// FuzzCriterionOption rebuilds criteria decoded from JSON, as received from
+// clients, failing when Option panics or rebuilds an option it does not
+// describe the same way again.
+func FuzzCriterionOption(f *testing.F) {
+	for _, opt := range []CriteriaOption{
+		And("name = ?", "x"), And(map[string]interface{}{"name": "x"}), OrderBy("name", "asc", false),
+		Order("name", Desc, OrderOpts{NullsLast: true}), In("name", "x", "y"), Like("name", "x", Contains),
+		Collate("name", "C").Eq("x"), Collate("name", "C").Order(Asc), Limit(10), Offset(5), Select("name"),
+		GroupBy("name"), Preload("Orders"), PreloadWhere("Orders", "state = ?", "paid"), Joins("JOIN a ON true"),
+		ILikeAny([]string{"name", "email"}, "x"), LockForUpdate(SkipLocked), Unscoped(),
+	} {
+		c, _ := Describe(opt)
+		b, err := json.Marshal(c)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(b)
+	}
+	f.Add([]byte(`{"op":"order_by","column":"name"}`))
+	f.Add([]byte(`{"op":"collate_eq","column":"name","args":["C\" OR \"1",1]}`))
+	f.Add([]byte(`{"op":"limit","args":[1.5]}`))
+	f.Add([]byte(`{"op":"in","args":[]}`))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var c Criterion
+		if json.Unmarshal(data, &c) != nil {
+			return
+		}
+		opt, err := c.Option()
+		if err != nil {
+			if !errors.Is(err, ErrInvalidCriterion) {
+				t.Errorf("%s: Option fails with %v", data, err)
+			}
+			return
+		}
+		described, ok := Describe(opt)
+		if !ok {
+			t.Fatalf("%s: rebuilt option is not described", data)
+		}
+		// The descriptions of the rebuilt options are normalized, and
+		// rebuilt the same again.
+		b, err := json.Marshal(described)
+		if err != nil {
+			return
+		}
+		var decoded Criterion
+		if err := json.Unmarshal(b, &decoded); err != nil {
+			t.Fatal(err)
+		}
+		again, err := decoded.Option()
+		if err != nil {
+			t.Fatalf("%s: rebuilt %s fails: %v", data, b, err)
+		}
+		d, _ := Describe(again)
+		if b2, _ := json.Marshal(d); !bytes.Equal(b2, b) {
+			t.Errorf("%s: rebuilt as %s, then as %s", data, b, b2)
+		}
+		// Allow-list mode refuses the options checkRawSQL rejects.
+		if described.checkRawSQL() != nil {
+			if _, _, err := fuzzQuery("postgres", true, opt); !errors.Is(err, ErrRawSQLForbidden) {
+				t.Errorf("%s: allow-list mode runs %s: %v", data, b, err)
+			}
+		}
+	})
+}
+
+// FuzzCheckRawSQL fails when checkRawSQL accepts a criterion of hand written
+// SQL in allow-list mode.
+func FuzzCheckRawSQL(f *testing.F) {
+	for _, op := range []string{"and", "or", "not", "having", "preload_where", "select", "joins", "raw", "group_by", "order_by", "order", "collate_order", "ilike_any", "published", "in"} {
+		f.Add(op, "name", "name")
+		f.Add(op, "name; --", "asc")
+		f.Add(op, "", "1=1) OR (1=1")
+	}
+	f.Fuzz(func(t *testing.T, op, column, arg string) {
+		c := Criterion{Op: op, Column: column, Args: []interface{}{arg, arg}}
+		if c.checkRawSQL() != nil {
+			return
+		}
+		if column != "" && !identifier.MatchString(column) {
+			t.Errorf("%s accepted with the column %q", op, column)
+		}
+		switch op {
+		case "and", "or", "not", "having", "preload_where", "joins", "raw":
+			t.Errorf("%s accepted with the SQL %q", op, arg)
+		case "select", "group_by", "ilike_any":
+			if !columnNames(arg) {
+				t.Errorf("%s accepted with the column %q", op, arg)
+			}
+		case "published":
+			if arg != "" && !identifier.MatchString(arg) {
+				t.Errorf("%s accepted with the column %q", op, arg)
+			}
+		case "order_by", "order", "collate_order":
+			if !direction(arg) {
+				t.Errorf("%s accepted with the direction %q", op, arg)
+			}
+		}
+	})
+}
@@ -0,0 +1,17 @@
+package gen
+
+// generateAdmin emits AdminEntity, passed to admin.New to expose the
+// repository on the admin endpoint.
+func (g *Generator) generateAdmin(repoNameRecv, typeName string) {
+	if !g.opts.Admin {
+		return
+	}
+	g.Import("github.com/l-vitaly/gormrepo/admin")
+	g.Printf(repoAdmin, repoNameRecv, typeName)
+}
+
+const repoAdmin = `
+func (r %[1]s) AdminEntity() admin.Entity {
+	return admin.EntityOf(r.DB, &%[2]s{})
+}
+`
@@ -0,0 +1,47 @@
+package gen
+
+// generateBlobs emits streaming accessors for fields tagged `gormrepo:"blob"`,
+// or `gormrepo:"blob:lo"` for Postgres large object (oid) columns.
+func (g *Generator) generateBlobs(repoNameRecv, typeName string) {
+	st := g.getStructType(typeName)
+	if st == nil {
+		return
+	}
+	blobs := 0
+	for _, field := range st.Fields.List {
+		kind, ok := g.gormrepoTag(field)["blob"]
+		if !ok || len(field.Names) == 0 {
+			continue
+		}
+		g.Printf(repoBlob, repoNameRecv, typeName, field.Names[0].Name, kind == "lo")
+		blobs++
+	}
+	if blobs > 0 {
+		g.Import("io")
+		g.Printf(repoBlobColumn, repoNameRecv, typeName)
+	}
+}
+
+const repoBlob = `
+func (r %[1]s) Open%[3]s(id uint) (io.ReadCloser, error) {
+	blob, err := gormrepo.OpenBlob(r.DB, r.blobColumn(%[3]q, %[4]t), id)
+	return blob, r.wrapError("Open%[3]s", err)
+}
+
+func (r %[1]s) Write%[3]s(id uint, src io.Reader) error {
+	return r.wrapError("Write%[3]s", gormrepo.WriteBlob(r.DB, r.blobColumn(%[3]q, %[4]t), id, src))
+}
+`
+
+const repoBlobColumn = `
+func (r %[1]s) blobColumn(name string, largeObject bool) gormrepo.BlobColumn {
+	scope := r.DB.NewScope(&%[2]s{})
+	field, _ := scope.FieldByName(name)
+	return gormrepo.BlobColumn{
+		Table:       scope.QuotedTableName(),
+		Column:      scope.Quote(field.DBName),
+		Key:         scope.Quote(scope.PrimaryKey()),
+		LargeObject: largeObject,
+	}
+}
+`
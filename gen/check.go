@@ -0,0 +1,28 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"log"
+)
+
+// checkPending compares the staged files with the files on disk instead of
+// writing them, and reports whether they are all up to date.
+func (g *Generator) checkPending() bool {
+	pending := g.pending
+	g.pending = nil
+
+	upToDate := true
+	for _, p := range pending {
+		existing, err := ioutil.ReadFile(p.name)
+		switch {
+		case err != nil:
+			log.Printf("%s is not generated: %s", p.name, err)
+			upToDate = false
+		case !bytes.Equal(existing, p.src):
+			log.Printf("%s is out of date, run go generate", p.name)
+			upToDate = false
+		}
+	}
+	return upToDate
+}
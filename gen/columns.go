@@ -0,0 +1,218 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// modelColumn is a column of a model and the Go type of its values.
+type modelColumn struct {
+	field, name, goType string
+	settings            map[string]string // Gorm tag of the field.
+	doc                 string            // Doc comment of the field.
+	json                string            // JSON name of the field, "" when left out.
+}
+
+// gormModelColumns are the columns of an embedded gorm.Model.
+var gormModelColumns = []modelColumn{
+	{"ID", "id", "uint", map[string]string{"PRIMARY_KEY": ""}, "", "ID"},
+	{"CreatedAt", "created_at", "time.Time", nil, "", "CreatedAt"},
+	{"UpdatedAt", "updated_at", "time.Time", nil, "", "UpdatedAt"},
+	{"DeletedAt", "deleted_at", "time.Time", map[string]string{"INDEX": ""}, "", "DeletedAt"},
+}
+
+// modelColumns returns the columns of the struct, with the fields of
+// embedded structs declared in the package and of gorm.Model. Relations and
+// fields ignored by gorm are left out. Pointer fields have the type of their
+// element.
+func (g *Generator) modelColumns(st *ast.StructType) []modelColumn {
+	var result []modelColumn
+	if st == nil {
+		return result
+	}
+	for _, field := range st.Fields.List {
+		settings := gormTag(field)
+		if _, ignored := settings["-"]; ignored {
+			continue
+		}
+		typ := field.Type
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+		}
+		if len(field.Names) == 0 {
+			switch expr := typ.(type) {
+			case *ast.SelectorExpr:
+				if types.ExprString(expr) == "gorm.Model" {
+					result = append(result, gormModelColumns...)
+				}
+			case *ast.Ident:
+				result = append(result, g.modelColumns(g.getStructType(expr.Name))...)
+			}
+			continue
+		}
+		if g.isRelation(typ) {
+			continue
+		}
+		for _, ident := range field.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			result = append(result, modelColumn{ident.Name, columnName(ident.Name, settings), types.ExprString(typ), settings, fieldDoc(field), jsonName(ident.Name, field)})
+		}
+	}
+	return result
+}
+
+// integerID reports whether the only primary key of the struct is an
+// integer ID field, as gorm defaults to without primary_key tags.
+func (g *Generator) integerID(st *ast.StructType) bool {
+	var primary []modelColumn
+	columns := g.modelColumns(st)
+	for _, c := range columns {
+		if _, ok := c.settings["PRIMARY_KEY"]; ok {
+			primary = append(primary, c)
+		}
+	}
+	if len(primary) == 0 {
+		for _, c := range columns {
+			if c.field == "ID" {
+				primary = append(primary, c)
+			}
+		}
+	}
+	return len(primary) == 1 && primary[0].field == "ID" && strings.Contains(primary[0].goType, "int")
+}
+
+// gormTag parses the gorm tag of the field, with upper cased keys as gorm
+// does.
+func gormTag(field *ast.Field) map[string]string {
+	settings := map[string]string{}
+	if field.Tag == nil {
+		return settings
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return settings
+	}
+	for _, item := range strings.Split(reflect.StructTag(tag).Get("gorm"), ";") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, ":", 2)
+		key := strings.ToUpper(strings.TrimSpace(kv[0]))
+		if len(kv) == 2 {
+			settings[key] = strings.TrimSpace(kv[1])
+		} else {
+			settings[key] = ""
+		}
+	}
+	return settings
+}
+
+// jsonName returns the name of the field in JSON, named by its json tag or
+// after the field as encoding/json does, "" when the tag leaves it out.
+func jsonName(name string, field *ast.Field) string {
+	if field.Tag == nil {
+		return name
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return name
+	}
+	value, ok := reflect.StructTag(tag).Lookup("json")
+	if !ok {
+		return name
+	}
+	if value == "-" {
+		return ""
+	}
+	if value = strings.Split(value, ",")[0]; value != "" {
+		return value
+	}
+	return name
+}
+
+// fieldDoc returns the doc or line comment of the field on one line.
+func fieldDoc(field *ast.Field) string {
+	doc := field.Doc
+	if doc == nil {
+		doc = field.Comment
+	}
+	return strings.Join(strings.Fields(doc.Text()), " ")
+}
+
+// columnName returns the column of the field, named by its column tag or
+// after the field.
+func columnName(field string, settings map[string]string) string {
+	if name := settings["COLUMN"]; name != "" {
+		return name
+	}
+	return gorm.ToColumnName(field)
+}
+
+// isRelation reports whether the field type is a struct of the package, or
+// a slice of anything but bytes.
+func (g *Generator) isRelation(typ ast.Expr) bool {
+	switch expr := typ.(type) {
+	case *ast.ArrayType:
+		elem, ok := expr.Elt.(*ast.Ident)
+		return !ok || elem.Name != "byte"
+	case *ast.Ident:
+		return g.getStructType(expr.Name) != nil
+	}
+	return false
+}
+
+// importsOf returns the import paths of the packages the type expressions
+// refer to, as imported by the file declaring the model.
+func importsOf(file *ast.File, exprs []string) []string {
+	var paths []string
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		name, ok := importName(spec)
+		if !ok {
+			continue
+		}
+		for _, expr := range exprs {
+			if strings.HasPrefix(expr, name+".") {
+				paths = append(paths, path)
+				break
+			}
+		}
+	}
+	return paths
+}
+
+// generateColumns emits the column descriptors of the type.
+func (g *Generator) generateColumns(f *File, typeName string) {
+	cols := g.modelColumns(g.getStructType(typeName))
+	var goTypes []string
+	var fields, values strings.Builder
+	for _, c := range cols {
+		goTypes = append(goTypes, c.goType)
+		fmt.Fprintf(&fields, "\t%s gormrepo.Column[%s]\n", c.field, c.goType)
+		fmt.Fprintf(&values, "\t%s: gormrepo.NewColumn[%s](%q),\n", c.field, c.goType, c.name)
+	}
+	g.Import("github.com/l-vitaly/gormrepo")
+	imports := importsOf(f.file, goTypes)
+	for _, c := range cols {
+		if strings.HasPrefix(c.goType, "time.") {
+			imports = append(imports, "time")
+		}
+	}
+	g.Import(imports...)
+	g.Printf(repoColumns, typeName, fields.String(), values.String())
+}
+
+const repoColumns = `
+// %[1]sColumns describes the columns of %[1]s for the typed criteria.
+var %[1]sColumns = struct {
+%[2]s}{
+%[3]s}
+`
@@ -0,0 +1,142 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"strings"
+)
+
+// generateDecorators emits the interface of the exported methods generated on
+// the repository so far, and a struct embedding it with every method
+// delegating, for custom decorators overriding only the methods of interest.
+// Methods referring to types unexported by the generated code, such as the
+// constructors of the generated decorators, are left out.
+func (g *Generator) generateDecorators(repoName, typeName string) {
+	if !g.opts.Decorators {
+		return
+	}
+	repoMethods, err := g.repoMethods(repoName)
+	if err != nil {
+		log.Printf("warning: cannot generate decorators of %s: %s", typeName, err)
+		return
+	}
+	iface, decorator := typeName+"Repository", typeName+"Decorator"
+	var methods, delegates bytes.Buffer
+	for _, m := range repoMethods {
+		call := fmt.Sprintf("d.%s.%s(%s)", iface, m.name, m.args)
+		if len(m.results) > 0 {
+			call = "return " + call
+		}
+		fmt.Fprintf(&methods, "\t%s\n", m.signature)
+		fmt.Fprintf(&delegates, "\nfunc (d %s) %s {\n\t%s\n}\n", decorator, m.signature, call)
+	}
+	g.Printf(repoDecorators, repoName, iface, decorator, methods.String(), delegates.String())
+}
+
+// repoMethod is an exported method generated on a repository, parsed back
+// from the output.
+type repoMethod struct {
+	name      string
+	signature string   // Name, parameters and results.
+	params    []string // Parameter names.
+	args      string   // Parameters passed on, the variadic one expanded.
+	results   []string // Result types.
+}
+
+// repoMethods returns the exported methods generated on the repository so
+// far, in order. Methods referring to types unexported by the generated code,
+// such as the constructors of the generated decorators, are left out.
+func (g *Generator) repoMethods(repoName string) ([]repoMethod, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", "package p\n"+g.buf.String(), 0)
+	if err != nil {
+		return nil, err
+	}
+	unexported := map[string]bool{}
+	for _, decl := range file.Decls {
+		if gd, ok := decl.(*ast.GenDecl); ok && gd.Tok == token.TYPE {
+			for _, spec := range gd.Specs {
+				if name := spec.(*ast.TypeSpec).Name; !name.IsExported() {
+					unexported[name.Name] = true
+				}
+			}
+		}
+	}
+
+	var methods []repoMethod
+	for _, decl := range file.Decls {
+		fd, ok := decl.(*ast.FuncDecl)
+		if !ok || fd.Recv == nil || !fd.Name.IsExported() || receiverName(fd) != repoName || refersTo(fd.Type, unexported) {
+			continue
+		}
+		m := repoMethod{name: fd.Name.Name}
+		var args []string
+		for i, field := range fd.Type.Params.List {
+			if len(field.Names) == 0 {
+				field.Names = []*ast.Ident{ast.NewIdent(fmt.Sprintf("p%d", i))}
+			}
+			for _, name := range field.Names {
+				m.params = append(m.params, name.Name)
+				if _, variadic := field.Type.(*ast.Ellipsis); variadic {
+					args = append(args, name.Name+"...")
+				} else {
+					args = append(args, name.Name)
+				}
+			}
+		}
+		m.args = strings.Join(args, ", ")
+		if fd.Type.Results != nil {
+			for _, field := range fd.Type.Results.List {
+				for i := 0; i < len(field.Names) || i == 0; i++ {
+					m.results = append(m.results, types.ExprString(field.Type))
+				}
+			}
+		}
+		var sig bytes.Buffer
+		format.Node(&sig, fset, fd.Type)
+		m.signature = fd.Name.Name + strings.TrimPrefix(sig.String(), "func")
+		methods = append(methods, m)
+	}
+	return methods, nil
+}
+
+func receiverName(fd *ast.FuncDecl) string {
+	expr := fd.Recv.List[0].Type
+	if star, ok := expr.(*ast.StarExpr); ok {
+		expr = star.X
+	}
+	if ident, ok := expr.(*ast.Ident); ok {
+		return ident.Name
+	}
+	return ""
+}
+
+// refersTo reports whether node uses one of the named identifiers.
+func refersTo(node ast.Node, names map[string]bool) bool {
+	found := false
+	ast.Inspect(node, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && names[ident.Name] {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+const repoDecorators = `
+// %[2]s is implemented by %[1]s and its decorators.
+type %[2]s interface {
+%[4]s}
+
+// %[3]s delegates every method to the embedded repository. Embed it
+// in a custom decorator and override the methods of interest only.
+type %[3]s struct {
+	%[2]s
+}
+%[5]s`
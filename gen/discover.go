@@ -0,0 +1,125 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"strings"
+)
+
+// typeDecl is the declaration of a type of the package.
+type typeDecl struct {
+	file *File
+	spec *ast.TypeSpec
+}
+
+// lookupTypeSpec returns the declaration of the named type, in a single or
+// grouped declaration of any file of the package.
+func (g *Generator) lookupTypeSpec(typeName string) (typeDecl, bool) {
+	for _, f := range g.files {
+		if tp := declaredType(f.file, typeName); tp != nil {
+			return typeDecl{f, tp}, true
+		}
+	}
+	return typeDecl{}, false
+}
+
+func declaredType(file *ast.File, typeName string) *ast.TypeSpec {
+	for _, d := range file.Decls {
+		gd, ok := d.(*ast.GenDecl)
+		if !ok || gd.Tok != token.TYPE {
+			continue
+		}
+		for _, s := range gd.Specs {
+			if tp, ok := s.(*ast.TypeSpec); ok && tp.Name.Name == typeName {
+				return tp
+			}
+		}
+	}
+	return nil
+}
+
+// lookupStruct returns the struct type of the named type, following aliases
+// and definitions of other struct types of the package, such as
+//
+//	type Customer = customer
+//	type Archived Customer
+//
+// The error tells where the type is declared when it is not a struct of the
+// package, or where it was looked for when it is not declared.
+func (g *Generator) lookupStruct(typeName string) (*ast.StructType, error) {
+	decl, ok := g.lookupTypeSpec(typeName)
+	if !ok {
+		return nil, g.notFound(typeName)
+	}
+	seen := map[string]bool{}
+	for {
+		seen[decl.spec.Name.Name] = true
+		typ := decl.spec.Type
+		for {
+			paren, ok := typ.(*ast.ParenExpr)
+			if !ok {
+				break
+			}
+			typ = paren.X
+		}
+		switch expr := typ.(type) {
+		case *ast.StructType:
+			return expr, nil
+		case *ast.Ident:
+			next, ok := g.lookupTypeSpec(expr.Name)
+			if ok && !seen[expr.Name] {
+				decl = next
+				continue
+			}
+		case *ast.SelectorExpr:
+			return nil, fmt.Errorf("%s: type %s refers to %s of another package, generate it in that package",
+				g.position(decl.spec.Name.Pos()), typeName, types.ExprString(expr))
+		}
+		return nil, fmt.Errorf("%s: type %s is not a struct type: %s", g.position(decl.spec.Name.Pos()), typeName, types.ExprString(typ))
+	}
+}
+
+// position returns the file:line:column of pos, relative to the working
+// directory when possible.
+func (g *Generator) position(pos token.Pos) string {
+	p := g.fset.Position(pos)
+	if wd, err := filepath.Abs("."); err == nil {
+		if rel, err := filepath.Rel(wd, p.Filename); err == nil && !strings.HasPrefix(rel, "..") {
+			p.Filename = rel
+		}
+	}
+	return p.String()
+}
+
+// notFound returns the error of a type the package does not declare,
+// locating it in the files left out of the package by build constraints or
+// in its test files when declared there.
+func (g *Generator) notFound(typeName string) error {
+	fset := token.NewFileSet()
+	for _, group := range []struct {
+		names  []string
+		reason string
+	}{
+		{g.ignoredFiles, "in a file excluded by build constraints, see -tags"},
+		{g.testFiles, "in a test file, test files are not generated for"},
+	} {
+		for _, name := range group.names {
+			file, err := parser.ParseFile(fset, name, nil, 0)
+			if err != nil {
+				continue
+			}
+			if tp := declaredType(file, typeName); tp != nil {
+				return fmt.Errorf("%s: type %s is declared %s", fset.Position(tp.Name.Pos()), typeName, group.reason)
+			}
+		}
+	}
+	pkgName, dir := "", ""
+	if len(g.files) > 0 {
+		pkgName, dir = g.files[0].file.Name.Name, filepath.Dir(g.position(g.files[0].file.Package))
+	}
+	return fmt.Errorf("%s: type %s is not declared in package %s", dir, typeName, pkgName)
+}
@@ -0,0 +1,233 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// readMethods are the generated reads, passed through to the primary by the
+// double-write repository.
+var readMethods = map[string]bool{
+	"Get": true, "GetAll": true, "GetBy": true, "GetByNamed": true, "GetByFirst": true, "GetByLast": true,
+	"GetPublished": true, "Count": true, "RawScan": true, "CountByPeriod": true, "Percentiles": true, "Histogram": true,
+	"Checksum": true, "Stats": true, "Related": true, "GetTranslation": true,
+	"GetChildren": true, "GetDescendants": true, "GetAncestors": true,
+}
+
+// mirroredWrites are the writes mirrored by the double-write repository when
+// the repository has them, with the method of the writer interface they need,
+// in the order they are generated.
+var mirroredWrites = []struct{ name, writer, method string }{
+	{"Update", "Update(entity *%[1]s, fields gormrepo.Fields, criteria ...gormrepo.CriteriaOption) error", repoDoubleWriteUpdate},
+	{"Delete", "Delete(entity *%[1]s, criteria ...gormrepo.CriteriaOption) error", repoDoubleWriteDelete},
+	{"DeleteAndGet", "", repoDoubleWriteDeleteAndGet},
+	{"Upsert", "Upsert(entity *%[1]s, target gormrepo.ConflictTarget, update ...string) error", repoDoubleWriteUpsert},
+	{"BulkUpdateByKey", "BulkUpdateByKey(pairs map[uint]gormrepo.Fields) (int64, error)", repoDoubleWriteBulkUpdateByKey},
+}
+
+// generateDoubleWrite emits the writer interface of the type and a repository
+// mirroring Create, CreateContext, Update, Delete, DeleteAndGet, Upsert and
+// BulkUpdateByKey to a secondary writer, such as the same repository on the
+// rewritten table, while reads stay on the primary. It does not embed the
+// repository: the writes it cannot mirror are left out, so it is generated
+// once the reads are. The path and closure trees have no Upsert, their
+// CreateContext is left out too.
+func (g *Generator) generateDoubleWrite(strategy, repoName, typeName string) {
+	if !g.opts.DoubleWrite {
+		return
+	}
+	methods, err := g.repoMethods(repoName)
+	if err != nil {
+		fatalf("double write: cannot parse the repository of %s: %s", typeName, err)
+	}
+	has := map[string]bool{}
+	for _, m := range methods {
+		has[m.name] = true
+	}
+	doubleWrite := g.lcFirst(typeName) + "DoubleWriteRepo"
+	writer := typeName + "Writer"
+
+	var writes strings.Builder
+	fmt.Fprintf(&writes, "\tInsert(entity *%s) error\n", typeName)
+	for _, w := range mirroredWrites {
+		if has[w.name] && w.writer != "" {
+			fmt.Fprintf(&writes, "\t%s\n", strings.ReplaceAll(w.writer, "%[1]s", typeName))
+		}
+	}
+	insert := repoDoubleWriteInsert
+	if strategy == treePath || strategy == treeClosure {
+		insert = repoDoubleWriteInsertTree
+	}
+	g.Printf(repoDoubleWriteWriter, typeName, writer, writes.String())
+	g.Printf(insert, repoName, typeName)
+	g.Printf(repoDoubleWrite, repoName, typeName, doubleWrite, writer)
+	for _, w := range mirroredWrites {
+		if has[w.name] {
+			g.Printf(w.method, repoName, typeName, doubleWrite)
+		}
+	}
+	for _, m := range methods {
+		switch {
+		case m.name == "CreateContext" && has["Upsert"]:
+			g.Printf(repoDoubleWriteCreateContext, typeName, doubleWrite)
+		case readMethods[m.name]:
+			g.Printf(repoPassThrough, doubleWrite, m.signature, fmt.Sprintf("r.repo.%s(%s)", m.name, m.args))
+		}
+	}
+}
+
+const repoDoubleWriteWriter = `
+// %[2]s is implemented by repositories accepting the mirrored writes of %[1]s.
+type %[2]s interface {
+%[3]s}
+`
+
+const repoDoubleWrite = `
+type %[3]s struct {
+	repo      *%[1]s
+	secondary %[4]s
+	report    func(gormrepo.Divergence)
+}
+
+// DoubleWrite returns the repository mirroring successful writes to secondary.
+// A failed or mismatching mirrored write is passed to report, or to
+// gormrepo.LogDivergence when nil, and never fails the primary write. It has
+// the reads of the repository and the writes it mirrors only.
+func (r *%[1]s) DoubleWrite(secondary %[4]s, report func(gormrepo.Divergence)) *%[3]s {
+	if report == nil {
+		report = gormrepo.LogDivergence
+	}
+	return &%[3]s{r, secondary, report}
+}
+
+func (r *%[3]s) diverged(op string, primary, secondary interface{}, err error) {
+	r.report(gormrepo.Divergence{Entity: %[2]q, Op: op, Primary: primary, Secondary: secondary, Err: err})
+}
+
+func (r *%[3]s) Create(entity %[2]s) (*%[2]s, error) {
+	created, err := r.repo.Create(entity)
+	if err != nil {
+		return nil, err
+	}
+	// The mirror keeps the primary key assigned by the primary.
+	mirror := *created
+	if err := r.secondary.Insert(&mirror); err != nil {
+		r.diverged("Create", created, &mirror, err)
+	}
+	return created, nil
+}
+`
+
+const repoDoubleWriteInsert = `
+// Insert creates the entity keeping its primary key, unlike Create.
+func (r *%[1]s) Insert(entity *%[2]s) error {
+	return r.wrapError("Insert", r.DB.Create(entity).Error)
+}
+`
+
+const repoDoubleWriteInsertTree = `
+// Insert creates the entity keeping its primary key, unlike Create, with its
+// tree path.
+func (r *%[1]s) Insert(entity *%[2]s) error {
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		if err := tx.Create(entity).Error; err != nil {
+			return err
+		}
+		return (&%[1]s{tx}).insertTreePath(entity)
+	})
+	return r.wrapError("Insert", err)
+}
+`
+
+const repoDoubleWriteUpdate = `
+func (r *%[3]s) Update(entity *%[2]s, fields gormrepo.Fields, criteria ...gormrepo.CriteriaOption) error {
+	mirror := *entity
+	if err := r.repo.Update(entity, fields, criteria...); err != nil {
+		return err
+	}
+	if err := r.secondary.Update(&mirror, fields, criteria...); err != nil {
+		r.diverged("Update", entity, &mirror, err)
+	}
+	return nil
+}
+`
+
+const repoDoubleWriteDelete = `
+func (r *%[3]s) Delete(entity *%[2]s, criteria ...gormrepo.CriteriaOption) error {
+	mirror := *entity
+	if err := r.repo.Delete(entity, criteria...); err != nil {
+		return err
+	}
+	if err := r.secondary.Delete(&mirror, criteria...); err != nil {
+		r.diverged("Delete", entity, &mirror, err)
+	}
+	return nil
+}
+`
+
+const repoDoubleWriteDeleteAndGet = `
+func (r *%[3]s) DeleteAndGet(criteria ...gormrepo.CriteriaOption) ([]*%[2]s, error) {
+	deleted, err := r.repo.DeleteAndGet(criteria...)
+	if err != nil {
+		return nil, err
+	}
+	// The rows deleted from the primary are deleted by primary key, the
+	// criteria could match other rows on the secondary.
+	for _, entity := range deleted {
+		mirror := *entity
+		if err := r.secondary.Delete(&mirror); err != nil {
+			r.diverged("DeleteAndGet", entity, &mirror, err)
+		}
+	}
+	return deleted, nil
+}
+`
+
+const repoDoubleWriteUpsert = `
+func (r *%[3]s) Upsert(entity *%[2]s, target gormrepo.ConflictTarget, update ...string) error {
+	if err := r.repo.Upsert(entity, target, update...); err != nil {
+		return err
+	}
+	// The mirror keeps the primary key of the row inserted or updated.
+	mirror := *entity
+	if err := r.secondary.Upsert(&mirror, target, update...); err != nil {
+		r.diverged("Upsert", entity, &mirror, err)
+	}
+	return nil
+}
+`
+
+const repoDoubleWriteBulkUpdateByKey = `
+func (r *%[3]s) BulkUpdateByKey(pairs map[uint]gormrepo.Fields) (int64, error) {
+	n, err := r.repo.BulkUpdateByKey(pairs)
+	if err != nil {
+		return n, err
+	}
+	if mirrored, err := r.secondary.BulkUpdateByKey(pairs); err != nil || mirrored != n {
+		r.diverged("BulkUpdateByKey", n, mirrored, err)
+	}
+	return n, nil
+}
+`
+
+const repoDoubleWriteCreateContext = `
+func (r *%[2]s) CreateContext(ctx context.Context, entity %[1]s) (*%[1]s, error) {
+	created, err := r.repo.CreateContext(ctx, entity)
+	if err != nil {
+		return nil, err
+	}
+	// A retried request returns the entity created the first time, upserted
+	// on the primary key.
+	mirror := *created
+	if err := r.secondary.Upsert(&mirror, gormrepo.OnConstraint("primary")); err != nil {
+		r.diverged("CreateContext", created, &mirror, err)
+	}
+	return created, nil
+}
+`
+
+const repoPassThrough = `
+func (r *%[1]s) %[2]s {
+	return %[3]s
+}
+`
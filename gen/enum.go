@@ -0,0 +1,236 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// enum is a named string or integer type of the package with constants.
+type enum struct {
+	name       string
+	underlying string
+	values     []string // Names of the constants.
+}
+
+// enumKinds are the underlying types of enums.
+var enumKinds = map[string]bool{
+	"string": true, "int": true, "int8": true, "int16": true, "int32": true, "int64": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+}
+
+// lookupEnum returns the enum of the named type, nil when it is not a string
+// or integer type with constants.
+func (g *Generator) lookupEnum(name string) *enum {
+	e := &enum{name: name}
+	for _, f := range g.files {
+		for _, decl := range f.file.Decls {
+			gd, ok := decl.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			switch gd.Tok {
+			case token.TYPE:
+				for _, spec := range gd.Specs {
+					ts := spec.(*ast.TypeSpec)
+					if ident, ok := ts.Type.(*ast.Ident); ok && ts.Name.Name == name && ts.Assign == 0 && enumKinds[ident.Name] {
+						e.underlying = ident.Name
+					}
+				}
+			case token.CONST:
+				// Specs without type nor value repeat the previous one.
+				current := ""
+				for _, spec := range gd.Specs {
+					vs := spec.(*ast.ValueSpec)
+					if ident, ok := vs.Type.(*ast.Ident); ok {
+						current = ident.Name
+					} else if vs.Type != nil || len(vs.Values) > 0 {
+						current = ""
+					}
+					if current != name {
+						continue
+					}
+					for _, ident := range vs.Names {
+						if ident.Name != "_" {
+							e.values = append(e.values, ident.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	if e.underlying == "" || len(e.values) == 0 {
+		return nil
+	}
+	return e
+}
+
+// enumField is a field of a model whose type is an enum.
+type enumField struct {
+	field, column string
+	pointer       bool
+	enum          *enum
+}
+
+func (g *Generator) enumFields(typeName string) []enumField {
+	st := g.getStructType(typeName)
+	if st == nil {
+		return nil
+	}
+	var fields []enumField
+	for _, field := range st.Fields.List {
+		typ, pointer := field.Type, false
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ, pointer = star.X, true
+		}
+		ident, ok := typ.(*ast.Ident)
+		if !ok {
+			continue
+		}
+		e := g.lookupEnum(ident.Name)
+		if e == nil {
+			continue
+		}
+		for _, name := range field.Names {
+			fields = append(fields, enumField{name.Name, gorm.ToColumnName(name.Name), pointer, e})
+		}
+	}
+	return fields
+}
+
+// enumValidation returns the statement validating the enums of entity in
+// Create, "" when the type has none.
+func (g *Generator) enumValidation(typeName string) string {
+	if !g.opts.Enums || len(g.enumFields(typeName)) == 0 {
+		return ""
+	}
+	return `
+	if err := r.validateEnums(&entity); err != nil {
+		return nil, r.wrapError("Create", err)
+	}`
+}
+
+// enumFieldsValidation returns the statement validating the enums of the
+// fields in Update.
+func (g *Generator) enumFieldsValidation(typeName string) string {
+	if !g.opts.Enums || len(g.enumFields(typeName)) == 0 {
+		return ""
+	}
+	return `
+	if err := r.validateEnumFields(fields); err != nil {
+		return r.wrapError("Update", err, criteria...)
+	}`
+}
+
+// enumPairsValidation returns the statement validating the enums of the
+// fields of each key in BulkUpdateByKey.
+func (g *Generator) enumPairsValidation(typeName string) string {
+	if !g.opts.Enums || len(g.enumFields(typeName)) == 0 {
+		return ""
+	}
+	return `
+	for _, fields := range pairs {
+		if err := r.validateEnumFields(fields); err != nil {
+			return 0, r.wrapError("BulkUpdateByKey", err)
+		}
+	}`
+}
+
+// generateEnums emits the validation of the enum fields of the type and
+// their In criteria, and returns the enums used.
+func (g *Generator) generateEnums(repoNameRecv, typeName string) []*enum {
+	if !g.opts.Enums {
+		return nil
+	}
+	fields := g.enumFields(typeName)
+	if len(fields) == 0 {
+		return nil
+	}
+	g.Import("fmt")
+	var checks, cases strings.Builder
+	used := map[string]*enum{}
+	for _, f := range fields {
+		used[f.enum.name] = f.enum
+		if f.pointer {
+			fmt.Fprintf(&checks, "\tif entity.%[1]s != nil && !entity.%[1]s.IsValid() {\n\t\treturn fmt.Errorf(\"%%w: %[1]s %%v\", gormrepo.ErrInvalidEnum, *entity.%[1]s)\n\t}\n", f.field)
+		} else {
+			fmt.Fprintf(&checks, "\tif !entity.%[1]s.IsValid() {\n\t\treturn fmt.Errorf(\"%%w: %[1]s %%v\", gormrepo.ErrInvalidEnum, entity.%[1]s)\n\t}\n", f.field)
+		}
+		fmt.Fprintf(&cases, "\t\tcase %q, %q:\n\t\t\tok = valid%s(value)\n", f.field, f.column, f.enum.name)
+		g.Printf(repoEnumIn, typeName, f.field, f.enum.name, f.column)
+	}
+	g.Printf(repoEnumValidation, repoNameRecv, typeName, checks.String(), cases.String())
+
+	var result []*enum
+	for _, e := range used {
+		result = append(result, e)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result
+}
+
+// generateEnumType emits the value set of the enum, written to its own file
+// shared by the models using it.
+func (g *Generator) generateEnumType(e *enum) {
+	g.Printf(repoEnumType, e.name, e.underlying, strings.Join(e.values, ", "))
+}
+
+const repoEnumIn = `
+// %[1]s%[2]sIn limits the query to the rows whose %[2]s is one of the values.
+func %[1]s%[2]sIn(values ...%[3]s) gormrepo.CriteriaOption {
+	return gormrepo.In("%[4]s", values)
+}
+`
+
+const repoEnumValidation = `
+func (r %[1]s) validateEnums(entity *%[2]s) error {
+%[3]s	return nil
+}
+
+func (r %[1]s) validateEnumFields(fields gormrepo.Fields) error {
+	for key, value := range fields {
+		ok := true
+		switch key {
+%[4]s		}
+		if !ok {
+			return fmt.Errorf("%%w: %%s %%v", gormrepo.ErrInvalidEnum, key, value)
+		}
+	}
+	return nil
+}
+`
+
+const repoEnumType = `
+// %[1]sValues are the valid values of %[1]s.
+var %[1]sValues = []%[1]s{%[3]s}
+
+// IsValid reports whether v is one of the %[1]sValues.
+func (v %[1]s) IsValid() bool {
+	for _, valid := range %[1]sValues {
+		if v == valid {
+			return true
+		}
+	}
+	return false
+}
+
+// valid%[1]s reports whether the value of an update is a valid %[1]s, as
+// such or as its underlying type. Nil is valid for nullable columns.
+func valid%[1]s(value interface{}) bool {
+	switch v := value.(type) {
+	case nil:
+		return true
+	case %[1]s:
+		return v.IsValid()
+	case *%[1]s:
+		return v == nil || v.IsValid()
+	case %[2]s:
+		return %[1]s(v).IsValid()
+	}
+	return false
+}
+`
@@ -0,0 +1,412 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/inflection"
+)
+
+const (
+	erdMermaid = "mermaid"
+	erdDot     = "dot"
+)
+
+// relation kinds, as gorm associations.
+const (
+	belongsTo  = "belongs to"
+	hasOne     = "has one"
+	hasMany    = "has many"
+	manyToMany = "many to many"
+)
+
+// relation is an association of a model with another of the documented types.
+type relation struct {
+	field, kind, target string
+	foreignKey          string // Column of the foreign key, in the table of the owner for belongs to.
+	joinTable           string // Join table of many to many relations.
+}
+
+// entity is a documented type and its table.
+type entity struct {
+	typeName, table, doc string
+	view                 bool
+	columns              []modelColumn
+	relations            []relation
+}
+
+// erdFormats returns the diagram formats of -erd.
+func (g *Generator) erdFormats() map[string]bool {
+	formats := map[string]bool{}
+	if g.opts.ERD == "" {
+		return formats
+	}
+	for _, format := range strings.Split(g.opts.ERD, ",") {
+		format = strings.TrimSpace(format)
+		if format != erdMermaid && format != erdDot {
+			fatalf("unknown schema documentation format %q", format)
+		}
+		formats[format] = true
+	}
+	return formats
+}
+
+// generateERD writes the data dictionary and the diagrams of the types
+// generated by the run next to the first one.
+func (g *Generator) generateERD() {
+	formats := g.erdFormats()
+	if len(formats) == 0 || len(g.registered) == 0 {
+		return
+	}
+	entities := g.entities()
+	absPath, _ := filepath.Abs(g.registered[0].file.name)
+	dir := filepath.Dir(absPath)
+
+	var md strings.Builder
+	md.WriteString("<!-- Code generated by gormrepogen. DO NOT EDIT. -->\n\n# Data Dictionary\n")
+	if formats[erdMermaid] {
+		md.WriteString("\n```mermaid\n" + mermaidDiagram(entities) + "```\n")
+	}
+	if formats[erdDot] {
+		md.WriteString("\nThe diagram is in [schema.dot](schema.dot).\n")
+		g.stageSchema(filepath.Join(dir, "schema.dot"), dotDiagram(entities))
+	}
+	for _, e := range entities {
+		md.WriteString(dictionaryEntry(e, columnKeys(e, entities)))
+	}
+	g.stageSchema(filepath.Join(dir, "schema.md"), md.String())
+}
+
+func (g *Generator) stageSchema(name, content string) {
+	g.stage(name, []byte(content), fmt.Sprintf("Schema documentation is generated: %s", name))
+}
+
+// entities returns the generated types with their columns and their
+// relations with one another, foreign keys on the side of their table.
+func (g *Generator) entities() []*entity {
+	byType := map[string]*entity{}
+	var entities []*entity
+	for _, r := range g.registered {
+		e := &entity{
+			typeName: r.typeName,
+			table:    g.tableName(r.typeName),
+			doc:      g.typeDoc(r.typeName),
+			view:     r.view,
+			columns:  g.modelColumns(g.getStructType(r.typeName)),
+		}
+		byType[r.typeName] = e
+		entities = append(entities, e)
+	}
+	for _, e := range entities {
+		e.relations = g.relations(e.typeName, byType)
+	}
+	return entities
+}
+
+// relations returns the associations of the type with the documented types.
+func (g *Generator) relations(typeName string, documented map[string]*entity) []relation {
+	var result []relation
+	st := g.getStructType(typeName)
+	if st == nil {
+		return result
+	}
+	for _, field := range st.Fields.List {
+		if len(field.Names) == 0 {
+			continue
+		}
+		settings := gormTag(field)
+		if _, ignored := settings["-"]; ignored {
+			continue
+		}
+		typ, many := field.Type, false
+		if array, ok := typ.(*ast.ArrayType); ok {
+			typ, many = array.Elt, true
+		}
+		if star, ok := typ.(*ast.StarExpr); ok {
+			typ = star.X
+		}
+		ident, ok := typ.(*ast.Ident)
+		if !ok || documented[ident.Name] == nil {
+			continue
+		}
+		for _, name := range field.Names {
+			r := relation{field: name.Name, target: ident.Name}
+			switch {
+			case many && settings["MANY2MANY"] != "":
+				r.kind, r.joinTable = manyToMany, settings["MANY2MANY"]
+			case many:
+				r.kind, r.foreignKey = hasMany, g.foreignKey(settings, typeName+"ID", ident.Name)
+			case settings["FOREIGNKEY"] != "" && g.hasField(st, settings["FOREIGNKEY"]),
+				settings["FOREIGNKEY"] == "" && g.hasField(st, name.Name+"ID"):
+				r.kind, r.foreignKey = belongsTo, g.foreignKey(settings, name.Name+"ID", typeName)
+			default:
+				r.kind, r.foreignKey = hasOne, g.foreignKey(settings, typeName+"ID", ident.Name)
+			}
+			result = append(result, r)
+		}
+	}
+	return result
+}
+
+// foreignKey returns the column of the foreign key field of the owner type,
+// named by the foreignkey tag or the default field name.
+func (g *Generator) foreignKey(settings map[string]string, field, owner string) string {
+	if name := settings["FOREIGNKEY"]; name != "" {
+		field = name
+	}
+	if f := g.lookupField(g.getStructType(owner), field); f != nil {
+		return columnName(field, gormTag(f))
+	}
+	return gorm.ToColumnName(field)
+}
+
+// tableName returns the table of the type, as returned by its TableName
+// method when it returns a literal, or named by gorm after the type.
+func (g *Generator) tableName(typeName string) string {
+	for _, f := range g.files {
+		for _, d := range f.file.Decls {
+			fd, ok := d.(*ast.FuncDecl)
+			if !ok || fd.Name.Name != "TableName" || fd.Recv == nil || fd.Body == nil || len(fd.Body.List) != 1 {
+				continue
+			}
+			recv := fd.Recv.List[0].Type
+			if star, ok := recv.(*ast.StarExpr); ok {
+				recv = star.X
+			}
+			if types.ExprString(recv) != typeName {
+				continue
+			}
+			if ret, ok := fd.Body.List[0].(*ast.ReturnStmt); ok && len(ret.Results) == 1 {
+				if lit, ok := ret.Results[0].(*ast.BasicLit); ok {
+					if name, err := strconv.Unquote(lit.Value); err == nil {
+						return name
+					}
+				}
+			}
+		}
+	}
+	return inflection.Plural(gorm.ToTableName(typeName))
+}
+
+// typeDoc returns the doc comment of the type on one line.
+func (g *Generator) typeDoc(typeName string) string {
+	for _, f := range g.files {
+		for _, d := range f.file.Decls {
+			gd, ok := d.(*ast.GenDecl)
+			if !ok {
+				continue
+			}
+			for _, s := range gd.Specs {
+				if tp, ok := s.(*ast.TypeSpec); ok && tp.Name.Name == typeName {
+					doc := tp.Doc
+					if doc == nil {
+						doc = gd.Doc
+					}
+					return strings.Join(strings.Fields(doc.Text()), " ")
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// columnKeys returns the key markers of the columns of the entity: PK, FK
+// and UK as in Mermaid diagrams.
+func columnKeys(e *entity, entities []*entity) map[string][]string {
+	keys := map[string][]string{}
+	hasPrimary := false
+	for _, c := range e.columns {
+		if _, ok := c.settings["PRIMARY_KEY"]; ok {
+			hasPrimary = true
+		}
+	}
+	for _, c := range e.columns {
+		_, primary := c.settings["PRIMARY_KEY"]
+		if primary || !hasPrimary && c.name == "id" {
+			keys[c.name] = append(keys[c.name], "PK")
+		}
+	}
+	for _, owner := range entities {
+		for _, r := range owner.relations {
+			fk := r.kind == belongsTo && owner == e || (r.kind == hasOne || r.kind == hasMany) && r.target == e.typeName
+			if fk && !containsString(keys[r.foreignKey], "FK") {
+				keys[r.foreignKey] = append(keys[r.foreignKey], "FK")
+			}
+		}
+	}
+	for _, c := range e.columns {
+		_, unique := c.settings["UNIQUE"]
+		if index, ok := c.settings["UNIQUE_INDEX"]; unique || ok && index == "" {
+			keys[c.name] = append(keys[c.name], "UK")
+		}
+	}
+	return keys
+}
+
+// edges returns the relations of the entities once per pair of tables and
+// cardinality, as parent, child, label and cardinality: "one", "many" or
+// "many to many".
+func edges(entities []*entity) [][4]string {
+	tables := map[string]string{}
+	for _, e := range entities {
+		tables[e.typeName] = e.table
+	}
+	seen := map[string]bool{}
+	var result [][4]string
+	for _, e := range entities {
+		for _, r := range e.relations {
+			parent, child, card := e.table, tables[r.target], "one"
+			switch r.kind {
+			case belongsTo:
+				parent, child, card = tables[r.target], e.table, "many"
+			case hasMany:
+				card = "many"
+			case manyToMany:
+				card = manyToMany
+				if child < parent {
+					parent, child = child, parent
+				}
+			}
+			key := parent + "\x00" + child + "\x00" + card
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			result = append(result, [4]string{parent, child, r.field, card})
+		}
+	}
+	return result
+}
+
+func containsString(values []string, s string) bool {
+	for _, v := range values {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+var mermaidUnsafe = regexp.MustCompile(`[^A-Za-z0-9_\[\]]`)
+
+// mermaidType returns the Go type as a Mermaid attribute type, slices
+// suffixed with [].
+func mermaidType(goType string) string {
+	suffix := ""
+	for strings.HasPrefix(goType, "[]") {
+		goType, suffix = goType[2:], suffix+"[]"
+	}
+	return mermaidUnsafe.ReplaceAllString(goType, "_") + suffix
+}
+
+func mermaidDiagram(entities []*entity) string {
+	var b strings.Builder
+	b.WriteString("erDiagram\n")
+	for _, e := range entities {
+		keys := columnKeys(e, entities)
+		fmt.Fprintf(&b, "    %s {\n", e.table)
+		for _, c := range e.columns {
+			fmt.Fprintf(&b, "        %s %s", mermaidType(c.goType), c.name)
+			if k := keys[c.name]; len(k) > 0 {
+				fmt.Fprintf(&b, " %s", strings.Join(k, ", "))
+			}
+			if c.doc != "" {
+				fmt.Fprintf(&b, " %q", strings.Replace(c.doc, `"`, "'", -1))
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("    }\n")
+	}
+	for _, edge := range edges(entities) {
+		arrow := map[string]string{"one": "||--o|", "many": "||--o{", manyToMany: "}o--o{"}[edge[3]]
+		fmt.Fprintf(&b, "    %s %s %s : %q\n", edge[0], arrow, edge[1], edge[2])
+	}
+	return b.String()
+}
+
+var dotEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "{", `\{`, "}", `\}`, "|", `\|`, "<", `\<`, ">", `\>`)
+
+func dotDiagram(entities []*entity) string {
+	var b strings.Builder
+	b.WriteString("// Code generated by gormrepogen. DO NOT EDIT.\n\ndigraph schema {\n\trankdir=LR;\n\tnode [shape=record];\n")
+	for _, e := range entities {
+		keys := columnKeys(e, entities)
+		var rows []string
+		for _, c := range e.columns {
+			row := c.name + " : " + c.goType
+			if k := keys[c.name]; len(k) > 0 {
+				row += " " + strings.Join(k, ", ")
+			}
+			rows = append(rows, dotEscaper.Replace(row)+`\l`)
+		}
+		fmt.Fprintf(&b, "\t%q [label=\"{%s|%s}\"];\n", e.table, dotEscaper.Replace(e.table), strings.Join(rows, ""))
+	}
+	for _, edge := range edges(entities) {
+		tail := map[string]string{"one": "teeodot", "many": "crowodot", manyToMany: "crowodot"}[edge[3]]
+		head := map[string]string{"one": "tee", "many": "tee", manyToMany: "crowodot"}[edge[3]]
+		fmt.Fprintf(&b, "\t%q -> %q [label=%q, dir=both, arrowtail=%s, arrowhead=%s];\n", edge[1], edge[0], edge[2], tail, head)
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+var markdownEscaper = strings.NewReplacer("|", `\|`, "\n", " ")
+
+// dictionaryEntry returns the Markdown section of the entity.
+func dictionaryEntry(e *entity, keys map[string][]string) string {
+	var b strings.Builder
+	kind := "Table"
+	if e.view {
+		kind = "View"
+	}
+	fmt.Fprintf(&b, "\n## %s\n\n%s `%s` of `%s`.", e.table, kind, e.table, e.typeName)
+	if e.doc != "" {
+		fmt.Fprintf(&b, " %s", e.doc)
+	}
+	b.WriteString("\n\n| Column | Type | Key | Constraints | Description |\n| --- | --- | --- | --- | --- |\n")
+	for _, c := range e.columns {
+		fmt.Fprintf(&b, "| `%s` | `%s` | %s | %s | %s |\n", c.name, c.goType, strings.Join(keys[c.name], ", "),
+			markdownEscaper.Replace(constraints(c.settings)), markdownEscaper.Replace(c.doc))
+	}
+	if len(e.relations) > 0 {
+		b.WriteString("\n")
+	}
+	for _, r := range e.relations {
+		fmt.Fprintf(&b, "- `%s` %s `%s`", r.field, r.kind, r.target)
+		switch {
+		case r.joinTable != "":
+			fmt.Fprintf(&b, " through `%s`", r.joinTable)
+		case r.foreignKey != "":
+			fmt.Fprintf(&b, " on `%s`", r.foreignKey)
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// constraintTags are the gorm tags listed as constraints of the columns, in
+// order.
+var constraintTags = []string{"PRIMARY_KEY", "TYPE", "SIZE", "NOT NULL", "UNIQUE", "UNIQUE_INDEX", "INDEX", "DEFAULT", "AUTO_INCREMENT"}
+
+// constraints returns the constraints of the column declared by its tag.
+func constraints(settings map[string]string) string {
+	var result []string
+	for _, key := range constraintTags {
+		value, ok := settings[key]
+		if !ok {
+			continue
+		}
+		item := strings.ToLower(strings.Replace(key, "_", " ", -1))
+		if value != "" {
+			item += " " + value
+		}
+		result = append(result, item)
+	}
+	return strings.Join(result, ", ")
+}
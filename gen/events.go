@@ -0,0 +1,28 @@
+package gen
+
+// generateEvents emits the typed change event of the type, shared by
+// interceptors, outbox and CDC code.
+func (g *Generator) generateEvents(repoNameRecv, typeName string) {
+	g.Printf(repoEvents, repoNameRecv, typeName)
+}
+
+const repoEvents = `
+// %[2]sChanged describes a change of a %[2]s. Old is nil for creates and New
+// for deletes, Fields holds the changed columns.
+type %[2]sChanged struct {
+	Op     gormrepo.Operation
+	Old    *%[2]s
+	New    *%[2]s
+	Fields []string
+}
+
+// Changed builds the change event of the entity, computing the changed columns.
+func (r %[1]s) Changed(op gormrepo.Operation, old, new *%[2]s) %[2]sChanged {
+	return %[2]sChanged{Op: op, Old: old, New: new, Fields: gormrepo.ChangedFields(r.DB, old, new)}
+}
+
+// Debezium returns the change in the Debezium envelope format.
+func (r %[1]s) Debezium(changed %[2]sChanged) gormrepo.DebeziumEnvelope {
+	return gormrepo.NewDebeziumEnvelope(r.DB, changed.Op, changed.Old, changed.New)
+}
+`
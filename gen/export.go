@@ -0,0 +1,69 @@
+package gen
+
+import (
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// baseRepoName returns the name of the generated base repository of the type.
+func (g *Generator) baseRepoName(typeName string) string {
+	if g.opts.Export {
+		return typeName + "BaseRepo"
+	}
+	return strings.ToLower(typeName[:1]) + typeName[1:] + "BaseRepo"
+}
+
+// writeRepoStub creates the file declaring the <T>Repo embedding the base
+// repository, where custom methods are added. It is never overwritten, nor
+// created when the package already declares <T>Repo. It has the build
+// constraint of the generated files.
+func (g *Generator) writeRepoStub(dir, pkgName, typeName, constraint string) {
+	if !g.opts.Export || g.getFileByTypeName(typeName+"Repo") != nil {
+		return
+	}
+	name := filepath.Join(dir, strings.ToLower(typeName)+"_repo.go")
+	if _, err := os.Stat(name); err == nil {
+		return
+	}
+	header := ""
+	if constraint != "" {
+		header = "//go:build " + constraint + "\n\n"
+	}
+	stub := repoStub
+	if len(g.outputVariants()) > 0 {
+		// The db of the constructor depends on the variant.
+		stub = repoStubVariants
+	}
+	src, err := format.Source([]byte(header + fmt.Sprintf(stub, pkgName, typeName)))
+	if err != nil {
+		fatalf("formatting stub: %s", err)
+	}
+	g.stage(name, src, fmt.Sprintf("Type %s repository stub is created: %s", typeName, name))
+}
+
+const repoStub = `package %[1]s
+
+import "github.com/jinzhu/gorm"
+
+// %[2]sRepo is the repository of %[2]s. Custom methods go in this file,
+// created once by gormrepogen and never overwritten.
+type %[2]sRepo struct {
+	*%[2]sBaseRepo
+}
+
+func New%[2]sRepo(db *gorm.DB) *%[2]sRepo {
+	return &%[2]sRepo{&%[2]sBaseRepo{db}}
+}
+`
+
+const repoStubVariants = `package %[1]s
+
+// %[2]sRepo is the repository of %[2]s. Custom methods go in this file,
+// created once by gormrepogen and never overwritten.
+type %[2]sRepo struct {
+	*%[2]sBaseRepo
+}
+`
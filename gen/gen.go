@@ -0,0 +1,944 @@
+// Copyright 2017 The Vitaly Lobchuk. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package gen generates the repositories of gorm models, the code written by
+// the gormrepogen command, for build tools embedding the generator:
+//
+//	g, err := gen.Load(nil, "./model")
+//	if err != nil {
+//		return err
+//	}
+//	src, err := g.Generate("User", gen.Options{Columns: true})
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/build"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+)
+
+// Options are the settings of the generation, the flags of gormrepogen.
+type Options struct {
+	Tree           bool   // Tree methods for self-referential types with a ParentID field.
+	TreeStrategies string // adjacency, path or closure, or a comma-separated list of Type=strategy.
+	DefaultOrder   bool   // GetBy and GetAll ordered by primary key after any OrderBy criteria.
+	Admin          bool
+	Columns        bool
+	Decorators     bool
+	DoubleWrite    bool
+	Enums          bool
+	ERD            string // Comma-separated list of schema documentation formats: mermaid, dot.
+	Export         bool
+	Header         string // File of the header template.
+	JSON           bool
+	Lang           string // Go version of the generated code, such as go1.21.
+	Merge          bool
+	Meta           bool
+	Notify         bool
+	Publish        bool
+	Registry       bool
+	Strict         bool
+	Rollout        bool
+	Shadow         bool
+	OutTags        string // Build constraint of the generated files.
+	Variants       string
+	Views          string
+	Check          bool
+
+	// Command is the command line printed in the header of the generated
+	// files, "gormrepogen" by default.
+	Command string
+}
+
+// Load parses the package of args, a directory or files of a single package,
+// selecting the files of the build tags. Files that do not parse are left
+// out, GenerateFiles fails on them with Options.Strict.
+func Load(tags []string, args ...string) (g *Generator, err error) {
+	defer recoverError(&err)
+	if len(args) == 0 {
+		args = []string{"."}
+	}
+	g = &Generator{}
+	g.context = build.Default
+	for _, tag := range tags {
+		if tag = strings.TrimSpace(tag); tag != "" {
+			g.context.BuildTags = append(g.context.BuildTags, tag)
+		}
+	}
+	if len(args) == 1 && isDirectory(args[0]) {
+		g.parsePackageDir(args[0])
+	} else {
+		g.parsePackageFiles(args)
+	}
+	return g, nil
+}
+
+// Generate returns the repository of the type, the content of its
+// <t>_base_repo.go file, without writing it.
+func (g *Generator) Generate(typeName string, opts Options) ([]byte, error) {
+	files, err := g.GenerateFiles([]string{typeName}, opts)
+	if err != nil {
+		return nil, err
+	}
+	return files[0].Src, nil
+}
+
+// Output is a file generated for the types.
+type Output struct {
+	Name string // Absolute path of the file.
+	Src  []byte
+}
+
+// GenerateFiles returns the files generated for the types without writing
+// them: the repositories first, with their enums, columns and variants, then
+// the registry and schema documentation.
+func (g *Generator) GenerateFiles(types []string, opts Options) (files []Output, err error) {
+	defer recoverError(&err)
+	g.prepare(opts)
+	if opts.Strict && len(g.parseErrors) > 0 {
+		return nil, g.parseErrors[0]
+	}
+	for _, typeName := range types {
+		if err := g.generate(typeName); err != nil {
+			g.pending = nil
+			return nil, err
+		}
+	}
+	g.generateRegistry()
+	g.generateERD()
+	for _, p := range g.pending {
+		files = append(files, Output{p.name, p.src})
+	}
+	g.pending = nil
+	return files, nil
+}
+
+// Write generates the types and writes their files as gormrepogen does,
+// printing the files written and the errors, and reports whether every type
+// was generated. Nothing is written when a type fails, or with
+// Options.Strict when a file does not parse. With Options.Check nothing is
+// written either, the files differing from the output are reported.
+func (g *Generator) Write(types []string, opts Options) (ok bool) {
+	defer recoverFailure(&ok)
+	g.prepare(opts)
+	if g.header != nil {
+		src, err := g.customHeader("gormrepogen", "p", "f.go")
+		if err == nil && !generatedBanner.Match(src) {
+			log.Printf("warning: header %s has no \"// Code generated ... DO NOT EDIT.\" line, linters will check the generated files", opts.Header)
+		}
+	}
+
+	var typeErrors []typeError
+	if opts.Strict {
+		for _, typeName := range types {
+			if _, err := g.lookupStruct(typeName); err != nil {
+				typeErrors = append(typeErrors, typeError{typeName, err})
+			}
+		}
+		if len(typeErrors) > 0 || len(g.parseErrors) > 0 {
+			g.report(types, 0, typeErrors)
+			return false
+		}
+	}
+
+	// Run generate for each type.
+	for _, typeName := range types {
+		if err := g.generate(typeName); err != nil {
+			typeErrors = append(typeErrors, typeError{typeName, err})
+		}
+	}
+	if len(typeErrors) > 0 {
+		// Previously generated files are left as they are.
+		g.pending = nil
+		g.report(types, 0, typeErrors)
+		return false
+	}
+	g.generateRegistry()
+	g.generateERD()
+	if opts.Check {
+		return g.checkPending() && !g.report(types, len(types), nil)
+	}
+	if err := g.commit(); err != nil {
+		log.Printf("writing output: %s", err)
+		return false
+	}
+	return !g.report(types, len(types), nil)
+}
+
+// prepare sets the options of a generation and clears the previous one.
+func (g *Generator) prepare(opts Options) {
+	if opts.Command == "" {
+		opts.Command = "gormrepogen"
+	}
+	g.opts = opts
+	g.registered, g.pending = nil, nil
+	g.buf.Reset()
+	g.imports = nil
+	g.langMinor = g.parseLang()
+	g.header = g.loadHeader()
+}
+
+// genError is the panic of fatalf, turned into the error of the generation
+// by the exported methods.
+type genError struct {
+	err error
+}
+
+// fatalf stops the generation with the error.
+func fatalf(format string, args ...interface{}) {
+	panic(genError{fmt.Errorf(format, args...)})
+}
+
+func recoverError(err *error) {
+	if e := recover(); e != nil {
+		ge, ok := e.(genError)
+		if !ok {
+			panic(e)
+		}
+		*err = ge.err
+	}
+}
+
+// recoverFailure prints the error of fatalf and sets ok to false.
+func recoverFailure(ok *bool) {
+	if e := recover(); e != nil {
+		ge, isGenError := e.(genError)
+		if !isGenError {
+			panic(e)
+		}
+		log.Print(ge.err)
+		*ok = false
+	}
+}
+
+func isDirectory(name string) bool {
+	info, err := os.Stat(name)
+	if err != nil {
+		fatalf("%s", err)
+	}
+	return info.IsDir()
+}
+
+// prefixDirectory places the directory name on the beginning of each name in the list.
+func prefixDirectory(directory string, names []string) []string {
+	if directory == "." {
+		return names
+	}
+	ret := make([]string, len(names))
+	for i, name := range names {
+		ret[i] = filepath.Join(directory, name)
+	}
+	return ret
+}
+
+type File struct {
+	name string    // Name of the constant type.
+	file *ast.File // Parsed AST.
+}
+
+// Generator generates the repositories of the types of a package.
+type Generator struct {
+	opts      Options
+	context   build.Context // Selects the files of the build tags.
+	langMinor int
+	header    *template.Template
+
+	buf     bytes.Buffer // Accumulated output.
+	fset    *token.FileSet
+	files   []*File
+	imports map[string]bool // Packages used by the accumulated output.
+
+	ignoredFiles []string // Files excluded by build constraints.
+	testFiles    []string
+	parseErrors  []error // Files of the package that do not parse.
+
+	registered []registered // Repositories generated, for the registry.
+	pending    []pendingFile
+}
+
+func (g *Generator) Printf(format string, args ...interface{}) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// Import records packages used by the generated code.
+func (g *Generator) Import(paths ...string) {
+	if g.imports == nil {
+		g.imports = map[string]bool{}
+	}
+	for _, path := range paths {
+		g.imports[path] = true
+	}
+}
+
+// writeHeader places the header, build constraint, package clause and imports
+// before the accumulated output.
+func (g *Generator) writeHeader(pkgName, fileName, constraint string) {
+	body := append([]byte(nil), g.buf.Bytes()...)
+	g.buf.Reset()
+
+	// Standard library packages go first, separated from the others.
+	var std, paths []string
+	for path := range g.imports {
+		if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+			paths = append(paths, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(paths)
+	if len(std) > 0 && len(paths) > 0 {
+		std = append(std, "")
+	}
+	paths = append(std, paths...)
+
+	if g.header != nil {
+		header, err := g.customHeader(g.command(), pkgName, fileName)
+		if err != nil {
+			fatalf("executing header: %s", err)
+		}
+		g.buf.Write(header)
+	} else if g.opts.Merge {
+		// The file is edited outside the regions, a stable header keeps
+		// it from being stale after a merge.
+		g.Printf("%s gormrepogen. Code outside the gormrepogen:begin/end regions is kept on regeneration.\n\n", generatedHeader)
+	} else {
+		g.Printf("%s \"%s\"; DO NOT EDIT\n\n", generatedHeader, g.command())
+	}
+	if constraint != "" {
+		g.Printf("//go:build %s\n\n", constraint)
+	}
+	g.Printf("package %s", pkgName)
+	g.Printf("\n")
+	if len(paths) > 0 {
+		g.Printf("import (\n")
+		for _, path := range paths {
+			if path == "" {
+				g.Printf("\n")
+				continue
+			}
+			g.Printf("  %q\n", path)
+		}
+		g.Printf(")\n")
+	}
+	if g.opts.Merge {
+		g.Printf("\n%s\n", beginMarker)
+		g.buf.Write(body)
+		g.Printf("\n%s\n", endMarker)
+		return
+	}
+	g.buf.Write(body)
+}
+
+func (g *Generator) parsePackageDir(directory string) {
+	pkg, err := g.context.ImportDir(directory, 0)
+	if err != nil {
+		fatalf("cannot process directory %s: %s", directory, err)
+	}
+	var names []string
+	names = append(names, pkg.GoFiles...)
+	names = append(names, pkg.CgoFiles...)
+	names = append(names, pkg.SFiles...)
+	names = prefixDirectory(directory, names)
+	for _, name := range pkg.IgnoredGoFiles {
+		if !strings.HasSuffix(name, "_test.go") {
+			g.ignoredFiles = append(g.ignoredFiles, filepath.Join(directory, name))
+		}
+	}
+	g.testFiles = prefixDirectory(directory, append(pkg.TestGoFiles, pkg.XTestGoFiles...))
+
+	g.parsePackage(directory, names, nil)
+}
+
+func (g *Generator) parsePackageFiles(names []string) {
+	// Files are parsed in name order, as in a directory, whatever the
+	// order they are given in.
+	names = append([]string(nil), names...)
+	sort.Strings(names)
+	g.parsePackage(".", names, nil)
+}
+
+func (g *Generator) parsePackage(directory string, names []string, text interface{}) {
+	var files []*File
+	fs := token.NewFileSet()
+	for _, name := range names {
+		if !strings.HasSuffix(name, ".go") {
+			continue
+		}
+		parsedFile, err := parser.ParseFile(fs, name, text, parser.ParseComments)
+		if err != nil {
+			// Types of other files can still be generated, -strict
+			// fails on it.
+			g.parseErrors = append(g.parseErrors, err)
+			continue
+		}
+
+		files = append(files, &File{
+			file: parsedFile,
+			name: name,
+		})
+	}
+	if len(files) == 0 {
+		if len(g.parseErrors) > 0 {
+			fatalf("%s: no buildable Go files: %s", directory, g.parseErrors[0])
+		}
+		fatalf("%s: no buildable Go files", directory)
+	}
+
+	g.fset = fs
+	g.files = files
+}
+
+func (g *Generator) format() []byte {
+	src, err := format.Source(g.buf.Bytes())
+	if err != nil {
+		// Should never happen, but can arise when developing this code.
+		// The user can compile the output to see the error.
+		log.Printf("warning: internal error: invalid Go generated: %s", err)
+		log.Printf("warning: compile the package to analyze the error")
+		return g.buf.Bytes()
+	}
+	return src
+}
+
+func (g *Generator) ucFirst(s string) string {
+	return strings.ToUpper(s[:1]) + s[1:]
+}
+
+func (g *Generator) lcFirst(s string) string {
+	return strings.ToLower(s[:1]) + s[1:]
+}
+
+func (g *Generator) getFileByTypeName(typeName string) *File {
+	decl, _ := g.lookupTypeSpec(typeName)
+	return decl.file
+}
+
+// getStructType returns the struct type of the named type, nil when it is
+// not a struct of the package.
+func (g *Generator) getStructType(typeName string) *ast.StructType {
+	st, _ := g.lookupStruct(typeName)
+	return st
+}
+
+// hasField reports whether the struct declares a field with the given name.
+func (g *Generator) hasField(st *ast.StructType, name string) bool {
+	return g.lookupField(st, name) != nil
+}
+
+func (g *Generator) lookupField(st *ast.StructType, name string) *ast.Field {
+	if st == nil {
+		return nil
+	}
+	for _, field := range st.Fields.List {
+		for _, ident := range field.Names {
+			if ident.Name == name {
+				return field
+			}
+		}
+	}
+	return nil
+}
+
+// fieldType returns the type expression of the named field, or "" when the
+// struct does not declare it.
+func (g *Generator) fieldType(st *ast.StructType, name string) string {
+	if field := g.lookupField(st, name); field != nil {
+		return types.ExprString(field.Type)
+	}
+	return ""
+}
+
+// generate repository for the named type.
+func (g *Generator) generate(typeName string) error {
+	f := g.getFileByTypeName(typeName)
+	if _, err := g.lookupStruct(typeName); err != nil {
+		return err
+	}
+	repoName := g.baseRepoName(typeName)
+	repoNameRecv := "*" + repoName
+	typeNameWithPointer := "*" + typeName
+
+	strategy := ""
+	if g.opts.Tree {
+		strategy = g.treeStrategy(typeName)
+	}
+	view := g.viewKind(typeName)
+	var usedEnums []*enum
+
+	g.Import("github.com/jinzhu/gorm", "github.com/l-vitaly/gormrepo")
+	g.Printf(baseRepo, repoName)
+	g.Printf(repoApplyCriteria, repoNameRecv, typeName)
+	g.Printf(repoRelated, repoNameRecv, typeNameWithPointer)
+	g.Printf(repoGet, repoNameRecv, typeNameWithPointer, typeName)
+	g.Printf(repoGetAll, repoNameRecv, typeNameWithPointer)
+	order := ""
+	if g.opts.DefaultOrder {
+		// The primary key is appended to the order by the query callback.
+		order = `.Set("gorm:order_by_primary_key", "ASC")`
+	}
+	g.Printf(repoGetBy, repoNameRecv, typeNameWithPointer, order)
+	g.Printf(repoGetByNamed, repoNameRecv, typeNameWithPointer, order)
+	g.Printf(repoGetByFirst, repoNameRecv, typeNameWithPointer, typeName)
+	g.Printf(repoGetByLast, repoNameRecv, typeNameWithPointer, typeName)
+	g.Printf(repoCount, repoNameRecv, typeName)
+	g.Printf(repoRawScan, repoNameRecv)
+	g.Import("time")
+	g.Printf(repoCountByPeriod, repoNameRecv, typeName)
+	g.Printf(repoAggregate, repoNameRecv, typeName)
+	// The path and closure trees get the writes keeping their paths and
+	// closure rows consistent only, from generateTree.
+	treeRows := strategy == treePath || strategy == treeClosure
+	if view == "" {
+		if !treeRows {
+			g.Printf(repoCreate, repoNameRecv, typeName, typeNameWithPointer, g.enumValidation(typeName))
+			g.Printf(repoCopyFrom, repoNameRecv, typeNameWithPointer)
+		}
+		// The idempotency keys record the created ids.
+		if g.integerID(g.getStructType(typeName)) {
+			g.Import("context")
+			g.Printf(repoCreateContext, repoNameRecv, typeName, typeNameWithPointer, repoName)
+		}
+		if !treeRows {
+			g.Printf(repoUpdate, repoNameRecv, typeNameWithPointer, g.enumFieldsValidation(typeName))
+			g.Printf(repoBulkUpdateByKey, repoNameRecv, typeName, g.enumPairsValidation(typeName))
+			g.Printf(repoDelete, repoNameRecv, typeNameWithPointer)
+			g.Printf(repoDeleteAndGet, repoNameRecv, typeNameWithPointer)
+			g.Printf(repoDeleteBatch, repoNameRecv, typeName, repoName)
+			g.Printf(repoArchive, repoNameRecv, typeName, repoName)
+			g.generateUpsert(repoNameRecv, typeName)
+		}
+	}
+	g.Printf(repoChecksum, repoNameRecv, typeName)
+	g.Printf(repoStats, repoNameRecv, typeName)
+	switch view {
+	case "":
+		if !treeRows {
+			g.Printf(repoSeed, repoNameRecv, typeName)
+		}
+		g.Printf(repoAutomigrate, repoNameRecv, typeName)
+		g.Printf(repoTruncate, repoNameRecv, typeName)
+		g.Printf(repoAddUniqueIndex, repoNameRecv, typeName)
+		g.Printf(repoAddForeignKey, repoNameRecv, typeName)
+		g.Printf(repoAddIndex, repoNameRecv, typeName)
+	case viewMaterialized:
+		g.Printf(repoRefresh, repoNameRecv, typeName)
+	}
+
+	g.generateEvents(repoNameRecv, typeName)
+	g.generatePublished(repoNameRecv, typeName)
+	g.generateShadow(repoName, typeName)
+	g.generateAdmin(repoNameRecv, typeName)
+	g.generateMeta(typeName)
+	g.generateJSONColumns(typeName)
+
+	// Views get no generated method writing to them.
+	if view == "" {
+		g.generateState(repoNameRecv, typeName)
+		g.generateI18n(repoNameRecv, typeName)
+		g.generateBlobs(repoNameRecv, typeName)
+		g.generateNotify(repoName, typeName)
+		g.generatePublish(repoName, typeName)
+		g.generateRollout(repoName, typeName)
+		usedEnums = g.generateEnums(repoNameRecv, typeName)
+		if g.opts.Tree {
+			g.generateTree(strategy, repoName, typeName)
+		}
+		// Passes the reads through, once generated.
+		g.generateDoubleWrite(strategy, repoName, typeName)
+	}
+
+	// Generated last, once the methods returning entities are.
+	g.generateMask(repoName, typeName)
+	g.generateDecorators(repoName, typeName)
+
+	g.register(f, typeName, repoName, view, strategy)
+
+	extra := g.outputVariants()
+	constraint := g.outputConstraint(f.name)
+	dir := g.writeOutput(f, typeName, "repository", typeName+"_base_repo.go", andConstraint(constraint, gormV1Constraint(extra)))
+	for _, variant := range extra {
+		g.generateGormV2(repoName, typeName)
+		g.writeOutput(f, typeName, "repository", typeName+"_base_repo_"+variant+".go", andConstraint(constraint, variant))
+	}
+	for _, e := range usedEnums {
+		g.generateEnumType(e)
+		g.writeOutput(f, e.name, "enum", e.name+"_enum.go", constraint)
+	}
+	if g.opts.Columns || g.langAtLeast(18) {
+		// The descriptors need generics, built with go1.18 unless -lang
+		// already targets it.
+		columnsConstraint := constraint
+		if !g.langAtLeast(18) {
+			columnsConstraint = andConstraint(constraint, "go1.18")
+		}
+		g.generateColumns(f, typeName)
+		g.writeOutput(f, typeName, "columns", typeName+"_columns.go", columnsConstraint)
+	}
+	g.writeRepoStub(dir, f.file.Name.Name, typeName, constraint)
+	return nil
+}
+
+// writeOutput writes the accumulated output to the named file next to the
+// type, lower cased, with the build constraint if any, and returns the
+// directory of the file.
+func (g *Generator) writeOutput(f *File, typeName, kind, baseName, constraint string) string {
+	// Print the header and package clause.
+	g.writeHeader(f.file.Name.Name, strings.ToLower(baseName), constraint)
+
+	//Format the output.
+	src := g.applyLang(g.format())
+
+	absPath, _ := filepath.Abs(f.name)
+	dir := filepath.Dir(absPath)
+
+	outputName := filepath.Join(dir, strings.ToLower(baseName))
+
+	src, err := g.mergeOutput(outputName, src)
+	if err != nil {
+		fatalf("merging output: %s: %s", outputName, err)
+	}
+	g.stage(outputName, src, fmt.Sprintf("Type %s %s is generated: %s", typeName, kind, outputName))
+
+	g.buf.Reset()
+	g.imports = nil
+	return dir
+}
+
+const baseRepo = `
+type %[1]s struct {
+    *gorm.DB
+}`
+
+const repoApplyCriteria = `
+func (r %[1]s) applyCriteria(criteria []gormrepo.CriteriaOption) *gorm.DB {
+	// The criteria checking their columns see the model.
+	return gormrepo.ApplyCriteria(r.DB.Model(&%[2]s{}), criteria...)
+}
+
+func (r %[1]s) wrapError(method string, err error, criteria ...gormrepo.CriteriaOption) error {
+	return gormrepo.WrapError(err, %[2]q, method, criteria...)
+}
+`
+
+const repoRelated = `
+func (r %[1]s) Related(claim %[2]s, related interface{}, criteria ...gormrepo.CriteriaOption) error {
+	err := r.applyCriteria(criteria).Model(claim).Related(related).Error
+	return r.wrapError("Related", err, criteria...)
+}
+`
+
+const repoGet = `
+func (r %[1]s) Get(id uint) (%[2]s, error) {
+    var entity %[3]s
+	err := r.DB.Where(map[string]interface{}{"id": id}).Find(&entity).Error
+	return &entity, r.wrapError("Get", err)
+}`
+
+const repoGetAll = `
+func (r %[1]s) GetAll() ([]%[2]s, error) {
+    return r.GetBy()
+}
+`
+
+const repoGetBy = `
+func (r %[1]s) GetBy(criteria ...gormrepo.CriteriaOption) ([]%[2]s, error) {
+    var entities []%[2]s
+	err := r.applyCriteria(criteria)%[3]s.Find(&entities).Error
+	return entities, r.wrapError("GetBy", err, criteria...)
+}
+`
+
+const repoGetByNamed = `
+func (r %[1]s) GetByNamed(name string, args ...interface{}) ([]%[2]s, error) {
+	criteria, err := gormrepo.NamedQuery(name, args...)
+	if err != nil {
+		return nil, r.wrapError("GetByNamed", err)
+	}
+	var entities []%[2]s
+	err = r.applyCriteria(criteria)%[3]s.Find(&entities).Error
+	return entities, r.wrapError("GetByNamed "+name, err, criteria...)
+}
+`
+
+const repoGetByFirst = `
+func (r %[1]s) GetByFirst(criteria ...gormrepo.CriteriaOption) (%[2]s, error) {
+    var entity %[3]s
+	err := r.applyCriteria(criteria).First(&entity).Error
+	return &entity, r.wrapError("GetByFirst", err, criteria...)
+}
+`
+
+const repoGetByLast = `
+func (r %[1]s) GetByLast(criteria ...gormrepo.CriteriaOption) (%[2]s, error) {
+    var entity %[3]s
+	err := r.applyCriteria(criteria).Last(&entity).Error
+	return &entity, r.wrapError("GetByLast", err, criteria...)
+}
+`
+
+const repoCount = `
+func (r %[1]s) Count(criteria ...gormrepo.CriteriaOption) (int, error) {
+	var count int
+	err := r.applyCriteria(criteria).Model(&%[2]s{}).Count(&count).Error
+	return count, r.wrapError("Count", err, criteria...)
+}
+`
+
+const repoRawScan = `
+// RawScan runs the statement sql, with its arguments bound, and scans its
+// rows into dest, see gormrepo.Raw.
+func (r %[1]s) RawScan(dest interface{}, sql string, args ...interface{}) error {
+	raw := gormrepo.Raw(sql, args...)
+	err := r.applyCriteria([]gormrepo.CriteriaOption{raw}).Scan(dest).Error
+	return r.wrapError("RawScan", err, raw)
+}
+`
+
+const repoCountByPeriod = `
+func (r %[1]s) CountByPeriod(column string, period gormrepo.Period, criteria ...gormrepo.CriteriaOption) (map[time.Time]int64, error) {
+	counts, err := gormrepo.CountByPeriod(r.applyCriteria(criteria).Model(&%[2]s{}), column, period)
+	return counts, r.wrapError("CountByPeriod", err, criteria...)
+}
+`
+
+const repoAggregate = `
+func (r %[1]s) Percentiles(column string, ps []float64, criteria ...gormrepo.CriteriaOption) ([]float64, error) {
+	values, err := gormrepo.Percentiles(r.applyCriteria(criteria).Model(&%[2]s{}), column, ps...)
+	return values, r.wrapError("Percentiles", err, criteria...)
+}
+
+func (r %[1]s) Histogram(column string, min, max float64, buckets int, criteria ...gormrepo.CriteriaOption) ([]gormrepo.Bucket, error) {
+	histogram, err := gormrepo.Histogram(r.applyCriteria(criteria).Model(&%[2]s{}), column, min, max, buckets)
+	return histogram, r.wrapError("Histogram", err, criteria...)
+}
+`
+
+const repoCreate = `
+func (r %[1]s) Create(entity %[2]s) (%[3]s, error) {
+    if !r.DB.NewRecord(entity) {
+		return nil, r.wrapError("Create", gormrepo.ErrPrimaryNotBlank)
+	}%[4]s
+	err := r.DB.Create(&entity).Error
+	if err != nil {
+		return nil, r.wrapError("Create", err)
+	}
+	return &entity, nil
+}
+`
+
+const repoCopyFrom = `
+// CopyFrom inserts the entities in bulk, see gormrepo.CopyFrom.
+func (r %[1]s) CopyFrom(entities []%[2]s) error {
+	return r.wrapError("CopyFrom", gormrepo.CopyFrom(r.DB, entities))
+}
+`
+
+const repoCreateContext = `
+func (r %[1]s) CreateContext(ctx context.Context, entity %[2]s) (%[3]s, error) {
+	key, ok := gormrepo.IdempotencyKeyFromContext(ctx)
+	if !ok {
+		return r.Create(entity)
+	}
+	table := r.DB.NewScope(&entity).TableName()
+	var created %[3]s
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		repo := &%[4]s{tx}
+		id, found, err := gormrepo.FindIdempotencyKey(tx, key, table)
+		if err != nil {
+			return err
+		}
+		if found {
+			created, err = repo.Get(id)
+			return err
+		}
+		if created, err = repo.Create(entity); err != nil {
+			return err
+		}
+		return gormrepo.SaveIdempotencyKey(tx, key, table, uint(created.ID))
+	})
+	if err != nil {
+		return nil, r.wrapError("CreateContext", err)
+	}
+	return created, nil
+}
+`
+
+const repoUpdate = `
+func (r %[1]s) Update(entity %[2]s, fields gormrepo.Fields, criteria ...gormrepo.CriteriaOption) error {%[3]s
+	err := r.applyCriteria(criteria).Model(entity).Updates(fields).Error
+	return r.wrapError("Update", err, criteria...)
+}
+`
+
+const repoBulkUpdateByKey = `
+// BulkUpdateByKey updates the fields of the rows by primary key, see
+// gormrepo.BulkUpdateByKey.
+func (r %[1]s) BulkUpdateByKey(pairs map[uint]gormrepo.Fields) (int64, error) {%[3]s
+	n, err := gormrepo.BulkUpdateByKey(r.DB, &%[2]s{}, pairs)
+	return n, r.wrapError("BulkUpdateByKey", err)
+}
+`
+
+const repoDelete = `
+func (r %[1]s) Delete(entity %[2]s, criteria ...gormrepo.CriteriaOption) error {
+	err := r.applyCriteria(criteria).Delete(entity).Error
+	return r.wrapError("Delete", err, criteria...)
+}
+`
+
+const repoDeleteAndGet = `
+// DeleteAndGet deletes the rows matching the criteria and returns them, see
+// gormrepo.DeleteReturning.
+func (r %[1]s) DeleteAndGet(criteria ...gormrepo.CriteriaOption) ([]%[2]s, error) {
+	var entities []%[2]s
+	err := gormrepo.DeleteReturning(r.DB, &entities, criteria...)
+	return entities, r.wrapError("DeleteAndGet", err, criteria...)
+}
+`
+
+const repoDeleteBatch = `
+func (r %[1]s) batchIDs(size int, criteria []gormrepo.CriteriaOption) (string, []interface{}, error) {
+	scope := r.DB.NewScope(&%[2]s{})
+	key := scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())
+	var ids []interface{}
+	err := r.applyCriteria(criteria).Model(&%[2]s{}).Limit(size).Pluck(key, &ids).Error
+	return key, ids, err
+}
+
+func (r %[1]s) DeleteBatch(size int, criteria ...gormrepo.CriteriaOption) (int64, error) {
+	key, ids, err := r.batchIDs(size, criteria)
+	if err != nil || len(ids) == 0 {
+		return 0, r.wrapError("DeleteBatch", err, criteria...)
+	}
+	result := r.applyCriteria(criteria).Where(key+" IN (?)", ids).Delete(&%[2]s{})
+	return result.RowsAffected, r.wrapError("DeleteBatch", result.Error, criteria...)
+}
+
+func (r %[1]s) ArchiveBatch(table string, size int, criteria ...gormrepo.CriteriaOption) (int64, error) {
+	var archived int64
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		repo := &%[3]s{tx}
+		key, ids, err := repo.batchIDs(size, criteria)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		insert := "INSERT INTO " + tx.Dialect().Quote(table) + " SELECT * FROM " + tx.NewScope(&%[2]s{}).QuotedTableName() + " WHERE " + key + " IN (?)"
+		if err := tx.Exec(insert, ids).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where(key+" IN (?)", ids).Delete(&%[2]s{})
+		archived = result.RowsAffected
+		return result.Error
+	})
+	return archived, r.wrapError("ArchiveBatch", err, criteria...)
+}
+`
+
+const repoArchive = `
+func (r %[1]s) Archive(sink gormrepo.Sink, size int, criteria ...gormrepo.CriteriaOption) (int64, error) {
+	if size <= 0 {
+		size = gormrepo.ArchiveBatchSize
+	}
+	var archived int64
+	for {
+		var n int
+		err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+			scope := tx.NewScope(&%[2]s{})
+			key := scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())
+			var entities []*%[2]s
+			if err := (&%[3]s{tx}).applyCriteria(criteria).Order(key).Limit(size).Find(&entities).Error; err != nil {
+				return err
+			}
+			n = len(entities)
+			if n == 0 {
+				return nil
+			}
+			ids := make([]interface{}, 0, n)
+			for _, entity := range entities {
+				if err := sink.Write(entity); err != nil {
+					return err
+				}
+				ids = append(ids, tx.NewScope(entity).PrimaryKeyValue())
+			}
+			if err := sink.Flush(); err != nil {
+				return err
+			}
+			return tx.Unscoped().Where(key+" IN (?)", ids).Delete(&%[2]s{}).Error
+		})
+		if err != nil {
+			return archived, r.wrapError("Archive", err, criteria...)
+		}
+		archived += int64(n)
+		if n < size {
+			return archived, nil
+		}
+	}
+}
+`
+
+const repoChecksum = `
+func (r %[1]s) Checksum(criteria ...gormrepo.CriteriaOption) (string, error) {
+	scope := r.DB.NewScope(&%[2]s{})
+	rows, err := r.applyCriteria(criteria).Model(&%[2]s{}).Order(scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())).Rows()
+	if err != nil {
+		return "", r.wrapError("Checksum", err, criteria...)
+	}
+	sum, err := gormrepo.Checksum(rows)
+	return sum, r.wrapError("Checksum", err, criteria...)
+}
+`
+
+const repoStats = `
+func (r %[1]s) Stats() gormrepo.Stats {
+	return gormrepo.TableStats(r.DB.NewScope(&%[2]s{}).TableName())
+}
+`
+
+const repoSeed = `
+// SeedDemoData inserts n demo rows, see gormrepo.Seed.
+func (r %[1]s) SeedDemoData(n int) error {
+	return r.wrapError("SeedDemoData", gormrepo.Seed(r.DB, n, &%[2]s{}))
+}
+`
+
+const repoAutomigrate = `
+func (r %[1]s) AutoMigrate() error {
+	return r.wrapError("AutoMigrate", r.DB.AutoMigrate(&%[2]s{}).Error)
+}
+`
+
+const repoTruncate = `
+// Truncate empties the table, see gormrepo.Truncate.
+func (r %[1]s) Truncate(cascade bool) error {
+	return r.wrapError("Truncate", gormrepo.Truncate(r.DB, cascade, &%[2]s{}))
+}
+`
+
+const repoAddUniqueIndex = `
+func (r %[1]s) AddUniqueIndex(name string, columns ...string) error {
+	return r.wrapError("AddUniqueIndex", r.DB.Model(&%[2]s{}).AddUniqueIndex(name, columns...).Error)
+}
+`
+const repoAddForeignKey = `
+func (r %[1]s) AddForeignKey(field string, dest string, onDelete string, onUpdate string) error {
+	return r.wrapError("AddForeignKey", r.DB.Model(&%[2]s{}).AddForeignKey(field, dest, onDelete, onUpdate).Error)
+}
+`
+const repoAddIndex = `
+func (r %[1]s) AddIndex(name string, columns ...string) error {
+	return r.wrapError("AddIndex", r.DB.Model(&%[2]s{}).AddIndex(name, columns...).Error)
+}
+`
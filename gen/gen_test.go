@@ -0,0 +1,294 @@
+package gen
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"math/rand"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// updateGoldenEnv rewrites the golden files instead of comparing them, as
+// repotest.UpdateGoldenEnv does: GORMREPO_UPDATE_GOLDEN=1 go test ./gen
+const updateGoldenEnv = "GORMREPO_UPDATE_GOLDEN"
+
+// goldenPackage is a model package of testdata, generated with the options
+// into the <file>.golden files next to the models.
+type goldenPackage struct {
+	dir   string
+	types []string
+	opts  Options
+}
+
+var goldenPackages = []goldenPackage{
+	{"models", []string{"Article", "ArticleTranslation"}, Options{
+		Admin: true, Columns: true, Decorators: true, DoubleWrite: true, Enums: true, JSON: true,
+		Meta: true, Notify: true, Publish: true, Registry: true, Rollout: true, Shadow: true,
+	}},
+	{"keys", []string{"Coded", "Keyed"}, Options{
+		Columns: true, Decorators: true, DoubleWrite: true, Export: true, JSON: true, Meta: true, Shadow: true,
+	}},
+	{"tree", []string{"Node", "PathNode", "ClosureNode"}, Options{
+		Tree: true, TreeStrategies: "PathNode=path,ClosureNode=closure", Decorators: true, DoubleWrite: true, Registry: true,
+	}},
+}
+
+// generate returns the files generated for the package, by base name.
+func (p goldenPackage) generate(t *testing.T) map[string][]byte {
+	t.Helper()
+	g, err := Load(nil, filepath.Join("testdata", p.dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	opts := p.opts
+	opts.Strict = true
+	files, err := g.GenerateFiles(p.types, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	generated := map[string][]byte{}
+	for _, f := range files {
+		generated[filepath.Base(f.Name)] = f.Src
+	}
+	return generated
+}
+
+func TestGolden(t *testing.T) {
+	for _, p := range goldenPackages {
+		t.Run(p.dir, func(t *testing.T) {
+			dir := filepath.Join("testdata", p.dir)
+			generated := p.generate(t)
+			update := os.Getenv(updateGoldenEnv) != ""
+			for name, src := range generated {
+				path := filepath.Join(dir, name+".golden")
+				if update {
+					if err := ioutil.WriteFile(path, src, 0644); err != nil {
+						t.Fatal(err)
+					}
+					continue
+				}
+				want, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Errorf("%v, run with %s=1 to create it", err, updateGoldenEnv)
+					continue
+				}
+				if line, got, wanted, ok := firstDiff(src, want); ok {
+					t.Errorf("%s changed at line %d:\n\twant: %s\n\tgot:  %s", path, line, wanted, got)
+				}
+			}
+			goldens, err := filepath.Glob(filepath.Join(dir, "*.golden"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, path := range goldens {
+				if _, ok := generated[strings.TrimSuffix(filepath.Base(path), ".golden")]; ok {
+					continue
+				}
+				if update {
+					os.Remove(path)
+				} else {
+					t.Errorf("%s is not generated anymore", path)
+				}
+			}
+		})
+	}
+}
+
+// TestGenerateOrder generates the packages of testdata from their files and
+// types in shuffled orders, expecting the same bytes each time.
+func TestGenerateOrder(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	for _, p := range goldenPackages {
+		t.Run(p.dir, func(t *testing.T) {
+			names, err := filepath.Glob(filepath.Join("testdata", p.dir, "*.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			generate := func(names, types []string) map[string][]byte {
+				g, err := Load(nil, names...)
+				if err != nil {
+					t.Fatal(err)
+				}
+				files, err := g.GenerateFiles(types, p.opts)
+				if err != nil {
+					t.Fatal(err)
+				}
+				generated := map[string][]byte{}
+				for _, f := range files {
+					generated[f.Name] = f.Src
+				}
+				return generated
+			}
+			want := generate(names, p.types)
+			for i := 0; i < 5; i++ {
+				names := append([]string(nil), names...)
+				types := append([]string(nil), p.types...)
+				rnd.Shuffle(len(names), func(a, b int) { names[a], names[b] = names[b], names[a] })
+				rnd.Shuffle(len(types), func(a, b int) { types[a], types[b] = types[b], types[a] })
+				got := generate(names, types)
+				if len(got) != len(want) {
+					t.Fatalf("files %v and types %v: %d files generated, want %d", names, types, len(got), len(want))
+				}
+				for name, src := range want {
+					if !bytes.Equal(got[name], src) {
+						line, g, w, _ := firstDiff(got[name], src)
+						t.Errorf("files %v and types %v: %s changed at line %d:\n\twant: %s\n\tgot:  %s", names, types, name, line, w, g)
+					}
+				}
+			}
+		})
+	}
+}
+
+// firstDiff returns the first line differing between got and want.
+func firstDiff(got, want []byte) (line int, gotLine, wantLine string, ok bool) {
+	gotLines, wantLines := strings.Split(string(got), "\n"), strings.Split(string(want), "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w || i >= len(gotLines) || i >= len(wantLines) {
+			return i + 1, g, w, true
+		}
+	}
+	return 0, "", "", false
+}
+
+// TestGoldenBuild compiles the models of each package of testdata with their
+// generated files, in a copy of the package within the module, and runs the
+// tests of the package against them.
+func TestGoldenBuild(t *testing.T) {
+	if testing.Short() {
+		t.Skip("builds the generated packages")
+	}
+	goTool, err := exec.LookPath("go")
+	if err != nil {
+		t.Skip("go is not installed")
+	}
+	for _, p := range goldenPackages {
+		t.Run(p.dir, func(t *testing.T) {
+			generated := p.generate(t)
+			build, err := ioutil.TempDir("testdata", "build_"+p.dir+"_")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.RemoveAll(build)
+			models, err := filepath.Glob(filepath.Join("testdata", p.dir, "*.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, path := range models {
+				src, err := ioutil.ReadFile(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				generated[filepath.Base(path)] = src
+			}
+			for name, src := range generated {
+				if err := ioutil.WriteFile(filepath.Join(build, name), src, 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			cmd := exec.Command(goTool, "build", "./"+filepath.ToSlash(build))
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("generated code of %s does not build: %v\n%s", p.dir, err, out)
+			}
+			// The tests of the models run against the generated code.
+			tests, err := filepath.Glob(filepath.Join(build, "*_test.go"))
+			if err != nil || len(tests) == 0 {
+				return
+			}
+			cmd = exec.Command(goTool, "test", "./"+filepath.ToSlash(build))
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Errorf("tests of %s fail on the generated code: %v\n%s", p.dir, err, out)
+			}
+		})
+	}
+}
+
+// TestMaskedMethods fails when a method of a repository returns entities of
+// a masked type but its masked repository lacks the method, or has it without
+// masking them.
+func TestMaskedMethods(t *testing.T) {
+	for _, p := range goldenPackages {
+		for name, src := range p.generate(t) {
+			if !strings.HasSuffix(name, "_base_repo.go") {
+				continue
+			}
+			file, err := parser.ParseFile(token.NewFileSet(), name, src, 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var base, masked, entity string
+			methods := map[string]map[string]*ast.FuncDecl{}
+			for _, decl := range file.Decls {
+				fd, ok := decl.(*ast.FuncDecl)
+				if !ok || fd.Recv == nil {
+					continue
+				}
+				recv := receiverName(fd)
+				if methods[recv] == nil {
+					methods[recv] = map[string]*ast.FuncDecl{}
+				}
+				methods[recv][fd.Name.Name] = fd
+				switch fd.Name.Name {
+				case "Masked":
+					base, masked = recv, strings.TrimPrefix(types.ExprString(fd.Type.Results.List[0].Type), "*")
+				case "Mask":
+					entity = types.ExprString(fd.Type.Params.List[0].Type)
+				}
+			}
+			if masked == "" {
+				continue
+			}
+			for method, fd := range methods[base] {
+				if !fd.Name.IsExported() || !returns(fd, entity, "[]"+entity) {
+					continue
+				}
+				if m, ok := methods[masked][method]; !ok {
+					t.Errorf("%s: %s.%s returns %s, unmasked by %s", name, base, method, entity, masked)
+				} else if !callsMask(m) {
+					t.Errorf("%s: %s.%s does not mask the entities it returns", name, masked, method)
+				}
+			}
+		}
+	}
+}
+
+// returns reports whether the method has a result of one of the types.
+func returns(fd *ast.FuncDecl, typeNames ...string) bool {
+	if fd.Type.Results == nil {
+		return false
+	}
+	for _, result := range fd.Type.Results.List {
+		for _, typ := range typeNames {
+			if types.ExprString(result.Type) == typ {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// callsMask reports whether the method calls r.mask.
+func callsMask(fd *ast.FuncDecl) bool {
+	found := false
+	ast.Inspect(fd.Body, func(n ast.Node) bool {
+		if call, ok := n.(*ast.CallExpr); ok && types.ExprString(call.Fun) == "r.mask" {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
@@ -0,0 +1,77 @@
+package gen
+
+import (
+	"bytes"
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+// generatedBanner is the line go tools and linters recognize generated files
+// by, see go help generate.
+var generatedBanner = regexp.MustCompile(`(?m)^// Code generated .* DO NOT EDIT\.$`)
+
+// headerData is the data of the header template.
+type headerData struct {
+	Command string // The gormrepogen command line, stable with Options.Merge.
+	Package string
+	File    string // Base name of the generated file.
+}
+
+// loadHeader parses the template of Options.Header, nil when unset.
+func (g *Generator) loadHeader() *template.Template {
+	if g.opts.Header == "" {
+		return nil
+	}
+	text, err := ioutil.ReadFile(g.opts.Header)
+	if err != nil {
+		fatalf("reading header: %s", err)
+	}
+	header, err := template.New("header").Parse(string(text))
+	if err != nil {
+		fatalf("parsing header: %s", err)
+	}
+	return header
+}
+
+// customHeader returns the header comment of a generated file, the lines
+// of text commented, followed by an empty line.
+func (g *Generator) customHeader(command, pkgName, fileName string) ([]byte, error) {
+	var text bytes.Buffer
+	if err := g.header.Execute(&text, headerData{command, pkgName, fileName}); err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	for _, line := range strings.Split(strings.TrimRight(text.String(), "\n"), "\n") {
+		line = strings.TrimRight(line, " \t")
+		if line != "" && !strings.HasPrefix(line, "//") {
+			line = "// " + line
+		}
+		b.WriteString(line + "\n")
+	}
+	b.WriteString("\n")
+	return b.Bytes(), nil
+}
+
+// command returns the command line printed in the header of the generated
+// files, without the arguments with Options.Merge so the header stays the
+// same.
+func (g *Generator) command() string {
+	if g.opts.Merge {
+		return "gormrepogen"
+	}
+	return g.opts.Command
+}
+
+// isGeneratedFile reports whether the existing file is a generated file,
+// starting with the default header or with the banner in its header.
+func isGeneratedFile(src []byte) bool {
+	if bytes.HasPrefix(src, []byte(generatedHeader)) {
+		return true
+	}
+	if end := bytes.Index(src, []byte("\npackage ")); end >= 0 {
+		src = src[:end]
+	}
+	return generatedBanner.Match(src)
+}
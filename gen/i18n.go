@@ -0,0 +1,147 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/types"
+	"strings"
+)
+
+// generateI18n emits the translation methods when the package declares a
+// <T>Translation type with Locale and <T>ID fields, and a unique index on
+// them for UpsertTranslation. When T has a slice field of the translation
+// type, WithLocale preloads it. The criteria are described and registered
+// under ops qualified by the package and the type.
+func (g *Generator) generateI18n(repoNameRecv, typeName string) {
+	translationName := typeName + "Translation"
+	translation := g.getStructType(translationName)
+	if translation == nil {
+		return
+	}
+	foreignKey := typeName + "ID"
+	if !g.hasField(translation, "Locale") || !g.hasField(translation, foreignKey) {
+		fatalf("type %s must have Locale and %s fields", translationName, foreignKey)
+	}
+	target := []string{g.i18nColumn(translation, foreignKey), g.i18nColumn(translation, "Locale")}
+	var index *uniqueIndex
+	for _, candidate := range g.uniqueIndexes(translation) {
+		if len(candidate.columns) == 2 && (candidate.columns[0] == target[0] && candidate.columns[1] == target[1] ||
+			candidate.columns[0] == target[1] && candidate.columns[1] == target[0]) {
+			candidate := candidate
+			index = &candidate
+		}
+	}
+	if index == nil {
+		fatalf("type %s must have a unique index on %s and Locale", translationName, foreignKey)
+	}
+
+	var association string
+	if st := g.getStructType(typeName); st != nil {
+		for _, field := range st.Fields.List {
+			fieldType := strings.TrimLeft(types.ExprString(field.Type), "[]*")
+			if fieldType == translationName && strings.HasPrefix(types.ExprString(field.Type), "[]") && len(field.Names) > 0 {
+				association = field.Names[0].Name
+			}
+		}
+	}
+
+	lc := g.lcFirst(typeName)
+	op := g.getFileByTypeName(typeName).file.Name.Name + "." + typeName + "."
+	g.Import("fmt")
+	g.Printf(repoTranslationColumns, lc, translationName, foreignKey)
+	builders := fmt.Sprintf("\t\t%q: %sJoinTranslation,\n", op+"join_translation", lc)
+	if association != "" {
+		g.Printf(repoWithLocale, repoNameRecv, association, lc, op+"with_locale")
+		builders = fmt.Sprintf("\t\t%q: %sWithLocale,\n", op+"with_locale", lc) + builders
+	}
+	g.Printf(repoJoinTranslation, repoNameRecv, typeName, lc, op+"join_translation")
+	g.Printf(repoTranslationCriteria, builders)
+	g.Printf(repoGetTranslation, repoNameRecv, "*"+typeName, translationName, lc)
+	g.Printf(repoUpsertTranslation, repoNameRecv, "*"+translationName, quoteAll(target), index.name, quoteAll(index.columns))
+}
+
+// i18nColumn returns the column of the named field of the translation type.
+func (g *Generator) i18nColumn(translation *ast.StructType, name string) string {
+	if field := g.lookupField(translation, name); field != nil {
+		return columnName(name, gormTag(field))
+	}
+	return columnName(name, nil)
+}
+
+const repoTranslationColumns = `
+// %[1]sTranslationColumns returns the quoted table of %[2]s and its
+// foreign key and locale columns.
+func %[1]sTranslationColumns(db *gorm.DB) (table, foreignKey, locale string) {
+	scope := db.NewScope(&%[2]s{})
+	foreignKeyField, _ := scope.FieldByName(%[3]q)
+	localeField, _ := scope.FieldByName("Locale")
+	return scope.QuotedTableName(), scope.Quote(foreignKeyField.DBName), scope.Quote(localeField.DBName)
+}
+`
+
+const repoWithLocale = `
+func (r %[1]s) WithLocale(locale string) gormrepo.CriteriaOption {
+	return %[3]sWithLocale(locale)
+}
+
+func %[3]sWithLocale(locale string) gormrepo.CriteriaOption {
+	return gormrepo.Described(gormrepo.Criterion{Op: %[4]q, Args: []interface{}{locale}}, func(db *gorm.DB) *gorm.DB {
+		_, _, localeColumn := %[3]sTranslationColumns(db)
+		return db.Preload(%[2]q, localeColumn+" = ?", locale)
+	})
+}
+`
+
+const repoJoinTranslation = `
+func (r %[1]s) JoinTranslation(locale string) gormrepo.CriteriaOption {
+	return %[3]sJoinTranslation(locale)
+}
+
+func %[3]sJoinTranslation(locale string) gormrepo.CriteriaOption {
+	return gormrepo.Described(gormrepo.Criterion{Op: %[4]q, Args: []interface{}{locale}}, func(db *gorm.DB) *gorm.DB {
+		table, foreignKey, localeColumn := %[3]sTranslationColumns(db)
+		scope := db.NewScope(&%[2]s{})
+		join := "JOIN " + table + " ON " + table + "." + foreignKey + " = " + scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey()) +
+			" AND " + table + "." + localeColumn + " = ?"
+		return db.Joins(join, locale).Select(scope.QuotedTableName() + ".*")
+	})
+}
+`
+
+const repoTranslationCriteria = `
+func init() {
+	for op, build := range map[string]func(locale string) gormrepo.CriteriaOption{
+%[1]s	} {
+		build := build
+		gormrepo.RegisterCriterion(op, func(c gormrepo.Criterion) (gormrepo.CriteriaOption, error) {
+			locale, ok := "", len(c.Args) == 1
+			if ok {
+				locale, ok = c.Args[0].(string)
+			}
+			if !ok {
+				return nil, fmt.Errorf("%%w: %%s", gormrepo.ErrInvalidCriterion, c)
+			}
+			return build(locale), nil
+		})
+	}
+}
+`
+
+const repoGetTranslation = `
+func (r %[1]s) GetTranslation(entity %[2]s, locale string) (*%[3]s, error) {
+	_, foreignKey, localeColumn := %[4]sTranslationColumns(r.DB)
+	var translation %[3]s
+	err := r.DB.Where(foreignKey+" = ? AND "+localeColumn+" = ?", entity.ID, locale).First(&translation).Error
+	return &translation, r.wrapError("GetTranslation", err)
+}
+`
+
+const repoUpsertTranslation = `
+// UpsertTranslation inserts the translation or updates the translation of its
+// locale, on conflict on their unique index.
+func (r %[1]s) UpsertTranslation(translation %[2]s) error {
+	indexes := []gormrepo.UniqueIndex{{Name: %[4]q, Columns: []string{%[5]s}}}
+	err := gormrepo.Upsert(r.DB, translation, gormrepo.OnColumns(%[3]s), indexes)
+	return r.wrapError("UpsertTranslation", err)
+}
+`
@@ -0,0 +1,434 @@
+package gen
+
+import (
+	"database/sql"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+	"github.com/jinzhu/inflection"
+)
+
+// dbTable is a table read from the database.
+type dbTable struct {
+	name        string
+	columns     []dbColumn
+	foreignKeys []dbForeignKey
+}
+
+type dbColumn struct {
+	name, sqlType     string
+	nullable, primary bool
+}
+
+// dbForeignKey is a single column foreign key.
+type dbForeignKey struct {
+	column, refTable, refColumn string
+}
+
+// Introspect reads the tables of the database, the named ones or all the
+// tables of the schema, and writes a model per table to the package of dir,
+// if missing, named pkgName or after the directory. It then generates the
+// repositories of the tables keyed by an integer id as Write does, and
+// reports whether they were generated. The models written are removed when
+// they are not.
+func Introspect(db *sql.DB, dialect string, tables []string, dir, pkgName string, opts Options) (ok bool) {
+	defer recoverFailure(&ok)
+	schema, err := readSchema(db, dialect)
+	if err != nil {
+		fatalf("reading schema: %s", err)
+	}
+	if len(tables) > 0 {
+		schema = selectTables(schema, tables)
+	}
+	if len(schema) == 0 {
+		fatalf("no tables found")
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		fatalf("creating output directory: %s", err)
+	}
+	if pkgName == "" {
+		abs, _ := filepath.Abs(dir)
+		pkgName = strings.Replace(filepath.Base(abs), "-", "_", -1)
+	}
+	var g Generator
+	typeNames := map[string]string{}
+	for _, t := range schema {
+		typeNames[t.name] = goName(inflection.Singular(t.name))
+	}
+	var types, created []string
+	// The models are removed with the repositories they failed to
+	// generate.
+	defer func() {
+		if !ok {
+			removeAll(created)
+		}
+	}()
+	for _, t := range schema {
+		if hasIntegerID(t) {
+			types = append(types, typeNames[t.name])
+		} else {
+			fmt.Printf("Table %s has no integer id primary key, its repository is not generated\n", t.name)
+		}
+		name := filepath.Join(dir, strings.ToLower(t.name)+".go")
+		if _, err := os.Stat(name); err == nil {
+			fmt.Printf("Model of table %s exists, kept: %s\n", t.name, name)
+			continue
+		}
+		g.generateModel(pkgName, t, typeNames)
+		src, err := format.Source(g.buf.Bytes())
+		if err != nil {
+			fatalf("formatting model of table %s: %s", t.name, err)
+		}
+		if err := writeFileAtomic(name, src); err != nil {
+			fatalf("writing output: %s", err)
+		}
+		created = append(created, name)
+		fmt.Printf("Model of table %s is generated: %s\n", t.name, name)
+		g.buf.Reset()
+	}
+	if len(types) == 0 {
+		return true
+	}
+	repos, err := Load(nil, dir)
+	if err != nil {
+		fatalf("%s", err)
+	}
+	return repos.Write(types, opts)
+}
+
+func removeAll(names []string) {
+	for _, name := range names {
+		os.Remove(name)
+	}
+}
+
+// hasIntegerID reports whether the only primary key of the table is an
+// integer id column, which the generated repositories are keyed by.
+func hasIntegerID(t dbTable) bool {
+	id := false
+	for _, c := range t.columns {
+		if !c.primary {
+			continue
+		}
+		typ, _ := goColumnType(c)
+		if c.name != "id" || !strings.Contains(typ, "int") {
+			return false
+		}
+		id = true
+	}
+	return id
+}
+
+// selectTables returns the named tables of the schema, in the given order.
+func selectTables(schema []dbTable, names []string) []dbTable {
+	byName := map[string]dbTable{}
+	for _, t := range schema {
+		byName[t.name] = t
+	}
+	var result []dbTable
+	for _, name := range names {
+		t, ok := byName[strings.TrimSpace(name)]
+		if !ok {
+			fatalf("table %s is not found", name)
+		}
+		result = append(result, t)
+	}
+	return result
+}
+
+// readSchema returns the tables of the current schema of the database, in
+// name order, with their columns in order.
+func readSchema(db *sql.DB, dialect string) ([]dbTable, error) {
+	var columns, primaryKeys, foreignKeys string
+	switch dialect {
+	case "postgres":
+		columns = `SELECT c.table_name, c.column_name, CASE WHEN c.data_type IN ('ARRAY', 'USER-DEFINED') THEN c.udt_name ELSE c.data_type END, c.is_nullable = 'YES'
+			FROM information_schema.columns c JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+			WHERE c.table_schema = CURRENT_SCHEMA() AND t.table_type = 'BASE TABLE' ORDER BY c.table_name, c.ordinal_position`
+		primaryKeys = `SELECT kcu.table_name, kcu.column_name FROM information_schema.table_constraints tc
+			JOIN information_schema.key_column_usage kcu ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema AND kcu.table_name = tc.table_name
+			WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = CURRENT_SCHEMA()`
+		foreignKeys = `SELECT kcu.table_name, tc.constraint_name, kcu.column_name, ccu.table_name, ccu.column_name FROM information_schema.table_constraints tc
+			JOIN information_schema.key_column_usage kcu ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema AND kcu.table_name = tc.table_name
+			JOIN information_schema.constraint_column_usage ccu ON ccu.constraint_name = tc.constraint_name AND ccu.table_schema = tc.table_schema
+			WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = CURRENT_SCHEMA()`
+	case "mysql":
+		columns = `SELECT c.table_name, c.column_name, c.column_type, c.is_nullable = 'YES'
+			FROM information_schema.columns c JOIN information_schema.tables t ON t.table_schema = c.table_schema AND t.table_name = c.table_name
+			WHERE c.table_schema = DATABASE() AND t.table_type = 'BASE TABLE' ORDER BY c.table_name, c.ordinal_position`
+		primaryKeys = `SELECT table_name, column_name FROM information_schema.key_column_usage
+			WHERE table_schema = DATABASE() AND constraint_name = 'PRIMARY'`
+		foreignKeys = `SELECT table_name, constraint_name, column_name, referenced_table_name, referenced_column_name FROM information_schema.key_column_usage
+			WHERE table_schema = DATABASE() AND referenced_table_name IS NOT NULL`
+	case "sqlite3":
+		columns = `SELECT m.name, p.name, p.type, p."notnull" = 0 AND p.pk = 0 FROM sqlite_master m JOIN pragma_table_info(m.name) p
+			WHERE m.type = 'table' AND m.name NOT LIKE 'sqlite_%' ORDER BY m.name, p.cid`
+		primaryKeys = `SELECT m.name, p.name FROM sqlite_master m JOIN pragma_table_info(m.name) p
+			WHERE m.type = 'table' AND p.pk > 0`
+		foreignKeys = `SELECT m.name, f.id, f."from", f."table", COALESCE(f."to", '') FROM sqlite_master m JOIN pragma_foreign_key_list(m.name) f
+			WHERE m.type = 'table'`
+	default:
+		return nil, fmt.Errorf("unsupported dialect %s", dialect)
+	}
+
+	var result []dbTable
+	index := map[string]int{}
+	err := queryRows(db, columns, func(values []string) {
+		i, ok := index[values[0]]
+		if !ok {
+			i = len(result)
+			index[values[0]] = i
+			result = append(result, dbTable{name: values[0]})
+		}
+		result[i].columns = append(result[i].columns, dbColumn{name: values[1], sqlType: strings.ToLower(values[2]), nullable: values[3] == "1" || values[3] == "true"})
+	}, 4)
+	if err != nil {
+		return nil, err
+	}
+	err = queryRows(db, primaryKeys, func(values []string) {
+		if i, ok := index[values[0]]; ok {
+			for j := range result[i].columns {
+				if result[i].columns[j].name == values[1] {
+					result[i].columns[j].primary = true
+				}
+			}
+		}
+	}, 2)
+	if err != nil {
+		return nil, err
+	}
+	// Foreign keys are grouped by constraint, those over several columns
+	// are left out.
+	var constraints []string
+	fks := map[string][]dbForeignKey{}
+	err = queryRows(db, foreignKeys, func(values []string) {
+		key := values[0] + "\x00" + values[1]
+		if fks[key] == nil {
+			constraints = append(constraints, key)
+		}
+		fks[key] = append(fks[key], dbForeignKey{values[2], values[3], values[4]})
+	}, 5)
+	if err != nil {
+		return nil, err
+	}
+	for _, key := range constraints {
+		i, ok := index[strings.SplitN(key, "\x00", 2)[0]]
+		if ok && len(fks[key]) == 1 {
+			result[i].foreignKeys = append(result[i].foreignKeys, fks[key][0])
+		}
+	}
+	for _, t := range result {
+		sort.Slice(t.foreignKeys, func(i, j int) bool { return t.foreignKeys[i].column < t.foreignKeys[j].column })
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].name < result[j].name })
+	return result, nil
+}
+
+// queryRows calls fn with the n string values of each row of the query.
+func queryRows(db *sql.DB, query string, fn func(values []string), n int) error {
+	rows, err := db.Query(query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	values := make([]sql.NullString, n)
+	dest := make([]interface{}, n)
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		strs := make([]string, n)
+		for i, v := range values {
+			strs[i] = v.String
+		}
+		fn(strs)
+	}
+	return rows.Err()
+}
+
+// initialisms are the words named upper case in Go names.
+var initialisms = map[string]bool{
+	"ID": true, "URL": true, "URI": true, "API": true, "HTTP": true, "UUID": true,
+	"SQL": true, "JSON": true, "XML": true, "IP": true, "SKU": true, "UID": true,
+}
+
+// goName returns the exported Go name of the snake cased name.
+func goName(name string) string {
+	var b strings.Builder
+	for _, word := range strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == ' ' || r == '.' }) {
+		if upper := strings.ToUpper(word); initialisms[upper] {
+			b.WriteString(upper)
+		} else {
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	if b.Len() == 0 || b.String()[0] >= '0' && b.String()[0] <= '9' {
+		return "X" + b.String()
+	}
+	return b.String()
+}
+
+// goColumnType returns the Go type of a column type and the package it
+// needs, pointers for nullable columns.
+func goColumnType(c dbColumn) (string, string) {
+	t, pkg := "string", ""
+	base := c.sqlType
+	if i := strings.Index(base, "("); i >= 0 {
+		base = base[:i]
+	}
+	unsigned := strings.Contains(c.sqlType, "unsigned")
+	base = strings.TrimSpace(strings.TrimSuffix(base, " unsigned"))
+	switch {
+	case c.sqlType == "tinyint(1)", base == "boolean", base == "bool":
+		t = "bool"
+	case base == "bigint", base == "int8", base == "bigserial":
+		t = "int64"
+	case base == "integer", base == "int", base == "int4", base == "serial", base == "smallint", base == "int2",
+		base == "mediumint", base == "tinyint", base == "smallserial":
+		t = "int"
+	case base == "real", base == "float", base == "float4", base == "float8", base == "double", base == "double precision",
+		base == "numeric", base == "decimal":
+		t = "float64"
+	case base == "bytea", base == "blob", base == "binary", base == "varbinary", base == "longblob", base == "mediumblob":
+		return "[]byte", ""
+	case strings.HasPrefix(base, "timestamp"), base == "datetime", base == "date", strings.HasPrefix(base, "time"):
+		t, pkg = "time.Time", "time"
+	}
+	if unsigned && strings.HasPrefix(t, "int") {
+		t = "u" + t
+	}
+	if c.nullable {
+		t = "*" + t
+	}
+	return t, pkg
+}
+
+// isGormModel reports whether the table has the columns of gorm.Model.
+func isGormModel(t dbTable) bool {
+	want := map[string]bool{"id": false, "created_at": false, "updated_at": false, "deleted_at": false}
+	for _, c := range t.columns {
+		if _, ok := want[c.name]; !ok {
+			continue
+		}
+		typ, _ := goColumnType(c)
+		switch {
+		case c.name == "id" && c.primary && (strings.HasPrefix(typ, "int") || strings.HasPrefix(typ, "uint")):
+		case c.name != "id" && strings.HasSuffix(typ, "time.Time"):
+		default:
+			return false
+		}
+		want[c.name] = true
+	}
+	for _, found := range want {
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// generateModel emits the model of the table, its belongs to relations with
+// the introspected tables and its TableName when gorm would name it
+// otherwise.
+func (g *Generator) generateModel(pkgName string, t dbTable, typeNames map[string]string) {
+	typeName := typeNames[t.name]
+	embed := isGormModel(t)
+	imports := map[string]bool{}
+	if embed {
+		imports["github.com/jinzhu/gorm"] = true
+	}
+
+	var fields strings.Builder
+	if embed {
+		fields.WriteString("\tgorm.Model\n")
+	}
+	names := map[string]bool{}
+	primaries := 0
+	for _, c := range t.columns {
+		if c.primary {
+			primaries++
+		}
+	}
+	for _, c := range t.columns {
+		if embed && (c.name == "id" || c.name == "created_at" || c.name == "updated_at" || c.name == "deleted_at") {
+			continue
+		}
+		name := goName(c.name)
+		names[name] = true
+		typ, pkg := goColumnType(c)
+		if pkg != "" {
+			imports[pkg] = true
+		}
+		var tags []string
+		if gorm.ToColumnName(name) != c.name {
+			tags = append(tags, "column:"+c.name)
+		}
+		if c.primary && (c.name != "id" || primaries > 1) {
+			tags = append(tags, "primary_key")
+		}
+		if c.primary && primaries > 1 {
+			// Gorm makes integer primary keys auto incremented.
+			tags = append(tags, "auto_increment:false")
+		}
+		fmt.Fprintf(&fields, "\t%s %s%s // %s\n", name, typ, gormTags(tags), c.sqlType)
+	}
+	for _, fk := range t.foreignKeys {
+		refType, ok := typeNames[fk.refTable]
+		if !ok {
+			continue
+		}
+		// customer_id and manager_code are named Customer and Manager.
+		field := goName(fk.column)
+		name := refType
+		if i := strings.LastIndex(fk.column, "_"); i > 0 {
+			name = goName(fk.column[:i])
+		}
+		if names[name] {
+			continue
+		}
+		names[name] = true
+		var tags []string
+		if name+"ID" != field {
+			tags = append(tags, "foreignkey:"+field)
+		}
+		if fk.refColumn != "" && fk.refColumn != "id" {
+			tags = append(tags, "association_foreignkey:"+goName(fk.refColumn))
+		}
+		fmt.Fprintf(&fields, "\t%s *%s%s\n", name, refType, gormTags(tags))
+	}
+
+	g.Printf("// Code generated by \"gormrepogen introspect\" from the %s table. It is the model of the table\n", t.name)
+	g.Printf("// from now on, edit it as needed.\n\npackage %s\n", pkgName)
+	if len(imports) > 0 {
+		paths := make([]string, 0, len(imports))
+		for path := range imports {
+			paths = append(paths, path)
+		}
+		sort.Strings(paths)
+		g.Printf("\nimport (\n")
+		for _, path := range paths {
+			g.Printf("\t%q\n", path)
+		}
+		g.Printf(")\n")
+	}
+	g.Printf("\ntype %s struct {\n%s}\n", typeName, fields.String())
+	if inflection.Plural(gorm.ToTableName(typeName)) != t.name {
+		g.Printf("\nfunc (%s) TableName() string {\n\treturn %q\n}\n", typeName, t.name)
+	}
+}
+
+func gormTags(tags []string) string {
+	if len(tags) == 0 {
+		return ""
+	}
+	return " `gorm:\"" + strings.Join(tags, ";") + "\"`"
+}
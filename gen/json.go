@@ -0,0 +1,45 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// generateJSONColumns emits the translation between the JSON names and the
+// columns of the type, for API sort and filter parameters named as in JSON.
+func (g *Generator) generateJSONColumns(typeName string) {
+	if !g.opts.JSON {
+		return
+	}
+	var toColumn, toJSON strings.Builder
+	for _, c := range g.modelColumns(g.getStructType(typeName)) {
+		if c.json == "" {
+			continue
+		}
+		fmt.Fprintf(&toColumn, "\t%q: %q,\n", c.json, c.name)
+		fmt.Fprintf(&toJSON, "\t%q: %q,\n", c.name, c.json)
+	}
+	g.Printf(repoJSONColumns, typeName, g.lcFirst(typeName), toColumn.String(), toJSON.String())
+}
+
+const repoJSONColumns = `
+var %[2]sJSONColumns = map[string]string{
+%[3]s}
+
+var %[2]sColumnsJSON = map[string]string{
+%[4]s}
+
+// %[1]sJSONColumn returns the column of the %[1]s field named name in JSON,
+// false when no field has the name.
+func %[1]sJSONColumn(name string) (string, bool) {
+	column, ok := %[2]sJSONColumns[name]
+	return column, ok
+}
+
+// %[1]sColumnJSON returns the JSON name of the field of the %[1]s column,
+// false when the column is not a field in JSON.
+func %[1]sColumnJSON(column string) (string, bool) {
+	name, ok := %[2]sColumnsJSON[column]
+	return name, ok
+}
+`
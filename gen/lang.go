@@ -0,0 +1,84 @@
+package gen
+
+import (
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseLang returns the minor Go version of Options.Lang, 0 when unset.
+func (g *Generator) parseLang() int {
+	if g.opts.Lang == "" {
+		return 0
+	}
+	version := strings.TrimPrefix(g.opts.Lang, "go")
+	parts := strings.Split(version, ".")
+	minor := 0
+	if len(parts) >= 2 && parts[0] == "1" {
+		minor, _ = strconv.Atoi(parts[1])
+	}
+	if minor == 0 {
+		fatalf("invalid Go version %q, want a Go version such as go1.21", g.opts.Lang)
+	}
+	return minor
+}
+
+// langAtLeast reports whether Options.Lang targets the Go version 1.minor or
+// later.
+func (g *Generator) langAtLeast(minor int) bool {
+	return g.langMinor >= minor
+}
+
+// edit replaces the bytes of the source from start to end.
+type edit struct {
+	start, end int
+	text       string
+}
+
+// applyLang rewrites the formatted source for Options.Lang: empty interfaces are
+// written any from go1.18, and errors are wrapped with %v before go1.13.
+func (g *Generator) applyLang(src []byte) []byte {
+	minor := g.langMinor
+	if minor == 0 || minor >= 13 && minor < 18 {
+		return src
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return src
+	}
+	var edits []edit
+	ast.Inspect(file, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.InterfaceType:
+			if minor >= 18 && len(n.Methods.List) == 0 {
+				edits = append(edits, edit{fset.Position(n.Pos()).Offset, fset.Position(n.End()).Offset, "any"})
+			}
+		case *ast.CallExpr:
+			if minor >= 13 || types.ExprString(n.Fun) != "fmt.Errorf" || len(n.Args) == 0 {
+				break
+			}
+			if lit, ok := n.Args[0].(*ast.BasicLit); ok && strings.Contains(lit.Value, "%w") {
+				edits = append(edits, edit{fset.Position(lit.Pos()).Offset, fset.Position(lit.End()).Offset, strings.Replace(lit.Value, "%w", "%v", -1)})
+			}
+		}
+		return true
+	})
+	if len(edits) == 0 {
+		return src
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].start > edits[j].start })
+	out := append([]byte(nil), src...)
+	for _, e := range edits {
+		out = append(out[:e.start], append([]byte(e.text), out[e.end:]...)...)
+	}
+	if formatted, err := format.Source(out); err == nil {
+		return formatted
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package gen
+
+import (
+	"fmt"
+	"strings"
+)
+
+// generateMask emits Mask and a Masked(ctx) repository redacting the fields
+// tagged `gormrepo:"mask"`, or `gormrepo:"mask:email"` / `gormrepo:"mask:last4"`,
+// unless the context is unmasked. The masked repository does not embed the
+// repository: it has every method generated so far returning entities, masking
+// them, and nothing else, so it is generated last.
+func (g *Generator) generateMask(repoName, typeName string) {
+	st := g.getStructType(typeName)
+	if st == nil {
+		return
+	}
+	var body strings.Builder
+	for _, field := range st.Fields.List {
+		kind, ok := g.gormrepoTag(field)["mask"]
+		if !ok || len(field.Names) == 0 {
+			continue
+		}
+		name := field.Names[0].Name
+		switch g.fieldType(st, name) {
+		case "string":
+			fmt.Fprintf(&body, "\tentity.%[1]s = gormrepo.Mask(%[2]q, entity.%[1]s)\n", name, kind)
+		case "*string":
+			fmt.Fprintf(&body, "\tif entity.%[1]s != nil {\n\t\tmasked := gormrepo.Mask(%[2]q, *entity.%[1]s)\n\t\tentity.%[1]s = &masked\n\t}\n", name, kind)
+		default:
+			fatalf("mask: field %s.%s must be a string", typeName, name)
+		}
+	}
+	if body.Len() == 0 {
+		return
+	}
+	methods, err := g.repoMethods(repoName)
+	if err != nil {
+		fatalf("mask: cannot parse the repository of %s: %s", typeName, err)
+	}
+	masked := g.lcFirst(typeName) + "MaskedRepo"
+	g.Import("context")
+	g.Printf(repoMask, "*"+repoName, typeName, body.String())
+	g.Printf(repoMasked, repoName, typeName, masked)
+	for _, m := range methods {
+		g.generateMaskedMethod(masked, typeName, m)
+	}
+}
+
+// generateMaskedMethod emits the method of the masked repository calling m
+// and masking the entities it returns, unless m returns none.
+func (g *Generator) generateMaskedMethod(masked, typeName string, m repoMethod) {
+	taken := map[string]bool{}
+	for _, param := range m.params {
+		taken[param] = true
+	}
+	// The first name not taken by a parameter or a previous result.
+	name := func(names ...string) string {
+		for _, name := range names {
+			if !taken[name] {
+				taken[name] = true
+				return name
+			}
+		}
+		name := fmt.Sprintf("%s%d", names[0], len(taken))
+		taken[name] = true
+		return name
+	}
+	results := make([]string, len(m.results))
+	var mask []string
+	for i, result := range m.results {
+		switch result {
+		case "*" + typeName:
+			results[i] = name("entity", "result")
+			mask = append(mask, results[i])
+		case "[]*" + typeName:
+			results[i] = name("entities", "results")
+			mask = append(mask, results[i]+"...")
+		case "error":
+			results[i] = name("err")
+		default:
+			results[i] = name("v")
+		}
+	}
+	if len(mask) == 0 {
+		return
+	}
+	var calls strings.Builder
+	for _, entities := range mask {
+		fmt.Fprintf(&calls, "\tr.mask(%s)\n", entities)
+	}
+	list := strings.Join(results, ", ")
+	g.Printf(repoMaskedMethod, masked, m.signature, list, m.name, m.args, calls.String())
+}
+
+const repoMask = `
+// Mask redacts the masked fields of the entity in place.
+func (r %[1]s) Mask(entity *%[2]s) {
+%[3]s}
+`
+
+const repoMasked = `
+type %[3]s struct {
+	repo *%[1]s
+	ctx  context.Context
+}
+
+// Masked returns the repository masking the entities it returns, unless ctx
+// carries gormrepo.WithUnmasked. It has the methods of the repository
+// returning entities only.
+func (r *%[1]s) Masked(ctx context.Context) *%[3]s {
+	return &%[3]s{r, ctx}
+}
+
+func (r *%[3]s) mask(entities ...*%[2]s) {
+	if gormrepo.IsUnmasked(r.ctx) {
+		return
+	}
+	for _, entity := range entities {
+		if entity != nil {
+			r.repo.Mask(entity)
+		}
+	}
+}
+`
+
+const repoMaskedMethod = `
+func (r *%[1]s) %[2]s {
+	%[3]s := r.repo.%[4]s(%[5]s)
+%[6]s	return %[3]s
+}
+`
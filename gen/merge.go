@@ -0,0 +1,223 @@
+package gen
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	beginMarker     = "// gormrepogen:begin"
+	endMarker       = "// gormrepogen:end"
+	generatedHeader = "// Code generated by"
+)
+
+// region is a named region of a generated file, markers included.
+type region struct {
+	name  string
+	lines []string
+}
+
+// markerName returns the name of the region started or ended by the line.
+func markerName(line, marker string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if line != marker && !strings.HasPrefix(line, marker+" ") {
+		return "", false
+	}
+	return strings.TrimSpace(strings.TrimPrefix(line, marker)), true
+}
+
+// splitRegions returns the lines of src outside the regions, with nil
+// standing for each region, and the regions in order.
+func splitRegions(src []byte) ([]*region, []region, error) {
+	var outside []*region
+	var regions []region
+	var current *region
+	for i, line := range strings.Split(string(src), "\n") {
+		if name, ok := markerName(line, beginMarker); ok {
+			if current != nil {
+				return nil, nil, fmt.Errorf("line %d: nested region %q", i+1, name)
+			}
+			current = &region{name: name}
+		}
+		if current == nil {
+			outside = append(outside, &region{lines: []string{line}})
+			continue
+		}
+		current.lines = append(current.lines, line)
+		if name, ok := markerName(line, endMarker); ok {
+			if name != current.name {
+				return nil, nil, fmt.Errorf("line %d: region %q ended by %q", i+1, current.name, name)
+			}
+			regions = append(regions, *current)
+			outside = append(outside, nil)
+			current = nil
+		}
+	}
+	if current != nil {
+		return nil, nil, fmt.Errorf("region %q not ended", current.name)
+	}
+	return outside, regions, nil
+}
+
+// mergeRegions replaces the regions of the existing file by the regions of
+// the same name of the generated one, keeping the code outside them. New
+// regions are appended, regions no longer generated are removed.
+func mergeRegions(existing, generated []byte) ([]byte, error) {
+	outside, old, err := splitRegions(existing)
+	if err != nil {
+		return nil, err
+	}
+	_, regions, err := splitRegions(generated)
+	if err != nil {
+		return nil, err
+	}
+	byName := map[string]region{}
+	for _, r := range regions {
+		byName[r.name] = r
+	}
+
+	var out []string
+	next := 0
+	for _, segment := range outside {
+		if segment != nil {
+			out = append(out, segment.lines...)
+			continue
+		}
+		name := old[next].name
+		next++
+		if r, ok := byName[name]; ok {
+			out = append(out, r.lines...)
+			delete(byName, name)
+		}
+	}
+	for _, r := range regions {
+		if _, ok := byName[r.name]; ok {
+			out = append(out, "")
+			out = append(out, r.lines...)
+		}
+	}
+	return []byte(strings.Join(out, "\n")), nil
+}
+
+// fixImports replaces the import declarations of the merged file by a single
+// one importing the packages used by the generated code, and the packages
+// of the file still in use.
+func fixImports(src []byte, imports map[string]bool) ([]byte, error) {
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "", src, parser.ParseComments)
+	if err != nil {
+		return nil, err
+	}
+	used := map[string]bool{}
+	ast.Inspect(file, func(n ast.Node) bool {
+		if sel, ok := n.(*ast.SelectorExpr); ok {
+			if ident, ok := sel.X.(*ast.Ident); ok {
+				used[ident.Name] = true
+			}
+		}
+		return true
+	})
+	offset := func(pos token.Pos) int {
+		return fset.Position(pos).Offset
+	}
+
+	specs := map[string]string{}
+	for path := range imports {
+		specs[path] = strconv.Quote(path)
+	}
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if name, ok := importName(spec); ok && !used[name] && !imports[path] {
+			continue
+		}
+		specs[path] = string(src[offset(spec.Pos()):offset(spec.End())])
+	}
+	var std, paths []string
+	for path := range specs {
+		if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+			paths = append(paths, path)
+		} else {
+			std = append(std, path)
+		}
+	}
+	sort.Strings(std)
+	sort.Strings(paths)
+	if len(std) > 0 && len(paths) > 0 {
+		std = append(std, "")
+	}
+	var block strings.Builder
+	block.WriteString("\n\nimport (\n")
+	for _, path := range append(std, paths...) {
+		if path != "" {
+			block.WriteString("\t" + specs[path])
+		}
+		block.WriteString("\n")
+	}
+	block.WriteString(")")
+
+	out := append([]byte(nil), src...)
+	for i := len(file.Decls) - 1; i >= 0; i-- {
+		if gd, ok := file.Decls[i].(*ast.GenDecl); ok && gd.Tok == token.IMPORT {
+			out = append(out[:offset(gd.Pos())], out[offset(gd.End()):]...)
+		}
+	}
+	if len(specs) > 0 {
+		at := offset(file.Name.End())
+		out = append(out[:at], append([]byte(block.String()), out[at:]...)...)
+	}
+	return format.Source(out)
+}
+
+// importName returns the name the import is referred to by, when it can be
+// told from the import path.
+func importName(spec *ast.ImportSpec) (string, bool) {
+	if spec.Name != nil {
+		return spec.Name.Name, spec.Name.Name != "_" && spec.Name.Name != "."
+	}
+	path, _ := strconv.Unquote(spec.Path.Value)
+	elems := strings.Split(path, "/")
+	name := elems[len(elems)-1]
+	if len(elems) > 1 && len(name) > 1 && name[0] == 'v' && strings.Trim(name[1:], "0123456789") == "" {
+		name = elems[len(elems)-2]
+	}
+	name = strings.TrimPrefix(name, "go-")
+	name = strings.TrimSuffix(strings.TrimSuffix(name, "-go"), ".go")
+	return name, token.IsIdentifier(name)
+}
+
+// mergeOutput returns the content to write to the named file: src when the
+// file does not exist or is an unmarked generated file, otherwise src merged
+// into the regions of the file.
+func (g *Generator) mergeOutput(name string, src []byte) ([]byte, error) {
+	if !g.opts.Merge {
+		return src, nil
+	}
+	existing, err := ioutil.ReadFile(name)
+	if os.IsNotExist(err) {
+		return src, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Contains(existing, []byte(beginMarker)) {
+		if isGeneratedFile(existing) {
+			return src, nil
+		}
+		return nil, errors.New("file exists and has no gormrepogen:begin/end regions")
+	}
+	merged, err := mergeRegions(existing, src)
+	if err != nil {
+		return nil, err
+	}
+	return fixImports(merged, g.imports)
+}
@@ -0,0 +1,155 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// metaKinds are the gormrepo constants of the relation kinds.
+var metaKinds = map[string]string{
+	belongsTo:  "gormrepo.BelongsTo",
+	hasOne:     "gormrepo.HasOne",
+	hasMany:    "gormrepo.HasMany",
+	manyToMany: "gormrepo.ManyToMany",
+}
+
+// generateMeta emits the registration of the metadata of the type into
+// gormrepo.Meta.
+func (g *Generator) generateMeta(typeName string) {
+	if !g.opts.Meta {
+		return
+	}
+	st := g.getStructType(typeName)
+	table := g.tableName(typeName)
+	columns := g.modelColumns(st)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "\t\tPackage: %q,\n\t\tName: %q,\n\t\tTable: %q,\n", g.files[0].file.Name.Name, typeName, table)
+	b.WriteString("\t\tColumns: []gormrepo.ColumnMeta{\n")
+	for _, c := range columns {
+		fmt.Fprintf(&b, "\t\t\t{Field: %q, Name: %q, Type: %q", c.field, c.name, c.goType)
+		if c.json != "" {
+			fmt.Fprintf(&b, ", JSON: %q", c.json)
+		}
+		if _, ok := c.settings["PRIMARY_KEY"]; ok || c.name == "id" && !hasPrimaryKey(columns) {
+			b.WriteString(", PrimaryKey: true")
+		}
+		if _, ok := c.settings["UNIQUE"]; ok {
+			b.WriteString(", Unique: true")
+		}
+		b.WriteString("},\n")
+	}
+	b.WriteString("\t\t},\n")
+	if relations := g.relations(typeName, g.packageStructs()); len(relations) > 0 {
+		b.WriteString("\t\tRelations: []gormrepo.RelationMeta{\n")
+		for _, r := range relations {
+			fmt.Fprintf(&b, "\t\t\t{Field: %q, Kind: %s, Target: %q", r.field, metaKinds[r.kind], r.target)
+			if r.foreignKey != "" {
+				fmt.Fprintf(&b, ", ForeignKey: %q", r.foreignKey)
+			}
+			if r.joinTable != "" {
+				fmt.Fprintf(&b, ", JoinTable: %q", r.joinTable)
+			}
+			b.WriteString("},\n")
+		}
+		b.WriteString("\t\t},\n")
+	}
+	if indexes := tagIndexes(table, columns); len(indexes) > 0 {
+		b.WriteString("\t\tIndexes: []gormrepo.IndexMeta{\n")
+		for _, index := range indexes {
+			fmt.Fprintf(&b, "\t\t\t{Name: %q", index.name)
+			if index.unique {
+				b.WriteString(", Unique: true")
+			}
+			fmt.Fprintf(&b, ", Columns: []string{%s}},\n", quoteAll(index.columns))
+		}
+		b.WriteString("\t\t},\n")
+	}
+	g.Printf(repoMeta, typeName, b.String())
+}
+
+func hasPrimaryKey(columns []modelColumn) bool {
+	for _, c := range columns {
+		if _, ok := c.settings["PRIMARY_KEY"]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// packageStructs returns the struct types of the package, as the entities
+// relations are looked for between.
+func (g *Generator) packageStructs() map[string]*entity {
+	structs := map[string]*entity{}
+	for _, f := range g.files {
+		for _, d := range f.file.Decls {
+			gd, ok := d.(*ast.GenDecl)
+			if !ok || gd.Tok != token.TYPE {
+				continue
+			}
+			for _, s := range gd.Specs {
+				name := s.(*ast.TypeSpec).Name.Name
+				if g.getStructType(name) != nil {
+					structs[name] = &entity{typeName: name}
+				}
+			}
+		}
+	}
+	return structs
+}
+
+// tagIndex is an index of the index and unique_index tags of a model.
+type tagIndex struct {
+	name    string
+	unique  bool
+	columns []string
+}
+
+var keyNameUnsafe = regexp.MustCompile("[^a-zA-Z0-9]+")
+
+// tagIndexes returns the indexes of the index and unique_index tags of the
+// columns, named as gorm names them when the tags do not, in name order.
+func tagIndexes(table string, columns []modelColumn) []tagIndex {
+	byName := map[string]*tagIndex{}
+	var names []string
+	for _, c := range columns {
+		for _, tag := range []struct {
+			key, prefix string
+			unique      bool
+		}{{"INDEX", "idx", false}, {"UNIQUE_INDEX", "uix", true}} {
+			value, ok := c.settings[tag.key]
+			if !ok {
+				continue
+			}
+			for _, name := range strings.Split(value, ",") {
+				if name = strings.TrimSpace(name); name == "" {
+					name = keyNameUnsafe.ReplaceAllString(tag.prefix+"_"+table+"_"+c.name, "_")
+				}
+				index, ok := byName[name]
+				if !ok {
+					index = &tagIndex{name: name, unique: tag.unique}
+					byName[name] = index
+					names = append(names, name)
+				}
+				index.columns = append(index.columns, c.name)
+			}
+		}
+	}
+	sort.Strings(names)
+	result := make([]tagIndex, len(names))
+	for i, name := range names {
+		result[i] = *byName[name]
+	}
+	return result
+}
+
+const repoMeta = `
+func init() {
+	gormrepo.Meta.Register(gormrepo.EntityMeta{
+%[2]s	})
+}
+`
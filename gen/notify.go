@@ -0,0 +1,72 @@
+package gen
+
+// generateNotify emits a decorator publishing a notify.Event after each
+// write, and OnChanged dispatching the received events as typed changes.
+func (g *Generator) generateNotify(repoName, typeName string) {
+	if !g.opts.Notify {
+		return
+	}
+	g.Import("github.com/l-vitaly/gormrepo/notify")
+	g.Printf(repoNotify, repoName, typeName, g.lcFirst(typeName)+"NotifyingRepo")
+}
+
+const repoNotify = `
+type %[3]s struct {
+	*%[1]s
+	channel string
+}
+
+// Notifying returns the repository publishing a notify.Event on the channel
+// after each successful write.
+func (r *%[1]s) Notifying(channel string) *%[3]s {
+	return &%[3]s{r, channel}
+}
+
+func (r *%[3]s) Create(entity %[2]s) (*%[2]s, error) {
+	created, err := r.%[1]s.Create(entity)
+	if err != nil {
+		return nil, err
+	}
+	err = notify.Publish(r.DB, r.channel, notify.Event{Entity: %[2]q, Op: gormrepo.OpCreate, ID: uint(created.ID)})
+	return created, r.wrapError("Create", err)
+}
+
+func (r *%[3]s) Update(entity *%[2]s, fields gormrepo.Fields, criteria ...gormrepo.CriteriaOption) error {
+	if err := r.%[1]s.Update(entity, fields, criteria...); err != nil {
+		return err
+	}
+	columns := make([]string, 0, len(fields))
+	for column := range fields {
+		columns = append(columns, column)
+	}
+	err := notify.Publish(r.DB, r.channel, notify.Event{Entity: %[2]q, Op: gormrepo.OpUpdate, ID: uint(entity.ID), Fields: columns})
+	return r.wrapError("Update", err, criteria...)
+}
+
+func (r *%[3]s) Delete(entity *%[2]s, criteria ...gormrepo.CriteriaOption) error {
+	if err := r.%[1]s.Delete(entity, criteria...); err != nil {
+		return err
+	}
+	err := notify.Publish(r.DB, r.channel, notify.Event{Entity: %[2]q, Op: gormrepo.OpDelete, ID: uint(entity.ID)})
+	return r.wrapError("Delete", err, criteria...)
+}
+
+// OnChanged registers fn for the %[2]s changes received by the listener. New
+// is loaded from the database, for deletes Old only holds the primary key.
+func (r *%[1]s) OnChanged(l *notify.Listener, fn func(%[2]sChanged)) {
+	l.Handle(%[2]q, func(event notify.Event) {
+		changed := %[2]sChanged{Op: event.Op, Fields: event.Fields}
+		if event.Op == gormrepo.OpDelete {
+			changed.Old = &%[2]s{}
+			r.DB.NewScope(changed.Old).SetColumn("ID", event.ID)
+		} else {
+			entity, err := r.Get(event.ID)
+			if err != nil {
+				return
+			}
+			changed.New = entity
+		}
+		fn(changed)
+	})
+}
+`
@@ -0,0 +1,121 @@
+package gen
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// pendingFile is an output file written once every type is generated.
+type pendingFile struct {
+	name    string
+	src     []byte
+	message string // Printed once the file is written.
+}
+
+// stage records an output file of the run.
+func (g *Generator) stage(name string, src []byte, message string) {
+	g.pending = append(g.pending, pendingFile{name, src, message})
+}
+
+// commit writes the staged files. Each file is written to a temporary file
+// next to it and renamed over it, and the files already replaced are
+// restored when one fails, so a run writes all its files or none.
+func (g *Generator) commit() error {
+	pending := g.pending
+	g.pending = nil
+
+	temps := make([]string, len(pending))
+	cleanup := func() {
+		for _, temp := range temps {
+			if temp != "" {
+				os.Remove(temp)
+			}
+		}
+	}
+	for i, p := range pending {
+		temp, err := writeTemp(p.name, p.src)
+		if err != nil {
+			cleanup()
+			return err
+		}
+		temps[i] = temp
+	}
+
+	// backups holds the previous content of the replaced files, "" for
+	// the files created.
+	var backups []string
+	rollback := func() {
+		for i := len(backups) - 1; i >= 0; i-- {
+			if backups[i] == "" {
+				os.Remove(pending[i].name)
+			} else {
+				os.Rename(backups[i], pending[i].name)
+			}
+		}
+	}
+	for i, p := range pending {
+		backup := ""
+		if _, err := os.Stat(p.name); err == nil {
+			backup = temps[i] + ".orig"
+			if err := os.Rename(p.name, backup); err != nil {
+				rollback()
+				cleanup()
+				return err
+			}
+		}
+		backups = append(backups, backup)
+		if err := os.Rename(temps[i], p.name); err != nil {
+			rollback()
+			cleanup()
+			return err
+		}
+		temps[i] = ""
+	}
+	for i, p := range pending {
+		if backups[i] != "" {
+			os.Remove(backups[i])
+		}
+		fmt.Println(p.message)
+	}
+	return nil
+}
+
+// writeTemp writes src to a new temporary file in the directory of name,
+// with the mode of the generated files.
+func writeTemp(name string, src []byte) (string, error) {
+	f, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".")
+	if err != nil {
+		return "", err
+	}
+	_, err = f.Write(src)
+	if err == nil {
+		err = f.Sync()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Chmod(f.Name(), 0644)
+	}
+	if err != nil {
+		os.Remove(f.Name())
+		return "", err
+	}
+	return f.Name(), nil
+}
+
+// writeFileAtomic writes src to the named file through a temporary file
+// renamed over it.
+func writeFileAtomic(name string, src []byte) error {
+	temp, err := writeTemp(name, src)
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(temp, name); err != nil {
+		os.Remove(temp)
+		return err
+	}
+	return nil
+}
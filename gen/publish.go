@@ -0,0 +1,56 @@
+package gen
+
+// generatePublish emits a decorator publishing the <T>Changed event of each
+// successful write.
+func (g *Generator) generatePublish(repoName, typeName string) {
+	if !g.opts.Publish {
+		return
+	}
+	g.Import("context", "fmt")
+	g.Printf(repoPublish, repoName, typeName, g.lcFirst(typeName)+"PublishingRepo")
+}
+
+const repoPublish = `
+type %[3]s struct {
+	*%[1]s
+	ctx       context.Context
+	publisher gormrepo.Publisher
+}
+
+// Publishing returns the repository publishing the %[2]sChanged event of each
+// successful write. Events of writes in a transaction are published before
+// the commit.
+func (r *%[1]s) Publishing(ctx context.Context, publisher gormrepo.Publisher) *%[3]s {
+	return &%[3]s{r, ctx, publisher}
+}
+
+func (r *%[3]s) publish(changed %[2]sChanged, entity *%[2]s) error {
+	return r.publisher.Publish(r.ctx, gormrepo.Event{Entity: %[2]q, Op: changed.Op, Key: fmt.Sprint(entity.ID), Payload: changed})
+}
+
+func (r *%[3]s) Create(entity %[2]s) (*%[2]s, error) {
+	created, err := r.%[1]s.Create(entity)
+	if err != nil {
+		return nil, err
+	}
+	return created, r.wrapError("Create", r.publish(r.Changed(gormrepo.OpCreate, nil, created), created))
+}
+
+func (r *%[3]s) Update(entity *%[2]s, fields gormrepo.Fields, criteria ...gormrepo.CriteriaOption) error {
+	if err := r.%[1]s.Update(entity, fields, criteria...); err != nil {
+		return err
+	}
+	changed := %[2]sChanged{Op: gormrepo.OpUpdate, New: entity}
+	for column := range fields {
+		changed.Fields = append(changed.Fields, column)
+	}
+	return r.wrapError("Update", r.publish(changed, entity), criteria...)
+}
+
+func (r *%[3]s) Delete(entity *%[2]s, criteria ...gormrepo.CriteriaOption) error {
+	if err := r.%[1]s.Delete(entity, criteria...); err != nil {
+		return err
+	}
+	return r.wrapError("Delete", r.publish(r.Changed(gormrepo.OpDelete, entity, nil), entity), criteria...)
+}
+`
@@ -0,0 +1,38 @@
+package gen
+
+// generatePublished emits the Published criteria and GetPublished for types
+// with fields tagged `gormrepo:"publish"` and optionally
+// `gormrepo:"unpublish"`.
+func (g *Generator) generatePublished(repoNameRecv, typeName string) {
+	st := g.getStructType(typeName)
+	publishField, _, ok := g.taggedField(st, "publish")
+	if !ok {
+		return
+	}
+	unpublishField, _, _ := g.taggedField(st, "unpublish")
+
+	g.Import("time")
+	g.Printf(repoPublished, repoNameRecv, typeName, publishField, unpublishField)
+}
+
+const repoPublished = `
+func (r %[1]s) Published(now time.Time) gormrepo.CriteriaOption {
+	scope := r.DB.NewScope(&%[2]s{})
+	var publish, unpublish string
+	if field, ok := scope.FieldByName(%[3]q); ok {
+		publish = scope.QuotedTableName() + "." + scope.Quote(field.DBName)
+	}
+	if field, ok := scope.FieldByName(%[4]q); ok {
+		unpublish = scope.QuotedTableName() + "." + scope.Quote(field.DBName)
+	}
+	return gormrepo.Published(publish, unpublish, now)
+}
+
+// GetPublished is Get limited to the entity published at now, for Get takes
+// no criteria.
+func (r %[1]s) GetPublished(id uint, now time.Time) (*%[2]s, error) {
+	var entity %[2]s
+	err := r.applyCriteria([]gormrepo.CriteriaOption{r.Published(now)}).Where(map[string]interface{}{"id": id}).Find(&entity).Error
+	return &entity, r.wrapError("GetPublished", err)
+}
+`
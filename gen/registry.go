@@ -0,0 +1,270 @@
+package gen
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// registered is a repository generated by this run, for the registry.
+type registered struct {
+	file               *File
+	typeName, repoName string
+	view               bool
+	strategy           string
+}
+
+// register records the repository of a type for the registry, in type name
+// order whatever the order the types are given in.
+func (g *Generator) register(f *File, typeName, repoName, view, strategy string) {
+	i := sort.Search(len(g.registered), func(i int) bool { return g.registered[i].typeName >= typeName })
+	g.registered = append(g.registered, registered{})
+	copy(g.registered[i+1:], g.registered[i:])
+	g.registered[i] = registered{f, typeName, repoName, view != "", strategy}
+}
+
+// generateRegistry writes the registry of the repositories generated by the
+// run to gormrepo_registry.go.
+func (g *Generator) generateRegistry() {
+	if !g.opts.Registry || len(g.registered) == 0 {
+		return
+	}
+	g.Import("context", "encoding/json", "errors", "fmt", "io", "sort", "github.com/jinzhu/gorm", "github.com/l-vitaly/gormrepo")
+	var fields, entities, values, migrations, models, references, cases strings.Builder
+	for _, r := range g.registered {
+		fmt.Fprintf(&fields, "\t%s *%s\n", r.typeName, r.repoName)
+		fmt.Fprintf(&entities, "\t%q,\n", r.typeName)
+		fmt.Fprintf(&values, "\tr.%s = &%s{r.dbs[%q]}\n", r.typeName, r.repoName, r.typeName)
+		if r.view {
+			continue
+		}
+		fmt.Fprintf(&migrations, "\tif err := r.%s.AutoMigrate(); err != nil {\n\t\treturn err\n\t}\n", r.typeName)
+		if r.strategy == treeClosure {
+			fmt.Fprintf(&migrations, "\tif err := r.%s.AutoMigrateTree(); err != nil {\n\t\treturn err\n\t}\n", r.typeName)
+		}
+		fmt.Fprintf(&models, "\t\t%q: &%s{},\n", r.typeName, r.typeName)
+		if r.strategy == treePath || r.strategy == treeClosure {
+			// The references are upserted, and the path and closure trees
+			// have no Upsert.
+			continue
+		}
+		fmt.Fprintf(&references, repoRegistryReference, r.typeName, r.repoName)
+		fmt.Fprintf(&cases, "\t\tcase %[1]q:\n\t\t\tvar rows []%[1]s\n\t\t\tif err := json.Unmarshal(ref.Rows, &rows); err != nil {\n\t\t\t\treturn fmt.Errorf(\"reference %%s: %%w\", entity, err)\n\t\t\t}\n\t\t\tr.Reference%[1]s(target, ref.Update, rows...)\n", r.typeName)
+	}
+	g.Printf(repoRegistry, fields.String(), values.String(), migrations.String(), references.String(), cases.String(), models.String(), entities.String())
+
+	f := g.registered[0].file
+	g.writeOutput(f, "Registry", "registry", "gormrepo_registry.go", g.outputConstraint(f.name))
+}
+
+const repoRegistry = `
+// registryEntities are the type names of the entities of the Registry.
+var registryEntities = []string{
+%[7]s}
+
+// registryModels are the models of the migrated entities, by type name.
+var registryModels = map[string]interface{}{
+%[6]s}
+
+// Registry holds the repositories of the package.
+type Registry struct {
+	DB *gorm.DB
+%[1]s
+	dbs        map[string]*gorm.DB
+	references []registryReference
+}
+
+// registryReference ensures reference rows of the entity exist.
+type registryReference struct {
+	entity string
+	ensure func(tx *gorm.DB) error
+}
+
+func NewRegistry(db *gorm.DB) *Registry {
+	return newRegistry(db, func(string) *gorm.DB { return db })
+}
+
+// NewRegistryDBs returns the registry of the entities bound to the databases
+// of dbs, its DB being dbs.Default. It fails with gormrepo.ErrInvalidConfig
+// when dbs lists an unknown entity or leaves one without a database.
+func NewRegistryDBs(dbs gormrepo.EntityDBs) (*Registry, error) {
+	if err := dbs.Check(registryEntities...); err != nil {
+		return nil, err
+	}
+	return newRegistry(dbs.Default, dbs.DB), nil
+}
+
+func newRegistry(db *gorm.DB, dbOf func(entity string) *gorm.DB) *Registry {
+	r := &Registry{DB: db, dbs: make(map[string]*gorm.DB, len(registryEntities))}
+	for _, entity := range registryEntities {
+		r.dbs[entity] = dbOf(entity)
+	}
+%[2]s	return r
+}
+
+// DBOf returns the database of the entity.
+func (r *Registry) DBOf(entity string) *gorm.DB {
+	return r.dbs[entity]
+}
+
+// Transaction runs fn in a transaction of db, one of the databases of the
+// registry, with a registry whose repositories of the entities of db use the
+// transaction. The repositories of the entities of other databases fail with
+// gormrepo.ErrCrossDatabaseTx, since the transaction would not cover them.
+func (r *Registry) Transaction(db *gorm.DB, fn func(tx *Registry) error, opts ...gormrepo.TxOption) error {
+	return gormrepo.Transaction(db, func(tx *gorm.DB) error {
+		return fn(newRegistry(tx, func(entity string) *gorm.DB {
+			if r.dbs[entity] == db {
+				return tx
+			}
+			return gormrepo.CrossDatabase(r.dbs[entity])
+		}))
+	}, opts...)
+}
+
+// databases returns the databases of the entities, in entity order.
+func (r *Registry) databases() []*gorm.DB {
+	var dbs []*gorm.DB
+	seen := map[*gorm.DB]bool{}
+	for _, entity := range registryEntities {
+		if db := r.dbs[entity]; !seen[db] {
+			seen[db] = true
+			dbs = append(dbs, db)
+		}
+	}
+	return dbs
+}
+
+// MigrateAll migrates the tables of the entities, then ensures their
+// reference rows exist, in a transaction of each database.
+func (r *Registry) MigrateAll() error {
+%[3]s	for _, db := range r.databases() {
+		err := gormrepo.Transaction(db, func(tx *gorm.DB) error {
+			for _, ref := range r.references {
+				if r.dbs[ref.entity] != db {
+					continue
+				}
+				if err := ref.ensure(tx); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// TruncateAll empties the tables of the entities of each database, the
+// tables referencing the others first, see gormrepo.Truncate.
+func (r *Registry) TruncateAll() error {
+	for _, db := range r.databases() {
+		var models []interface{}
+		for _, entity := range registryEntities {
+			if model, ok := registryModels[entity]; ok && r.dbs[entity] == db {
+				models = append(models, model)
+			}
+		}
+		if len(models) == 0 {
+			continue
+		}
+		if err := gormrepo.Truncate(db, false, models...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ValidateSchema compares the tables of the entities with their databases,
+// so services can refuse to start on a drifted schema.
+func (r *Registry) ValidateSchema(ctx context.Context) (*gormrepo.SchemaReport, error) {
+	report := &gormrepo.SchemaReport{}
+	for _, db := range r.databases() {
+		var models []interface{}
+		for _, entity := range registryEntities {
+			if model, ok := registryModels[entity]; ok && r.dbs[entity] == db {
+				models = append(models, model)
+			}
+		}
+		if len(models) == 0 {
+			continue
+		}
+		dbReport, err := gormrepo.ValidateSchema(ctx, db, models...)
+		if dbReport != nil {
+			report.Drifts = append(report.Drifts, dbReport.Drifts...)
+		}
+		if err != nil && !errors.Is(err, gormrepo.ErrSchemaDrift) {
+			return nil, err
+		}
+	}
+	if !report.OK() {
+		return report, fmt.Errorf("%%w: %%s", gormrepo.ErrSchemaDrift, report)
+	}
+	return report, nil
+}
+
+// DDL returns the statements creating the tables of the entities on the
+// dialect, for external migration tools.
+func (r *Registry) DDL(dialect string) (string, error) {
+	var models []interface{}
+	for _, entity := range registryEntities {
+		if model, ok := registryModels[entity]; ok {
+			models = append(models, model)
+		}
+	}
+	return gormrepo.DDL(dialect, models...)
+}
+%[4]s
+// LoadReferences declares the reference rows read from JSON, an object with
+// the rows of each entity by type name, upserted on the target columns, the
+// primary key when blank, in entity name order:
+//
+//	{"Role": {"target": ["name"], "rows": [{"Name": "admin"}]}}
+func (r *Registry) LoadReferences(reader io.Reader) error {
+	var config map[string]struct {
+		Target []string        ` + "`json:\"target\"`" + `
+		Update []string        ` + "`json:\"update\"`" + `
+		Rows   json.RawMessage ` + "`json:\"rows\"`" + `
+	}
+	if err := json.NewDecoder(reader).Decode(&config); err != nil {
+		return err
+	}
+	// The rows are declared in entity name order, not in the random order
+	// of the map, so MigrateAll upserts them the same way on each run.
+	entities := make([]string, 0, len(config))
+	for entity := range config {
+		entities = append(entities, entity)
+	}
+	sort.Strings(entities)
+	for _, entity := range entities {
+		ref := config[entity]
+		target := gormrepo.OnConstraint("primary")
+		if len(ref.Target) > 0 {
+			target = gormrepo.OnColumns(ref.Target...)
+		}
+		switch entity {
+%[5]s		default:
+			return fmt.Errorf("reference %%s: unknown entity", entity)
+		}
+	}
+	return nil
+}
+`
+
+const repoRegistryReference = `
+// Reference%[1]s declares rows of %[1]s that MigrateAll upserts on target,
+// updating the update columns, or all the columns but target when none.
+func (r *Registry) Reference%[1]s(target gormrepo.ConflictTarget, update []string, rows ...%[1]s) {
+	r.references = append(r.references, registryReference{%[1]q, func(tx *gorm.DB) error {
+		repo := &%[2]s{tx}
+		for _, row := range rows {
+			if err := repo.Upsert(&row, target, update...); err != nil {
+				return fmt.Errorf("reference %[1]s: %%w", err)
+			}
+		}
+		return nil
+	}})
+}
+`
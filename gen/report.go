@@ -0,0 +1,49 @@
+package gen
+
+import (
+	"fmt"
+	"log"
+	"strings"
+)
+
+// typeError is the failure to generate a type.
+type typeError struct {
+	typeName string
+	err      error
+}
+
+func (e typeError) Error() string {
+	return e.err.Error()
+}
+
+// report prints the errors of the run and a summary of the generated
+// types, and reports whether the run failed.
+func (g *Generator) report(types []string, generated int, typeErrors []typeError) bool {
+	parseErrors := g.parseErrors
+	for _, err := range parseErrors {
+		if g.opts.Strict {
+			log.Print(err)
+		} else {
+			log.Printf("skipped file: %s", err)
+		}
+	}
+	for _, err := range typeErrors {
+		log.Print(err)
+	}
+	if len(typeErrors) == 0 && (!g.opts.Strict || len(parseErrors) == 0) {
+		return false
+	}
+	failed := make([]string, len(typeErrors))
+	for i, err := range typeErrors {
+		failed[i] = err.typeName
+	}
+	summary := fmt.Sprintf("generated %d of %d types", generated, len(types))
+	if len(failed) > 0 {
+		summary += ", failed: " + strings.Join(failed, ", ")
+	}
+	if g.opts.Strict && len(parseErrors) > 0 {
+		summary += fmt.Sprintf(", files not parsed: %d", len(parseErrors))
+	}
+	log.Print(summary)
+	return true
+}
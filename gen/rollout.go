@@ -0,0 +1,120 @@
+package gen
+
+// generateRollout emits the store interface of the type and a decorator
+// routing each operation to the repository or an alternative store, as
+// decided by a gormrepo.Rollout.
+func (g *Generator) generateRollout(repoName, typeName string) {
+	if !g.opts.Rollout {
+		return
+	}
+	g.Import("strconv", "time")
+	g.Printf(repoRollout, repoName, typeName, g.lcFirst(typeName)+"RolloutRepo", typeName+"Store")
+}
+
+const repoRollout = `
+// %[4]s is implemented by the implementations %[2]s is stored in.
+type %[4]s interface {
+	Get(id uint) (*%[2]s, error)
+	GetAll() ([]*%[2]s, error)
+	GetBy(criteria ...gormrepo.CriteriaOption) ([]*%[2]s, error)
+	GetByFirst(criteria ...gormrepo.CriteriaOption) (*%[2]s, error)
+	GetByLast(criteria ...gormrepo.CriteriaOption) (*%[2]s, error)
+	Count(criteria ...gormrepo.CriteriaOption) (int, error)
+	Create(entity %[2]s) (*%[2]s, error)
+	Update(entity *%[2]s, fields gormrepo.Fields, criteria ...gormrepo.CriteriaOption) error
+	Delete(entity *%[2]s, criteria ...gormrepo.CriteriaOption) error
+}
+
+type %[3]s struct {
+	*%[1]s
+	alternative %[4]s
+	rollout     *gormrepo.Rollout
+}
+
+// Rollout returns the repository running each operation on alternative
+// instead, for the percentage of the operation set on rollout. Operations on
+// an entity by primary key are routed consistently, the others at random.
+func (r *%[1]s) Rollout(alternative %[4]s, rollout *gormrepo.Rollout) *%[3]s {
+	return &%[3]s{r, alternative, rollout}
+}
+
+// route returns the store of the operation and a func recording its outcome.
+func (r *%[3]s) route(op string, id uint) (%[4]s, func(error)) {
+	var key string
+	if id != 0 {
+		key = strconv.FormatUint(uint64(id), 10)
+	}
+	alternative := r.rollout.Route(%[2]q, op, key)
+	start := time.Now()
+	record := func(err error) {
+		r.rollout.Record(%[2]q, op, alternative, time.Since(start), err)
+	}
+	if alternative {
+		return r.alternative, record
+	}
+	return r.%[1]s, record
+}
+
+func (r *%[3]s) Get(id uint) (*%[2]s, error) {
+	store, record := r.route("Get", id)
+	entity, err := store.Get(id)
+	record(err)
+	return entity, err
+}
+
+func (r *%[3]s) GetAll() ([]*%[2]s, error) {
+	store, record := r.route("GetAll", 0)
+	entities, err := store.GetAll()
+	record(err)
+	return entities, err
+}
+
+func (r *%[3]s) GetBy(criteria ...gormrepo.CriteriaOption) ([]*%[2]s, error) {
+	store, record := r.route("GetBy", 0)
+	entities, err := store.GetBy(criteria...)
+	record(err)
+	return entities, err
+}
+
+func (r *%[3]s) GetByFirst(criteria ...gormrepo.CriteriaOption) (*%[2]s, error) {
+	store, record := r.route("GetByFirst", 0)
+	entity, err := store.GetByFirst(criteria...)
+	record(err)
+	return entity, err
+}
+
+func (r *%[3]s) GetByLast(criteria ...gormrepo.CriteriaOption) (*%[2]s, error) {
+	store, record := r.route("GetByLast", 0)
+	entity, err := store.GetByLast(criteria...)
+	record(err)
+	return entity, err
+}
+
+func (r *%[3]s) Count(criteria ...gormrepo.CriteriaOption) (int, error) {
+	store, record := r.route("Count", 0)
+	count, err := store.Count(criteria...)
+	record(err)
+	return count, err
+}
+
+func (r *%[3]s) Create(entity %[2]s) (*%[2]s, error) {
+	store, record := r.route("Create", 0)
+	created, err := store.Create(entity)
+	record(err)
+	return created, err
+}
+
+func (r *%[3]s) Update(entity *%[2]s, fields gormrepo.Fields, criteria ...gormrepo.CriteriaOption) error {
+	store, record := r.route("Update", entity.ID)
+	err := store.Update(entity, fields, criteria...)
+	record(err)
+	return err
+}
+
+func (r *%[3]s) Delete(entity *%[2]s, criteria ...gormrepo.CriteriaOption) error {
+	store, record := r.route("Delete", entity.ID)
+	err := store.Delete(entity, criteria...)
+	record(err)
+	return err
+}
+`
@@ -0,0 +1,85 @@
+package gen
+
+// generateShadow emits the reader interface of the type and a repository
+// running the reads of the interface on a shadow reader too, reporting the
+// results that do not match the primary ones. It does not embed the
+// repository, so no read escapes the comparison.
+func (g *Generator) generateShadow(repoName, typeName string) {
+	if !g.opts.Shadow {
+		return
+	}
+	g.Printf(repoShadow, repoName, typeName, g.lcFirst(typeName)+"ShadowRepo", typeName+"Reader")
+}
+
+const repoShadow = `
+// %[4]s is implemented by the implementations %[2]s is read from.
+type %[4]s interface {
+	Get(id uint) (*%[2]s, error)
+	GetAll() ([]*%[2]s, error)
+	GetBy(criteria ...gormrepo.CriteriaOption) ([]*%[2]s, error)
+	GetByFirst(criteria ...gormrepo.CriteriaOption) (*%[2]s, error)
+	GetByLast(criteria ...gormrepo.CriteriaOption) (*%[2]s, error)
+}
+
+type %[3]s struct {
+	repo   *%[1]s
+	shadow %[4]s
+	report func(gormrepo.Divergence)
+}
+
+// Shadow returns the repository running every read of %[4]s on shadow as well
+// and returning the primary result, without the other methods. Results
+// differing according to gormrepo.ShadowEqual, or an error on one side only,
+// are passed to report, or to gormrepo.LogDivergence when nil.
+func (r *%[1]s) Shadow(shadow %[4]s, report func(gormrepo.Divergence)) *%[3]s {
+	if report == nil {
+		report = gormrepo.LogDivergence
+	}
+	return &%[3]s{r, shadow, report}
+}
+
+func (r *%[3]s) compare(op string, primary, shadow interface{}, primaryErr, shadowErr error) {
+	switch {
+	case primaryErr != nil && shadowErr != nil:
+	case shadowErr != nil:
+		r.report(gormrepo.Divergence{Entity: %[2]q, Op: op, Primary: primary, Err: shadowErr})
+	case primaryErr != nil || !gormrepo.ShadowEqual(primary, shadow):
+		r.report(gormrepo.Divergence{Entity: %[2]q, Op: op, Primary: primary, Secondary: shadow})
+	}
+}
+
+func (r *%[3]s) Get(id uint) (*%[2]s, error) {
+	entity, err := r.repo.Get(id)
+	shadow, shadowErr := r.shadow.Get(id)
+	r.compare("Get", entity, shadow, err, shadowErr)
+	return entity, err
+}
+
+func (r *%[3]s) GetAll() ([]*%[2]s, error) {
+	entities, err := r.repo.GetAll()
+	shadow, shadowErr := r.shadow.GetAll()
+	r.compare("GetAll", entities, shadow, err, shadowErr)
+	return entities, err
+}
+
+func (r *%[3]s) GetBy(criteria ...gormrepo.CriteriaOption) ([]*%[2]s, error) {
+	entities, err := r.repo.GetBy(criteria...)
+	shadow, shadowErr := r.shadow.GetBy(criteria...)
+	r.compare("GetBy", entities, shadow, err, shadowErr)
+	return entities, err
+}
+
+func (r *%[3]s) GetByFirst(criteria ...gormrepo.CriteriaOption) (*%[2]s, error) {
+	entity, err := r.repo.GetByFirst(criteria...)
+	shadow, shadowErr := r.shadow.GetByFirst(criteria...)
+	r.compare("GetByFirst", entity, shadow, err, shadowErr)
+	return entity, err
+}
+
+func (r *%[3]s) GetByLast(criteria ...gormrepo.CriteriaOption) (*%[2]s, error) {
+	entity, err := r.repo.GetByLast(criteria...)
+	shadow, shadowErr := r.shadow.GetByLast(criteria...)
+	r.compare("GetByLast", entity, shadow, err, shadowErr)
+	return entity, err
+}
+`
@@ -0,0 +1,73 @@
+package gen
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// generateState emits the state machine and Transition method for the field
+// tagged `gormrepo:"state:draft>review,review>published"`.
+func (g *Generator) generateState(repoNameRecv, typeName string) {
+	fieldName, value, ok := g.taggedField(g.getStructType(typeName), "state")
+	if !ok {
+		return
+	}
+	transitions := map[string][]string{}
+	for _, item := range strings.Split(value, ",") {
+		states := strings.Split(item, ">")
+		if len(states) != 2 || strings.TrimSpace(states[0]) == "" || strings.TrimSpace(states[1]) == "" {
+			fatalf("type %s: invalid state transition %q on field %s", typeName, item, fieldName)
+		}
+		from := strings.TrimSpace(states[0])
+		transitions[from] = append(transitions[from], strings.TrimSpace(states[1]))
+	}
+	froms := make([]string, 0, len(transitions))
+	for from := range transitions {
+		froms = append(froms, from)
+	}
+	sort.Strings(froms)
+
+	var machine bytes.Buffer
+	for _, from := range froms {
+		fmt.Fprintf(&machine, "\t%q: {", from)
+		for i, to := range transitions[from] {
+			if i > 0 {
+				machine.WriteString(", ")
+			}
+			fmt.Fprintf(&machine, "%q", to)
+		}
+		machine.WriteString("},\n")
+	}
+
+	statesName := g.lcFirst(typeName) + "States"
+	g.Printf(stateMachine, statesName, machine.String())
+	g.Printf(repoTransition, repoNameRecv, "*"+typeName, statesName, fieldName)
+}
+
+const stateMachine = `
+var %[1]s = gormrepo.StateMachine{
+%[2]s}
+`
+
+const repoTransition = `
+func (r %[1]s) Transition(entity %[2]s, from, to string) error {
+	if !%[3]s.Can(from, to) {
+		return r.wrapError("Transition", gormrepo.ErrInvalidState)
+	}
+	scope := r.DB.NewScope(entity)
+	field, _ := scope.FieldByName(%[4]q)
+	previous := field.Field.Interface()
+	result := r.DB.Model(entity).Where(scope.Quote(field.DBName)+" = ?", from).Update(%[4]q, to)
+	if result.Error != nil {
+		field.Set(previous)
+		return r.wrapError("Transition", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		field.Set(previous)
+		return r.wrapError("Transition", gormrepo.ErrStateConflict)
+	}
+	return nil
+}
+`
@@ -0,0 +1,47 @@
+package gen
+
+import (
+	"go/ast"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// gormrepoTag parses the `gormrepo:"key:value;flag"` tag of the field.
+func (g *Generator) gormrepoTag(field *ast.Field) map[string]string {
+	settings := map[string]string{}
+	if field.Tag == nil {
+		return settings
+	}
+	tag, err := strconv.Unquote(field.Tag.Value)
+	if err != nil {
+		return settings
+	}
+	for _, item := range strings.Split(reflect.StructTag(tag).Get("gormrepo"), ";") {
+		if item = strings.TrimSpace(item); item == "" {
+			continue
+		}
+		kv := strings.SplitN(item, ":", 2)
+		key := strings.ToLower(strings.TrimSpace(kv[0]))
+		if len(kv) == 2 {
+			settings[key] = strings.TrimSpace(kv[1])
+		} else {
+			settings[key] = ""
+		}
+	}
+	return settings
+}
+
+// taggedField returns the name and tag value of the first field whose
+// gormrepo tag has the key.
+func (g *Generator) taggedField(st *ast.StructType, key string) (string, string, bool) {
+	if st == nil {
+		return "", "", false
+	}
+	for _, field := range st.Fields.List {
+		if value, ok := g.gormrepoTag(field)[key]; ok && len(field.Names) > 0 {
+			return field.Names[0].Name, value, true
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,65 @@
+package gen
+
+import (
+	"bufio"
+	"go/build/constraint"
+	"os"
+	"strings"
+)
+
+// fileConstraint returns the expression of the //go:build line of the named
+// file, or "" when it has none.
+func fileConstraint(name string) string {
+	f, err := os.Open(name)
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if constraint.IsGoBuild(line) {
+			return strings.TrimSpace(strings.TrimPrefix(line, "//go:build"))
+		}
+		if line != "" && !strings.HasPrefix(line, "//") {
+			// Constraints precede the package clause.
+			return ""
+		}
+	}
+	return ""
+}
+
+// outputConstraint returns the constraint of the generated files of the type
+// declared in the named file, with -out-tags replacing the constraint of
+// the file.
+func (g *Generator) outputConstraint(name string) string {
+	expr := g.opts.OutTags
+	if expr == "" {
+		expr = fileConstraint(name)
+	}
+	if expr == "" {
+		return ""
+	}
+	if _, err := constraint.Parse("//go:build " + expr); err != nil {
+		fatalf("invalid build constraint %q: %s", expr, err)
+	}
+	return expr
+}
+
+// andConstraint combines two build constraints.
+func andConstraint(a, b string) string {
+	switch {
+	case a == "":
+		return b
+	case b == "":
+		return a
+	}
+	return paren(a) + " && " + paren(b)
+}
+
+func paren(expr string) string {
+	if strings.ContainsAny(expr, " |&") {
+		return "(" + expr + ")"
+	}
+	return expr
+}
@@ -0,0 +1,13 @@
+package keys
+
+// Coded is keyed by a string column tagged primary_key.
+type Coded struct {
+	Code string `gorm:"primary_key"`
+	Name string
+}
+
+// Keyed is keyed by a string ID.
+type Keyed struct {
+	ID   string
+	Name string
+}
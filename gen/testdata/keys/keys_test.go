@@ -0,0 +1,34 @@
+package keys
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/l-vitaly/gormrepo"
+)
+
+func TestOrderColumnOfModel(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// Every connection would open its own in-memory database.
+	db.DB().SetMaxOpenConns(1)
+	repo := &KeyedBaseRepo{db}
+	if err := repo.AutoMigrate(); err != nil {
+		t.Fatal(err)
+	}
+	for _, column := range []string{"bogus", "keyeds.bogus"} {
+		if _, err := repo.GetBy(gormrepo.Order(column, gormrepo.Asc)); !errors.Is(err, gormrepo.ErrInvalidCriterion) {
+			t.Errorf("ordering by %s: got %v, want %v", column, err, gormrepo.ErrInvalidCriterion)
+		}
+	}
+	for _, column := range []string{"name", "keyeds.name"} {
+		if _, err := repo.GetBy(gormrepo.Order(column, gormrepo.Asc)); err != nil {
+			t.Errorf("ordering by %s: %v", column, err)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Article is an entity embedding gorm.Model with most generated features.
+type Article struct {
+	gorm.Model
+	Title        string     `gorm:"unique_index"`
+	AuthorEmail  string     `gormrepo:"mask:email"`
+	Status       Status     `gormrepo:"state:draft>review,review>published,review>draft"`
+	PublishedAt  *time.Time `gormrepo:"publish"`
+	ExpiresAt    *time.Time `gormrepo:"unpublish"`
+	Cover        []byte     `gormrepo:"blob"`
+	Translations []ArticleTranslation
+}
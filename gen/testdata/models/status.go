@@ -0,0 +1,10 @@
+package models
+
+// Status is the review state of an article.
+type Status string
+
+const (
+	StatusDraft     Status = "draft"
+	StatusReview    Status = "review"
+	StatusPublished Status = "published"
+)
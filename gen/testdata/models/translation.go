@@ -0,0 +1,9 @@
+package models
+
+// ArticleTranslation is a translation of an article.
+type ArticleTranslation struct {
+	ID        uint
+	ArticleID uint   `gorm:"unique_index:idx_article_locale"`
+	Locale    string `gorm:"unique_index:idx_article_locale"`
+	Title     string
+}
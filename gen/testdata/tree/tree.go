@@ -0,0 +1,26 @@
+package tree
+
+import "time"
+
+// Node is a tree kept as an adjacency list.
+type Node struct {
+	ID       uint
+	ParentID *uint
+	Name     string `gormrepo:"mask"`
+}
+
+// PathNode is a tree kept with materialized paths.
+type PathNode struct {
+	ID       uint
+	ParentID uint
+	Path     string
+	Name     string
+}
+
+// ClosureNode is a soft deleted tree kept with a closure table.
+type ClosureNode struct {
+	ID        uint
+	ParentID  *uint
+	Name      string
+	DeletedAt *time.Time
+}
@@ -0,0 +1,193 @@
+package tree
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/l-vitaly/gormrepo"
+)
+
+func openDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Every connection would open its own in-memory database.
+	db.DB().SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// pathNames returns the sorted names of the nodes, or the error.
+func pathNames(nodes []*PathNode, err error) []string {
+	if err != nil {
+		return []string{err.Error()}
+	}
+	names := []string{}
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// closureNames returns the sorted names of the nodes, or the error.
+func closureNames(nodes []*ClosureNode, err error) []string {
+	if err != nil {
+		return []string{err.Error()}
+	}
+	names := []string{}
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func wantNames(t *testing.T, what string, got []string, want ...string) {
+	t.Helper()
+	if want == nil {
+		want = []string{}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("%s: got %q, want %q", what, got, want)
+	}
+}
+
+func TestPathNodeWrites(t *testing.T) {
+	repo := &pathNodeBaseRepo{openDB(t)}
+	if err := repo.AutoMigrate(); err != nil {
+		t.Fatal(err)
+	}
+	create := func(name string, parent *PathNode) *PathNode {
+		t.Helper()
+		node := PathNode{Name: name}
+		if parent != nil {
+			node.ParentID = parent.ID
+		}
+		created, err := repo.Create(node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return created
+	}
+	get := func(node *PathNode) *PathNode {
+		t.Helper()
+		stored, err := repo.Get(node.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return stored
+	}
+	a := create("a", nil)
+	b := create("b", a)
+	c := create("c", b)
+	d := create("d", nil)
+
+	if err := repo.Update(b, gormrepo.Fields{"ParentID": d.ID}); err != nil {
+		t.Fatal(err)
+	}
+	wantNames(t, "descendants of d", pathNames(repo.GetDescendants(get(d))), "b", "c")
+	wantNames(t, "descendants of a", pathNames(repo.GetDescendants(get(a))))
+	wantNames(t, "ancestors of c", pathNames(repo.GetAncestors(get(c))), "b", "d")
+
+	err := repo.Update(b, gormrepo.Fields{"parent_id": c.ID})
+	if !errors.Is(err, gormrepo.ErrTreeCycle) {
+		t.Errorf("moving b under c: got %v, want %v", err, gormrepo.ErrTreeCycle)
+	}
+	if parent := get(b).ParentID; parent != d.ID {
+		t.Errorf("failed move: parent of b is %d, want %d", parent, d.ID)
+	}
+
+	e, err := repo.CreateContext(context.Background(), PathNode{Name: "e", ParentID: c.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantNames(t, "ancestors of e", pathNames(repo.GetAncestors(e)), "b", "c", "d")
+
+	if err := repo.Delete(b); err != nil {
+		t.Fatal(err)
+	}
+	wantNames(t, "nodes after deleting b", pathNames(repo.GetAll()), "a", "d")
+}
+
+func TestClosureNodeWrites(t *testing.T) {
+	db := openDB(t)
+	repo := &closureNodeBaseRepo{db}
+	if err := repo.AutoMigrate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := repo.AutoMigrateTree(); err != nil {
+		t.Fatal(err)
+	}
+	create := func(name string, parent *ClosureNode) *ClosureNode {
+		t.Helper()
+		node := ClosureNode{Name: name}
+		if parent != nil {
+			id := parent.ID
+			node.ParentID = &id
+		}
+		created, err := repo.Create(node)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return created
+	}
+	closureRows := func(nodes ...*ClosureNode) int {
+		t.Helper()
+		ids := []uint{}
+		for _, node := range nodes {
+			ids = append(ids, node.ID)
+		}
+		var n int
+		err := db.Table("closure_nodes_tree_paths").Where("ancestor_id IN (?) OR descendant_id IN (?)", ids, ids).Count(&n).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+	a := create("a", nil)
+	b := create("b", a)
+	c := create("c", b)
+	d := create("d", nil)
+
+	if err := repo.Update(b, gormrepo.Fields{"ParentID": d.ID}); err != nil {
+		t.Fatal(err)
+	}
+	wantNames(t, "descendants of d", closureNames(repo.GetDescendants(d)), "b", "c")
+	wantNames(t, "descendants of a", closureNames(repo.GetDescendants(a)))
+	wantNames(t, "ancestors of c", closureNames(repo.GetAncestors(c)), "b", "d")
+
+	err := repo.Update(b, gormrepo.Fields{"parent_id": c.ID})
+	if !errors.Is(err, gormrepo.ErrTreeCycle) {
+		t.Errorf("moving b under c: got %v, want %v", err, gormrepo.ErrTreeCycle)
+	}
+	wantNames(t, "ancestors of c after the failed move", closureNames(repo.GetAncestors(c)), "b", "d")
+
+	// The soft deleted nodes keep their closure rows.
+	if err := repo.DeleteSubtree(b); err != nil {
+		t.Fatal(err)
+	}
+	wantNames(t, "nodes after deleting b", closureNames(repo.GetAll()), "a", "d")
+	wantNames(t, "descendants of d after deleting b", closureNames(repo.GetDescendants(d)))
+	if n := closureRows(b, c); n == 0 {
+		t.Error("soft deleting b deleted the closure rows of b and c")
+	}
+
+	if err := repo.Delete(d, gormrepo.Unscoped()); err != nil {
+		t.Fatal(err)
+	}
+	wantNames(t, "nodes after deleting d", closureNames(repo.GetBy(gormrepo.Unscoped())), "a")
+	if n := closureRows(b, c, d); n != 0 {
+		t.Errorf("deleting d left %d closure rows of b, c and d", n)
+	}
+	if n := closureRows(a); n != 1 {
+		t.Errorf("a has %d closure rows, want 1", n)
+	}
+}
@@ -0,0 +1,525 @@
+package gen
+
+import (
+	"strings"
+)
+
+const (
+	treeAdjacency = "adjacency"
+	treePath      = "path"
+	treeClosure   = "closure"
+)
+
+// treeStrategy returns the tree strategy of the named type from the -tree-strategy
+// flag, which holds a single strategy or a comma-separated list of Type=strategy.
+func (g *Generator) treeStrategy(typeName string) string {
+	strategy := treeAdjacency
+	for _, item := range strings.Split(g.opts.TreeStrategies, ",") {
+		item = strings.TrimSpace(item)
+		if i := strings.Index(item, "="); i >= 0 {
+			if item[:i] == typeName {
+				strategy = item[i+1:]
+			}
+		} else if item != "" {
+			strategy = item
+		}
+	}
+	switch strategy {
+	case treeAdjacency, treePath, treeClosure:
+	default:
+		fatalf("unknown tree strategy %q for type %s", strategy, typeName)
+	}
+	return strategy
+}
+
+// generateTree emits the tree methods of the strategy for types with a ParentID field.
+func (g *Generator) generateTree(strategy, repoName, typeName string) {
+	st := g.getStructType(typeName)
+	if !g.hasField(st, "ParentID") {
+		fatalf("type %s has no ParentID field, -tree requires a self-referential type", typeName)
+	}
+	if strategy == treePath && !g.hasField(st, "Path") {
+		fatalf("type %s has no Path field, required by the path tree strategy", typeName)
+	}
+	idType := g.fieldType(st, "ID")
+	if idType == "" {
+		idType = "uint"
+	}
+
+	repoNameRecv := "*" + repoName
+	typeNameWithPointer := "*" + typeName
+	closureName := g.lcFirst(typeName) + "TreePath"
+
+	if strategy == treePath {
+		g.Import("strings")
+	} else {
+		g.Import("fmt")
+	}
+
+	g.Printf(repoTreeColumns, repoNameRecv, typeName)
+	g.Printf(repoGetChildren, repoNameRecv, typeNameWithPointer)
+	if strategy != treeAdjacency {
+		if strings.HasPrefix(g.fieldType(st, "ParentID"), "*") {
+			g.Printf(repoTreeParentPointer, repoNameRecv, typeNameWithPointer)
+		} else {
+			g.Printf(repoTreeParent, repoNameRecv, typeNameWithPointer)
+		}
+		// The writes of the repository keep the paths and closure rows
+		// through insertTreePath, moveTree and deleteTree.
+		g.Printf(repoCreateTree, repoNameRecv, typeName, typeNameWithPointer, repoName, g.enumValidation(typeName))
+		g.Printf(repoUpdateTree, repoNameRecv, typeName, typeNameWithPointer, repoName, g.enumFieldsValidation(typeName))
+		g.Printf(repoDeleteTree, repoNameRecv, typeName, typeNameWithPointer, repoName)
+		g.Printf(repoMoveSubtreeTree, repoNameRecv, typeName, typeNameWithPointer, repoName)
+	}
+
+	switch strategy {
+	case treeAdjacency:
+		g.Printf(repoGetDescendants, repoNameRecv, typeNameWithPointer)
+		g.Printf(repoGetAncestors, repoNameRecv, typeNameWithPointer)
+		g.Printf(repoMoveSubtree, repoNameRecv, typeNameWithPointer)
+		g.Printf(repoDeleteSubtree, repoNameRecv, typeName, typeNameWithPointer, repoName)
+	case treePath:
+		g.Printf(repoTreePathColumn, repoNameRecv, typeName)
+		g.Printf(repoInsertTreePath, repoNameRecv, typeName, typeNameWithPointer)
+		g.Printf(repoGetDescendantsPath, repoNameRecv, typeNameWithPointer)
+		g.Printf(repoGetAncestorsPath, repoNameRecv, typeNameWithPointer)
+		g.Printf(repoMoveTreePath, repoNameRecv, typeName, typeNameWithPointer)
+		g.Printf(repoDeleteTreePath, repoNameRecv, typeName, typeNameWithPointer)
+	case treeClosure:
+		g.Printf(closureType, closureName, idType)
+		g.Printf(repoTreeClosure, repoNameRecv, typeName, closureName)
+		g.Printf(repoInsertTreeClosure, repoNameRecv, typeNameWithPointer, closureName)
+		g.Printf(repoGetDescendantsClosure, repoNameRecv, typeNameWithPointer)
+		g.Printf(repoGetAncestorsClosure, repoNameRecv, typeNameWithPointer)
+		g.Printf(repoMoveTreeClosure, repoNameRecv, typeName, typeNameWithPointer)
+		g.Printf(repoDeleteTreeClosure, repoNameRecv, typeName, typeNameWithPointer)
+	}
+}
+
+const repoTreeColumns = `
+func (r %[1]s) treeColumns() (table, id, parent string) {
+	scope := r.DB.NewScope(&%[2]s{})
+	field, _ := scope.FieldByName("ParentID")
+	return scope.QuotedTableName(), scope.Quote(scope.PrimaryKey()), scope.Quote(field.DBName)
+}
+`
+
+const repoTreeParent = `
+func (r %[1]s) treeParent(entity %[2]s) (interface{}, bool) {
+	if entity.ParentID == 0 {
+		return nil, false
+	}
+	return entity.ParentID, true
+}
+`
+
+const repoTreeParentPointer = `
+func (r %[1]s) treeParent(entity %[2]s) (interface{}, bool) {
+	if entity.ParentID == nil {
+		return nil, false
+	}
+	return *entity.ParentID, true
+}
+`
+
+const repoCreateTree = `
+func (r %[1]s) Create(entity %[2]s) (%[3]s, error) {
+	if !r.DB.NewRecord(entity) {
+		return nil, r.wrapError("Create", gormrepo.ErrPrimaryNotBlank)
+	}%[5]s
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		if err := tx.Create(&entity).Error; err != nil {
+			return err
+		}
+		return (&%[4]s{tx}).insertTreePath(&entity)
+	})
+	if err != nil {
+		return nil, r.wrapError("Create", err)
+	}
+	return &entity, nil
+}
+`
+
+const repoUpdateTree = `
+// Update updates the fields of the node, moving its subtree when they update
+// its parent, as MoveSubtree does.
+func (r %[1]s) Update(entity %[3]s, fields gormrepo.Fields, criteria ...gormrepo.CriteriaOption) error {%[5]s
+	if !r.treeMoves(fields) {
+		err := r.applyCriteria(criteria).Model(entity).Updates(fields).Error
+		return r.wrapError("Update", err, criteria...)
+	}
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		repo := &%[4]s{tx}
+		result := repo.applyCriteria(criteria).Model(entity).Updates(fields)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return repo.moveTree(entity)
+	})
+	return r.wrapError("Update", err, criteria...)
+}
+
+// treeMoves reports whether the fields update the parent of the node.
+func (r %[1]s) treeMoves(fields gormrepo.Fields) bool {
+	field, _ := r.DB.NewScope(&%[2]s{}).FieldByName("ParentID")
+	for name := range fields {
+		if name == field.Name || name == field.DBName {
+			return true
+		}
+	}
+	return false
+}
+`
+
+const repoDeleteTree = `
+// Delete deletes the node matching the criteria with its descendants, as
+// DeleteSubtree does.
+func (r %[1]s) Delete(entity %[3]s, criteria ...gormrepo.CriteriaOption) error {
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		return (&%[4]s{tx}).deleteTree(entity, criteria)
+	})
+	return r.wrapError("Delete", err, criteria...)
+}
+
+func (r %[1]s) DeleteSubtree(node %[3]s) error {
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		return (&%[4]s{tx}).deleteTree(node, nil)
+	})
+	return r.wrapError("DeleteSubtree", err)
+}
+`
+
+const repoMoveSubtreeTree = `
+func (r %[1]s) MoveSubtree(node %[3]s, parent %[3]s) error {
+	var parentID interface{}
+	if parent != nil {
+		parentID = parent.ID
+	}
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		if err := tx.Model(node).Update("ParentID", parentID).Error; err != nil {
+			return err
+		}
+		return (&%[4]s{tx}).moveTree(node)
+	})
+	return r.wrapError("MoveSubtree", err)
+}
+`
+
+const repoGetChildren = `
+func (r %[1]s) GetChildren(parent %[2]s, criteria ...gormrepo.CriteriaOption) ([]%[2]s, error) {
+	_, _, parentColumn := r.treeColumns()
+	var entities []%[2]s
+	err := r.applyCriteria(criteria).Where(parentColumn+" = ?", parent.ID).Find(&entities).Error
+	return entities, r.wrapError("GetChildren", err, criteria...)
+}
+`
+
+const repoDeleteSubtree = `
+func (r %[1]s) DeleteSubtree(node %[3]s) error {
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		repo := &%[4]s{tx}
+		descendants, err := repo.GetDescendants(node)
+		if err != nil {
+			return err
+		}
+		ids := []interface{}{node.ID}
+		for _, descendant := range descendants {
+			ids = append(ids, descendant.ID)
+		}
+		_, id, _ := repo.treeColumns()
+		return tx.Where(id+" IN (?)", ids).Delete(&%[2]s{}).Error
+	})
+	return r.wrapError("DeleteSubtree", err)
+}
+`
+
+const repoGetDescendants = `
+func (r %[1]s) GetDescendants(node %[2]s, criteria ...gormrepo.CriteriaOption) ([]%[2]s, error) {
+	table, id, parent := r.treeColumns()
+	query := fmt.Sprintf(
+		"%%[2]s IN (WITH RECURSIVE tree AS (SELECT %%[2]s FROM %%[1]s WHERE %%[3]s = ? "+
+			"UNION ALL SELECT t.%%[2]s FROM %%[1]s t JOIN tree ON t.%%[3]s = tree.%%[2]s) SELECT %%[2]s FROM tree)",
+		table, id, parent,
+	)
+	var entities []%[2]s
+	err := r.applyCriteria(criteria).Where(query, node.ID).Find(&entities).Error
+	return entities, r.wrapError("GetDescendants", err, criteria...)
+}
+`
+
+const repoGetAncestors = `
+func (r %[1]s) GetAncestors(node %[2]s, criteria ...gormrepo.CriteriaOption) ([]%[2]s, error) {
+	table, id, parent := r.treeColumns()
+	query := fmt.Sprintf(
+		"%%[2]s IN (WITH RECURSIVE tree AS (SELECT %%[2]s, %%[3]s FROM %%[1]s WHERE %%[2]s = ? "+
+			"UNION ALL SELECT t.%%[2]s, t.%%[3]s FROM %%[1]s t JOIN tree ON t.%%[2]s = tree.%%[3]s) SELECT %%[2]s FROM tree WHERE %%[2]s <> ?)",
+		table, id, parent,
+	)
+	var entities []%[2]s
+	err := r.applyCriteria(criteria).Where(query, node.ID, node.ID).Find(&entities).Error
+	return entities, r.wrapError("GetAncestors", err, criteria...)
+}
+`
+
+const repoMoveSubtree = `
+func (r %[1]s) MoveSubtree(node %[2]s, parent %[2]s) error {
+	if parent == nil {
+		return r.wrapError("MoveSubtree", r.DB.Model(node).Update("ParentID", nil).Error)
+	}
+	if parent.ID == node.ID {
+		return r.wrapError("MoveSubtree", gormrepo.ErrTreeCycle)
+	}
+	_, id, _ := r.treeColumns()
+	descendants, err := r.GetDescendants(node, gormrepo.And(id+" = ?", parent.ID))
+	if err != nil {
+		return r.wrapError("MoveSubtree", err)
+	}
+	if len(descendants) > 0 {
+		return r.wrapError("MoveSubtree", gormrepo.ErrTreeCycle)
+	}
+	return r.wrapError("MoveSubtree", r.DB.Model(node).Update("ParentID", parent.ID).Error)
+}
+`
+
+const repoTreePathColumn = `
+func (r %[1]s) treePathColumn() string {
+	scope := r.DB.NewScope(&%[2]s{})
+	field, _ := scope.FieldByName("Path")
+	return scope.Quote(field.DBName)
+}
+`
+
+const repoInsertTreePath = `
+// treeParentPath returns the path of the parent of the entity, "/" for a root.
+func (r %[1]s) treeParentPath(entity %[3]s) (string, error) {
+	parentID, ok := r.treeParent(entity)
+	if !ok {
+		return "/", nil
+	}
+	var parent %[2]s
+	_, id, _ := r.treeColumns()
+	if err := r.DB.Unscoped().Where(id+" = ?", parentID).First(&parent).Error; err != nil {
+		return "", err
+	}
+	return parent.Path, nil
+}
+
+func (r %[1]s) insertTreePath(entity %[3]s) error {
+	parentPath, err := r.treeParentPath(entity)
+	if err != nil {
+		return err
+	}
+	return r.DB.Model(entity).UpdateColumn("Path", gormrepo.TreePath(parentPath, entity.ID)).Error
+}
+`
+
+const repoGetDescendantsPath = `
+func (r %[1]s) GetDescendants(node %[2]s, criteria ...gormrepo.CriteriaOption) ([]%[2]s, error) {
+	if node.Path == "" {
+		return nil, r.wrapError("GetDescendants", gormrepo.ErrTreePathBlank, criteria...)
+	}
+	_, id, _ := r.treeColumns()
+	var entities []%[2]s
+	err := r.applyCriteria(criteria).Where(r.treePathColumn()+" LIKE ? AND "+id+" <> ?", node.Path+"%%", node.ID).Find(&entities).Error
+	return entities, r.wrapError("GetDescendants", err, criteria...)
+}
+`
+
+const repoGetAncestorsPath = `
+func (r %[1]s) GetAncestors(node %[2]s, criteria ...gormrepo.CriteriaOption) ([]%[2]s, error) {
+	var entities []%[2]s
+	ids := gormrepo.TreePathIDs(node.Path)
+	if len(ids) < 2 {
+		return entities, nil
+	}
+	_, id, _ := r.treeColumns()
+	err := r.applyCriteria(criteria).Where(id+" IN (?)", ids[:len(ids)-1]).Find(&entities).Error
+	return entities, r.wrapError("GetAncestors", err, criteria...)
+}
+`
+
+const repoMoveTreePath = `
+// moveTree moves the paths of the node and of its descendants under the
+// stored parent of the node.
+func (r %[1]s) moveTree(node %[3]s) error {
+	_, id, _ := r.treeColumns()
+	var stored %[2]s
+	if err := r.DB.Unscoped().Where(id+" = ?", node.ID).First(&stored).Error; err != nil {
+		return err
+	}
+	if stored.Path == "" {
+		return gormrepo.ErrTreePathBlank
+	}
+	parentPath, err := r.treeParentPath(&stored)
+	if err != nil {
+		return err
+	}
+	if strings.HasPrefix(parentPath, stored.Path) {
+		return gormrepo.ErrTreeCycle
+	}
+	oldPath, newPath := stored.Path, gormrepo.TreePath(parentPath, node.ID)
+	pathColumn := r.treePathColumn()
+	err = r.DB.Unscoped().Model(&%[2]s{}).Where(pathColumn+" LIKE ?", oldPath+"%%").
+		UpdateColumn("Path", gormrepo.TreeMovePath(r.DB, pathColumn, oldPath, newPath)).Error
+	if err != nil {
+		return err
+	}
+	node.Path = newPath
+	return nil
+}
+`
+
+const repoDeleteTreePath = `
+// deleteTree deletes the node matching the criteria with its descendants,
+// soft deleted when the node is.
+func (r %[1]s) deleteTree(node %[3]s, criteria []gormrepo.CriteriaOption) error {
+	_, id, _ := r.treeColumns()
+	var stored %[2]s
+	if err := r.DB.Unscoped().Where(id+" = ?", node.ID).First(&stored).Error; err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return nil
+		}
+		return err
+	}
+	if stored.Path == "" {
+		return gormrepo.ErrTreePathBlank
+	}
+	query := r.applyCriteria(criteria)
+	result := query.Delete(node)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	descendants := r.DB
+	if !gormrepo.SoftDeletes(query, node) {
+		descendants = descendants.Unscoped()
+	}
+	return descendants.Where(r.treePathColumn()+" LIKE ?", stored.Path+"%%").Delete(&%[2]s{}).Error
+}
+`
+
+const closureType = `
+type %[1]s struct {
+	AncestorID   %[2]s ` + "`gorm:\"primary_key;auto_increment:false\"`" + `
+	DescendantID %[2]s ` + "`gorm:\"primary_key;auto_increment:false\"`" + `
+	Depth        int
+}
+`
+
+const repoTreeClosure = `
+func (r %[1]s) treeClosure() string {
+	return r.DB.NewScope(&%[2]s{}).TableName() + "_tree_paths"
+}
+
+func (r %[1]s) AutoMigrateTree() error {
+	return r.wrapError("AutoMigrateTree", r.DB.Table(r.treeClosure()).AutoMigrate(&%[3]s{}).Error)
+}
+
+func (r %[1]s) attachTreePath(id, parentID interface{}) error {
+	return r.DB.Exec(fmt.Sprintf(
+		"INSERT INTO %%[1]s (ancestor_id, descendant_id, depth) "+
+			"SELECT sup.ancestor_id, sub.descendant_id, sup.depth + sub.depth + 1 "+
+			"FROM %%[1]s sup CROSS JOIN %%[1]s sub WHERE sup.descendant_id = ? AND sub.ancestor_id = ?",
+		r.DB.Dialect().Quote(r.treeClosure()),
+	), parentID, id).Error
+}
+
+func (r %[1]s) detachTreePath(id interface{}) error {
+	return r.DB.Exec(fmt.Sprintf(
+		"DELETE FROM %%[1]s WHERE descendant_id IN (SELECT descendant_id FROM (SELECT descendant_id FROM %%[1]s WHERE ancestor_id = ?) subtree) "+
+			"AND ancestor_id NOT IN (SELECT descendant_id FROM (SELECT descendant_id FROM %%[1]s WHERE ancestor_id = ?) subtree)",
+		r.DB.Dialect().Quote(r.treeClosure()),
+	), id, id).Error
+}
+`
+
+const repoInsertTreeClosure = `
+func (r %[1]s) insertTreePath(entity %[2]s) error {
+	err := r.DB.Table(r.treeClosure()).Create(&%[3]s{AncestorID: entity.ID, DescendantID: entity.ID}).Error
+	if err != nil {
+		return err
+	}
+	if parentID, ok := r.treeParent(entity); ok {
+		return r.attachTreePath(entity.ID, parentID)
+	}
+	return nil
+}
+`
+
+const repoGetDescendantsClosure = `
+func (r %[1]s) GetDescendants(node %[2]s, criteria ...gormrepo.CriteriaOption) ([]%[2]s, error) {
+	_, id, _ := r.treeColumns()
+	query := fmt.Sprintf("%%s IN (SELECT descendant_id FROM %%s WHERE ancestor_id = ? AND depth > 0)", id, r.DB.Dialect().Quote(r.treeClosure()))
+	var entities []%[2]s
+	err := r.applyCriteria(criteria).Where(query, node.ID).Find(&entities).Error
+	return entities, r.wrapError("GetDescendants", err, criteria...)
+}
+`
+
+const repoGetAncestorsClosure = `
+func (r %[1]s) GetAncestors(node %[2]s, criteria ...gormrepo.CriteriaOption) ([]%[2]s, error) {
+	_, id, _ := r.treeColumns()
+	query := fmt.Sprintf("%%s IN (SELECT ancestor_id FROM %%s WHERE descendant_id = ? AND depth > 0)", id, r.DB.Dialect().Quote(r.treeClosure()))
+	var entities []%[2]s
+	err := r.applyCriteria(criteria).Where(query, node.ID).Find(&entities).Error
+	return entities, r.wrapError("GetAncestors", err, criteria...)
+}
+`
+
+const repoMoveTreeClosure = `
+// moveTree moves the closure rows of the node and of its descendants under
+// the stored parent of the node.
+func (r %[1]s) moveTree(node %[3]s) error {
+	_, id, _ := r.treeColumns()
+	var stored %[2]s
+	if err := r.DB.Unscoped().Where(id+" = ?", node.ID).First(&stored).Error; err != nil {
+		return err
+	}
+	parentID, ok := r.treeParent(&stored)
+	if !ok {
+		return r.detachTreePath(node.ID)
+	}
+	var count int
+	err := r.DB.Table(r.treeClosure()).Where("ancestor_id = ? AND descendant_id = ?", node.ID, parentID).Count(&count).Error
+	if err != nil {
+		return err
+	}
+	if count > 0 {
+		return gormrepo.ErrTreeCycle
+	}
+	if err := r.detachTreePath(node.ID); err != nil {
+		return err
+	}
+	return r.attachTreePath(node.ID, parentID)
+}
+`
+
+const repoDeleteTreeClosure = `
+// deleteTree deletes the node matching the criteria with its descendants,
+// and their closure rows unless they are soft deleted.
+func (r %[1]s) deleteTree(node %[3]s, criteria []gormrepo.CriteriaOption) error {
+	query := r.applyCriteria(criteria)
+	result := query.Delete(node)
+	if result.Error != nil || result.RowsAffected == 0 {
+		return result.Error
+	}
+	closure := r.DB.Dialect().Quote(r.treeClosure())
+	_, id, _ := r.treeColumns()
+	soft := gormrepo.SoftDeletes(query, node)
+	descendants := r.DB
+	if !soft {
+		descendants = descendants.Unscoped()
+	}
+	subtree := fmt.Sprintf("%%s IN (SELECT descendant_id FROM %%s WHERE ancestor_id = ?)", id, closure)
+	if err := descendants.Where(subtree, node.ID).Delete(&%[2]s{}).Error; err != nil {
+		return err
+	}
+	if soft {
+		// The soft deleted rows keep their closure rows.
+		return nil
+	}
+	return r.DB.Exec(fmt.Sprintf(
+		"DELETE FROM %%[1]s WHERE descendant_id IN (SELECT descendant_id FROM (SELECT descendant_id FROM %%[1]s WHERE ancestor_id = ?) subtree)",
+		closure,
+	), node.ID).Error
+}
+`
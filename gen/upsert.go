@@ -0,0 +1,91 @@
+package gen
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+	"strings"
+)
+
+// uniqueIndex is a unique index declared by unique_index tags, unnamed
+// indexes being named by gorm from the table at run time.
+type uniqueIndex struct {
+	name    string
+	columns []string
+}
+
+// uniqueIndexes returns the unique indexes of the struct and of its embedded
+// structs of the package, named ones in name order after the unnamed ones.
+func (g *Generator) uniqueIndexes(st *ast.StructType) []uniqueIndex {
+	var unnamed []uniqueIndex
+	named := map[string][]string{}
+	var walk func(st *ast.StructType)
+	walk = func(st *ast.StructType) {
+		if st == nil {
+			return
+		}
+		for _, field := range st.Fields.List {
+			if len(field.Names) == 0 {
+				if ident, ok := field.Type.(*ast.Ident); ok {
+					walk(g.getStructType(ident.Name))
+				}
+				continue
+			}
+			settings := gormTag(field)
+			value, ok := settings["UNIQUE_INDEX"]
+			if !ok {
+				continue
+			}
+			for _, ident := range field.Names {
+				column := columnName(ident.Name, settings)
+				for _, name := range strings.Split(value, ",") {
+					if name = strings.TrimSpace(name); name == "" {
+						unnamed = append(unnamed, uniqueIndex{columns: []string{column}})
+					} else {
+						named[name] = append(named[name], column)
+					}
+				}
+			}
+		}
+	}
+	walk(st)
+
+	names := make([]string, 0, len(named))
+	for name := range named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		unnamed = append(unnamed, uniqueIndex{name, named[name]})
+	}
+	return unnamed
+}
+
+// generateUpsert emits the unique indexes of the type and its Upsert.
+func (g *Generator) generateUpsert(repoNameRecv, typeName string) {
+	var indexes strings.Builder
+	for _, index := range g.uniqueIndexes(g.getStructType(typeName)) {
+		fmt.Fprintf(&indexes, "\t{Name: %q, Columns: []string{%s}},\n", index.name, quoteAll(index.columns))
+	}
+	g.Printf(repoUpsert, repoNameRecv, typeName, g.lcFirst(typeName)+"UniqueIndexes", indexes.String())
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, value := range values {
+		quoted[i] = fmt.Sprintf("%q", value)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+const repoUpsert = `
+// %[3]s are the unique indexes declared by the tags of %[2]s.
+var %[3]s = []gormrepo.UniqueIndex{
+%[4]s}
+
+// Upsert inserts the entity or updates the row it conflicts with on target,
+// the primary key or one of the unique indexes of %[2]s.
+func (r %[1]s) Upsert(entity *%[2]s, target gormrepo.ConflictTarget, update ...string) error {
+	return r.wrapError("Upsert", gormrepo.Upsert(r.DB, entity, target, %[3]s, update...))
+}
+`
@@ -0,0 +1,124 @@
+package gen
+
+import (
+	"strings"
+)
+
+const (
+	variantGormV1 = "gormv1"
+	variantGormV2 = "gormv2"
+)
+
+// outputVariants returns the variants other than gorm v1 to generate, each
+// in its own file built with the tag of its name, while the gorm v1 file is
+// built without any of them.
+func (g *Generator) outputVariants() []string {
+	var extra []string
+	for _, v := range strings.Split(g.opts.Variants, ",") {
+		switch v = strings.TrimSpace(v); v {
+		case "", variantGormV1:
+		case variantGormV2:
+			extra = append(extra, v)
+		default:
+			fatalf("unknown variant %s", v)
+		}
+	}
+	return extra
+}
+
+// gormV1Constraint returns the build constraint of the gorm v1 file.
+func gormV1Constraint(extra []string) string {
+	var terms []string
+	for _, v := range extra {
+		terms = append(terms, "!"+v)
+	}
+	return strings.Join(terms, " && ")
+}
+
+// generateGormV2 emits the repository of the type for gorm.io/gorm, with the
+// core methods of the gorm v1 repository and criteria of package gormv2.
+func (g *Generator) generateGormV2(repoName, typeName string) {
+	g.Import("gorm.io/gorm", "github.com/l-vitaly/gormrepo", "github.com/l-vitaly/gormrepo/gormv2")
+	g.Printf(repoGormV2, repoName, typeName)
+}
+
+const repoGormV2 = `
+type %[1]s struct {
+	*gorm.DB
+}
+
+func (r *%[1]s) applyCriteria(criteria []gormv2.CriteriaOption) *gorm.DB {
+	search := r.DB
+	for _, co := range criteria {
+		search = co(search)
+	}
+	return search
+}
+
+// wrapError wraps err with the entity and the method. The gorm v2 criteria
+// have no description.
+func (r *%[1]s) wrapError(method string, err error) error {
+	return gormrepo.WrapError(err, %[2]q, method)
+}
+
+func (r *%[1]s) Get(id uint) (*%[2]s, error) {
+	var entity %[2]s
+	err := r.DB.Where(map[string]interface{}{"id": id}).Take(&entity).Error
+	return &entity, r.wrapError("Get", err)
+}
+
+func (r *%[1]s) GetAll() ([]*%[2]s, error) {
+	return r.GetBy()
+}
+
+func (r *%[1]s) GetBy(criteria ...gormv2.CriteriaOption) ([]*%[2]s, error) {
+	var entities []*%[2]s
+	err := r.applyCriteria(criteria).Find(&entities).Error
+	return entities, r.wrapError("GetBy", err)
+}
+
+func (r *%[1]s) GetByFirst(criteria ...gormv2.CriteriaOption) (*%[2]s, error) {
+	var entity %[2]s
+	err := r.applyCriteria(criteria).First(&entity).Error
+	return &entity, r.wrapError("GetByFirst", err)
+}
+
+func (r *%[1]s) GetByLast(criteria ...gormv2.CriteriaOption) (*%[2]s, error) {
+	var entity %[2]s
+	err := r.applyCriteria(criteria).Last(&entity).Error
+	return &entity, r.wrapError("GetByLast", err)
+}
+
+func (r *%[1]s) Count(criteria ...gormv2.CriteriaOption) (int, error) {
+	var count int64
+	err := r.applyCriteria(criteria).Model(&%[2]s{}).Count(&count).Error
+	return int(count), r.wrapError("Count", err)
+}
+
+func (r *%[1]s) Create(entity %[2]s) (*%[2]s, error) {
+	blank, err := gormv2.NewRecord(r.DB, &entity)
+	if err != nil {
+		return nil, r.wrapError("Create", err)
+	}
+	if !blank {
+		return nil, r.wrapError("Create", gormrepo.ErrPrimaryNotBlank)
+	}
+	if err := r.DB.Create(&entity).Error; err != nil {
+		return nil, r.wrapError("Create", err)
+	}
+	return &entity, nil
+}
+
+func (r *%[1]s) Update(entity *%[2]s, fields gormrepo.Fields, criteria ...gormv2.CriteriaOption) error {
+	err := r.applyCriteria(criteria).Model(entity).Updates(map[string]interface{}(fields)).Error
+	return r.wrapError("Update", err)
+}
+
+func (r *%[1]s) Delete(entity *%[2]s, criteria ...gormv2.CriteriaOption) error {
+	return r.wrapError("Delete", r.applyCriteria(criteria).Delete(entity).Error)
+}
+
+func (r *%[1]s) AutoMigrate() error {
+	return r.wrapError("AutoMigrate", r.DB.AutoMigrate(&%[2]s{}))
+}
+`
@@ -0,0 +1,45 @@
+package gen
+
+import (
+	"strings"
+)
+
+const (
+	viewPlain        = "plain"
+	viewMaterialized = "materialized"
+)
+
+// viewKind returns how the type is backed by a view, "" for a table.
+func (g *Generator) viewKind(typeName string) string {
+	for _, item := range strings.Split(g.opts.Views, ",") {
+		item = strings.TrimSpace(item)
+		name, kind := item, viewPlain
+		if i := strings.Index(item, "="); i >= 0 {
+			name, kind = item[:i], item[i+1:]
+		}
+		if name != typeName {
+			continue
+		}
+		switch kind {
+		case viewPlain, viewMaterialized:
+			return kind
+		}
+		fatalf("unknown view kind %q for type %s", kind, typeName)
+	}
+	return ""
+}
+
+const repoRefresh = `
+// Refresh recomputes the materialized view. Concurrently keeps it readable
+// during the refresh and requires a unique index on the view.
+func (r %[1]s) Refresh(concurrently bool) error {
+	if r.DB.Dialect().GetName() != "postgres" {
+		return r.wrapError("Refresh", gormrepo.ErrUnsupported)
+	}
+	query := "REFRESH MATERIALIZED VIEW "
+	if concurrently {
+		query += "CONCURRENTLY "
+	}
+	return r.wrapError("Refresh", r.DB.Exec(query+r.DB.NewScope(&%[2]s{}).QuotedTableName()).Error)
+}
+`
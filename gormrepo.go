@@ -2,12 +2,37 @@ package gormrepo
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+	"time"
 
 	"github.com/jinzhu/gorm"
 )
 
 var (
-	ErrPrimaryNotBlank = errors.New("primary key not blank")
+	ErrPrimaryNotBlank       = errors.New("primary key not blank")
+	ErrTreeCycle             = errors.New("tree node cannot be moved under itself or its descendant")
+	ErrTreePathBlank         = errors.New("tree path blank")
+	ErrInvalidState          = errors.New("invalid state transition")
+	ErrStateConflict         = errors.New("state does not match transition source")
+	ErrNoTransaction         = errors.New("transaction required")
+	ErrUnsupported           = errors.New("not supported by dialect")
+	ErrLockNotAcquired       = errors.New("advisory lock not acquired")
+	ErrInvalidCollation      = errors.New("invalid collation name")
+	ErrInvalidPeriod         = errors.New("invalid period")
+	ErrInvalidBuckets        = errors.New("invalid histogram buckets")
+	ErrInvalidPercentile     = errors.New("percentile out of [0, 1]")
+	ErrInvalidEnum           = errors.New("invalid enum value")
+	ErrUnknownConflictTarget = errors.New("conflict target is not a unique index of the model")
+	ErrSchemaDrift           = errors.New("database schema does not match the models")
+	ErrOpaqueCriteria        = errors.New("criteria option has no description")
+	ErrInvalidCriterion      = errors.New("invalid criterion")
+	ErrInvalidConfig         = errors.New("invalid database config")
+	ErrCrossDatabaseTx       = errors.New("transaction cannot span databases")
+	ErrUnknownQuery          = errors.New("unknown named query")
+	ErrRawSQLForbidden       = errors.New("raw SQL forbidden by the allow-list mode")
+	ErrUnknownColumn         = errors.New("unknown column of the model")
+	ErrUnsafeTruncate        = errors.New("truncate refused outside of tests")
 )
 
 type Fields map[string]interface{}
@@ -19,50 +44,147 @@ const (
 	Last
 )
 
+// withError returns a copy of db failing with err. Criteria must not add
+// errors to the db they receive, which may be the repository's own.
+func withError(db *gorm.DB, err error) *gorm.DB {
+	clone := db.New()
+	clone.AddError(err)
+	return clone
+}
+
 func And(query interface{}, args ...interface{}) CriteriaOption {
-	return func(db *gorm.DB) *gorm.DB {
-		return db.Where(query, args)
-	}
+	return Described(Criterion{Op: "and", Args: append([]interface{}{query}, args...)}, func(db *gorm.DB) *gorm.DB {
+		return db.Where(query, args...)
+	})
 }
 
 func Not(query interface{}, args ...interface{}) CriteriaOption {
-	return func(db *gorm.DB) *gorm.DB {
-		return db.Not(query, args)
-	}
+	return Described(Criterion{Op: "not", Args: append([]interface{}{query}, args...)}, func(db *gorm.DB) *gorm.DB {
+		return db.Not(query, args...)
+	})
 }
 
 func Or(query interface{}, args ...interface{}) CriteriaOption {
-	return func(db *gorm.DB) *gorm.DB {
-		return db.Or(query, args)
-	}
+	return Described(Criterion{Op: "or", Args: append([]interface{}{query}, args...)}, func(db *gorm.DB) *gorm.DB {
+		return db.Or(query, args...)
+	})
 }
 
 func Select(columns interface{}, args ...interface{}) CriteriaOption {
-	return func(db *gorm.DB) *gorm.DB {
+	return Described(Criterion{Op: "select", Args: append([]interface{}{columns}, args...)}, func(db *gorm.DB) *gorm.DB {
 		return db.Select(columns, args...)
+	})
+}
+
+// GroupBy groups the rows of the query by the columns, for the aggregates
+// of Select. No columns leave the query ungrouped.
+func GroupBy(columns ...string) CriteriaOption {
+	args := make([]interface{}, len(columns))
+	for i, column := range columns {
+		args[i] = column
 	}
+	return Described(Criterion{Op: "group_by", Args: args}, func(db *gorm.DB) *gorm.DB {
+		if len(columns) == 0 {
+			return db
+		}
+		return db.Group(strings.Join(columns, ", "))
+	})
+}
+
+// Having limits the groups of GroupBy to those matching the query, such as
+// "COUNT(*) > ?".
+func Having(query interface{}, args ...interface{}) CriteriaOption {
+	return Described(Criterion{Op: "having", Args: append([]interface{}{query}, args...)}, func(db *gorm.DB) *gorm.DB {
+		return db.Having(query, args...)
+	})
+}
+
+// Raw runs the statement sql, with its arguments bound, instead of the query
+// of the method, for the few queries the other criteria cannot express. The
+// ordering, limit and offset criteria are appended to it. It is refused in
+// the mode of AllowListOnly.
+func Raw(sql string, args ...interface{}) CriteriaOption {
+	return Described(Criterion{Op: "raw", Args: append([]interface{}{sql}, args...)}, func(db *gorm.DB) *gorm.DB {
+		return db.Raw(sql, args...)
+	})
+}
+
+// Joins joins the rows of another table with the clause, such as "LEFT
+// JOIN orders ON orders.user_id = users.id AND orders.status = ?", binding
+// its arguments. It is refused in the mode of AllowListOnly.
+func Joins(query string, args ...interface{}) CriteriaOption {
+	return Described(Criterion{Op: "joins", Args: append([]interface{}{query}, args...)}, func(db *gorm.DB) *gorm.DB {
+		return db.Joins(query, args...)
+	})
 }
 
+// OrderBy sorts by the column name in the orientation, "asc" or "desc" in
+// any case, or the default of the database when empty. Other orientations
+// and the names Order rejects, which would be interpolated into the SQL,
+// fail with ErrInvalidCriterion.
 func OrderBy(name string, orientation string, reorder bool) CriteriaOption {
-	return func(db *gorm.DB) *gorm.DB {
-		return db.Order(name+" "+orientation, reorder)
-	}
+	return Described(Criterion{Op: "order_by", Column: name, Args: []interface{}{orientation, reorder}}, func(db *gorm.DB) *gorm.DB {
+		if !direction(orientation) {
+			return withError(db, fmt.Errorf("%w: order by %q %q", ErrInvalidCriterion, name, orientation))
+		}
+		if err := checkOrderColumn(db, name); err != nil {
+			return withError(db, err)
+		}
+		return db.Order(strings.TrimSpace(name+" "+orientation), reorder)
+	})
 }
 
 func Limit(limit int) CriteriaOption {
-	return func(db *gorm.DB) *gorm.DB {
+	return Described(Criterion{Op: "limit", Args: []interface{}{limit}}, func(db *gorm.DB) *gorm.DB {
 		return db.Limit(limit)
-	}
+	})
 }
 
 func Offset(offset int) CriteriaOption {
-	return func(db *gorm.DB) *gorm.DB {
+	return Described(Criterion{Op: "offset", Args: []interface{}{offset}}, func(db *gorm.DB) *gorm.DB {
 		return db.Offset(offset)
-	}
+	})
 }
 
+// Preload loads the association field of the rows, or the nested
+// association of a dotted path such as "Orders.Items", loading "Orders" too.
 func Preload(field string) CriteriaOption {
-	return func(db *gorm.DB) *gorm.DB {
+	return Described(Criterion{Op: "preload", Column: field}, func(db *gorm.DB) *gorm.DB {
 		return db.Preload(field)
-	}
+	})
+}
+
+// PreloadWhere loads the association field, or dotted path, limited to the
+// associated rows matching the query, such as "state <> ?".
+func PreloadWhere(field string, query interface{}, args ...interface{}) CriteriaOption {
+	return Described(Criterion{Op: "preload_where", Column: field, Args: append([]interface{}{query}, args...)}, func(db *gorm.DB) *gorm.DB {
+		return db.Preload(field, append([]interface{}{query}, args...)...)
+	})
+}
+
+// PreloadAll loads every association of the model, not the nested ones.
+func PreloadAll() CriteriaOption {
+	return Described(Criterion{Op: "preload_all"}, func(db *gorm.DB) *gorm.DB {
+		return db.Set("gorm:auto_preload", true)
+	})
+}
+
+// Unscoped includes the soft deleted rows of the models with a DeletedAt
+// field, and makes Delete remove the rows instead of soft deleting them.
+func Unscoped() CriteriaOption {
+	return Described(Criterion{Op: "unscoped"}, func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped()
+	})
+}
+
+// Published limits the query to rows whose publish column is set and not in
+// the future, and whose optional unpublish column is unset or in the future.
+func Published(publishColumn, unpublishColumn string, now time.Time) CriteriaOption {
+	return Described(Criterion{Op: "published", Column: publishColumn, Args: []interface{}{unpublishColumn, now}}, func(db *gorm.DB) *gorm.DB {
+		db = db.Where(publishColumn+" IS NOT NULL AND "+publishColumn+" <= ?", now)
+		if unpublishColumn != "" {
+			db = db.Where(unpublishColumn+" IS NULL OR "+unpublishColumn+" > ?", now)
+		}
+		return db
+	})
 }
@@ -0,0 +1,83 @@
+// Package gormv2 holds the criteria of the repositories generated for
+// gorm.io/gorm with -variants, with the names of the gorm v1 criteria of
+// package gormrepo, so both variants of a repository are used alike.
+package gormv2
+
+import (
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CriteriaOption func(db *gorm.DB) *gorm.DB
+
+func And(query interface{}, args ...interface{}) CriteriaOption {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(query, args...)
+	}
+}
+
+func Not(query interface{}, args ...interface{}) CriteriaOption {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Not(query, args...)
+	}
+}
+
+func Or(query interface{}, args ...interface{}) CriteriaOption {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Or(query, args...)
+	}
+}
+
+func Select(columns interface{}, args ...interface{}) CriteriaOption {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Select(columns, args...)
+	}
+}
+
+// OrderBy orders by the column. With reorder the orders of the previous
+// criteria are replaced.
+func OrderBy(name string, orientation string, reorder bool) CriteriaOption {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Order(clause.OrderByColumn{
+			Column:  clause.Column{Name: name, Raw: true},
+			Desc:    strings.EqualFold(orientation, "DESC"),
+			Reorder: reorder,
+		})
+	}
+}
+
+func Limit(limit int) CriteriaOption {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Limit(limit)
+	}
+}
+
+func Offset(offset int) CriteriaOption {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Offset(offset)
+	}
+}
+
+func Preload(field string) CriteriaOption {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Preload(field)
+	}
+}
+
+// NewRecord reports whether the primary key of value is blank, as gorm v1
+// NewRecord does.
+func NewRecord(db *gorm.DB, value interface{}) (bool, error) {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(value); err != nil {
+		return false, err
+	}
+	field := stmt.Schema.PrioritizedPrimaryField
+	if field == nil {
+		return true, nil
+	}
+	_, zero := field.ValueOf(db.Statement.Context, reflect.Indirect(reflect.ValueOf(value)))
+	return zero, nil
+}
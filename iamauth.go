@@ -0,0 +1,124 @@
+package gormrepo
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AWSCredentials are the credentials signing the RDS auth tokens.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+}
+
+// EnvAWSCredentials returns the credentials of the AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment variables.
+func EnvAWSCredentials(ctx context.Context) (AWSCredentials, error) {
+	creds := AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return AWSCredentials{}, fmt.Errorf("%w: AWS credentials not set", ErrInvalidConfig)
+	}
+	return creds, nil
+}
+
+// rdsTokenTTL is the validity of an RDS auth token, and rdsTokenRefresh the
+// age at which a new one is signed.
+const (
+	rdsTokenTTL     = 15 * time.Minute
+	rdsTokenRefresh = 10 * time.Minute
+)
+
+// RDSAuthToken returns the Config.AuthToken of the IAM authentication of the
+// user to the RDS instance at endpoint (host:port) in the region, signing the
+// tokens with the credentials, such as EnvAWSCredentials or a function of the
+// AWS SDK credentials provider. A token is reused until it is 10 minutes old,
+// the tokens expiring after 15 minutes. RDS only accepts them over TLS.
+func RDSAuthToken(endpoint, region, user string, credentials func(ctx context.Context) (AWSCredentials, error)) func(ctx context.Context) (string, error) {
+	var (
+		mu       sync.Mutex
+		token    string
+		signedAt time.Time
+	)
+	return func(ctx context.Context) (string, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		now := time.Now()
+		if token != "" && now.Sub(signedAt) < rdsTokenRefresh {
+			return token, nil
+		}
+		creds, err := credentials(ctx)
+		if err != nil {
+			return "", err
+		}
+		token, signedAt = signRDSToken(endpoint, region, user, creds, now), now
+		return token, nil
+	}
+}
+
+// signRDSToken returns the auth token of the user, a URL presigned with AWS
+// signature version 4 for the connect action of the rds-db service, without
+// its scheme.
+func signRDSToken(endpoint, region, user string, creds AWSCredentials, now time.Time) string {
+	now = now.UTC()
+	date := now.Format("20060102")
+	scope := date + "/" + region + "/rds-db/aws4_request"
+	params := map[string]string{
+		"Action":              "connect",
+		"DBUser":              user,
+		"X-Amz-Algorithm":     "AWS4-HMAC-SHA256",
+		"X-Amz-Credential":    creds.AccessKeyID + "/" + scope,
+		"X-Amz-Date":          now.Format("20060102T150405Z"),
+		"X-Amz-Expires":       fmt.Sprint(int(rdsTokenTTL.Seconds())),
+		"X-Amz-SignedHeaders": "host",
+	}
+	if creds.SessionToken != "" {
+		params["X-Amz-Security-Token"] = creds.SessionToken
+	}
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	query := make([]string, len(keys))
+	for i, k := range keys {
+		query[i] = awsEscape(k) + "=" + awsEscape(params[k])
+	}
+	canonicalQuery := strings.Join(query, "&")
+
+	emptyHash := sha256.Sum256(nil)
+	request := strings.Join([]string{"GET", "/", canonicalQuery, "host:" + endpoint, "", "host", hex.EncodeToString(emptyHash[:])}, "\n")
+	requestHash := sha256.Sum256([]byte(request))
+	toSign := strings.Join([]string{"AWS4-HMAC-SHA256", params["X-Amz-Date"], scope, hex.EncodeToString(requestHash[:])}, "\n")
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date, region, "rds-db", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return endpoint + "/?" + canonicalQuery + "&X-Amz-Signature=" + hex.EncodeToString(hmacSHA256(key, toSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+// awsEscape escapes s as the canonical requests of AWS signatures do, every
+// byte but the unreserved characters.
+func awsEscape(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
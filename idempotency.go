@@ -0,0 +1,60 @@
+package gormrepo
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+type idempotencyKey struct{}
+
+// IdempotencyRecord maps an idempotency key to the entity created with it.
+// Scope is the table of the entity, so keys are unique per table.
+type IdempotencyRecord struct {
+	Key       string `gorm:"primary_key"`
+	Scope     string `gorm:"primary_key"`
+	EntityID  uint
+	CreatedAt time.Time
+}
+
+func (IdempotencyRecord) TableName() string {
+	return "gormrepo_idempotency_keys"
+}
+
+// WithIdempotencyKey returns a context carrying the idempotency key. The
+// generated CreateContext records the key in the same transaction as the
+// entity and returns the originally created entity when the key is replayed.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKey{}, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key carried by ctx.
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKey{}).(string)
+	return key, ok && key != ""
+}
+
+// AutoMigrateIdempotency creates the idempotency key table.
+func AutoMigrateIdempotency(db *gorm.DB) error {
+	return db.AutoMigrate(&IdempotencyRecord{}).Error
+}
+
+// FindIdempotencyKey returns the id of the entity created with the key in scope.
+func FindIdempotencyKey(db *gorm.DB, key, scope string) (uint, bool, error) {
+	var record IdempotencyRecord
+	err := db.Where(&IdempotencyRecord{Key: key, Scope: scope}).First(&record).Error
+	if gorm.IsRecordNotFoundError(err) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, err
+	}
+	return record.EntityID, true, nil
+}
+
+// SaveIdempotencyKey records the entity created with the key in scope. A
+// concurrent request with the same key fails on the primary key.
+func SaveIdempotencyKey(db *gorm.DB, key, scope string, entityID uint) error {
+	return db.Create(&IdempotencyRecord{Key: key, Scope: scope, EntityID: entityID}).Error
+}
@@ -0,0 +1,62 @@
+package gormrepo
+
+import (
+	"reflect"
+
+	"github.com/jinzhu/gorm"
+)
+
+// In limits the query to rows whose column is one of the values. A single
+// slice argument is expanded into the values, and no values match no rows
+// instead of producing an invalid IN ().
+func In(column string, values ...interface{}) CriteriaOption {
+	values = expandValues(values)
+	return Described(Criterion{Op: "in", Column: column, Args: values}, func(db *gorm.DB) *gorm.DB {
+		if len(values) == 0 {
+			return db.Where("1 = 0")
+		}
+		return db.Where(column+" IN (?)", values)
+	})
+}
+
+// NotIn limits the query to rows whose column is none of the values, taking
+// them as In does. No values match every row. As in SQL, rows whose column is
+// NULL are not matched either.
+func NotIn(column string, values ...interface{}) CriteriaOption {
+	values = expandValues(values)
+	return Described(Criterion{Op: "not_in", Column: column, Args: values}, func(db *gorm.DB) *gorm.DB {
+		if len(values) == 0 {
+			return db
+		}
+		return db.Where(column+" NOT IN (?)", values)
+	})
+}
+
+// expandValues returns the elements of a single slice argument, other than
+// []byte, or the values.
+func expandValues(values []interface{}) []interface{} {
+	if len(values) != 1 {
+		return values
+	}
+	if _, ok := values[0].([]byte); ok {
+		return values
+	}
+	v := reflect.ValueOf(values[0])
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return values
+	}
+	expanded := make([]interface{}, v.Len())
+	for i := range expanded {
+		expanded[i] = v.Index(i).Interface()
+	}
+	return expanded
+}
+
+func init() {
+	RegisterCriterion("in", func(c Criterion) (CriteriaOption, error) {
+		return In(c.Column, c.Args...), nil
+	})
+	RegisterCriterion("not_in", func(c Criterion) (CriteriaOption, error) {
+		return NotIn(c.Column, c.Args...), nil
+	})
+}
@@ -0,0 +1,81 @@
+package gormrepo
+
+import (
+	"database/sql"
+
+	"github.com/jinzhu/gorm"
+)
+
+// dialectTxOptions returns the options of a transaction as the dialect runs
+// them: an isolation level the database lacks becomes the nearest stronger
+// one, and the access mode is dropped where the driver rejects it.
+func dialectTxOptions(dialect string, opts sql.TxOptions) sql.TxOptions {
+	switch opts.Isolation {
+	case sql.LevelWriteCommitted:
+		opts.Isolation = sql.LevelRepeatableRead
+	case sql.LevelLinearizable:
+		opts.Isolation = sql.LevelSerializable
+	}
+	switch dialect {
+	case "postgres", "mysql":
+		// REPEATABLE READ reads a snapshot on Postgres and InnoDB.
+		if opts.Isolation == sql.LevelSnapshot {
+			opts.Isolation = sql.LevelRepeatableRead
+		}
+	case "sqlite3":
+		// SQLite transactions are serializable.
+		opts.Isolation = sql.LevelDefault
+	case "mssql":
+		opts.ReadOnly = false
+	}
+	return opts
+}
+
+// isolationNames are the SQL names of the isolation levels.
+var isolationNames = map[sql.IsolationLevel]string{
+	sql.LevelReadUncommitted: "READ UNCOMMITTED",
+	sql.LevelReadCommitted:   "READ COMMITTED",
+	sql.LevelRepeatableRead:  "REPEATABLE READ",
+	sql.LevelSnapshot:        "SNAPSHOT",
+	sql.LevelSerializable:    "SERIALIZABLE",
+}
+
+// ReadIsolation runs the rest of the enclosing transaction at the isolation
+// level, for the reads needing for example repeatable read semantics in a
+// transaction not started with WithIsolation. Outside of a transaction the
+// call fails with ErrNoTransaction. Postgres only accepts it before the first
+// query of the transaction, and MySQL, which cannot change the level of a
+// running transaction, fails with ErrUnsupported. It does nothing on SQLite,
+// whose transactions are serializable.
+func ReadIsolation(level sql.IsolationLevel) CriteriaOption {
+	return Described(Criterion{Op: "read_isolation", Args: []interface{}{int(level)}}, func(db *gorm.DB) *gorm.DB {
+		if _, ok := db.CommonDB().(*sql.Tx); !ok {
+			return withError(db, ErrNoTransaction)
+		}
+		dialect := db.Dialect().GetName()
+		switch dialect {
+		case "sqlite3":
+			return db
+		case "mysql":
+			return withError(db, ErrUnsupported)
+		}
+		name, ok := isolationNames[dialectTxOptions(dialect, sql.TxOptions{Isolation: level}).Isolation]
+		if !ok {
+			return db
+		}
+		if err := db.Exec("SET TRANSACTION ISOLATION LEVEL " + name).Error; err != nil {
+			return withError(db, err)
+		}
+		return db
+	})
+}
+
+func init() {
+	RegisterCriterion("read_isolation", func(c Criterion) (CriteriaOption, error) {
+		var level int
+		if err := c.scan(&level); err != nil {
+			return nil, err
+		}
+		return ReadIsolation(sql.IsolationLevel(level)), nil
+	})
+}
@@ -0,0 +1,118 @@
+// Package kafka publishes entity change events to Kafka, keyed by entity
+// primary key so the events of an entity stay ordered in its partition.
+package kafka
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/l-vitaly/gormrepo"
+	"github.com/linkedin/goavro/v2"
+	kafkago "github.com/segmentio/kafka-go"
+)
+
+// Encoder encodes the value of the message of an event.
+type Encoder interface {
+	Encode(topic string, event gormrepo.Event) ([]byte, error)
+}
+
+// withSchemaID prefixes data with the Confluent wire format header of the
+// schema registry, when id is set.
+func withSchemaID(id int, data []byte) []byte {
+	if id <= 0 {
+		return data
+	}
+	header := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(header[1:], uint32(id))
+	return append(header, data...)
+}
+
+// JSONEncoder encodes the payload of the event as JSON. With a SchemaID the
+// schema registry wire format is used, for JSON Schema aware consumers.
+type JSONEncoder struct {
+	SchemaID int
+}
+
+func (e JSONEncoder) Encode(topic string, event gormrepo.Event) ([]byte, error) {
+	data, err := json.Marshal(event.Payload)
+	if err != nil {
+		return nil, err
+	}
+	return withSchemaID(e.SchemaID, data), nil
+}
+
+// AvroEncoder encodes the payload of the event with the Avro codec, going
+// through its JSON form, which must match the Avro JSON encoding of the
+// schema. SchemaID is the id of the schema in the registry.
+type AvroEncoder struct {
+	Codec    *goavro.Codec
+	SchemaID int
+}
+
+func (e AvroEncoder) Encode(topic string, event gormrepo.Event) ([]byte, error) {
+	text, err := json.Marshal(event.Payload)
+	if err != nil {
+		return nil, err
+	}
+	native, _, err := e.Codec.NativeFromTextual(text)
+	if err != nil {
+		return nil, err
+	}
+	data, err := e.Codec.BinaryFromNative(nil, native)
+	if err != nil {
+		return nil, err
+	}
+	return withSchemaID(e.SchemaID, data), nil
+}
+
+// Publisher is a gormrepo.Publisher writing to Kafka.
+type Publisher struct {
+	Writer  *kafkago.Writer
+	Encoder Encoder
+	// Topic returns the topic of the event, the entity name prefixed by
+	// the prefix given to NewPublisher by default.
+	Topic func(event gormrepo.Event) string
+}
+
+// NewPublisher returns a publisher to the brokers, sending the events of
+// each entity to the topic <prefix>.<Entity> as JSON.
+func NewPublisher(brokers []string, prefix string) *Publisher {
+	return &Publisher{
+		Writer: &kafkago.Writer{
+			Addr:         kafkago.TCP(brokers...),
+			Balancer:     &kafkago.Hash{},
+			RequiredAcks: kafkago.RequireAll,
+		},
+		Encoder: JSONEncoder{},
+		Topic: func(event gormrepo.Event) string {
+			return prefix + "." + event.Entity
+		},
+	}
+}
+
+func (p *Publisher) Publish(ctx context.Context, events ...gormrepo.Event) error {
+	messages := make([]kafkago.Message, 0, len(events))
+	for _, event := range events {
+		topic := p.Topic(event)
+		value, err := p.Encoder.Encode(topic, event)
+		if err != nil {
+			return err
+		}
+		messages = append(messages, kafkago.Message{
+			Topic: topic,
+			Key:   []byte(event.Key),
+			Value: value,
+			Headers: []kafkago.Header{
+				{Key: "entity", Value: []byte(event.Entity)},
+				{Key: "op", Value: []byte(event.Op.String())},
+			},
+		})
+	}
+	return p.Writer.WriteMessages(ctx, messages...)
+}
+
+// Close flushes the pending messages and closes the writer.
+func (p *Publisher) Close() error {
+	return p.Writer.Close()
+}
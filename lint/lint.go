@@ -0,0 +1,305 @@
+// Package lint implements gormrepolint, the analyzer keeping the database
+// access of a service behind its generated repositories.
+package lint
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"regexp"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const (
+	gormPath     = "github.com/jinzhu/gorm"
+	gormrepoPath = "github.com/l-vitaly/gormrepo"
+)
+
+// Analyzer reports
+//
+//   - the queries run on a *gorm.DB outside of the generated files, the
+//     methods of the repositories and the criteria options,
+//   - the repository calls deleting or updating without criteria a blank
+//     entity, and the batch deletes and archives without criteria,
+//   - the column names given as strings to the gormrepo criteria which are
+//     not columns of the package's generated <T>Columns.
+var Analyzer = &analysis.Analyzer{
+	Name: "gormrepolint",
+	Doc:  "report database access bypassing the generated repositories",
+	Run:  run,
+}
+
+// lifecycleMethods are the methods of *gorm.DB setting up the connection or
+// a transaction, not querying.
+var lifecycleMethods = map[string]bool{
+	"AddError":                true,
+	"Begin":                   true,
+	"BeginTx":                 true,
+	"BlockGlobalUpdate":       true,
+	"Callback":                true,
+	"Close":                   true,
+	"CommonDB":                true,
+	"Commit":                  true,
+	"DB":                      true,
+	"Dialect":                 true,
+	"GetErrors":               true,
+	"LogMode":                 true,
+	"New":                     true,
+	"Rollback":                true,
+	"RollbackUnlessCommitted": true,
+	"SetLogger":               true,
+	"SetNowFuncOverride":      true,
+	"SingularTable":           true,
+}
+
+// unscopedMethods are the repository methods whose entity argument scopes
+// them by its primary key, and batchMethods those working on every row
+// without criteria.
+var (
+	unscopedMethods = map[string]bool{"Update": true, "Delete": true}
+	batchMethods    = map[string]bool{"DeleteBatch": true, "DeleteAndGet": true, "ArchiveBatch": true, "Archive": true}
+)
+
+var generatedComment = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.?$`)
+
+func run(pass *analysis.Pass) (interface{}, error) {
+	if path := pass.Pkg.Path(); path == gormrepoPath || strings.HasPrefix(path, gormrepoPath+"/") {
+		return nil, nil
+	}
+	c := &checker{pass: pass, columns: generatedColumns(pass), chained: map[*ast.CallExpr]bool{}}
+	for _, file := range pass.Files {
+		if isGenerated(file) {
+			continue
+		}
+		var funcs []ast.Node
+		ast.Inspect(file, func(n ast.Node) bool {
+			if n == nil {
+				funcs = funcs[:len(funcs)-1]
+				return true
+			}
+			switch n := n.(type) {
+			case *ast.FuncDecl, *ast.FuncLit:
+				funcs = append(funcs, n)
+				return true
+			case *ast.CallExpr:
+				c.checkCall(n, funcs)
+			}
+			funcs = append(funcs, nil)
+			return true
+		})
+	}
+	return nil, nil
+}
+
+// checker checks the calls of a package.
+type checker struct {
+	pass    *analysis.Pass
+	columns map[string]bool
+	// chained are the calls of the chains of gorm calls already reported,
+	// once for the chain.
+	chained map[*ast.CallExpr]bool
+}
+
+func (c *checker) checkCall(call *ast.CallExpr, funcs []ast.Node) {
+	pass := c.pass
+	fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+	if !ok {
+		return
+	}
+	sig := fn.Type().(*types.Signature)
+	switch {
+	case sig.Recv() != nil && isGormDB(sig.Recv().Type()):
+		if c.chained[call] || lifecycleMethods[fn.Name()] || allowsRawDB(pass, funcs) {
+			break
+		}
+		pass.Reportf(call.Pos(), "raw gorm query %s outside of a repository, use the generated repository methods", fn.Name())
+		for inner := call; ; {
+			sel, ok := ast.Unparen(inner.Fun).(*ast.SelectorExpr)
+			if !ok {
+				break
+			}
+			if inner, ok = ast.Unparen(sel.X).(*ast.CallExpr); !ok {
+				break
+			}
+			c.chained[inner] = true
+		}
+	case sig.Recv() != nil && takesCriteria(sig):
+		withoutCriteria := len(call.Args) < sig.Params().Len() && !call.Ellipsis.IsValid()
+		switch {
+		case !withoutCriteria:
+		case batchMethods[fn.Name()]:
+			pass.Reportf(call.Pos(), "%s without criteria runs on every row", fn.Name())
+		case unscopedMethods[fn.Name()] && len(call.Args) > 0 && isBlankEntity(call.Args[0]):
+			pass.Reportf(call.Pos(), "%s without criteria of an entity without primary key runs on every row", fn.Name())
+		}
+	case fn.Pkg() != nil && fn.Pkg().Path() == gormrepoPath && len(c.columns) > 0:
+		checkColumn(pass, call, sig, c.columns)
+	}
+}
+
+// checkColumn reports the column named by a string constant not found in
+// the generated columns.
+func checkColumn(pass *analysis.Pass, call *ast.CallExpr, sig *types.Signature, columns map[string]bool) {
+	if sig.Params().Len() == 0 || len(call.Args) == 0 {
+		return
+	}
+	param := sig.Params().At(0)
+	if param.Name() != "column" || !types.Identical(param.Type(), types.Typ[types.String]) {
+		return
+	}
+	tv, ok := pass.TypesInfo.Types[call.Args[0]]
+	if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+		return
+	}
+	name := constant.StringVal(tv.Value)
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	if !columns[strings.Trim(name, "`\"")] {
+		pass.Reportf(call.Args[0].Pos(), "column %q is not a column of the generated <T>Columns", constant.StringVal(tv.Value))
+	}
+}
+
+// generatedColumns returns the names of the columns declared by the
+// generated <T>Columns of the package.
+func generatedColumns(pass *analysis.Pass) map[string]bool {
+	columns := map[string]bool{}
+	for _, file := range pass.Files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			call, ok := n.(*ast.CallExpr)
+			if !ok || len(call.Args) != 1 {
+				return true
+			}
+			fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+			if !ok || fn.Name() != "NewColumn" || fn.Pkg() == nil || fn.Pkg().Path() != gormrepoPath {
+				return true
+			}
+			if tv, ok := pass.TypesInfo.Types[call.Args[0]]; ok && tv.Value != nil && tv.Value.Kind() == constant.String {
+				columns[constant.StringVal(tv.Value)] = true
+			}
+			return true
+		})
+	}
+	return columns
+}
+
+// allowsRawDB reports whether the innermost function may query a *gorm.DB:
+// a method of a repository, embedding the *gorm.DB, or a criteria option.
+func allowsRawDB(pass *analysis.Pass, funcs []ast.Node) bool {
+	for i := len(funcs) - 1; i >= 0; i-- {
+		switch fn := funcs[i].(type) {
+		case *ast.FuncLit:
+			if isCriteria(pass.TypesInfo.TypeOf(fn)) {
+				return true
+			}
+		case *ast.FuncDecl:
+			if fn.Recv != nil && len(fn.Recv.List) > 0 && embedsGormDB(pass.TypesInfo.TypeOf(fn.Recv.List[0].Type), 0) {
+				return true
+			}
+			if obj := pass.TypesInfo.Defs[fn.Name]; obj != nil && isCriteria(obj.Type()) {
+				return true
+			}
+			return false
+		}
+	}
+	return false
+}
+
+// isCriteria reports whether t is the type of a criteria option,
+// func(*gorm.DB) *gorm.DB.
+func isCriteria(t types.Type) bool {
+	sig, ok := t.(*types.Signature)
+	if !ok {
+		return false
+	}
+	return sig.Params().Len() == 1 && sig.Results().Len() == 1 &&
+		isGormDB(sig.Params().At(0).Type()) && isGormDB(sig.Results().At(0).Type())
+}
+
+// embedsGormDB reports whether t is a struct embedding a *gorm.DB, directly
+// or through another embedded struct.
+func embedsGormDB(t types.Type, depth int) bool {
+	if t == nil || depth > 5 {
+		return false
+	}
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return false
+	}
+	for i := 0; i < st.NumFields(); i++ {
+		if f := st.Field(i); f.Embedded() && (isGormDB(f.Type()) || embedsGormDB(f.Type(), depth+1)) {
+			return true
+		}
+	}
+	return false
+}
+
+func isGormDB(t types.Type) bool {
+	if ptr, ok := t.(*types.Pointer); ok {
+		t = ptr.Elem()
+	}
+	named, ok := t.(*types.Named)
+	if !ok {
+		return false
+	}
+	obj := named.Obj()
+	return obj.Pkg() != nil && obj.Pkg().Path() == gormPath && obj.Name() == "DB"
+}
+
+// takesCriteria reports whether the last parameter of the method is
+// ...gormrepo.CriteriaOption.
+func takesCriteria(sig *types.Signature) bool {
+	if !sig.Variadic() {
+		return false
+	}
+	last := sig.Params().At(sig.Params().Len() - 1).Type().(*types.Slice).Elem()
+	named, ok := last.(*types.Named)
+	return ok && named.Obj().Pkg() != nil && named.Obj().Pkg().Path() == gormrepoPath && named.Obj().Name() == "CriteriaOption"
+}
+
+// isBlankEntity reports whether the expression is an entity without primary
+// key: new(T), or &T{} not setting ID.
+func isBlankEntity(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.CallExpr:
+		ident, ok := e.Fun.(*ast.Ident)
+		return ok && ident.Name == "new"
+	case *ast.UnaryExpr:
+		lit, ok := e.X.(*ast.CompositeLit)
+		if !ok {
+			return false
+		}
+		for _, elt := range lit.Elts {
+			kv, ok := elt.(*ast.KeyValueExpr)
+			if !ok {
+				// Positional fields may set the key.
+				return false
+			}
+			if key, ok := kv.Key.(*ast.Ident); ok && (key.Name == "ID" || key.Name == "Model") {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+func isGenerated(file *ast.File) bool {
+	for _, group := range file.Comments {
+		if group.Pos() >= file.Package {
+			return false
+		}
+		for _, c := range group.List {
+			if generatedComment.MatchString(c.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+// Package loadtest runs weighted mixes of repository operations at a target
+// rate and reports their latency percentiles, to validate schema and index
+// changes before a rollout.
+package loadtest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var ErrNoOps = errors.New("no operation to run")
+
+// Op is an operation of the mix, usually a closure calling a generated
+// repository method. It runs with a probability proportional to Weight.
+type Op struct {
+	Name   string
+	Weight int
+	Fn     func(ctx context.Context) error
+}
+
+// ReadWrite returns the mix running read for readPercent of the operations
+// and write for the others, 70 for the classic 70% reads / 30% writes.
+func ReadWrite(readPercent int, read, write func(ctx context.Context) error) []Op {
+	return []Op{
+		{Name: "read", Weight: readPercent, Fn: read},
+		{Name: "write", Weight: 100 - readPercent, Fn: write},
+	}
+}
+
+type Config struct {
+	Ops         []Op
+	RPS         int           // Target operations per second.
+	Duration    time.Duration // Run length, until ctx is done when zero.
+	Concurrency int           // Maximum operations in flight, RPS by default.
+}
+
+// OpReport holds the latency percentiles of an operation.
+type OpReport struct {
+	Count, Errors      int
+	P50, P90, P99, Max time.Duration
+}
+
+type Report struct {
+	Elapsed time.Duration
+	Total   OpReport
+	Ops     map[string]OpReport
+	// Dropped counts the operations not started because Concurrency
+	// operations were in flight, the target rate was not sustained.
+	Dropped int
+}
+
+// RPS is the achieved rate of started operations.
+func (r *Report) RPS() float64 {
+	return float64(r.Total.Count) / r.Elapsed.Seconds()
+}
+
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%-12s %8s %8s %10s %10s %10s %10s\n", "op", "count", "errors", "p50", "p90", "p99", "max")
+	names := make([]string, 0, len(r.Ops))
+	for name := range r.Ops {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	line := func(name string, o OpReport) {
+		fmt.Fprintf(&b, "%-12s %8d %8d %10s %10s %10s %10s\n", name, o.Count, o.Errors, o.P50, o.P90, o.P99, o.Max)
+	}
+	for _, name := range names {
+		line(name, r.Ops[name])
+	}
+	line("total", r.Total)
+	fmt.Fprintf(&b, "%.1f ops/s over %s, %d dropped\n", r.RPS(), r.Elapsed, r.Dropped)
+	return b.String()
+}
+
+type sample struct {
+	op      string
+	latency time.Duration
+	failed  bool
+}
+
+// Run executes the mix at the configured rate until Duration elapsed or ctx
+// is done, then waits for the operations in flight.
+func Run(ctx context.Context, cfg Config) (*Report, error) {
+	weights := 0
+	for _, op := range cfg.Ops {
+		weights += op.Weight
+	}
+	if weights <= 0 || cfg.RPS <= 0 {
+		return nil, ErrNoOps
+	}
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = cfg.RPS
+	}
+	if cfg.Duration > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, cfg.Duration)
+		defer cancel()
+	}
+
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	pick := func() Op {
+		n := rnd.Intn(weights)
+		for _, op := range cfg.Ops {
+			if n < op.Weight {
+				return op
+			}
+			n -= op.Weight
+		}
+		return cfg.Ops[len(cfg.Ops)-1]
+	}
+
+	var (
+		mu      sync.Mutex
+		samples []sample
+		wg      sync.WaitGroup
+		dropped int
+	)
+	slots := make(chan struct{}, concurrency)
+	ticker := time.NewTicker(time.Second / time.Duration(cfg.RPS))
+	defer ticker.Stop()
+	start := time.Now()
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-ticker.C:
+		}
+		select {
+		case slots <- struct{}{}:
+		default:
+			dropped++
+			continue
+		}
+		op := pick()
+		wg.Add(1)
+		go func() {
+			defer func() { <-slots; wg.Done() }()
+			began := time.Now()
+			err := op.Fn(ctx)
+			s := sample{op: op.Name, latency: time.Since(began), failed: err != nil && ctx.Err() == nil}
+			mu.Lock()
+			samples = append(samples, s)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return report(samples, time.Since(start), dropped), nil
+}
+
+func report(samples []sample, elapsed time.Duration, dropped int) *Report {
+	byOp := map[string][]sample{}
+	for _, s := range samples {
+		byOp[s.op] = append(byOp[s.op], s)
+	}
+	r := &Report{Elapsed: elapsed, Total: summarize(samples), Ops: map[string]OpReport{}, Dropped: dropped}
+	for name, ss := range byOp {
+		r.Ops[name] = summarize(ss)
+	}
+	return r
+}
+
+func summarize(samples []sample) OpReport {
+	o := OpReport{Count: len(samples)}
+	if len(samples) == 0 {
+		return o
+	}
+	latencies := make([]time.Duration, len(samples))
+	for i, s := range samples {
+		latencies[i] = s.latency
+		if s.failed {
+			o.Errors++
+		}
+	}
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	at := func(p float64) time.Duration {
+		return latencies[int(p*float64(len(latencies)-1))]
+	}
+	o.P50, o.P90, o.P99, o.Max = at(0.5), at(0.9), at(0.99), latencies[len(latencies)-1]
+	return o
+}
@@ -0,0 +1,128 @@
+package gormrepo
+
+import (
+	"context"
+	"database/sql"
+	"hash/fnv"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Lock is an advisory lock held on a database connection.
+type Lock struct {
+	key     string
+	dialect string
+	conn    *sql.Conn // Pinned connection, nil inside a transaction.
+	tx      *sql.Tx
+	xact    bool // Released with the transaction.
+}
+
+type queryRower interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// AdvisoryLock blocks until the named advisory lock is acquired or ctx is
+// done, using pg_advisory_lock on Postgres and GET_LOCK on MySQL. The lock is
+// bound to a connection taken from the pool until Unlock. Inside a
+// transaction Postgres uses pg_advisory_xact_lock, released with it.
+func AdvisoryLock(ctx context.Context, db *gorm.DB, key string) (*Lock, error) {
+	lock, ok, err := acquire(ctx, db, key, true)
+	if err == nil && !ok {
+		err = ErrLockNotAcquired
+	}
+	if err != nil {
+		return nil, err
+	}
+	return lock, nil
+}
+
+// TryAdvisoryLock acquires the named advisory lock without waiting and reports
+// whether it was free.
+func TryAdvisoryLock(ctx context.Context, db *gorm.DB, key string) (*Lock, bool, error) {
+	return acquire(ctx, db, key, false)
+}
+
+func acquire(ctx context.Context, db *gorm.DB, key string, wait bool) (*Lock, bool, error) {
+	lock := &Lock{key: key, dialect: db.Dialect().GetName()}
+	if lock.dialect != "postgres" && lock.dialect != "mysql" {
+		return nil, false, ErrUnsupported
+	}
+
+	var q queryRower
+	if tx, ok := db.CommonDB().(*sql.Tx); ok {
+		lock.tx, lock.xact = tx, lock.dialect == "postgres"
+		q = tx
+	} else {
+		conn, err := db.DB().Conn(ctx)
+		if err != nil {
+			return nil, false, err
+		}
+		lock.conn = conn
+		q = conn
+	}
+
+	var query string
+	var args []interface{}
+	switch {
+	case lock.dialect == "mysql" && wait:
+		query, args = "SELECT COALESCE(GET_LOCK(?, -1), 0) = 1", []interface{}{key}
+	case lock.dialect == "mysql":
+		query, args = "SELECT COALESCE(GET_LOCK(?, 0), 0) = 1", []interface{}{key}
+	case lock.xact && wait:
+		query, args = "SELECT pg_advisory_xact_lock($1)", []interface{}{lockID(key)}
+	case lock.xact:
+		query, args = "SELECT pg_try_advisory_xact_lock($1)", []interface{}{lockID(key)}
+	case wait:
+		query, args = "SELECT pg_advisory_lock($1)", []interface{}{lockID(key)}
+	default:
+		query, args = "SELECT pg_try_advisory_lock($1)", []interface{}{lockID(key)}
+	}
+
+	var acquired bool
+	var err error
+	if lock.dialect == "postgres" && wait {
+		// pg_advisory_lock returns void once the lock is held.
+		_, err = q.ExecContext(ctx, query, args...)
+		acquired = err == nil
+	} else {
+		err = q.QueryRowContext(ctx, query, args...).Scan(&acquired)
+	}
+	if err != nil || !acquired {
+		lock.release()
+		return nil, false, err
+	}
+	return lock, true, nil
+}
+
+// Unlock releases the lock and returns its connection to the pool.
+// Transaction scoped Postgres locks are released on commit or rollback.
+func (l *Lock) Unlock(ctx context.Context) error {
+	defer l.release()
+	if l.xact {
+		return nil
+	}
+	var q queryRower = l.conn
+	if l.tx != nil {
+		q = l.tx
+	}
+	var released bool
+	if l.dialect == "mysql" {
+		return q.QueryRowContext(ctx, "SELECT COALESCE(RELEASE_LOCK(?), 0) = 1", l.key).Scan(&released)
+	}
+	return q.QueryRowContext(ctx, "SELECT pg_advisory_unlock($1)", lockID(l.key)).Scan(&released)
+}
+
+func (l *Lock) release() {
+	if l.conn != nil {
+		l.conn.Close()
+		l.conn = nil
+	}
+}
+
+// lockID maps the lock name to the bigint key of Postgres advisory locks.
+func lockID(key string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	return int64(h.Sum64())
+}
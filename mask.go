@@ -0,0 +1,42 @@
+package gormrepo
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+type unmaskedKey struct{}
+
+// WithUnmasked returns a context carrying the privilege to read masked fields
+// in clear.
+func WithUnmasked(ctx context.Context) context.Context {
+	return context.WithValue(ctx, unmaskedKey{}, true)
+}
+
+// IsUnmasked reports whether ctx carries the unmasked privilege.
+func IsUnmasked(ctx context.Context) bool {
+	unmasked, _ := ctx.Value(unmaskedKey{}).(bool)
+	return unmasked
+}
+
+// Mask redacts the value according to kind: "email" keeps the first letter
+// and the domain, "last4" keeps the last four characters and any other kind
+// hides the whole value. Empty values are left empty.
+func Mask(kind, value string) string {
+	if value == "" {
+		return value
+	}
+	switch kind {
+	case "email":
+		if at := strings.LastIndex(value, "@"); at > 0 {
+			_, size := utf8.DecodeRuneInString(value)
+			return value[:size] + "***" + value[at:]
+		}
+	case "last4":
+		if r := []rune(value); len(r) > 4 {
+			return "***" + string(r[len(r)-4:])
+		}
+	}
+	return "***"
+}
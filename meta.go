@@ -0,0 +1,139 @@
+package gormrepo
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Relation kinds, as gorm names the associations.
+const (
+	BelongsTo  = "belongs_to"
+	HasOne     = "has_one"
+	HasMany    = "has_many"
+	ManyToMany = "many_to_many"
+)
+
+// EntityMeta describes a model, generated with -meta and registered into
+// Meta when its package is initialized.
+type EntityMeta struct {
+	Package   string // Name of the package of the model.
+	Name      string // Type name of the model.
+	Table     string
+	Columns   []ColumnMeta
+	Relations []RelationMeta
+	Indexes   []IndexMeta
+}
+
+// ColumnMeta is a column of a model.
+type ColumnMeta struct {
+	Field      string
+	Name       string
+	Type       string // Go type of the field, of its element for pointers.
+	JSON       string // Name of the field in JSON, "" when left out.
+	PrimaryKey bool
+	Unique     bool
+}
+
+// RelationMeta is an association of a model with another model of its
+// package.
+type RelationMeta struct {
+	Field      string
+	Kind       string // BelongsTo, HasOne, HasMany or ManyToMany.
+	Target     string // Type name of the associated model.
+	ForeignKey string // Column of the foreign key, in the table of the model for BelongsTo.
+	JoinTable  string // Join table of ManyToMany relations.
+}
+
+// IndexMeta is an index declared by the index and unique_index tags of a
+// model.
+type IndexMeta struct {
+	Name    string
+	Unique  bool
+	Columns []string
+}
+
+// Column returns the column of the entity named name, or of the field named
+// name.
+func (e EntityMeta) Column(name string) (ColumnMeta, bool) {
+	for _, c := range e.Columns {
+		if c.Name == name || c.Field == name {
+			return c, true
+		}
+	}
+	return ColumnMeta{}, false
+}
+
+// PrimaryKey returns the primary key columns of the entity.
+func (e EntityMeta) PrimaryKey() []string {
+	var columns []string
+	for _, c := range e.Columns {
+		if c.PrimaryKey {
+			columns = append(columns, c.Name)
+		}
+	}
+	return columns
+}
+
+// MetaRegistry holds the metadata of the entities.
+type MetaRegistry struct {
+	mu       sync.RWMutex
+	entities []EntityMeta
+}
+
+// Meta is the registry of the entities generated with -meta.
+var Meta = &MetaRegistry{}
+
+// Register adds the entity, replacing the entity of the same package and
+// name.
+func (m *MetaRegistry) Register(e EntityMeta) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, registered := range m.entities {
+		if registered.Package == e.Package && registered.Name == e.Name {
+			m.entities[i] = e
+			return
+		}
+	}
+	m.entities = append(m.entities, e)
+	sort.Slice(m.entities, func(i, j int) bool {
+		a, b := m.entities[i], m.entities[j]
+		return a.Package < b.Package || a.Package == b.Package && a.Name < b.Name
+	})
+}
+
+// Entity returns the entity named "Type", or "package.Type" when several
+// packages declare the type.
+func (m *MetaRegistry) Entity(name string) (EntityMeta, bool) {
+	pkg := ""
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		pkg, name = name[:i], name[i+1:]
+	}
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, e := range m.entities {
+		if e.Name == name && (pkg == "" || e.Package == pkg) {
+			return e, true
+		}
+	}
+	return EntityMeta{}, false
+}
+
+// Table returns the entity of the table.
+func (m *MetaRegistry) Table(table string) (EntityMeta, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, e := range m.entities {
+		if e.Table == table {
+			return e, true
+		}
+	}
+	return EntityMeta{}, false
+}
+
+// Entities returns the registered entities, by package and name.
+func (m *MetaRegistry) Entities() []EntityMeta {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return append([]EntityMeta(nil), m.entities...)
+}
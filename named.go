@@ -0,0 +1,64 @@
+package gormrepo
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	queriesMu sync.RWMutex
+	queries   = map[string]func(args ...interface{}) ([]CriteriaOption, error){}
+)
+
+// RegisterQuery registers the criteria of a frequently used query under
+// name, for the generated GetByNamed. Registering a name again replaces its
+// query.
+func RegisterQuery(name string, opts ...CriteriaOption) {
+	RegisterQueryFunc(name, func(args ...interface{}) ([]CriteriaOption, error) {
+		if len(args) > 0 {
+			return nil, fmt.Errorf("%w: %s takes no arguments", ErrInvalidCriterion, name)
+		}
+		return opts, nil
+	})
+}
+
+// RegisterQueryFunc registers a query taking arguments, whose criteria build
+// returns, such as the minimum age of
+//
+//	gormrepo.RegisterQueryFunc("adultsOver", func(args ...interface{}) ([]gormrepo.CriteriaOption, error) {
+//		if len(args) != 1 {
+//			return nil, gormrepo.ErrInvalidCriterion
+//		}
+//		return []gormrepo.CriteriaOption{gormrepo.And("age >= ?", args[0])}, nil
+//	})
+func RegisterQueryFunc(name string, build func(args ...interface{}) ([]CriteriaOption, error)) {
+	queriesMu.Lock()
+	defer queriesMu.Unlock()
+	queries[name] = build
+}
+
+// NamedQuery returns the criteria of the query registered under name, with
+// the arguments. It fails with ErrUnknownQuery when no query has the name.
+func NamedQuery(name string, args ...interface{}) ([]CriteriaOption, error) {
+	queriesMu.RLock()
+	build, ok := queries[name]
+	queriesMu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownQuery, name)
+	}
+	return build(args...)
+}
+
+// NamedQueries returns the names of the registered queries, sorted, so they
+// can be listed and tested one by one.
+func NamedQueries() []string {
+	queriesMu.RLock()
+	defer queriesMu.RUnlock()
+	names := make([]string, 0, len(queries))
+	for name := range queries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,56 @@
+// Package nats publishes entity change events to NATS JetStream, on the
+// subject <prefix>.<Entity>.<op>.
+package nats
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/l-vitaly/gormrepo"
+	natsgo "github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+)
+
+// Publisher is a gormrepo.Publisher writing to JetStream. The subjects must
+// be bound to a stream, e.g. with the subject filter <prefix>.>.
+type Publisher struct {
+	JetStream jetstream.JetStream
+	// Subject returns the subject of the event, <prefix>.<Entity>.<op> with
+	// the prefix given to NewPublisher by default.
+	Subject func(event gormrepo.Event) string
+}
+
+// NewPublisher returns a publisher using the JetStream of the connection.
+func NewPublisher(nc *natsgo.Conn, prefix string) (*Publisher, error) {
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, err
+	}
+	return &Publisher{
+		JetStream: js,
+		Subject: func(event gormrepo.Event) string {
+			return prefix + "." + event.Entity + "." + event.Op.String()
+		},
+	}, nil
+}
+
+// Publish sends the events as JSON, one at a time, waiting for the ack of
+// the stream so a failed write is reported.
+func (p *Publisher) Publish(ctx context.Context, events ...gormrepo.Event) error {
+	for _, event := range events {
+		data, err := json.Marshal(event.Payload)
+		if err != nil {
+			return err
+		}
+		msg := natsgo.NewMsg(p.Subject(event))
+		msg.Data = data
+		msg.Header.Set("Content-Type", "application/json")
+		msg.Header.Set("Entity", event.Entity)
+		msg.Header.Set("Op", event.Op.String())
+		msg.Header.Set("Key", event.Key)
+		if _, err := p.JetStream.PublishMsg(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+// Package notify propagates entity changes between instances with Postgres
+// LISTEN/NOTIFY, for example to invalidate caches.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/l-vitaly/gormrepo"
+	"github.com/lib/pq"
+)
+
+// Event is the payload of a change notification. It carries the id and
+// changed columns only, as NOTIFY payloads are limited to 8000 bytes.
+type Event struct {
+	Entity string             `json:"entity"`
+	Op     gormrepo.Operation `json:"op"`
+	ID     uint               `json:"id"`
+	Fields []string           `json:"fields,omitempty"`
+}
+
+// Publish sends the event on the channel. Inside a transaction the
+// notification is delivered on commit, and dropped on rollback.
+func Publish(db *gorm.DB, channel string, event Event) error {
+	if db.Dialect().GetName() != "postgres" {
+		return gormrepo.ErrUnsupported
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return db.Exec("SELECT pg_notify(?, ?)", channel, string(payload)).Error
+}
+
+// Listener dispatches the events received on its channels to the handlers
+// registered for their entity.
+type Listener struct {
+	listener *pq.Listener
+	mu       sync.RWMutex
+	handlers map[string][]func(Event)
+	// OnReconnect is called after the connection was lost and restored, as
+	// notifications sent meanwhile are lost: caches should be flushed.
+	OnReconnect func()
+}
+
+// NewListener connects to the Postgres server of dsn and listens on the channels.
+func NewListener(dsn string, channels ...string) (*Listener, error) {
+	l := &Listener{
+		listener: pq.NewListener(dsn, time.Second, time.Minute, nil),
+		handlers: map[string][]func(Event){},
+	}
+	for _, channel := range channels {
+		if err := l.listener.Listen(channel); err != nil {
+			l.listener.Close()
+			return nil, err
+		}
+	}
+	return l, nil
+}
+
+// Handle registers fn for the events of the entity, "*" for every entity.
+// The generated OnChanged methods register typed handlers.
+func (l *Listener) Handle(entity string, fn func(Event)) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.handlers[entity] = append(l.handlers[entity], fn)
+}
+
+// Run dispatches the notifications until ctx is done. Handlers run one at a
+// time, in the order of the notifications.
+func (l *Listener) Run(ctx context.Context) error {
+	ping := time.NewTicker(90 * time.Second)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ping.C:
+			go l.listener.Ping()
+		case n := <-l.listener.Notify:
+			if n == nil {
+				// pq sends nil once the connection is re-established.
+				if l.OnReconnect != nil {
+					l.OnReconnect()
+				}
+				continue
+			}
+			var event Event
+			if err := json.Unmarshal([]byte(n.Extra), &event); err != nil {
+				continue
+			}
+			l.dispatch(event)
+		}
+	}
+}
+
+func (l *Listener) dispatch(event Event) {
+	l.mu.RLock()
+	handlers := append(append([]func(Event){}, l.handlers[event.Entity]...), l.handlers["*"]...)
+	l.mu.RUnlock()
+	for _, fn := range handlers {
+		fn(event)
+	}
+}
+
+func (l *Listener) Close() error {
+	return l.listener.Close()
+}
@@ -0,0 +1,299 @@
+package gormrepo
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jinzhu/gorm"
+)
+
+// Config is the configuration of a database connection, see Open. The
+// dialect must be registered, by importing its github.com/jinzhu/gorm/dialects
+// package.
+type Config struct {
+	// Dialect is the gorm dialect: postgres, mysql, mssql or sqlite3.
+	Dialect string
+	// DSN is the data source name of the driver, in any of its formats.
+	DSN string
+
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// LogMode logs every statement to Logger, or to the gorm logger when nil.
+	LogMode bool
+	Logger  interface{ Print(v ...interface{}) }
+
+	// TLS is added to the options of the DSN, when set.
+	TLS *TLSConfig
+
+	// AuthToken returns the password of each new connection, an IAM token
+	// such as the ones of RDSAuthToken or of a GCP token source, which
+	// replaces the password of the DSN. Postgres and MySQL only.
+	AuthToken func(ctx context.Context) (string, error)
+	// Dial opens the network connections of the driver, with the dialer of
+	// the GCP Cloud SQL connector for example. Postgres and MySQL only.
+	Dial func(ctx context.Context, network, addr string) (net.Conn, error)
+
+	// Credentials provides the user and password of new connections, read
+	// again every CredentialsRefresh (a minute by default). Once they are
+	// rotated, the pooled connections opened with the previous ones are
+	// closed as they are released, instead of being reused. Postgres and
+	// MySQL only, and exclusive of AuthToken.
+	Credentials        CredentialsProvider
+	CredentialsRefresh time.Duration
+}
+
+// TLSConfig is the TLS configuration of a connection.
+type TLSConfig struct {
+	// Mode is disable, require (encrypted, the server is not verified),
+	// verify-ca (the certificate is signed by CAFile) or verify-full (it is
+	// also issued for the host, or ServerName).
+	Mode       string
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+}
+
+// ConfigFromEnv returns the config of the environment variables named after
+// the prefix, such as BILLING_DB_DIALECT and BILLING_DB_DSN for the prefix
+// BILLING_DB:
+//
+//	<prefix>_DIALECT, <prefix>_DSN
+//	<prefix>_MAX_OPEN_CONNS, <prefix>_MAX_IDLE_CONNS
+//	<prefix>_CONN_MAX_LIFETIME, <prefix>_CONN_MAX_IDLE_TIME (durations, 5m)
+//	<prefix>_LOG (a boolean)
+//	<prefix>_TLS_MODE, <prefix>_TLS_CA, <prefix>_TLS_CERT, <prefix>_TLS_KEY,
+//	<prefix>_TLS_SERVER_NAME
+//
+// Unset variables keep the zero value. A malformed value fails with
+// ErrInvalidConfig.
+func ConfigFromEnv(prefix string) (Config, error) {
+	env := func(name string) string {
+		return os.Getenv(prefix + "_" + name)
+	}
+	c := Config{Dialect: env("DIALECT"), DSN: env("DSN")}
+	for name, dest := range map[string]*int{"MAX_OPEN_CONNS": &c.MaxOpenConns, "MAX_IDLE_CONNS": &c.MaxIdleConns} {
+		if v := env(name); v != "" {
+			n, err := strconv.Atoi(v)
+			if err != nil {
+				return Config{}, fmt.Errorf("%w: %s_%s: %v", ErrInvalidConfig, prefix, name, err)
+			}
+			*dest = n
+		}
+	}
+	for name, dest := range map[string]*time.Duration{"CONN_MAX_LIFETIME": &c.ConnMaxLifetime, "CONN_MAX_IDLE_TIME": &c.ConnMaxIdleTime} {
+		if v := env(name); v != "" {
+			d, err := time.ParseDuration(v)
+			if err != nil {
+				return Config{}, fmt.Errorf("%w: %s_%s: %v", ErrInvalidConfig, prefix, name, err)
+			}
+			*dest = d
+		}
+	}
+	if v := env("LOG"); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return Config{}, fmt.Errorf("%w: %s_LOG: %v", ErrInvalidConfig, prefix, err)
+		}
+		c.LogMode = b
+	}
+	if mode := env("TLS_MODE"); mode != "" {
+		c.TLS = &TLSConfig{
+			Mode:       mode,
+			CAFile:     env("TLS_CA"),
+			CertFile:   env("TLS_CERT"),
+			KeyFile:    env("TLS_KEY"),
+			ServerName: env("TLS_SERVER_NAME"),
+		}
+	}
+	return c, nil
+}
+
+// Open opens the database of the config and sets up its pool and logger. It
+// fails with ErrInvalidConfig when the config is incomplete or its TLS
+// configuration does not apply to the dialect, and with ErrUnsupported when
+// the dialect takes no auth token, credentials provider or dialer.
+func Open(c Config) (*gorm.DB, error) {
+	if c.Dialect == "" || c.DSN == "" {
+		return nil, fmt.Errorf("%w: dialect and DSN required", ErrInvalidConfig)
+	}
+	dsn := c.DSN
+	if c.TLS != nil {
+		var err error
+		if dsn, err = c.TLS.dsn(c.Dialect, dsn); err != nil {
+			return nil, err
+		}
+	}
+	var db *gorm.DB
+	var err error
+	if c.AuthToken != nil && c.Credentials != nil {
+		return nil, fmt.Errorf("%w: both auth token and credentials", ErrInvalidConfig)
+	}
+	if c.AuthToken != nil || c.Dial != nil || c.Credentials != nil {
+		db, err = openConnector(c, dsn)
+	} else {
+		db, err = gorm.Open(c.Dialect, dsn)
+	}
+	if err != nil {
+		return nil, err
+	}
+	pool := db.DB()
+	if c.MaxOpenConns > 0 {
+		pool.SetMaxOpenConns(c.MaxOpenConns)
+	}
+	if c.MaxIdleConns > 0 {
+		pool.SetMaxIdleConns(c.MaxIdleConns)
+	}
+	if c.ConnMaxLifetime > 0 {
+		pool.SetConnMaxLifetime(c.ConnMaxLifetime)
+	}
+	if c.ConnMaxIdleTime > 0 {
+		pool.SetConnMaxIdleTime(c.ConnMaxIdleTime)
+	}
+	if c.Logger != nil {
+		db.SetLogger(c.Logger)
+	}
+	return db.LogMode(c.LogMode), nil
+}
+
+// dsn returns the DSN with the options of the TLS configuration.
+func (t *TLSConfig) dsn(dialect, dsn string) (string, error) {
+	switch t.Mode {
+	case "disable", "require", "verify-ca", "verify-full":
+	default:
+		return "", fmt.Errorf("%w: TLS mode %q", ErrInvalidConfig, t.Mode)
+	}
+	switch dialect {
+	case "postgres":
+		params := [][2]string{{"sslmode", t.Mode}}
+		for _, p := range [][2]string{{"sslrootcert", t.CAFile}, {"sslcert", t.CertFile}, {"sslkey", t.KeyFile}} {
+			if p[1] != "" {
+				params = append(params, p)
+			}
+		}
+		return addDSNParams(dsn, params, " "), nil
+	case "mysql":
+		name := t.Mode
+		switch t.Mode {
+		case "disable":
+			name = "false"
+		case "require":
+			name = "skip-verify"
+		default:
+			var err error
+			if name, err = t.registerMySQL(); err != nil {
+				return "", err
+			}
+		}
+		return addDSNParams(dsn, [][2]string{{"tls", name}}, "&"), nil
+	case "mssql":
+		params := [][2]string{{"encrypt", "true"}}
+		switch t.Mode {
+		case "disable":
+			params[0][1] = "disable"
+		case "require":
+			params = append(params, [2]string{"TrustServerCertificate", "true"})
+		default:
+			if t.CAFile != "" {
+				params = append(params, [2]string{"certificate", t.CAFile})
+			}
+			if t.ServerName != "" {
+				params = append(params, [2]string{"hostNameInCertificate", t.ServerName})
+			}
+		}
+		return addDSNParams(dsn, params, ";"), nil
+	}
+	return "", fmt.Errorf("%w: TLS with dialect %q", ErrInvalidConfig, dialect)
+}
+
+// registerMySQL registers the verifying TLS configuration with the MySQL
+// driver and returns its name.
+func (t *TLSConfig) registerMySQL() (string, error) {
+	cfg := &tls.Config{ServerName: t.ServerName}
+	if t.CAFile != "" {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+		cfg.RootCAs = x509.NewCertPool()
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return "", fmt.Errorf("%w: no certificate in %s", ErrInvalidConfig, t.CAFile)
+		}
+	}
+	if t.CertFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return "", fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	if t.Mode == "verify-ca" {
+		// The chain is verified without the host name.
+		cfg.InsecureSkipVerify = true
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			if len(cs.PeerCertificates) == 0 {
+				return fmt.Errorf("no server certificate")
+			}
+			opts := x509.VerifyOptions{Roots: cfg.RootCAs, Intermediates: x509.NewCertPool()}
+			for _, cert := range cs.PeerCertificates[1:] {
+				opts.Intermediates.AddCert(cert)
+			}
+			_, err := cs.PeerCertificates[0].Verify(opts)
+			return err
+		}
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{t.Mode, t.CAFile, t.CertFile, t.KeyFile, t.ServerName}, "\x00")))
+	name := "gormrepo-" + hex.EncodeToString(sum[:8])
+	if err := mysql.RegisterTLSConfig(name, cfg); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrInvalidConfig, err)
+	}
+	return name, nil
+}
+
+// addDSNParams adds the params to the DSN, in the query of a URL or appended
+// with the separator of the key=value format of the driver, quoted in the
+// Postgres format. MySQL DSNs take params after "?".
+func addDSNParams(dsn string, params [][2]string, sep string) string {
+	if strings.Contains(dsn, "://") {
+		u, err := url.Parse(dsn)
+		if err == nil {
+			q := u.Query()
+			for _, p := range params {
+				q.Set(p[0], p[1])
+			}
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	var b strings.Builder
+	b.WriteString(dsn)
+	for i, p := range params {
+		switch {
+		case sep == "&" && i == 0 && !strings.Contains(dsn, "?"):
+			b.WriteString("?")
+		case b.Len() > 0 && !strings.HasSuffix(b.String(), sep):
+			b.WriteString(sep)
+		}
+		value := p[1]
+		if sep == " " && (value == "" || strings.ContainsAny(value, ` '\`)) {
+			value = "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(value) + "'"
+		}
+		b.WriteString(p[0] + "=" + value)
+	}
+	return b.String()
+}
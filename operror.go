@@ -0,0 +1,55 @@
+package gormrepo
+
+import (
+	"errors"
+
+	"github.com/jinzhu/gorm"
+)
+
+// OpError is an error of a generated repository method, with the entity,
+// the method and the criteria of the call. The criteria show their
+// arguments, see DescribeCriteria.
+type OpError struct {
+	Entity   string
+	Method   string
+	Criteria string
+	Err      error
+}
+
+func (e *OpError) Error() string {
+	s := e.Entity + "." + e.Method
+	if e.Criteria != "" {
+		s += " " + e.Criteria
+	}
+	return s + ": " + e.Err.Error()
+}
+
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// WrapError returns err as an OpError of the method of the entity called
+// with the criteria, nil when err is nil. An error already wrapped by a method
+// the method called keeps the context of the inner call.
+func WrapError(err error, entity, method string, criteria ...CriteriaOption) error {
+	if err == nil {
+		return nil
+	}
+	var opErr *OpError
+	if errors.As(err, &opErr) {
+		return err
+	}
+	return &OpError{Entity: entity, Method: method, Criteria: DescribeCriteria(criteria...), Err: err}
+}
+
+// IsRecordNotFound reports whether err, wrapped or not, is a gorm record not
+// found error. gorm.IsRecordNotFoundError does not see through wrapping.
+func IsRecordNotFound(err error) bool {
+	for err != nil {
+		if gorm.IsRecordNotFoundError(err) {
+			return true
+		}
+		err = errors.Unwrap(err)
+	}
+	return false
+}
@@ -0,0 +1,112 @@
+package gormrepo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+type Direction string
+
+const (
+	Asc  Direction = "ASC"
+	Desc Direction = "DESC"
+)
+
+// ParseDirection returns the direction named s, "asc" or "desc" in any case,
+// such as the sort order of a request. Other names fail with
+// ErrInvalidCriterion.
+func ParseDirection(s string) (Direction, error) {
+	if d := Direction(strings.ToUpper(s)); d.Valid() {
+		return d, nil
+	}
+	return "", fmt.Errorf("%w: direction %q", ErrInvalidCriterion, s)
+}
+
+// Valid reports whether d is Asc or Desc.
+func (d Direction) Valid() bool {
+	return d == Asc || d == Desc
+}
+
+// OrderOpts refines the ordering of Order. NullsFirst and NullsLast are
+// emulated with an IS NULL sort key on dialects without NULLS FIRST / LAST.
+type OrderOpts struct {
+	NullsFirst      bool
+	NullsLast       bool
+	CaseInsensitive bool
+}
+
+// Order sorts by column in the direction. Without options the database
+// default applies: nulls last ascending on Postgres, first on MySQL. Other
+// directions than Asc and Desc, and columns rejected by checkOrderColumn,
+// fail with ErrInvalidCriterion.
+func Order(column string, direction Direction, opts ...OrderOpts) CriteriaOption {
+	var o OrderOpts
+	if len(opts) > 0 {
+		o = opts[0]
+	}
+	args := []interface{}{string(direction)}
+	if o.NullsFirst {
+		args = append(args, "nulls_first")
+	}
+	if o.NullsLast {
+		args = append(args, "nulls_last")
+	}
+	if o.CaseInsensitive {
+		args = append(args, "case_insensitive")
+	}
+	return Described(Criterion{Op: "order", Column: column, Args: args}, func(db *gorm.DB) *gorm.DB {
+		if !direction.Valid() {
+			return withError(db, fmt.Errorf("%w: direction %q", ErrInvalidCriterion, direction))
+		}
+		if err := checkOrderColumn(db, column); err != nil {
+			return withError(db, err)
+		}
+		expr := column
+		if o.CaseInsensitive {
+			expr = "LOWER(" + column + ")"
+		}
+		if !o.NullsFirst && !o.NullsLast {
+			return db.Order(expr + " " + string(direction))
+		}
+		switch db.Dialect().GetName() {
+		case "postgres", "sqlite3":
+			if o.NullsLast {
+				return db.Order(expr + " " + string(direction) + " NULLS LAST")
+			}
+			return db.Order(expr + " " + string(direction) + " NULLS FIRST")
+		}
+		nullsKey := "CASE WHEN " + column + " IS NULL THEN 1 ELSE 0 END"
+		if o.NullsFirst {
+			nullsKey += " DESC"
+		}
+		return db.Order(nullsKey).Order(expr + " " + string(direction))
+	})
+}
+
+// checkOrderColumn fails with ErrInvalidCriterion when column is not a column
+// name, or is not a column of the entity registered in Meta for its table:
+// the table qualifying the column or, unqualified, the table of the model of
+// db. The columns of tables without metadata are only checked to be names.
+func checkOrderColumn(db *gorm.DB, column string) error {
+	if !identifier.MatchString(column) {
+		return fmt.Errorf("%w: order by %q", ErrInvalidCriterion, column)
+	}
+	table, name := "", column
+	if i := strings.LastIndex(column, "."); i >= 0 {
+		table, name = column[:i], column[i+1:]
+	} else if db.Value != nil {
+		table = db.NewScope(db.Value).TableName()
+	}
+	e, ok := Meta.Table(table)
+	if !ok {
+		return nil
+	}
+	for _, c := range e.Columns {
+		if c.Name == name {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: order by %q, not a column of %s", ErrInvalidCriterion, column, e.Name)
+}
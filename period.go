@@ -0,0 +1,103 @@
+package gormrepo
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// Period is the bucket size of CountByPeriod.
+type Period string
+
+const (
+	Hour  Period = "hour"
+	Day   Period = "day"
+	Week  Period = "week" // Starting on Monday.
+	Month Period = "month"
+	Year  Period = "year"
+)
+
+var (
+	// Formats of DATE_FORMAT and strftime, which share the directives used here.
+	periodFormats = map[Period]string{
+		Hour:  "%Y-%m-%d %H:00:00",
+		Day:   "%Y-%m-%d 00:00:00",
+		Week:  "%Y-%m-%d 00:00:00",
+		Month: "%Y-%m-01 00:00:00",
+		Year:  "%Y-01-01 00:00:00",
+	}
+	periodLayouts = []string{"2006-01-02 15:04:05", time.RFC3339Nano, "2006-01-02"}
+)
+
+// PeriodExpr returns the SQL expression truncating column to the period, with
+// date_trunc on Postgres, DATE_FORMAT on MySQL and strftime on SQLite.
+func PeriodExpr(db *gorm.DB, column string, period Period) (string, error) {
+	if _, ok := periodFormats[period]; !ok {
+		return "", ErrInvalidPeriod
+	}
+	switch db.Dialect().GetName() {
+	case "postgres":
+		return "date_trunc('" + string(period) + "', " + column + ")", nil
+	case "mysql":
+		if period == Week {
+			column = "DATE_SUB(" + column + ", INTERVAL WEEKDAY(" + column + ") DAY)"
+		}
+		return "DATE_FORMAT(" + column + ", '" + periodFormats[period] + "')", nil
+	case "sqlite3":
+		if period == Week {
+			return "strftime('" + periodFormats[period] + "', " + column + ", '-6 days', 'weekday 1')", nil
+		}
+		return "strftime('" + periodFormats[period] + "', " + column + ")", nil
+	}
+	return "", ErrUnsupported
+}
+
+// CountByPeriod counts the rows of the query by period of column, keyed by
+// the UTC start of each period. Periods without rows are absent.
+func CountByPeriod(db *gorm.DB, column string, period Period) (map[time.Time]int64, error) {
+	expr, err := PeriodExpr(db, column, period)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := db.Select(expr + " AS bucket, COUNT(*) AS count").Group(expr).Rows()
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := map[time.Time]int64{}
+	for rows.Next() {
+		var bucket interface{}
+		var count int64
+		if err := rows.Scan(&bucket, &count); err != nil {
+			return nil, err
+		}
+		start, err := periodStart(bucket)
+		if err != nil {
+			return nil, err
+		}
+		counts[start] += count
+	}
+	return counts, rows.Err()
+}
+
+func periodStart(bucket interface{}) (time.Time, error) {
+	var s string
+	switch v := bucket.(type) {
+	case time.Time:
+		return v.UTC(), nil
+	case nil:
+		return time.Time{}, nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	}
+	var err error
+	for _, layout := range periodLayouts {
+		var t time.Time
+		if t, err = time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
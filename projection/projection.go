@@ -0,0 +1,133 @@
+// Package projection rebuilds read-model tables from source entities in
+// resumable batches.
+package projection
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/l-vitaly/gormrepo"
+)
+
+const DefaultBatchSize = 500
+
+// Projection builds the rows of the read model Target from the rows of Source,
+// both pointers to entity types such as &Order{} and &OrderSummary{}. Source
+// rows are visited by increasing numeric primary key.
+type Projection struct {
+	Name   string
+	Source interface{}
+	Target interface{}
+	// Scope restricts or preloads the source rows, may be nil.
+	Scope gormrepo.CriteriaOption
+	// Project returns the read model row of the source row, or nil to skip
+	// it. It runs in the transaction of the batch.
+	Project   func(tx *gorm.DB, source interface{}) (interface{}, error)
+	BatchSize int
+}
+
+// Checkpoint is the last source row projected by a projection.
+type Checkpoint struct {
+	Projection string `gorm:"primary_key"`
+	LastID     uint64
+	UpdatedAt  time.Time
+}
+
+func (Checkpoint) TableName() string {
+	return "gormrepo_projection_checkpoints"
+}
+
+type Rebuilder struct {
+	db *gorm.DB
+	// Progress is called after each batch with the checkpoint saved.
+	Progress func(Checkpoint)
+}
+
+func NewRebuilder(db *gorm.DB) *Rebuilder {
+	return &Rebuilder{db: db}
+}
+
+// AutoMigrate creates the checkpoint table.
+func (r *Rebuilder) AutoMigrate() error {
+	return r.db.AutoMigrate(&Checkpoint{}).Error
+}
+
+// Reset deletes the read model rows and the checkpoint of the projection, so
+// the next Rebuild starts over.
+func (r *Rebuilder) Reset(p Projection) error {
+	return gormrepo.Transaction(r.db, func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Delete(p.Target).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&Checkpoint{}, "projection = ?", p.Name).Error
+	})
+}
+
+// Rebuild projects the source rows after the checkpoint, upserting the read
+// model rows and saving the checkpoint in the transaction of each batch, until
+// every row is projected or ctx is done.
+func (r *Rebuilder) Rebuild(ctx context.Context, p Projection) error {
+	size := p.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	scope := r.db.NewScope(p.Source)
+	key := scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())
+	sliceType := reflect.SliceOf(reflect.TypeOf(p.Source))
+
+	checkpoint := Checkpoint{Projection: p.Name}
+	err := r.db.Where(&Checkpoint{Projection: p.Name}).First(&checkpoint).Error
+	if err != nil && !gorm.IsRecordNotFoundError(err) {
+		return err
+	}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		n := 0
+		err := gormrepo.Transaction(r.db, func(tx *gorm.DB) error {
+			query := tx.Model(p.Source)
+			if p.Scope != nil {
+				query = p.Scope(query)
+			}
+			rows := reflect.New(sliceType)
+			err := query.Where(key+" > ?", checkpoint.LastID).Order(key).Limit(size).Find(rows.Interface()).Error
+			if err != nil {
+				return err
+			}
+			n = rows.Elem().Len()
+			for i := 0; i < n; i++ {
+				source := rows.Elem().Index(i).Interface()
+				target, err := p.Project(tx, source)
+				if err != nil {
+					return err
+				}
+				if target != nil {
+					if err := tx.Set("gorm:save_associations", false).Save(target).Error; err != nil {
+						return err
+					}
+				}
+				id := reflect.ValueOf(tx.NewScope(source).PrimaryKeyValue())
+				checkpoint.LastID = id.Convert(reflect.TypeOf(uint64(0))).Uint()
+			}
+			if n == 0 {
+				return nil
+			}
+			return tx.Save(&checkpoint).Error
+		})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+		if r.Progress != nil {
+			r.Progress(checkpoint)
+		}
+		if n < size {
+			return nil
+		}
+	}
+}
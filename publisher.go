@@ -0,0 +1,17 @@
+package gormrepo
+
+import "context"
+
+// Event is an entity change sent to a message broker by a Publisher.
+type Event struct {
+	Entity  string
+	Op      Operation
+	Key     string      // Primary key of the entity, used as message key.
+	Payload interface{} // The generated <T>Changed event, or its Debezium envelope.
+}
+
+// Publisher sends entity change events to a message broker. The repositories
+// generated with -publish call it after each successful write.
+type Publisher interface {
+	Publish(ctx context.Context, events ...Event) error
+}
@@ -0,0 +1,93 @@
+package gormrepo
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/jinzhu/gorm"
+)
+
+// QueryBuilder builds the reporting queries spanning entities, which no
+// repository holds, with the criteria options of the repositories:
+//
+//	err := gormrepo.Query(db).Model(&User{}).
+//		Join("JOIN orders ON orders.user_id = users.id").
+//		Where(gormrepo.Select("users.name, orders.total"), gormrepo.And("orders.status = ?", "paid")).
+//		ScanInto(&rows)
+//
+// Each method returns a new builder, so a builder can be shared as the base
+// of several queries.
+type QueryBuilder struct {
+	db       *gorm.DB
+	entity   string
+	criteria []CriteriaOption
+}
+
+// Query returns a builder of the queries of db.
+func Query(db *gorm.DB) *QueryBuilder {
+	return &QueryBuilder{db: db, entity: "Query"}
+}
+
+// Model sets the model of the table queried first, naming the errors.
+func (q *QueryBuilder) Model(model interface{}) *QueryBuilder {
+	c := q.with(q.db.Model(model))
+	if t := reflect.TypeOf(model); t != nil {
+		for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+			t = t.Elem()
+		}
+		c.entity = t.Name()
+	}
+	return c
+}
+
+// Table sets the table queried first, for tables without a model.
+func (q *QueryBuilder) Table(name string) *QueryBuilder {
+	c := q.with(q.db.Table(name))
+	c.entity = name
+	return c
+}
+
+// Join adds the join clause, such as "LEFT JOIN orders ON orders.user_id =
+// users.id AND orders.status = ?", with its arguments bound. It fails with
+// ErrRawSQLForbidden on a db in the mode of AllowListOnly.
+func (q *QueryBuilder) Join(query string, args ...interface{}) *QueryBuilder {
+	if IsAllowListOnly(q.db) {
+		return q.with(withError(q.db, fmt.Errorf("%w: join %q", ErrRawSQLForbidden, query)))
+	}
+	return q.with(q.db.Joins(query, args...))
+}
+
+// Where applies the criteria options.
+func (q *QueryBuilder) Where(criteria ...CriteriaOption) *QueryBuilder {
+	c := q.with(ApplyCriteria(q.db, criteria...))
+	c.criteria = append(append([]CriteriaOption(nil), q.criteria...), criteria...)
+	return c
+}
+
+// ScanInto runs the query and scans its rows into dest, a pointer to a
+// slice of structs whose fields match the selected columns.
+func (q *QueryBuilder) ScanInto(dest interface{}) error {
+	return q.wrapError("ScanInto", q.db.Scan(dest).Error)
+}
+
+// Count returns the number of rows of the query.
+func (q *QueryBuilder) Count() (int, error) {
+	var count int
+	err := q.db.Count(&count).Error
+	return count, q.wrapError("Count", err)
+}
+
+// String returns the criteria of the query, see DescribeCriteria.
+func (q *QueryBuilder) String() string {
+	return DescribeCriteria(q.criteria...)
+}
+
+func (q *QueryBuilder) with(db *gorm.DB) *QueryBuilder {
+	c := *q
+	c.db = db
+	return &c
+}
+
+func (q *QueryBuilder) wrapError(method string, err error) error {
+	return WrapError(err, q.entity, method, q.criteria...)
+}
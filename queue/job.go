@@ -0,0 +1,35 @@
+package queue
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+//go:generate gormrepogen -t=Job -columns
+
+const (
+	StatusPending = "pending"
+	StatusRunning = "running"
+	StatusDone    = "done"
+	StatusDead    = "dead"
+)
+
+// Job is a queued task. Attempts counts the dequeues of the job, RunAt is the
+// earliest time it can be dequeued again.
+type Job struct {
+	gorm.Model
+	Queue       string    `gorm:"index:idx_queue_jobs_dequeue"`
+	Status      string    `gorm:"index:idx_queue_jobs_dequeue"`
+	RunAt       time.Time `gorm:"index:idx_queue_jobs_dequeue"`
+	Payload     string    `gorm:"type:text"`
+	Attempts    int
+	MaxAttempts int
+	LockedBy    string
+	HeartbeatAt *time.Time
+	LastError   string `gorm:"type:text"`
+}
+
+func (Job) TableName() string {
+	return "queue_jobs"
+}
@@ -0,0 +1,42 @@
+// Code generated by "gormrepogen -t=Job -columns"; DO NOT EDIT
+
+//go:build go1.18
+
+package queue
+
+import (
+	"time"
+
+	"github.com/l-vitaly/gormrepo"
+)
+
+// JobColumns describes the columns of Job for the typed criteria.
+var JobColumns = struct {
+	ID          gormrepo.Column[uint]
+	CreatedAt   gormrepo.Column[time.Time]
+	UpdatedAt   gormrepo.Column[time.Time]
+	DeletedAt   gormrepo.Column[time.Time]
+	Queue       gormrepo.Column[string]
+	Status      gormrepo.Column[string]
+	RunAt       gormrepo.Column[time.Time]
+	Payload     gormrepo.Column[string]
+	Attempts    gormrepo.Column[int]
+	MaxAttempts gormrepo.Column[int]
+	LockedBy    gormrepo.Column[string]
+	HeartbeatAt gormrepo.Column[time.Time]
+	LastError   gormrepo.Column[string]
+}{
+	ID:          gormrepo.NewColumn[uint]("id"),
+	CreatedAt:   gormrepo.NewColumn[time.Time]("created_at"),
+	UpdatedAt:   gormrepo.NewColumn[time.Time]("updated_at"),
+	DeletedAt:   gormrepo.NewColumn[time.Time]("deleted_at"),
+	Queue:       gormrepo.NewColumn[string]("queue"),
+	Status:      gormrepo.NewColumn[string]("status"),
+	RunAt:       gormrepo.NewColumn[time.Time]("run_at"),
+	Payload:     gormrepo.NewColumn[string]("payload"),
+	Attempts:    gormrepo.NewColumn[int]("attempts"),
+	MaxAttempts: gormrepo.NewColumn[int]("max_attempts"),
+	LockedBy:    gormrepo.NewColumn[string]("locked_by"),
+	HeartbeatAt: gormrepo.NewColumn[time.Time]("heartbeat_at"),
+	LastError:   gormrepo.NewColumn[string]("last_error"),
+}
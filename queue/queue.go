@@ -0,0 +1,182 @@
+// Package queue is a database backed job queue built on a generated
+// repository, with retries, backoff and dead-lettering. It needs Go 1.18 for
+// the typed criteria, accepted on a db in the mode of
+// gormrepo.AllowListOnly.
+package queue
+
+import (
+	"errors"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/l-vitaly/gormrepo"
+)
+
+// ErrNotLocked is returned by Heartbeat, Complete and Fail when the job is no
+// longer running for the worker that dequeued it, such as once reclaimed by
+// ReclaimStale and dequeued by another worker.
+var ErrNotLocked = errors.New("job is not running for the worker")
+
+const (
+	DefaultMaxAttempts = 5
+	DefaultTimeout     = 5 * time.Minute
+)
+
+// Backoff returns the delay before the retry following the given attempt.
+type Backoff func(attempt int) time.Duration
+
+// ExponentialBackoff doubles the delay from base on each attempt, up to max.
+func ExponentialBackoff(base, max time.Duration) Backoff {
+	return func(attempt int) time.Duration {
+		delay := base
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		return delay
+	}
+}
+
+type Queue struct {
+	Name        string
+	MaxAttempts int
+	Backoff     Backoff
+	// Timeout after which a running job without heartbeat is reclaimed.
+	Timeout time.Duration
+
+	db   *gorm.DB
+	jobs *jobBaseRepo
+}
+
+func New(db *gorm.DB, name string) *Queue {
+	return &Queue{
+		Name:        name,
+		MaxAttempts: DefaultMaxAttempts,
+		Backoff:     ExponentialBackoff(time.Second, time.Hour),
+		Timeout:     DefaultTimeout,
+		db:          db,
+		jobs:        &jobBaseRepo{db},
+	}
+}
+
+// AutoMigrate creates the job table.
+func (q *Queue) AutoMigrate() error {
+	return q.jobs.AutoMigrate()
+}
+
+// Enqueue adds a job runnable after delay.
+func (q *Queue) Enqueue(payload string, delay time.Duration) (*Job, error) {
+	return q.jobs.Create(Job{
+		Queue:       q.Name,
+		Status:      StatusPending,
+		RunAt:       time.Now().Add(delay),
+		Payload:     payload,
+		MaxAttempts: q.MaxAttempts,
+	})
+}
+
+// Dequeue claims the next runnable job for the worker, or returns nil when
+// there is none. Postgres and MySQL skip rows locked by concurrent workers.
+func (q *Queue) Dequeue(worker string) (*Job, error) {
+	var job *Job
+	err := gormrepo.Transaction(q.db, func(tx *gorm.DB) error {
+		now := time.Now()
+		// No criterion compares two columns: the condition is set on the
+		// transaction, out of the criteria checked on an allow-listed db.
+		candidate, err := (&jobBaseRepo{tx.Where("attempts < max_attempts")}).GetByFirst(
+			gormrepo.Eq(JobColumns.Queue, q.Name),
+			gormrepo.Eq(JobColumns.Status, StatusPending),
+			gormrepo.Lte(JobColumns.RunAt, now),
+			gormrepo.OrderBy("run_at", "ASC", false),
+			gormrepo.LockForUpdate(gormrepo.SkipLocked),
+		)
+		if gormrepo.IsRecordNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		claimed, err := q.claim(tx, candidate, worker, now)
+		if claimed {
+			job = candidate
+		}
+		return err
+	})
+	return job, err
+}
+
+// claim marks the job running, guarded on its pending status and remaining
+// attempts for dialects without row locks.
+func (q *Queue) claim(tx *gorm.DB, job *Job, worker string, now time.Time) (bool, error) {
+	result := tx.Model(job).Where("status = ? AND attempts < max_attempts", StatusPending).Updates(map[string]interface{}{
+		"status":       StatusRunning,
+		"attempts":     job.Attempts + 1,
+		"locked_by":    worker,
+		"heartbeat_at": now,
+	})
+	return result.RowsAffected == 1, result.Error
+}
+
+// Heartbeat records that the worker is still processing the job.
+func (q *Queue) Heartbeat(job *Job) error {
+	return q.release(job, gormrepo.Fields{"heartbeat_at": time.Now()})
+}
+
+// Complete marks the job done.
+func (q *Queue) Complete(job *Job) error {
+	return q.release(job, gormrepo.Fields{"status": StatusDone, "locked_by": ""})
+}
+
+// Fail schedules the job for a retry after the backoff delay, or moves it to
+// the dead letter state once it used all its attempts.
+func (q *Queue) Fail(job *Job, cause error) error {
+	fields := gormrepo.Fields{"locked_by": "", "last_error": cause.Error()}
+	if job.Attempts >= job.MaxAttempts {
+		fields["status"] = StatusDead
+	} else {
+		fields["status"] = StatusPending
+		fields["run_at"] = time.Now().Add(q.Backoff(job.Attempts))
+	}
+	return q.release(job, fields)
+}
+
+// release updates the job, guarded on its running status and the worker
+// locking it, failing with ErrNotLocked when the worker lost the job.
+func (q *Queue) release(job *Job, fields gormrepo.Fields) error {
+	result := q.db.Model(job).Where("status = ? AND locked_by = ?", StatusRunning, job.LockedBy).Updates(fields)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotLocked
+	}
+	return nil
+}
+
+// Dead returns the dead-lettered jobs of the queue.
+func (q *Queue) Dead(criteria ...gormrepo.CriteriaOption) ([]*Job, error) {
+	return q.jobs.GetBy(append([]gormrepo.CriteriaOption{
+		gormrepo.Eq(JobColumns.Queue, q.Name),
+		gormrepo.Eq(JobColumns.Status, StatusDead),
+	}, criteria...)...)
+}
+
+// Retry moves a dead job back to the queue with a fresh set of attempts.
+func (q *Queue) Retry(job *Job) error {
+	return q.jobs.Update(job, gormrepo.Fields{"status": StatusPending, "attempts": 0, "run_at": time.Now()})
+}
+
+// ReclaimStale returns running jobs whose heartbeat is older than the
+// timeout to the queue, for workers that died while processing them, and
+// dead-letters those that used all their attempts.
+func (q *Queue) ReclaimStale() (int64, error) {
+	result := q.db.Model(&Job{}).
+		Where("queue = ? AND status = ? AND heartbeat_at < ?", q.Name, StatusRunning, time.Now().Add(-q.Timeout)).
+		Updates(map[string]interface{}{
+			"status":    gorm.Expr("CASE WHEN attempts >= max_attempts THEN ? ELSE ? END", StatusDead, StatusPending),
+			"locked_by": "",
+		})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,58 @@
+package queue
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/sqlite"
+	"github.com/l-vitaly/gormrepo"
+)
+
+func TestQueueAllowListOnly(t *testing.T) {
+	db, err := gorm.Open("sqlite3", ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	// Every connection would open its own in-memory database.
+	db.DB().SetMaxOpenConns(1)
+	q := New(gormrepo.AllowListOnly(db), "emails")
+	q.MaxAttempts = 1
+	if err := q.AutoMigrate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := q.Enqueue("later", time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	enqueued, err := q.Enqueue("now", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	job, err := q.Dequeue("worker-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job == nil || job.ID != enqueued.ID {
+		t.Fatalf("dequeued %+v, want job %d", job, enqueued.ID)
+	}
+	if job, err := q.Dequeue("worker-2"); err != nil || job != nil {
+		t.Fatalf("dequeue with no runnable job: got %+v, %v", job, err)
+	}
+
+	if err := q.Fail(job, errors.New("unreachable")); err != nil {
+		t.Fatal(err)
+	}
+	dead, err := q.Dead()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dead) != 1 || dead[0].ID != job.ID {
+		t.Errorf("dead jobs: got %d, want job %d", len(dead), job.ID)
+	}
+	if _, err := q.Dead(gormrepo.And("payload = ?", "now")); !errors.Is(err, gormrepo.ErrRawSQLForbidden) {
+		t.Errorf("dead jobs with raw SQL criteria: got %v, want %v", err, gormrepo.ErrRawSQLForbidden)
+	}
+}
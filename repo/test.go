@@ -1,5 +1,7 @@
 package repo
 
+//go:generate gormrepogen -t=Test
+
 type Test struct {
     ID int
 }
@@ -1,24 +1,37 @@
-// Code generated by "gormrepogen -t=Test ./repo"; DO NOT EDIT
+// Code generated by "gormrepogen -t=Test"; DO NOT EDIT
 
 package repo
 
 import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
 	"github.com/l-vitaly/gormrepo"
 )
 
 type testBaseRepo struct {
-	gormrepo.Repo
+	*gorm.DB
+}
+
+func (r *testBaseRepo) applyCriteria(criteria []gormrepo.CriteriaOption) *gorm.DB {
+	// The criteria checking their columns see the model.
+	return gormrepo.ApplyCriteria(r.DB.Model(&Test{}), criteria...)
+}
+
+func (r *testBaseRepo) wrapError(method string, err error, criteria ...gormrepo.CriteriaOption) error {
+	return gormrepo.WrapError(err, "Test", method, criteria...)
 }
 
-func (r *testBaseRepo) Related(claim *Test, related interface{}, criteria ...gormrepo.CriteriaOption) (*Test, error) {
-	err := r.ApplyCriteria(criteria).Model(claim).Related(&related).Error
-	return claim, err
+func (r *testBaseRepo) Related(claim *Test, related interface{}, criteria ...gormrepo.CriteriaOption) error {
+	err := r.applyCriteria(criteria).Model(claim).Related(related).Error
+	return r.wrapError("Related", err, criteria...)
 }
 
 func (r *testBaseRepo) Get(id uint) (*Test, error) {
 	var entity Test
 	err := r.DB.Where(map[string]interface{}{"id": id}).Find(&entity).Error
-	return &entity, err
+	return &entity, r.wrapError("Get", err)
 }
 func (r *testBaseRepo) GetAll() ([]*Test, error) {
 	return r.GetBy()
@@ -26,41 +39,270 @@ func (r *testBaseRepo) GetAll() ([]*Test, error) {
 
 func (r *testBaseRepo) GetBy(criteria ...gormrepo.CriteriaOption) ([]*Test, error) {
 	var entities []*Test
-	err := r.ApplyCriteria(criteria).Find(&entities).Error
-	return entities, err
+	err := r.applyCriteria(criteria).Find(&entities).Error
+	return entities, r.wrapError("GetBy", err, criteria...)
+}
+
+func (r *testBaseRepo) GetByNamed(name string, args ...interface{}) ([]*Test, error) {
+	criteria, err := gormrepo.NamedQuery(name, args...)
+	if err != nil {
+		return nil, r.wrapError("GetByNamed", err)
+	}
+	var entities []*Test
+	err = r.applyCriteria(criteria).Find(&entities).Error
+	return entities, r.wrapError("GetByNamed "+name, err, criteria...)
 }
 
 func (r *testBaseRepo) GetByFirst(criteria ...gormrepo.CriteriaOption) (*Test, error) {
 	var entity Test
-	err := r.ApplyCriteria(criteria).First(&entity).Error
-	return &entity, err
+	err := r.applyCriteria(criteria).First(&entity).Error
+	return &entity, r.wrapError("GetByFirst", err, criteria...)
 }
 
 func (r *testBaseRepo) GetByLast(criteria ...gormrepo.CriteriaOption) (*Test, error) {
 	var entity Test
-	err := r.ApplyCriteria(criteria).Last(&entity).Error
-	return &entity, err
+	err := r.applyCriteria(criteria).Last(&entity).Error
+	return &entity, r.wrapError("GetByLast", err, criteria...)
+}
+
+func (r *testBaseRepo) Count(criteria ...gormrepo.CriteriaOption) (int, error) {
+	var count int
+	err := r.applyCriteria(criteria).Model(&Test{}).Count(&count).Error
+	return count, r.wrapError("Count", err, criteria...)
+}
+
+// RawScan runs the statement sql, with its arguments bound, and scans its
+// rows into dest, see gormrepo.Raw.
+func (r *testBaseRepo) RawScan(dest interface{}, sql string, args ...interface{}) error {
+	raw := gormrepo.Raw(sql, args...)
+	err := r.applyCriteria([]gormrepo.CriteriaOption{raw}).Scan(dest).Error
+	return r.wrapError("RawScan", err, raw)
+}
+
+func (r *testBaseRepo) CountByPeriod(column string, period gormrepo.Period, criteria ...gormrepo.CriteriaOption) (map[time.Time]int64, error) {
+	counts, err := gormrepo.CountByPeriod(r.applyCriteria(criteria).Model(&Test{}), column, period)
+	return counts, r.wrapError("CountByPeriod", err, criteria...)
+}
+
+func (r *testBaseRepo) Percentiles(column string, ps []float64, criteria ...gormrepo.CriteriaOption) ([]float64, error) {
+	values, err := gormrepo.Percentiles(r.applyCriteria(criteria).Model(&Test{}), column, ps...)
+	return values, r.wrapError("Percentiles", err, criteria...)
+}
+
+func (r *testBaseRepo) Histogram(column string, min, max float64, buckets int, criteria ...gormrepo.CriteriaOption) ([]gormrepo.Bucket, error) {
+	histogram, err := gormrepo.Histogram(r.applyCriteria(criteria).Model(&Test{}), column, min, max, buckets)
+	return histogram, r.wrapError("Histogram", err, criteria...)
 }
 
 func (r *testBaseRepo) Create(entity Test) (*Test, error) {
 	if !r.DB.NewRecord(entity) {
-		return nil, gormrepo.ErrPrimaryNotBlank
+		return nil, r.wrapError("Create", gormrepo.ErrPrimaryNotBlank)
 	}
 	err := r.DB.Create(&entity).Error
 	if err != nil {
-		return nil, err
+		return nil, r.wrapError("Create", err)
 	}
 	return &entity, nil
 }
 
-func (r *testBaseRepo) Update(entity *Test, fields gormrepo.Fields, criteria ...gormrepo.CriteriaOption) (*Test, error) {
-	err := r.ApplyCriteria(criteria).Model(&entity).Updates(fields).Error
+// CopyFrom inserts the entities in bulk, see gormrepo.CopyFrom.
+func (r *testBaseRepo) CopyFrom(entities []*Test) error {
+	return r.wrapError("CopyFrom", gormrepo.CopyFrom(r.DB, entities))
+}
+
+func (r *testBaseRepo) CreateContext(ctx context.Context, entity Test) (*Test, error) {
+	key, ok := gormrepo.IdempotencyKeyFromContext(ctx)
+	if !ok {
+		return r.Create(entity)
+	}
+	table := r.DB.NewScope(&entity).TableName()
+	var created *Test
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		repo := &testBaseRepo{tx}
+		id, found, err := gormrepo.FindIdempotencyKey(tx, key, table)
+		if err != nil {
+			return err
+		}
+		if found {
+			created, err = repo.Get(id)
+			return err
+		}
+		if created, err = repo.Create(entity); err != nil {
+			return err
+		}
+		return gormrepo.SaveIdempotencyKey(tx, key, table, uint(created.ID))
+	})
+	if err != nil {
+		return nil, r.wrapError("CreateContext", err)
+	}
+	return created, nil
+}
+
+func (r *testBaseRepo) Update(entity *Test, fields gormrepo.Fields, criteria ...gormrepo.CriteriaOption) error {
+	err := r.applyCriteria(criteria).Model(entity).Updates(fields).Error
+	return r.wrapError("Update", err, criteria...)
+}
+
+// BulkUpdateByKey updates the fields of the rows by primary key, see
+// gormrepo.BulkUpdateByKey.
+func (r *testBaseRepo) BulkUpdateByKey(pairs map[uint]gormrepo.Fields) (int64, error) {
+	n, err := gormrepo.BulkUpdateByKey(r.DB, &Test{}, pairs)
+	return n, r.wrapError("BulkUpdateByKey", err)
+}
+
+func (r *testBaseRepo) Delete(entity *Test, criteria ...gormrepo.CriteriaOption) error {
+	err := r.applyCriteria(criteria).Delete(entity).Error
+	return r.wrapError("Delete", err, criteria...)
+}
+
+// DeleteAndGet deletes the rows matching the criteria and returns them, see
+// gormrepo.DeleteReturning.
+func (r *testBaseRepo) DeleteAndGet(criteria ...gormrepo.CriteriaOption) ([]*Test, error) {
+	var entities []*Test
+	err := gormrepo.DeleteReturning(r.DB, &entities, criteria...)
+	return entities, r.wrapError("DeleteAndGet", err, criteria...)
+}
+
+func (r *testBaseRepo) batchIDs(size int, criteria []gormrepo.CriteriaOption) (string, []interface{}, error) {
+	scope := r.DB.NewScope(&Test{})
+	key := scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())
+	var ids []interface{}
+	err := r.applyCriteria(criteria).Model(&Test{}).Limit(size).Pluck(key, &ids).Error
+	return key, ids, err
+}
+
+func (r *testBaseRepo) DeleteBatch(size int, criteria ...gormrepo.CriteriaOption) (int64, error) {
+	key, ids, err := r.batchIDs(size, criteria)
+	if err != nil || len(ids) == 0 {
+		return 0, r.wrapError("DeleteBatch", err, criteria...)
+	}
+	result := r.applyCriteria(criteria).Where(key+" IN (?)", ids).Delete(&Test{})
+	return result.RowsAffected, r.wrapError("DeleteBatch", result.Error, criteria...)
+}
+
+func (r *testBaseRepo) ArchiveBatch(table string, size int, criteria ...gormrepo.CriteriaOption) (int64, error) {
+	var archived int64
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		repo := &testBaseRepo{tx}
+		key, ids, err := repo.batchIDs(size, criteria)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		insert := "INSERT INTO " + tx.Dialect().Quote(table) + " SELECT * FROM " + tx.NewScope(&Test{}).QuotedTableName() + " WHERE " + key + " IN (?)"
+		if err := tx.Exec(insert, ids).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where(key+" IN (?)", ids).Delete(&Test{})
+		archived = result.RowsAffected
+		return result.Error
+	})
+	return archived, r.wrapError("ArchiveBatch", err, criteria...)
+}
+
+func (r *testBaseRepo) Archive(sink gormrepo.Sink, size int, criteria ...gormrepo.CriteriaOption) (int64, error) {
+	if size <= 0 {
+		size = gormrepo.ArchiveBatchSize
+	}
+	var archived int64
+	for {
+		var n int
+		err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+			scope := tx.NewScope(&Test{})
+			key := scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())
+			var entities []*Test
+			if err := (&testBaseRepo{tx}).applyCriteria(criteria).Order(key).Limit(size).Find(&entities).Error; err != nil {
+				return err
+			}
+			n = len(entities)
+			if n == 0 {
+				return nil
+			}
+			ids := make([]interface{}, 0, n)
+			for _, entity := range entities {
+				if err := sink.Write(entity); err != nil {
+					return err
+				}
+				ids = append(ids, tx.NewScope(entity).PrimaryKeyValue())
+			}
+			if err := sink.Flush(); err != nil {
+				return err
+			}
+			return tx.Unscoped().Where(key+" IN (?)", ids).Delete(&Test{}).Error
+		})
+		if err != nil {
+			return archived, r.wrapError("Archive", err, criteria...)
+		}
+		archived += int64(n)
+		if n < size {
+			return archived, nil
+		}
+	}
+}
+
+// testUniqueIndexes are the unique indexes declared by the tags of Test.
+var testUniqueIndexes = []gormrepo.UniqueIndex{}
+
+// Upsert inserts the entity or updates the row it conflicts with on target,
+// the primary key or one of the unique indexes of Test.
+func (r *testBaseRepo) Upsert(entity *Test, target gormrepo.ConflictTarget, update ...string) error {
+	return r.wrapError("Upsert", gormrepo.Upsert(r.DB, entity, target, testUniqueIndexes, update...))
+}
+
+func (r *testBaseRepo) Checksum(criteria ...gormrepo.CriteriaOption) (string, error) {
+	scope := r.DB.NewScope(&Test{})
+	rows, err := r.applyCriteria(criteria).Model(&Test{}).Order(scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())).Rows()
 	if err != nil {
-		return nil, err
+		return "", r.wrapError("Checksum", err, criteria...)
 	}
-	return entity, nil
+	sum, err := gormrepo.Checksum(rows)
+	return sum, r.wrapError("Checksum", err, criteria...)
+}
+
+func (r *testBaseRepo) Stats() gormrepo.Stats {
+	return gormrepo.TableStats(r.DB.NewScope(&Test{}).TableName())
+}
+
+// SeedDemoData inserts n demo rows, see gormrepo.Seed.
+func (r *testBaseRepo) SeedDemoData(n int) error {
+	return r.wrapError("SeedDemoData", gormrepo.Seed(r.DB, n, &Test{}))
 }
 
 func (r *testBaseRepo) AutoMigrate() error {
-	return r.DB.AutoMigrate(&Test{}).Error
+	return r.wrapError("AutoMigrate", r.DB.AutoMigrate(&Test{}).Error)
+}
+
+// Truncate empties the table, see gormrepo.Truncate.
+func (r *testBaseRepo) Truncate(cascade bool) error {
+	return r.wrapError("Truncate", gormrepo.Truncate(r.DB, cascade, &Test{}))
+}
+
+func (r *testBaseRepo) AddUniqueIndex(name string, columns ...string) error {
+	return r.wrapError("AddUniqueIndex", r.DB.Model(&Test{}).AddUniqueIndex(name, columns...).Error)
+}
+
+func (r *testBaseRepo) AddForeignKey(field string, dest string, onDelete string, onUpdate string) error {
+	return r.wrapError("AddForeignKey", r.DB.Model(&Test{}).AddForeignKey(field, dest, onDelete, onUpdate).Error)
+}
+
+func (r *testBaseRepo) AddIndex(name string, columns ...string) error {
+	return r.wrapError("AddIndex", r.DB.Model(&Test{}).AddIndex(name, columns...).Error)
+}
+
+// TestChanged describes a change of a Test. Old is nil for creates and New
+// for deletes, Fields holds the changed columns.
+type TestChanged struct {
+	Op     gormrepo.Operation
+	Old    *Test
+	New    *Test
+	Fields []string
+}
+
+// Changed builds the change event of the entity, computing the changed columns.
+func (r *testBaseRepo) Changed(op gormrepo.Operation, old, new *Test) TestChanged {
+	return TestChanged{Op: op, Old: old, New: new, Fields: gormrepo.ChangedFields(r.DB, old, new)}
+}
+
+// Debezium returns the change in the Debezium envelope format.
+func (r *testBaseRepo) Debezium(changed TestChanged) gormrepo.DebeziumEnvelope {
+	return gormrepo.NewDebeziumEnvelope(r.DB, changed.Op, changed.Old, changed.New)
 }
@@ -0,0 +1,98 @@
+//go:build go1.18
+
+package repotest
+
+import (
+	"database/sql"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/l-vitaly/gormrepo"
+)
+
+// fuzzBaseline is the value whose SQL the fuzzed values are compared with.
+const fuzzBaseline = "x"
+
+// sqlRecorder is the database of FuzzCriteria, recording the query of the
+// criteria instead of running it.
+type sqlRecorder struct {
+	query string
+}
+
+var errRecorded = errors.New("query recorded")
+
+func (r *sqlRecorder) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return nil, errRecorded
+}
+
+func (r *sqlRecorder) Prepare(query string) (*sql.Stmt, error) {
+	return nil, errRecorded
+}
+
+func (r *sqlRecorder) Query(query string, args ...interface{}) (*sql.Rows, error) {
+	r.query = query
+	return nil, errRecorded
+}
+
+func (r *sqlRecorder) QueryRow(query string, args ...interface{}) *sql.Row {
+	return nil
+}
+
+// FuzzCriteria fuzzes the criteria options build returns for a value, such
+// as a search term from an HTTP request, failing when the value changes the
+// SQL of the query on the model rather than only its bind parameters, the
+// sign of a value interpolated into the SQL. The queries are built for the
+// dialect, whose package must be imported, and never run:
+//
+//	func FuzzUserSearch(f *testing.F) {
+//		repotest.FuzzCriteria(f, "postgres", &User{}, func(value string) []gormrepo.CriteriaOption {
+//			return []gormrepo.CriteriaOption{gormrepo.ILike("email", value, gormrepo.Contains), gormrepo.In("status", value)}
+//		}, "' OR 1=1 --", "%_")
+//	}
+//
+// The empty value, which criteria such as ILikeAny ignore, and the values the
+// criteria reject with an error are skipped.
+func FuzzCriteria(f *testing.F, dialect string, model interface{}, build func(value string) []gormrepo.CriteriaOption, seeds ...string) {
+	f.Helper()
+	want, err := criteriaSQL(dialect, model, build(fuzzBaseline))
+	if err != nil {
+		f.Fatalf("fuzz criteria: %v", err)
+	}
+	for _, seed := range seeds {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, value string) {
+		if value == "" {
+			return
+		}
+		got, err := criteriaSQL(dialect, model, build(value))
+		if err != nil {
+			return
+		}
+		if got != want {
+			t.Errorf("fuzz criteria: value %q is not bound:\n\twant: %s\n\tgot:  %s", value, want, got)
+		}
+	})
+}
+
+// criteriaSQL returns the SQL of the query of the criteria on the model,
+// without its variables.
+func criteriaSQL(dialect string, model interface{}, criteria []gormrepo.CriteriaOption) (string, error) {
+	rec := &sqlRecorder{}
+	db, err := gorm.Open(dialect, rec)
+	if err != nil {
+		return "", err
+	}
+	db.LogMode(false)
+	dest := reflect.New(reflect.Indirect(reflect.ValueOf(model)).Type()).Interface()
+	err = gormrepo.ApplyCriteria(db.Model(model), criteria...).Find(dest).Error
+	if !errors.Is(err, errRecorded) {
+		if err == nil {
+			err = errors.New("no query run")
+		}
+		return "", err
+	}
+	return rec.query, nil
+}
@@ -0,0 +1,93 @@
+package repotest
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+)
+
+// UpdateGoldenEnv names the environment variable rewriting the golden files
+// instead of comparing them, GORMREPO_UPDATE_GOLDEN=1 go test ./...
+const UpdateGoldenEnv = "GORMREPO_UPDATE_GOLDEN"
+
+type recorder struct {
+	mu      sync.Mutex
+	queries []string
+}
+
+func record(scope *gorm.Scope) {
+	if rec, ok := scope.Get("gormrepo:golden"); ok && scope.SQL != "" {
+		r := rec.(*recorder)
+		r.mu.Lock()
+		r.queries = append(r.queries, scope.SQL)
+		r.mu.Unlock()
+	}
+}
+
+// RecordSQL runs fn in a transaction that is rolled back and returns the SQL
+// of the queries run by gorm on the transaction, without their variables.
+// Statements run with Exec are not recorded.
+func RecordSQL(db *gorm.DB, fn func(tx *gorm.DB)) []string {
+	callbacks := db.Callback()
+	if callbacks.Query().Get("gormrepo:golden") == nil {
+		callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("gormrepo:golden", record)
+		callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("gormrepo:golden", record)
+		callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("gormrepo:golden", record)
+		callbacks.Query().After("gorm:after_query").Register("gormrepo:golden", record)
+		callbacks.RowQuery().After("gorm:row_query").Register("gormrepo:golden", record)
+	}
+	rec := &recorder{}
+	tx := db.Begin()
+	defer tx.Rollback()
+	fn(tx.Set("gormrepo:golden", rec))
+	return rec.queries
+}
+
+// AssertGoldenSQL records the SQL run by fn, see RecordSQL, and compares it
+// with testdata/<name>.golden.sql, so changes of the queries produced by the
+// generated methods, for example after a gorm upgrade, fail the test.
+// With GORMREPO_UPDATE_GOLDEN=1 the golden file is written instead.
+func AssertGoldenSQL(t testing.TB, db *gorm.DB, name string, fn func(tx *gorm.DB)) bool {
+	t.Helper()
+	got := strings.Join(RecordSQL(db, fn), ";\n") + ";\n"
+	path := filepath.Join("testdata", name+".golden.sql")
+	if os.Getenv(UpdateGoldenEnv) != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Errorf("golden %s: %v", name, err)
+			return false
+		}
+		if err := ioutil.WriteFile(path, []byte(got), 0644); err != nil {
+			t.Errorf("golden %s: %v", name, err)
+			return false
+		}
+		return true
+	}
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Errorf("golden %s: %v, run with %s=1 to create it", name, err, UpdateGoldenEnv)
+		return false
+	}
+	if got == string(want) {
+		return true
+	}
+	gotLines, wantLines := strings.Split(got, "\n"), strings.Split(string(want), "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			t.Errorf("golden %s: SQL changed at line %d:\n\twant: %s\n\tgot:  %s", name, i+1, w, g)
+			break
+		}
+	}
+	return false
+}
@@ -0,0 +1,80 @@
+// Package repotest provides assertions over generated repositories, and
+// isolated databases, for integration tests.
+package repotest
+
+import (
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	"github.com/l-vitaly/gormrepo"
+)
+
+// Repo is implemented by every generated repository.
+type Repo interface {
+	Count(criteria ...gormrepo.CriteriaOption) (int, error)
+}
+
+func softDeleted(db *gorm.DB) *gorm.DB {
+	return db.Where("deleted_at IS NOT NULL")
+}
+
+func count(t testing.TB, repo Repo, criteria []gormrepo.CriteriaOption) (int, bool) {
+	t.Helper()
+	n, err := repo.Count(criteria...)
+	if err != nil {
+		t.Errorf("count: %v", err)
+		return 0, false
+	}
+	return n, true
+}
+
+// AssertExists checks that at least one row matches the criteria.
+func AssertExists(t testing.TB, repo Repo, criteria ...gormrepo.CriteriaOption) bool {
+	t.Helper()
+	n, ok := count(t, repo, criteria)
+	if ok && n == 0 {
+		t.Errorf("expected a matching row, found none")
+		return false
+	}
+	return ok
+}
+
+// AssertNotExists checks that no row matches the criteria.
+func AssertNotExists(t testing.TB, repo Repo, criteria ...gormrepo.CriteriaOption) bool {
+	t.Helper()
+	return AssertCount(t, repo, 0, criteria...)
+}
+
+// AssertCount checks that exactly n rows match the criteria.
+func AssertCount(t testing.TB, repo Repo, n int, criteria ...gormrepo.CriteriaOption) bool {
+	t.Helper()
+	got, ok := count(t, repo, criteria)
+	if ok && got != n {
+		t.Errorf("expected %d matching rows, found %d", n, got)
+		return false
+	}
+	return ok
+}
+
+// AssertSoftDeleted checks that the rows matching the criteria are hidden
+// by the soft delete scope, and that at least one of them is still stored.
+func AssertSoftDeleted(t testing.TB, repo Repo, criteria ...gormrepo.CriteriaOption) bool {
+	t.Helper()
+	visible, ok := count(t, repo, criteria)
+	if !ok {
+		return false
+	}
+	deleted, ok := count(t, repo, append([]gormrepo.CriteriaOption{gormrepo.Unscoped(), softDeleted}, criteria...))
+	if !ok {
+		return false
+	}
+	switch {
+	case visible > 0:
+		t.Errorf("expected matching rows to be soft deleted, %d are visible", visible)
+		return false
+	case deleted == 0:
+		t.Errorf("expected soft deleted rows, found none")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package repotest
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jinzhu/gorm"
+	_ "github.com/jinzhu/gorm/dialects/postgres"
+	"github.com/l-vitaly/gormrepo"
+	"github.com/lib/pq"
+)
+
+// Template is a Postgres database migrated once and copied, with CREATE
+// DATABASE ... TEMPLATE, into an isolated database per test, so parallel
+// tests and test packages each get a migrated database in milliseconds.
+type Template struct {
+	admin *gorm.DB
+	dsn   string
+	name  string
+}
+
+// NewTemplate returns the template database named name, created and
+// migrated by migrate once for all the test packages of the DSN, whose user
+// must be allowed to create databases. Change the name when migrate changes,
+// as a template of the name is reused as is.
+func NewTemplate(dsn, name string, migrate func(db *gorm.DB) error) (*Template, error) {
+	admin, err := gorm.Open("postgres", dsn)
+	if err != nil {
+		return nil, err
+	}
+	admin.LogMode(false)
+	t := &Template{admin: admin, dsn: dsn, name: name}
+	if err := t.create(migrate); err != nil {
+		admin.Close()
+		return nil, fmt.Errorf("template %s: %w", name, err)
+	}
+	return t, nil
+}
+
+// NewModelsTemplate returns the template migrating the models with
+// AutoMigrate, named after their DDL so that changed models get a new
+// template.
+func NewModelsTemplate(dsn string, models ...interface{}) (*Template, error) {
+	ddl, err := gormrepo.DDL("postgres", models...)
+	if err != nil {
+		return nil, err
+	}
+	sum := sha256.Sum256([]byte(ddl))
+	return NewTemplate(dsn, "gormrepo_tmpl_"+hex.EncodeToString(sum[:6]), func(db *gorm.DB) error {
+		return db.AutoMigrate(models...).Error
+	})
+}
+
+// create creates and migrates the template unless it exists, under an
+// advisory lock taken by the test packages creating it concurrently.
+func (t *Template) create(migrate func(db *gorm.DB) error) error {
+	ctx := context.Background()
+	conn, err := t.admin.DB().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	sum := sha256.Sum256([]byte(t.name))
+	key := int64(binary.BigEndian.Uint64(sum[:8]))
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", key); err != nil {
+		return err
+	}
+	defer conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", key)
+
+	var ready bool
+	err = conn.QueryRowContext(ctx, "SELECT datistemplate FROM pg_database WHERE datname = $1", t.name).Scan(&ready)
+	switch {
+	case err == nil && ready:
+		return nil
+	case err == nil:
+		// Left by a failed migration.
+		if _, err := conn.ExecContext(ctx, "DROP DATABASE "+pq.QuoteIdentifier(t.name)); err != nil {
+			return err
+		}
+	case !errors.Is(err, sql.ErrNoRows):
+		return err
+	}
+	if _, err := conn.ExecContext(ctx, "CREATE DATABASE "+pq.QuoteIdentifier(t.name)); err != nil {
+		return err
+	}
+	db, err := gorm.Open("postgres", withDatabase(t.dsn, t.name))
+	if err != nil {
+		return err
+	}
+	err = migrate(db)
+	// A template cannot be copied while connected to.
+	db.Close()
+	if err != nil {
+		return err
+	}
+	_, err = conn.ExecContext(ctx, "ALTER DATABASE "+pq.QuoteIdentifier(t.name)+" WITH IS_TEMPLATE true")
+	return err
+}
+
+// DB returns a database copied from the template for the test, closed and
+// dropped when the test and its subtests complete.
+func (t *Template) DB(tb testing.TB) *gorm.DB {
+	tb.Helper()
+	var suffix [6]byte
+	if _, err := rand.Read(suffix[:]); err != nil {
+		tb.Fatalf("template %s: %v", t.name, err)
+	}
+	name := t.name + "_" + hex.EncodeToString(suffix[:])
+	if err := t.admin.Exec("CREATE DATABASE " + pq.QuoteIdentifier(name) + " TEMPLATE " + pq.QuoteIdentifier(t.name)).Error; err != nil {
+		tb.Fatalf("template %s: %v", t.name, err)
+	}
+	db, err := gorm.Open("postgres", withDatabase(t.dsn, name))
+	if err != nil {
+		t.admin.Exec("DROP DATABASE " + pq.QuoteIdentifier(name))
+		tb.Fatalf("template %s: %v", t.name, err)
+	}
+	tb.Cleanup(func() {
+		db.Close()
+		if err := t.admin.Exec("DROP DATABASE IF EXISTS " + pq.QuoteIdentifier(name)).Error; err != nil {
+			tb.Errorf("template %s: drop %s: %v", t.name, name, err)
+		}
+	})
+	return db
+}
+
+// Close closes the connection of the template, keeping the template for the
+// next runs.
+func (t *Template) Close() error {
+	return t.admin.Close()
+}
+
+// withDatabase returns the DSN, a URL or key=value pairs, connecting to the
+// database name.
+func withDatabase(dsn, name string) string {
+	if strings.Contains(dsn, "://") {
+		if u, err := url.Parse(dsn); err == nil {
+			u.Path = "/" + name
+			return u.String()
+		}
+	}
+	// The last of repeated keys applies.
+	return dsn + " dbname=" + name
+}
@@ -0,0 +1,151 @@
+// Package retention runs per-entity retention policies, deleting or
+// archiving expired rows in batches through the generated repositories.
+package retention
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/l-vitaly/gormrepo"
+)
+
+const DefaultBatchSize = 1000
+
+// Repo is implemented by every generated repository.
+type Repo interface {
+	DeleteBatch(size int, criteria ...gormrepo.CriteriaOption) (int64, error)
+	ArchiveBatch(table string, size int, criteria ...gormrepo.CriteriaOption) (int64, error)
+}
+
+// Policy selects the expired rows of an entity with Criteria. Rows are moved
+// to ArchiveTable, which must have the same columns, or deleted when it is
+// empty. Deleting keeps gorm's soft delete unless the criteria are unscoped.
+type Policy struct {
+	Name         string
+	Repo         Repo
+	Criteria     []gormrepo.CriteriaOption
+	ArchiveTable string
+	BatchSize    int
+}
+
+// Progress is reported after each batch.
+type Progress struct {
+	Policy    string
+	Batch     int
+	Processed int64 // Rows in this batch.
+	Total     int64 // Rows processed by the policy so far.
+}
+
+type Runner struct {
+	Policies []Policy
+	// Pause between batches, to leave room to the regular workload.
+	Pause    time.Duration
+	Progress func(Progress)
+}
+
+// Run applies the policies in order until no expired rows are left or ctx is done.
+func (r *Runner) Run(ctx context.Context) error {
+	for _, policy := range r.Policies {
+		if err := r.run(ctx, policy); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *Runner) run(ctx context.Context, policy Policy) error {
+	size := policy.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	progress := Progress{Policy: policy.Name}
+	for {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		var n int64
+		var err error
+		if policy.ArchiveTable != "" {
+			n, err = policy.Repo.ArchiveBatch(policy.ArchiveTable, size, policy.Criteria...)
+		} else {
+			n, err = policy.Repo.DeleteBatch(size, policy.Criteria...)
+		}
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+		progress.Batch++
+		progress.Processed = n
+		progress.Total += n
+		if r.Progress != nil {
+			r.Progress(progress)
+		}
+		if n < int64(size) {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(r.Pause):
+		}
+	}
+}
+
+// OlderThan matches rows whose column is older than age when the query runs.
+// It is described as the op "retention.older_than".
+func OlderThan(column string, age time.Duration) gormrepo.CriteriaOption {
+	return gormrepo.Described(gormrepo.Criterion{Op: "retention.older_than", Column: column, Args: []interface{}{age}}, func(db *gorm.DB) *gorm.DB {
+		return db.Where(column+" < ?", time.Now().Add(-age))
+	})
+}
+
+// SoftDeletedOlderThan matches rows soft deleted more than age ago. The
+// query is unscoped, so the matching rows are deleted permanently. It is
+// described as the op "retention.soft_deleted_older_than".
+func SoftDeletedOlderThan(age time.Duration) gormrepo.CriteriaOption {
+	return gormrepo.Described(gormrepo.Criterion{Op: "retention.soft_deleted_older_than", Args: []interface{}{age}}, func(db *gorm.DB) *gorm.DB {
+		return db.Unscoped().Where("deleted_at < ?", time.Now().Add(-age))
+	})
+}
+
+func init() {
+	gormrepo.RegisterCriterion("retention.older_than", func(c gormrepo.Criterion) (gormrepo.CriteriaOption, error) {
+		age, err := criterionAge(c)
+		if err != nil {
+			return nil, err
+		}
+		return OlderThan(c.Column, age), nil
+	})
+	gormrepo.RegisterCriterion("retention.soft_deleted_older_than", func(c gormrepo.Criterion) (gormrepo.CriteriaOption, error) {
+		age, err := criterionAge(c)
+		if err != nil {
+			return nil, err
+		}
+		return SoftDeletedOlderThan(age), nil
+	})
+}
+
+// criterionAge returns the age argument of the criterion, a number of
+// nanoseconds once decoded from JSON.
+func criterionAge(c gormrepo.Criterion) (time.Duration, error) {
+	if len(c.Args) == 1 {
+		switch age := c.Args[0].(type) {
+		case time.Duration:
+			return age, nil
+		case float64:
+			if age == math.Trunc(age) && math.Abs(age) < math.MaxInt64 {
+				return time.Duration(age), nil
+			}
+		case int64:
+			return time.Duration(age), nil
+		case int:
+			return time.Duration(age), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", gormrepo.ErrInvalidCriterion, c)
+}
@@ -0,0 +1,98 @@
+package gormrepo
+
+import (
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// RolloutStats counts the operations routed to each implementation by a
+// rollout decorator.
+type RolloutStats struct {
+	Primary     OpStats
+	Alternative OpStats
+}
+
+// Rollout routes a percentage of the operations of each entity to an
+// alternative implementation, for gradual rollouts of storage changes. It is
+// safe for concurrent use and can be changed while serving.
+type Rollout struct {
+	mu      sync.Mutex
+	percent map[string]float64
+	stats   map[string]*RolloutStats
+}
+
+func NewRollout() *Rollout {
+	return &Rollout{percent: map[string]float64{}, stats: map[string]*RolloutStats{}}
+}
+
+// Set routes percent (0 to 100) of the operation of the entity to the
+// alternative implementation. Op is a repository method name, or "*" for the
+// operations without their own percentage.
+func (r *Rollout) Set(entity, op string, percent float64) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.percent[entity+"."+op] = percent
+}
+
+// Percent returns the percentage of the operation routed to the alternative.
+func (r *Rollout) Percent(entity, op string) float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.percentOf(entity, op)
+}
+
+func (r *Rollout) percentOf(entity, op string) float64 {
+	if percent, ok := r.percent[entity+"."+op]; ok {
+		return percent
+	}
+	return r.percent[entity+".*"]
+}
+
+// Route reports whether the operation goes to the alternative. Operations
+// with a key, such as the primary key of the entity, are routed the same way
+// as long as the percentage does not change, others at random.
+func (r *Rollout) Route(entity, op, key string) bool {
+	percent := r.Percent(entity, op)
+	switch {
+	case percent <= 0:
+		return false
+	case percent >= 100:
+		return true
+	case key == "":
+		return rand.Float64()*100 < percent
+	}
+	h := fnv.New32a()
+	h.Write([]byte(entity + "." + key))
+	return float64(h.Sum32()%10000) < percent*100
+}
+
+// Record counts an operation run on the primary or the alternative. Record
+// not found errors are not counted as failures.
+func (r *Rollout) Record(entity, op string, alternative bool, latency time.Duration, err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.stats[entity+"."+op]
+	if !ok {
+		s = &RolloutStats{}
+		r.stats[entity+"."+op] = s
+	}
+	failed := err != nil && !IsRecordNotFound(err)
+	if alternative {
+		s.Alternative.add(latency, failed)
+	} else {
+		s.Primary.add(latency, failed)
+	}
+}
+
+// Stats returns the statistics of the operations, by <Entity>.<op>.
+func (r *Rollout) Stats() map[string]RolloutStats {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	result := make(map[string]RolloutStats, len(r.stats))
+	for key, s := range r.stats {
+		result[key] = RolloutStats{Primary: s.Primary.snapshot(), Alternative: s.Alternative.snapshot()}
+	}
+	return result
+}
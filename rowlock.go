@@ -0,0 +1,71 @@
+package gormrepo
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+)
+
+// LockWait is how the row locks of LockForUpdate and LockForShare treat the
+// rows already locked by other transactions, waiting for them by default.
+type LockWait string
+
+const (
+	SkipLocked LockWait = "skip_locked" // Leaves the locked rows out.
+	NoWait     LockWait = "nowait"      // Fails at once on a locked row.
+)
+
+// LockForUpdate locks the rows read, with FOR UPDATE, until the end of the
+// enclosing transaction, for read-modify-write workflows such as
+// GetByFirst then Update. Outside of a transaction the call fails with
+// ErrNoTransaction, since the lock would be released at once. It does nothing
+// on SQLite, which locks the whole database on write, and fails with
+// ErrUnsupported on other dialects than Postgres and MySQL. SkipLocked and
+// NoWait need MySQL 8.0.
+func LockForUpdate(wait ...LockWait) CriteriaOption {
+	return rowLock("lock_for_update", "FOR UPDATE", wait)
+}
+
+// LockForShare locks the rows read against updates, with FOR SHARE, or LOCK
+// IN SHARE MODE on MySQL without wait, as LockForUpdate does.
+func LockForShare(wait ...LockWait) CriteriaOption {
+	return rowLock("lock_for_share", "FOR SHARE", wait)
+}
+
+func rowLock(op, clause string, wait []LockWait) CriteriaOption {
+	var w LockWait
+	var args []interface{}
+	if len(wait) > 0 && wait[0] != "" {
+		w = wait[0]
+		args = []interface{}{string(w)}
+	}
+	return Described(Criterion{Op: op, Args: args}, func(db *gorm.DB) *gorm.DB {
+		if _, ok := db.CommonDB().(*sql.Tx); !ok {
+			return withError(db, ErrNoTransaction)
+		}
+		lock := clause
+		switch db.Dialect().GetName() {
+		case "sqlite3":
+			return db
+		case "postgres":
+		case "mysql":
+			if op == "lock_for_share" && w == "" {
+				// Also understood before MySQL 8.0.
+				lock = "LOCK IN SHARE MODE"
+			}
+		default:
+			return withError(db, ErrUnsupported)
+		}
+		switch w {
+		case "":
+		case SkipLocked:
+			lock += " SKIP LOCKED"
+		case NoWait:
+			lock += " NOWAIT"
+		default:
+			return withError(db, fmt.Errorf("%w: lock wait %q", ErrInvalidCriterion, w))
+		}
+		return db.Set("gorm:query_option", lock)
+	})
+}
@@ -0,0 +1,116 @@
+// Package saga runs sequences of repository operations with compensations,
+// persisting the progress so an interrupted saga can be resumed.
+package saga
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jinzhu/gorm"
+	"github.com/l-vitaly/gormrepo"
+)
+
+var (
+	ErrUnknownSaga = errors.New("saga name does not match state")
+	ErrFinished    = errors.New("saga already finished")
+)
+
+// Step is a saga operation. Action runs in a transaction together with the
+// state update recording its completion, Compensate undoes it when a later
+// step fails and may be nil for steps without side effects.
+type Step struct {
+	Name       string
+	Action     func(tx *gorm.DB) error
+	Compensate func(tx *gorm.DB) error
+}
+
+type Saga struct {
+	Name  string
+	Steps []Step
+}
+
+type Runner struct {
+	db     *gorm.DB
+	states *stateBaseRepo
+}
+
+func NewRunner(db *gorm.DB) *Runner {
+	return &Runner{db: db, states: &stateBaseRepo{db}}
+}
+
+// AutoMigrate creates the saga state table.
+func (r *Runner) AutoMigrate() error {
+	return r.states.AutoMigrate()
+}
+
+// Run executes the steps in order. When a step fails the completed steps are
+// compensated in reverse order and the step error is returned.
+func (r *Runner) Run(s Saga) (*State, error) {
+	state, err := r.states.Create(State{Name: s.Name, Status: StatusRunning})
+	if err != nil {
+		return nil, err
+	}
+	return state, r.run(s, state)
+}
+
+// Resume continues the saga run recorded in the state with the id, after the
+// process running it stopped.
+func (r *Runner) Resume(s Saga, id uint) (*State, error) {
+	state, err := r.states.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if state.Name != s.Name {
+		return state, ErrUnknownSaga
+	}
+	switch state.Status {
+	case StatusRunning:
+		return state, r.run(s, state)
+	case StatusCompensating:
+		return state, r.compensate(s, state, errors.New(state.Error))
+	}
+	return state, ErrFinished
+}
+
+func (r *Runner) run(s Saga, state *State) error {
+	for state.Step < len(s.Steps) {
+		step := s.Steps[state.Step]
+		err := gormrepo.Transaction(r.db, func(tx *gorm.DB) error {
+			if err := step.Action(tx); err != nil {
+				return err
+			}
+			return (&stateBaseRepo{tx}).Update(state, gormrepo.Fields{"step": state.Step + 1})
+		})
+		if err != nil {
+			cause := fmt.Errorf("saga %s: step %s: %v", s.Name, step.Name, err)
+			if err := r.states.Update(state, gormrepo.Fields{"status": StatusCompensating, "error": cause.Error()}); err != nil {
+				return err
+			}
+			return r.compensate(s, state, cause)
+		}
+	}
+	return r.states.Update(state, gormrepo.Fields{"status": StatusCompleted})
+}
+
+func (r *Runner) compensate(s Saga, state *State, cause error) error {
+	for state.Step > 0 {
+		step := s.Steps[state.Step-1]
+		err := gormrepo.Transaction(r.db, func(tx *gorm.DB) error {
+			if step.Compensate != nil {
+				if err := step.Compensate(tx); err != nil {
+					return err
+				}
+			}
+			return (&stateBaseRepo{tx}).Update(state, gormrepo.Fields{"step": state.Step - 1})
+		})
+		if err != nil {
+			failure := fmt.Errorf("%v; compensating %s: %v", cause, step.Name, err)
+			r.states.Update(state, gormrepo.Fields{"status": StatusFailed, "error": failure.Error()})
+			return failure
+		}
+	}
+	if err := r.states.Update(state, gormrepo.Fields{"status": StatusCompensated}); err != nil {
+		return err
+	}
+	return cause
+}
@@ -0,0 +1,28 @@
+package saga
+
+import "github.com/jinzhu/gorm"
+
+//go:generate gormrepogen -t=State
+
+const (
+	StatusRunning      = "running"
+	StatusCompleted    = "completed"
+	StatusCompensating = "compensating"
+	StatusCompensated  = "compensated"
+	StatusFailed       = "failed"
+)
+
+// State is the persisted progress of a saga run. Step is the number of
+// completed steps, counting down again while compensating.
+type State struct {
+	gorm.Model
+	Name   string `gorm:"index"`
+	Status string `gorm:"index"`
+	Step   int
+	Error  string `gorm:"type:text"`
+}
+
+// TableName keeps the saga state apart from application tables.
+func (State) TableName() string {
+	return "saga_states"
+}
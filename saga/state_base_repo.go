@@ -0,0 +1,308 @@
+// Code generated by "gormrepogen -t=State"; DO NOT EDIT
+
+package saga
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/l-vitaly/gormrepo"
+)
+
+type stateBaseRepo struct {
+	*gorm.DB
+}
+
+func (r *stateBaseRepo) applyCriteria(criteria []gormrepo.CriteriaOption) *gorm.DB {
+	// The criteria checking their columns see the model.
+	return gormrepo.ApplyCriteria(r.DB.Model(&State{}), criteria...)
+}
+
+func (r *stateBaseRepo) wrapError(method string, err error, criteria ...gormrepo.CriteriaOption) error {
+	return gormrepo.WrapError(err, "State", method, criteria...)
+}
+
+func (r *stateBaseRepo) Related(claim *State, related interface{}, criteria ...gormrepo.CriteriaOption) error {
+	err := r.applyCriteria(criteria).Model(claim).Related(related).Error
+	return r.wrapError("Related", err, criteria...)
+}
+
+func (r *stateBaseRepo) Get(id uint) (*State, error) {
+	var entity State
+	err := r.DB.Where(map[string]interface{}{"id": id}).Find(&entity).Error
+	return &entity, r.wrapError("Get", err)
+}
+func (r *stateBaseRepo) GetAll() ([]*State, error) {
+	return r.GetBy()
+}
+
+func (r *stateBaseRepo) GetBy(criteria ...gormrepo.CriteriaOption) ([]*State, error) {
+	var entities []*State
+	err := r.applyCriteria(criteria).Find(&entities).Error
+	return entities, r.wrapError("GetBy", err, criteria...)
+}
+
+func (r *stateBaseRepo) GetByNamed(name string, args ...interface{}) ([]*State, error) {
+	criteria, err := gormrepo.NamedQuery(name, args...)
+	if err != nil {
+		return nil, r.wrapError("GetByNamed", err)
+	}
+	var entities []*State
+	err = r.applyCriteria(criteria).Find(&entities).Error
+	return entities, r.wrapError("GetByNamed "+name, err, criteria...)
+}
+
+func (r *stateBaseRepo) GetByFirst(criteria ...gormrepo.CriteriaOption) (*State, error) {
+	var entity State
+	err := r.applyCriteria(criteria).First(&entity).Error
+	return &entity, r.wrapError("GetByFirst", err, criteria...)
+}
+
+func (r *stateBaseRepo) GetByLast(criteria ...gormrepo.CriteriaOption) (*State, error) {
+	var entity State
+	err := r.applyCriteria(criteria).Last(&entity).Error
+	return &entity, r.wrapError("GetByLast", err, criteria...)
+}
+
+func (r *stateBaseRepo) Count(criteria ...gormrepo.CriteriaOption) (int, error) {
+	var count int
+	err := r.applyCriteria(criteria).Model(&State{}).Count(&count).Error
+	return count, r.wrapError("Count", err, criteria...)
+}
+
+// RawScan runs the statement sql, with its arguments bound, and scans its
+// rows into dest, see gormrepo.Raw.
+func (r *stateBaseRepo) RawScan(dest interface{}, sql string, args ...interface{}) error {
+	raw := gormrepo.Raw(sql, args...)
+	err := r.applyCriteria([]gormrepo.CriteriaOption{raw}).Scan(dest).Error
+	return r.wrapError("RawScan", err, raw)
+}
+
+func (r *stateBaseRepo) CountByPeriod(column string, period gormrepo.Period, criteria ...gormrepo.CriteriaOption) (map[time.Time]int64, error) {
+	counts, err := gormrepo.CountByPeriod(r.applyCriteria(criteria).Model(&State{}), column, period)
+	return counts, r.wrapError("CountByPeriod", err, criteria...)
+}
+
+func (r *stateBaseRepo) Percentiles(column string, ps []float64, criteria ...gormrepo.CriteriaOption) ([]float64, error) {
+	values, err := gormrepo.Percentiles(r.applyCriteria(criteria).Model(&State{}), column, ps...)
+	return values, r.wrapError("Percentiles", err, criteria...)
+}
+
+func (r *stateBaseRepo) Histogram(column string, min, max float64, buckets int, criteria ...gormrepo.CriteriaOption) ([]gormrepo.Bucket, error) {
+	histogram, err := gormrepo.Histogram(r.applyCriteria(criteria).Model(&State{}), column, min, max, buckets)
+	return histogram, r.wrapError("Histogram", err, criteria...)
+}
+
+func (r *stateBaseRepo) Create(entity State) (*State, error) {
+	if !r.DB.NewRecord(entity) {
+		return nil, r.wrapError("Create", gormrepo.ErrPrimaryNotBlank)
+	}
+	err := r.DB.Create(&entity).Error
+	if err != nil {
+		return nil, r.wrapError("Create", err)
+	}
+	return &entity, nil
+}
+
+// CopyFrom inserts the entities in bulk, see gormrepo.CopyFrom.
+func (r *stateBaseRepo) CopyFrom(entities []*State) error {
+	return r.wrapError("CopyFrom", gormrepo.CopyFrom(r.DB, entities))
+}
+
+func (r *stateBaseRepo) CreateContext(ctx context.Context, entity State) (*State, error) {
+	key, ok := gormrepo.IdempotencyKeyFromContext(ctx)
+	if !ok {
+		return r.Create(entity)
+	}
+	table := r.DB.NewScope(&entity).TableName()
+	var created *State
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		repo := &stateBaseRepo{tx}
+		id, found, err := gormrepo.FindIdempotencyKey(tx, key, table)
+		if err != nil {
+			return err
+		}
+		if found {
+			created, err = repo.Get(id)
+			return err
+		}
+		if created, err = repo.Create(entity); err != nil {
+			return err
+		}
+		return gormrepo.SaveIdempotencyKey(tx, key, table, uint(created.ID))
+	})
+	if err != nil {
+		return nil, r.wrapError("CreateContext", err)
+	}
+	return created, nil
+}
+
+func (r *stateBaseRepo) Update(entity *State, fields gormrepo.Fields, criteria ...gormrepo.CriteriaOption) error {
+	err := r.applyCriteria(criteria).Model(entity).Updates(fields).Error
+	return r.wrapError("Update", err, criteria...)
+}
+
+// BulkUpdateByKey updates the fields of the rows by primary key, see
+// gormrepo.BulkUpdateByKey.
+func (r *stateBaseRepo) BulkUpdateByKey(pairs map[uint]gormrepo.Fields) (int64, error) {
+	n, err := gormrepo.BulkUpdateByKey(r.DB, &State{}, pairs)
+	return n, r.wrapError("BulkUpdateByKey", err)
+}
+
+func (r *stateBaseRepo) Delete(entity *State, criteria ...gormrepo.CriteriaOption) error {
+	err := r.applyCriteria(criteria).Delete(entity).Error
+	return r.wrapError("Delete", err, criteria...)
+}
+
+// DeleteAndGet deletes the rows matching the criteria and returns them, see
+// gormrepo.DeleteReturning.
+func (r *stateBaseRepo) DeleteAndGet(criteria ...gormrepo.CriteriaOption) ([]*State, error) {
+	var entities []*State
+	err := gormrepo.DeleteReturning(r.DB, &entities, criteria...)
+	return entities, r.wrapError("DeleteAndGet", err, criteria...)
+}
+
+func (r *stateBaseRepo) batchIDs(size int, criteria []gormrepo.CriteriaOption) (string, []interface{}, error) {
+	scope := r.DB.NewScope(&State{})
+	key := scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())
+	var ids []interface{}
+	err := r.applyCriteria(criteria).Model(&State{}).Limit(size).Pluck(key, &ids).Error
+	return key, ids, err
+}
+
+func (r *stateBaseRepo) DeleteBatch(size int, criteria ...gormrepo.CriteriaOption) (int64, error) {
+	key, ids, err := r.batchIDs(size, criteria)
+	if err != nil || len(ids) == 0 {
+		return 0, r.wrapError("DeleteBatch", err, criteria...)
+	}
+	result := r.applyCriteria(criteria).Where(key+" IN (?)", ids).Delete(&State{})
+	return result.RowsAffected, r.wrapError("DeleteBatch", result.Error, criteria...)
+}
+
+func (r *stateBaseRepo) ArchiveBatch(table string, size int, criteria ...gormrepo.CriteriaOption) (int64, error) {
+	var archived int64
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		repo := &stateBaseRepo{tx}
+		key, ids, err := repo.batchIDs(size, criteria)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		insert := "INSERT INTO " + tx.Dialect().Quote(table) + " SELECT * FROM " + tx.NewScope(&State{}).QuotedTableName() + " WHERE " + key + " IN (?)"
+		if err := tx.Exec(insert, ids).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where(key+" IN (?)", ids).Delete(&State{})
+		archived = result.RowsAffected
+		return result.Error
+	})
+	return archived, r.wrapError("ArchiveBatch", err, criteria...)
+}
+
+func (r *stateBaseRepo) Archive(sink gormrepo.Sink, size int, criteria ...gormrepo.CriteriaOption) (int64, error) {
+	if size <= 0 {
+		size = gormrepo.ArchiveBatchSize
+	}
+	var archived int64
+	for {
+		var n int
+		err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+			scope := tx.NewScope(&State{})
+			key := scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())
+			var entities []*State
+			if err := (&stateBaseRepo{tx}).applyCriteria(criteria).Order(key).Limit(size).Find(&entities).Error; err != nil {
+				return err
+			}
+			n = len(entities)
+			if n == 0 {
+				return nil
+			}
+			ids := make([]interface{}, 0, n)
+			for _, entity := range entities {
+				if err := sink.Write(entity); err != nil {
+					return err
+				}
+				ids = append(ids, tx.NewScope(entity).PrimaryKeyValue())
+			}
+			if err := sink.Flush(); err != nil {
+				return err
+			}
+			return tx.Unscoped().Where(key+" IN (?)", ids).Delete(&State{}).Error
+		})
+		if err != nil {
+			return archived, r.wrapError("Archive", err, criteria...)
+		}
+		archived += int64(n)
+		if n < size {
+			return archived, nil
+		}
+	}
+}
+
+// stateUniqueIndexes are the unique indexes declared by the tags of State.
+var stateUniqueIndexes = []gormrepo.UniqueIndex{}
+
+// Upsert inserts the entity or updates the row it conflicts with on target,
+// the primary key or one of the unique indexes of State.
+func (r *stateBaseRepo) Upsert(entity *State, target gormrepo.ConflictTarget, update ...string) error {
+	return r.wrapError("Upsert", gormrepo.Upsert(r.DB, entity, target, stateUniqueIndexes, update...))
+}
+
+func (r *stateBaseRepo) Checksum(criteria ...gormrepo.CriteriaOption) (string, error) {
+	scope := r.DB.NewScope(&State{})
+	rows, err := r.applyCriteria(criteria).Model(&State{}).Order(scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())).Rows()
+	if err != nil {
+		return "", r.wrapError("Checksum", err, criteria...)
+	}
+	sum, err := gormrepo.Checksum(rows)
+	return sum, r.wrapError("Checksum", err, criteria...)
+}
+
+func (r *stateBaseRepo) Stats() gormrepo.Stats {
+	return gormrepo.TableStats(r.DB.NewScope(&State{}).TableName())
+}
+
+// SeedDemoData inserts n demo rows, see gormrepo.Seed.
+func (r *stateBaseRepo) SeedDemoData(n int) error {
+	return r.wrapError("SeedDemoData", gormrepo.Seed(r.DB, n, &State{}))
+}
+
+func (r *stateBaseRepo) AutoMigrate() error {
+	return r.wrapError("AutoMigrate", r.DB.AutoMigrate(&State{}).Error)
+}
+
+// Truncate empties the table, see gormrepo.Truncate.
+func (r *stateBaseRepo) Truncate(cascade bool) error {
+	return r.wrapError("Truncate", gormrepo.Truncate(r.DB, cascade, &State{}))
+}
+
+func (r *stateBaseRepo) AddUniqueIndex(name string, columns ...string) error {
+	return r.wrapError("AddUniqueIndex", r.DB.Model(&State{}).AddUniqueIndex(name, columns...).Error)
+}
+
+func (r *stateBaseRepo) AddForeignKey(field string, dest string, onDelete string, onUpdate string) error {
+	return r.wrapError("AddForeignKey", r.DB.Model(&State{}).AddForeignKey(field, dest, onDelete, onUpdate).Error)
+}
+
+func (r *stateBaseRepo) AddIndex(name string, columns ...string) error {
+	return r.wrapError("AddIndex", r.DB.Model(&State{}).AddIndex(name, columns...).Error)
+}
+
+// StateChanged describes a change of a State. Old is nil for creates and New
+// for deletes, Fields holds the changed columns.
+type StateChanged struct {
+	Op     gormrepo.Operation
+	Old    *State
+	New    *State
+	Fields []string
+}
+
+// Changed builds the change event of the entity, computing the changed columns.
+func (r *stateBaseRepo) Changed(op gormrepo.Operation, old, new *State) StateChanged {
+	return StateChanged{Op: op, Old: old, New: new, Fields: gormrepo.ChangedFields(r.DB, old, new)}
+}
+
+// Debezium returns the change in the Debezium envelope format.
+func (r *stateBaseRepo) Debezium(changed StateChanged) gormrepo.DebeziumEnvelope {
+	return gormrepo.NewDebeziumEnvelope(r.DB, changed.Op, changed.Old, changed.New)
+}
@@ -0,0 +1,226 @@
+package gormrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// DriftKind is the kind of a difference between a model and its table.
+type DriftKind string
+
+const (
+	MissingTable  DriftKind = "missing table"
+	MissingColumn DriftKind = "missing column"
+	TypeMismatch  DriftKind = "type mismatch"
+	MissingIndex  DriftKind = "missing index"
+)
+
+// Drift is a difference between a model and its table in the database.
+type Drift struct {
+	Kind     DriftKind
+	Table    string
+	Column   string // Column of MissingColumn and TypeMismatch drifts.
+	Index    string // Index of MissingIndex drifts.
+	Expected string // Column type of the model.
+	Actual   string // Column type in the database.
+}
+
+func (d Drift) String() string {
+	switch d.Kind {
+	case MissingTable:
+		return fmt.Sprintf("%s %s", d.Kind, d.Table)
+	case MissingIndex:
+		return fmt.Sprintf("%s %s on %s", d.Kind, d.Index, d.Table)
+	case TypeMismatch:
+		return fmt.Sprintf("%s %s.%s: %s, expected %s", d.Kind, d.Table, d.Column, d.Actual, d.Expected)
+	}
+	return fmt.Sprintf("%s %s.%s", d.Kind, d.Table, d.Column)
+}
+
+// SchemaReport lists the drifts found by ValidateSchema.
+type SchemaReport struct {
+	Drifts []Drift
+}
+
+// OK reports whether the database matches the models.
+func (r *SchemaReport) OK() bool {
+	return len(r.Drifts) == 0
+}
+
+func (r *SchemaReport) String() string {
+	lines := make([]string, len(r.Drifts))
+	for i, d := range r.Drifts {
+		lines[i] = d.String()
+	}
+	return strings.Join(lines, "; ")
+}
+
+// ValidateSchema compares the tables of models with the database, reading
+// information_schema on Postgres and MySQL and the table pragmas on SQLite.
+// It reports missing tables, columns and indexes, and columns whose base
+// type differs from the one the model migrates to, lengths and precisions
+// are not compared. The error wraps ErrSchemaDrift when the report is not OK.
+func ValidateSchema(ctx context.Context, db *gorm.DB, models ...interface{}) (*SchemaReport, error) {
+	q, ok := db.CommonDB().(queryer)
+	if !ok {
+		return nil, ErrUnsupported
+	}
+	dialect := db.Dialect().GetName()
+	if dialect != "postgres" && dialect != "mysql" && dialect != "sqlite3" {
+		return nil, ErrUnsupported
+	}
+
+	report := &SchemaReport{}
+	for _, model := range models {
+		scope := db.NewScope(model)
+		table := scope.TableName()
+		columns, err := tableColumns(ctx, q, dialect, table)
+		if err != nil {
+			return nil, err
+		}
+		if len(columns) == 0 {
+			report.Drifts = append(report.Drifts, Drift{Kind: MissingTable, Table: table})
+			continue
+		}
+
+		for _, field := range scope.GetModelStruct().StructFields {
+			if !field.IsNormal || field.IsIgnored {
+				continue
+			}
+			actual, ok := columns[field.DBName]
+			if !ok {
+				report.Drifts = append(report.Drifts, Drift{Kind: MissingColumn, Table: table, Column: field.DBName})
+				continue
+			}
+			expected := scope.Dialect().DataTypeOf(field)
+			if baseType(dialect, expected) != baseType(dialect, actual) {
+				report.Drifts = append(report.Drifts, Drift{
+					Kind: TypeMismatch, Table: table, Column: field.DBName,
+					Expected: expected, Actual: actual,
+				})
+			}
+		}
+
+		indexes, err := tableIndexes(ctx, q, dialect, table)
+		if err != nil {
+			return nil, err
+		}
+		for _, index := range modelIndexes(scope) {
+			if !indexes[index.name] {
+				report.Drifts = append(report.Drifts, Drift{Kind: MissingIndex, Table: table, Index: index.name})
+			}
+		}
+	}
+	if !report.OK() {
+		return report, fmt.Errorf("%w: %s", ErrSchemaDrift, report)
+	}
+	return report, nil
+}
+
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// tableColumns returns the types of the columns of the table by name, none
+// when the table does not exist.
+func tableColumns(ctx context.Context, q queryer, dialect, table string) (map[string]string, error) {
+	var rows *sql.Rows
+	var err error
+	switch dialect {
+	case "postgres":
+		rows, err = q.QueryContext(ctx, "SELECT column_name, data_type, udt_name FROM information_schema.columns WHERE table_schema = CURRENT_SCHEMA() AND table_name = $1", table)
+	case "mysql":
+		rows, err = q.QueryContext(ctx, "SELECT column_name, data_type, data_type FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = ?", table)
+	default:
+		rows, err = q.QueryContext(ctx, "SELECT name, type, type FROM pragma_table_info(?)", table)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns := map[string]string{}
+	for rows.Next() {
+		var name, dataType, udtName string
+		if err := rows.Scan(&name, &dataType, &udtName); err != nil {
+			return nil, err
+		}
+		switch dataType {
+		case "ARRAY":
+			// Postgres names array element types with a leading underscore.
+			dataType = strings.TrimPrefix(udtName, "_") + "[]"
+		case "USER-DEFINED":
+			dataType = udtName
+		}
+		columns[name] = dataType
+	}
+	return columns, rows.Err()
+}
+
+// tableIndexes returns the names of the indexes of the table.
+func tableIndexes(ctx context.Context, q queryer, dialect, table string) (map[string]bool, error) {
+	var rows *sql.Rows
+	var err error
+	switch dialect {
+	case "postgres":
+		rows, err = q.QueryContext(ctx, "SELECT indexname FROM pg_indexes WHERE schemaname = CURRENT_SCHEMA() AND tablename = $1", table)
+	case "mysql":
+		rows, err = q.QueryContext(ctx, "SELECT DISTINCT index_name FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = ?", table)
+	default:
+		rows, err = q.QueryContext(ctx, "SELECT name FROM sqlite_master WHERE type = 'index' AND tbl_name = ?", table)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	indexes := map[string]bool{}
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		indexes[name] = true
+	}
+	return indexes, rows.Err()
+}
+
+var typeParams = regexp.MustCompile(`\([^)]*\)`)
+
+// typeAliases maps the type names the dialects accept or report to one name.
+var typeAliases = map[string]string{
+	"serial":            "integer",
+	"bigserial":         "bigint",
+	"int":               "integer",
+	"int4":              "integer",
+	"int8":              "bigint",
+	"bool":              "boolean",
+	"character varying": "varchar",
+	"character":         "char",
+	"float8":            "double precision",
+	"timestamptz":       "timestamp with time zone",
+}
+
+// baseType returns the column type without length, precision and column
+// constraints, under its canonical name.
+func baseType(dialect, sqlType string) string {
+	t := typeParams.ReplaceAllString(strings.ToLower(strings.TrimSpace(sqlType)), "")
+	for _, cut := range []string{" not null", " null", " unique", " default", " primary key", " auto_increment", " autoincrement", " unsigned"} {
+		if i := strings.Index(t, cut); i >= 0 {
+			t = t[:i]
+		}
+	}
+	if alias, ok := typeAliases[t]; ok {
+		t = alias
+	}
+	if dialect == "mysql" && t == "boolean" {
+		// MySQL stores booleans as tinyint(1).
+		t = "tinyint"
+	}
+	return t
+}
@@ -0,0 +1,113 @@
+package gormrepo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// likeEscaper escapes LIKE wildcards with "!", as a backslash would need to be
+// escaped in MySQL string literals.
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// ILikeAny limits the query to rows where any of the columns contains term,
+// ignoring case, with ILIKE on Postgres and LOWER elsewhere. Wildcards in
+// term match literally. A blank term matches every row.
+func ILikeAny(columns []string, term string) CriteriaOption {
+	description := []interface{}{term}
+	for _, column := range columns {
+		description = append(description, column)
+	}
+	return Described(Criterion{Op: "ilike_any", Args: description}, func(db *gorm.DB) *gorm.DB {
+		if term == "" || len(columns) == 0 {
+			return db
+		}
+		pattern := "%" + likeEscaper.Replace(term) + "%"
+		conditions := make([]string, len(columns))
+		args := make([]interface{}, len(columns))
+		for i, column := range columns {
+			if db.Dialect().GetName() == "postgres" {
+				conditions[i] = column + " ILIKE ? ESCAPE '!'"
+			} else {
+				conditions[i] = "LOWER(" + column + ") LIKE LOWER(?) ESCAPE '!'"
+			}
+			args[i] = pattern
+		}
+		return db.Where("("+strings.Join(conditions, " OR ")+")", args...)
+	})
+}
+
+// LikeMatch is where the text of Like and ILike is found in the column,
+// the whole column when empty.
+type LikeMatch string
+
+const (
+	Contains   LikeMatch = "contains"
+	StartsWith LikeMatch = "starts_with"
+	EndsWith   LikeMatch = "ends_with"
+)
+
+// likePattern returns the LIKE pattern of the text found as the match says,
+// the wildcards of the text escaped.
+func likePattern(text string, match LikeMatch) (string, bool) {
+	pattern := likeEscaper.Replace(text)
+	switch match {
+	case "":
+		return pattern, true
+	case Contains:
+		return "%" + pattern + "%", true
+	case StartsWith:
+		return pattern + "%", true
+	case EndsWith:
+		return "%" + pattern, true
+	}
+	return "", false
+}
+
+// Like limits the query to rows whose column is text or, with a match,
+// contains, starts or ends with it. Wildcards in text match literally. The
+// comparison ignores case where the collation of the column does, as on
+// MySQL by default.
+func Like(column, text string, match ...LikeMatch) CriteriaOption {
+	return like("like", column, text, match)
+}
+
+// ILike is Like ignoring case, with ILIKE on Postgres and LOWER elsewhere.
+func ILike(column, text string, match ...LikeMatch) CriteriaOption {
+	return like("ilike", column, text, match)
+}
+
+func like(op, column, text string, match []LikeMatch) CriteriaOption {
+	var m LikeMatch
+	if len(match) > 0 {
+		m = match[0]
+	}
+	return Described(Criterion{Op: op, Column: column, Args: []interface{}{text, string(m)}}, func(db *gorm.DB) *gorm.DB {
+		pattern, ok := likePattern(text, m)
+		if !ok {
+			return withError(db, fmt.Errorf("%w: like match %q", ErrInvalidCriterion, m))
+		}
+		switch {
+		case op == "like":
+			return db.Where(column+" LIKE ? ESCAPE '!'", pattern)
+		case db.Dialect().GetName() == "postgres":
+			return db.Where(column+" ILIKE ? ESCAPE '!'", pattern)
+		}
+		return db.Where("LOWER("+column+") LIKE LOWER(?) ESCAPE '!'", pattern)
+	})
+}
+
+// SimilarTo limits the query to rows whose column has a pg_trgm similarity
+// to term of at least threshold, between 0 and 1, most similar first. It
+// needs the pg_trgm extension and fails with ErrUnsupported on other
+// dialects.
+func SimilarTo(column, term string, threshold float64) CriteriaOption {
+	return Described(Criterion{Op: "similar_to", Column: column, Args: []interface{}{term, threshold}}, func(db *gorm.DB) *gorm.DB {
+		if db.Dialect().GetName() != "postgres" {
+			return withError(db, ErrUnsupported)
+		}
+		return db.Where("similarity("+column+", ?) >= ?", term, threshold).
+			Order(gorm.Expr("similarity("+column+", ?) DESC", term))
+	})
+}
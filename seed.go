@@ -0,0 +1,210 @@
+package gormrepo
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var (
+	seedFirstNames = []string{"Alice", "Bob", "Carol", "David", "Emma", "Frank", "Grace", "Henry", "Iris", "Jack"}
+	seedLastNames  = []string{"Smith", "Johnson", "Brown", "Garcia", "Miller", "Davis", "Wilson", "Moore", "Taylor", "Clark"}
+	seedCities     = []string{"Berlin", "Lisbon", "Oslo", "Austin", "Osaka", "Toronto", "Prague", "Lyon"}
+	seedWords      = []string{"alpha", "bright", "cedar", "delta", "ember", "forest", "granite", "harbor", "indigo", "juniper", "kite", "lumen"}
+)
+
+// Seed inserts n demo rows for each of the models, pointers to entity types,
+// in one transaction. Models referenced by another one through a belongs to
+// association or a <Model>ID field are seeded first, and foreign keys point
+// to random existing rows. Values are derived from the field names, such as
+// Email, FirstName or City, and from the field types otherwise.
+func Seed(db *gorm.DB, n int, models ...interface{}) error {
+	ordered := seedOrder(db, models)
+	rnd := rand.New(rand.NewSource(time.Now().UnixNano()))
+	return Transaction(db, func(tx *gorm.DB) error {
+		for _, model := range ordered {
+			if err := seedModel(tx, rnd, n, model); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// seedParents maps the foreign key fields of model to the referenced types.
+func seedParents(db *gorm.DB, model interface{}, types map[string]reflect.Type) map[string]reflect.Type {
+	self := reflect.TypeOf(model).Elem()
+	parents := map[string]reflect.Type{}
+	for _, field := range db.NewScope(model).GetModelStruct().StructFields {
+		if rel := field.Relationship; rel != nil && rel.Kind == "belongs_to" {
+			parent := field.Struct.Type
+			for parent.Kind() == reflect.Ptr {
+				parent = parent.Elem()
+			}
+			for _, name := range rel.ForeignFieldNames {
+				parents[name] = parent
+			}
+		}
+		if parent, ok := types[strings.TrimSuffix(field.Name, "ID")]; ok && strings.HasSuffix(field.Name, "ID") {
+			if _, ok := parents[field.Name]; !ok {
+				parents[field.Name] = parent
+			}
+		}
+	}
+	for name, parent := range parents {
+		if parent == self {
+			// Self references, such as tree parents, are left blank.
+			delete(parents, name)
+		}
+	}
+	return parents
+}
+
+// seedOrder sorts the models so referenced models come first, keeping the
+// given order otherwise.
+func seedOrder(db *gorm.DB, models []interface{}) []interface{} {
+	types := map[string]reflect.Type{}
+	for _, model := range models {
+		t := reflect.TypeOf(model).Elem()
+		types[t.Name()] = t
+	}
+	done := map[reflect.Type]bool{}
+	visiting := map[reflect.Type]bool{}
+	var ordered []interface{}
+	var visit func(model interface{})
+	visit = func(model interface{}) {
+		t := reflect.TypeOf(model).Elem()
+		if done[t] || visiting[t] {
+			return
+		}
+		visiting[t] = true
+		for _, parent := range seedParents(db, model, types) {
+			for _, m := range models {
+				if reflect.TypeOf(m).Elem() == parent {
+					visit(m)
+				}
+			}
+		}
+		done[t] = true
+		ordered = append(ordered, model)
+	}
+	for _, model := range models {
+		visit(model)
+	}
+	return ordered
+}
+
+func seedModel(tx *gorm.DB, rnd *rand.Rand, n int, model interface{}) error {
+	t := reflect.TypeOf(model).Elem()
+	types := map[string]reflect.Type{t.Name(): t}
+	keys := map[string][]interface{}{}
+	for name, parent := range seedParents(tx, model, types) {
+		parentModel := reflect.New(parent).Interface()
+		scope := tx.NewScope(parentModel)
+		var ids []interface{}
+		if err := tx.Model(parentModel).Pluck(scope.Quote(scope.PrimaryKey()), &ids).Error; err != nil {
+			return err
+		}
+		keys[name] = ids
+	}
+	for i := 0; i < n; i++ {
+		entity := reflect.New(t)
+		scope := tx.NewScope(entity.Interface())
+		for _, field := range scope.Fields() {
+			if !field.IsNormal || field.IsPrimaryKey || field.IsIgnored {
+				continue
+			}
+			switch field.Name {
+			case "CreatedAt", "UpdatedAt", "DeletedAt":
+				continue
+			}
+			if ids, ok := keys[field.Name]; ok {
+				if len(ids) > 0 {
+					seedAssign(field.Field, reflect.ValueOf(ids[rnd.Intn(len(ids))]))
+				}
+				continue
+			}
+			if v, ok := seedValue(rnd, field.Name, field.Struct.Type, i); ok {
+				seedAssign(field.Field, v)
+			}
+		}
+		if err := tx.Create(entity.Interface()).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// seedAssign sets the field to v, converting it and taking its address for
+// pointer fields.
+func seedAssign(field reflect.Value, v reflect.Value) {
+	target := field.Type()
+	if target.Kind() == reflect.Ptr {
+		target = target.Elem()
+	}
+	if !v.Type().ConvertibleTo(target) {
+		return
+	}
+	v = v.Convert(target)
+	if field.Kind() == reflect.Ptr {
+		p := reflect.New(target)
+		p.Elem().Set(v)
+		field.Set(p)
+		return
+	}
+	field.Set(v)
+}
+
+func seedValue(rnd *rand.Rand, name string, t reflect.Type, i int) (reflect.Value, bool) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t == reflect.TypeOf(time.Time{}) {
+		return reflect.ValueOf(time.Now().Add(-time.Duration(rnd.Int63n(int64(365 * 24 * time.Hour))))), true
+	}
+	switch t.Kind() {
+	case reflect.String:
+		return reflect.ValueOf(seedString(rnd, strings.ToLower(name), i)), true
+	case reflect.Bool:
+		return reflect.ValueOf(rnd.Intn(2) == 1), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return reflect.ValueOf(rnd.Intn(100)), true
+	case reflect.Float32, reflect.Float64:
+		return reflect.ValueOf(float64(rnd.Intn(100000)) / 100), true
+	}
+	return reflect.Value{}, false
+}
+
+func seedString(rnd *rand.Rand, name string, i int) string {
+	first := seedFirstNames[rnd.Intn(len(seedFirstNames))]
+	last := seedLastNames[rnd.Intn(len(seedLastNames))]
+	word := seedWords[rnd.Intn(len(seedWords))]
+	switch {
+	case strings.Contains(name, "email"):
+		return fmt.Sprintf("%s.%s%d@example.com", strings.ToLower(first), strings.ToLower(last), i)
+	case strings.Contains(name, "firstname"):
+		return first
+	case strings.Contains(name, "lastname"):
+		return last
+	case strings.Contains(name, "phone"):
+		return fmt.Sprintf("+1-555-%04d", rnd.Intn(10000))
+	case strings.Contains(name, "city"):
+		return seedCities[rnd.Intn(len(seedCities))]
+	case strings.Contains(name, "url"):
+		return fmt.Sprintf("https://example.com/%s/%d", word, i)
+	case strings.Contains(name, "sku"), strings.Contains(name, "code"):
+		return fmt.Sprintf("%s-%05d", strings.ToUpper(word[:3]), i)
+	case strings.Contains(name, "title"):
+		return strings.Title(word) + " " + seedWords[rnd.Intn(len(seedWords))]
+	case strings.Contains(name, "name"):
+		return first + " " + last
+	case strings.Contains(name, "status"), strings.Contains(name, "state"):
+		return "active"
+	}
+	return fmt.Sprintf("%s %s %d", word, seedWords[rnd.Intn(len(seedWords))], i)
+}
@@ -0,0 +1,26 @@
+package gormrepo
+
+import (
+	"database/sql"
+
+	"github.com/jinzhu/gorm"
+)
+
+// WithSessionVar sets the Postgres setting key (for example app.tenant_id used
+// by row level security policies) to value for the rest of the transaction the
+// repository call runs in. Outside of a transaction the call fails with
+// ErrNoTransaction, since the setting would leak to other users of the pool.
+func WithSessionVar(key, value string) CriteriaOption {
+	return Described(Criterion{Op: "session_var", Column: key, Args: []interface{}{value}}, func(db *gorm.DB) *gorm.DB {
+		if db.Dialect().GetName() != "postgres" {
+			return withError(db, ErrUnsupported)
+		}
+		if _, ok := db.CommonDB().(*sql.Tx); !ok {
+			return withError(db, ErrNoTransaction)
+		}
+		if err := db.Exec("SELECT set_config(?, ?, true)", key, value).Error; err != nil {
+			return withError(db, err)
+		}
+		return db
+	})
+}
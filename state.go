@@ -0,0 +1,14 @@
+package gormrepo
+
+// StateMachine maps each state to the states it may transition to.
+type StateMachine map[string][]string
+
+// Can reports whether the transition from -> to is declared.
+func (m StateMachine) Can(from, to string) bool {
+	for _, state := range m[from] {
+		if state == to {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package gormrepo
+
+import (
+	"sync"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+// OpStats aggregates the queries of one kind run on a table.
+type OpStats struct {
+	Count      int64
+	Errors     int64
+	ErrorRate  float64
+	AvgLatency time.Duration
+	total      time.Duration
+}
+
+// Stats of a table since EnableStats, as returned by the generated Stats.
+// Cache hits and misses are recorded by caching decorators with RecordCache.
+type Stats struct {
+	Table         string
+	Since         time.Time
+	Ops           map[string]OpStats // By operation: create, query, row_query, update, delete.
+	CacheHits     int64
+	CacheMisses   int64
+	CacheHitRatio float64
+}
+
+type tableStats struct {
+	ops          map[string]*OpStats
+	hits, misses int64
+}
+
+var stats = struct {
+	sync.Mutex
+	since  time.Time
+	tables map[string]*tableStats
+}{tables: map[string]*tableStats{}}
+
+// EnableStats registers the callbacks counting the operations run on db, and
+// the databases sharing its callbacks, with their errors and latency.
+func EnableStats(db *gorm.DB) {
+	callbacks := db.Callback()
+	if callbacks.Query().Get("gormrepo:stats_end") != nil {
+		return
+	}
+	stats.Lock()
+	if stats.since.IsZero() {
+		stats.since = time.Now()
+	}
+	stats.Unlock()
+
+	callbacks.Create().Before("gorm:begin_transaction").Register("gormrepo:stats_start", statsStart)
+	callbacks.Create().After("gorm:commit_or_rollback_transaction").Register("gormrepo:stats_end", statsEnd("create"))
+	callbacks.Update().Before("gorm:begin_transaction").Register("gormrepo:stats_start", statsStart)
+	callbacks.Update().After("gorm:commit_or_rollback_transaction").Register("gormrepo:stats_end", statsEnd("update"))
+	callbacks.Delete().Before("gorm:begin_transaction").Register("gormrepo:stats_start", statsStart)
+	callbacks.Delete().After("gorm:commit_or_rollback_transaction").Register("gormrepo:stats_end", statsEnd("delete"))
+	callbacks.Query().Before("gorm:query").Register("gormrepo:stats_start", statsStart)
+	callbacks.Query().After("gorm:after_query").Register("gormrepo:stats_end", statsEnd("query"))
+	callbacks.RowQuery().Before("gorm:row_query").Register("gormrepo:stats_start", statsStart)
+	callbacks.RowQuery().After("gorm:row_query").Register("gormrepo:stats_end", statsEnd("row_query"))
+}
+
+func statsStart(scope *gorm.Scope) {
+	scope.InstanceSet("gormrepo:stats_start", time.Now())
+}
+
+func statsEnd(op string) func(scope *gorm.Scope) {
+	return func(scope *gorm.Scope) {
+		start, ok := scope.InstanceGet("gormrepo:stats_start")
+		if !ok {
+			return
+		}
+		latency := time.Since(start.(time.Time))
+		failed := scope.HasError() && !gorm.IsRecordNotFoundError(scope.DB().Error)
+
+		stats.Lock()
+		defer stats.Unlock()
+		t := tableStatsOf(scope.TableName())
+		s, ok := t.ops[op]
+		if !ok {
+			s = &OpStats{}
+			t.ops[op] = s
+		}
+		s.add(latency, failed)
+	}
+}
+
+func (s *OpStats) add(latency time.Duration, failed bool) {
+	s.Count++
+	s.total += latency
+	if failed {
+		s.Errors++
+	}
+}
+
+// snapshot returns a copy of s with its rates computed.
+func (s *OpStats) snapshot() OpStats {
+	snapshot := *s
+	if s.Count > 0 {
+		snapshot.ErrorRate = float64(s.Errors) / float64(s.Count)
+		snapshot.AvgLatency = s.total / time.Duration(s.Count)
+	}
+	return snapshot
+}
+
+// tableStatsOf must be called with stats locked.
+func tableStatsOf(table string) *tableStats {
+	t, ok := stats.tables[table]
+	if !ok {
+		t = &tableStats{ops: map[string]*OpStats{}}
+		stats.tables[table] = t
+	}
+	return t
+}
+
+// RecordCache records a cache lookup for the table.
+func RecordCache(table string, hit bool) {
+	stats.Lock()
+	defer stats.Unlock()
+	if t := tableStatsOf(table); hit {
+		t.hits++
+	} else {
+		t.misses++
+	}
+}
+
+// TableStats returns the statistics of the table.
+func TableStats(table string) Stats {
+	stats.Lock()
+	defer stats.Unlock()
+	result := Stats{Table: table, Since: stats.since, Ops: map[string]OpStats{}}
+	t, ok := stats.tables[table]
+	if !ok {
+		return result
+	}
+	for op, s := range t.ops {
+		result.Ops[op] = s.snapshot()
+	}
+	result.CacheHits, result.CacheMisses = t.hits, t.misses
+	if lookups := t.hits + t.misses; lookups > 0 {
+		result.CacheHitRatio = float64(t.hits) / float64(lookups)
+	}
+	return result
+}
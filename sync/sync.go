@@ -0,0 +1,179 @@
+// Package sync copies an entity between databases incrementally, following
+// an updated_at watermark, to migrate tenants between clusters.
+package sync
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/l-vitaly/gormrepo"
+)
+
+var ErrConflict = errors.New("target row changed after source row")
+
+const DefaultBatchSize = 500
+
+// ConflictPolicy decides what happens to a row updated on the target more
+// recently than on the source.
+type ConflictPolicy int
+
+const (
+	SourceWins ConflictPolicy = iota // Overwrite the target row.
+	TargetWins                       // Keep the target row.
+	Fail                             // Stop with ErrConflict.
+)
+
+// Watermark is the position of the last synchronized row. Rows are visited
+// by updated_at then primary key, so rows sharing a timestamp are not skipped
+// between batches.
+type Watermark struct {
+	UpdatedAt time.Time
+	ID        interface{}
+}
+
+// Progress is reported after each batch.
+type Progress struct {
+	Batch     int
+	Copied    int // Rows written to the target so far.
+	Skipped   int // Conflicting rows kept by TargetWins so far.
+	Watermark Watermark
+}
+
+// Syncer copies the rows of Model, a pointer to the entity such as &User{},
+// from Source to Target. Both are usually the DB of generated repositories,
+// scoped with criteria to the synchronized tenant. Soft deleted rows are
+// copied too, so deletions are propagated.
+type Syncer struct {
+	Source    *gorm.DB
+	Target    *gorm.DB
+	Model     interface{}
+	Column    string // Watermark column, "updated_at" by default.
+	BatchSize int
+	Conflict  ConflictPolicy
+	Progress  func(Progress)
+}
+
+// Run copies the rows changed after from and returns the watermark to resume
+// from on the next run, also when it stops on an error.
+func (s *Syncer) Run(ctx context.Context, from Watermark) (Watermark, error) {
+	column := s.Column
+	if column == "" {
+		column = "updated_at"
+	}
+	size := s.BatchSize
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+	scope := s.Source.NewScope(s.Model)
+	quoted := scope.QuotedTableName() + "." + scope.Quote(column)
+	key := scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())
+	sliceType := reflect.SliceOf(reflect.TypeOf(s.Model))
+
+	progress := Progress{Watermark: from}
+	for {
+		if err := ctx.Err(); err != nil {
+			return progress.Watermark, err
+		}
+		wm := progress.Watermark
+		query := s.Source.Unscoped().Order(quoted).Order(key).Limit(size)
+		if wm.ID == nil {
+			query = query.Where(quoted+" >= ?", wm.UpdatedAt)
+		} else {
+			query = query.Where(quoted+" > ? OR ("+quoted+" = ? AND "+key+" > ?)", wm.UpdatedAt, wm.UpdatedAt, wm.ID)
+		}
+		rows := reflect.New(sliceType)
+		if err := query.Find(rows.Interface()).Error; err != nil {
+			return wm, err
+		}
+		n := rows.Elem().Len()
+		for i := 0; i < n; i++ {
+			row := rows.Elem().Index(i).Interface()
+			rowScope := s.Source.NewScope(row)
+			updatedAt, err := fieldTime(rowScope, column)
+			if err != nil {
+				return progress.Watermark, err
+			}
+			copied, err := s.copyRow(row, column, updatedAt)
+			if err != nil {
+				return progress.Watermark, err
+			}
+			if copied {
+				progress.Copied++
+			} else {
+				progress.Skipped++
+			}
+			progress.Watermark = Watermark{UpdatedAt: updatedAt, ID: rowScope.PrimaryKeyValue()}
+		}
+		if n == 0 {
+			return progress.Watermark, nil
+		}
+		progress.Batch++
+		if s.Progress != nil {
+			s.Progress(progress)
+		}
+		if n < size {
+			return progress.Watermark, nil
+		}
+	}
+}
+
+// copyRow writes row to the target, reporting false when the target row is
+// kept by the conflict policy.
+func (s *Syncer) copyRow(row interface{}, column string, updatedAt time.Time) (bool, error) {
+	copied := true
+	err := gormrepo.Transaction(s.Target, func(tx *gorm.DB) error {
+		existing := reflect.New(reflect.TypeOf(row).Elem()).Interface()
+		scope := tx.NewScope(row)
+		err := tx.Unscoped().Set("gorm:query_option", forUpdate(tx)).
+			Where(scope.Quote(scope.PrimaryKey())+" = ?", scope.PrimaryKeyValue()).First(existing).Error
+		if gorm.IsRecordNotFoundError(err) {
+			return tx.Set("gorm:save_associations", false).Create(row).Error
+		}
+		if err != nil {
+			return err
+		}
+		targetUpdatedAt, err := fieldTime(tx.NewScope(existing), column)
+		if err != nil {
+			return err
+		}
+		if targetUpdatedAt.After(updatedAt) {
+			switch s.Conflict {
+			case TargetWins:
+				copied = false
+				return nil
+			case Fail:
+				return ErrConflict
+			}
+		}
+		// update_column keeps the source timestamps.
+		return tx.Unscoped().Set("gorm:update_column", true).Set("gorm:save_associations", false).Save(row).Error
+	})
+	return copied, err
+}
+
+func forUpdate(db *gorm.DB) string {
+	if db.Dialect().GetName() == "sqlite3" {
+		return ""
+	}
+	return "FOR UPDATE"
+}
+
+func fieldTime(scope *gorm.Scope, column string) (time.Time, error) {
+	field, ok := scope.FieldByName(column)
+	if !ok {
+		return time.Time{}, errors.New("no column " + column)
+	}
+	switch v := field.Field.Interface().(type) {
+	case time.Time:
+		return v, nil
+	case *time.Time:
+		if v != nil {
+			return *v, nil
+		}
+		return time.Time{}, nil
+	}
+	return time.Time{}, errors.New("column " + column + " is not a time")
+}
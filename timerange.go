@@ -0,0 +1,70 @@
+package gormrepo
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var timeNow = time.Now
+
+// TimeRange matches rows whose column is in the half-open range [from, to).
+// The wall clocks of from and to are read in loc, UTC when nil, and the bounds
+// compared in UTC. A zero bound leaves its side open.
+func TimeRange(column string, from, to time.Time, loc *time.Location) CriteriaOption {
+	if loc == nil {
+		loc = time.UTC
+	}
+	if !from.IsZero() {
+		from = inLocation(from, loc).UTC()
+	}
+	if !to.IsZero() {
+		to = inLocation(to, loc).UTC()
+	}
+	return Described(Criterion{Op: "time_range", Column: column, Args: []interface{}{from, to}}, func(db *gorm.DB) *gorm.DB {
+		if !from.IsZero() {
+			db = db.Where(column+" >= ?", from)
+		}
+		if !to.IsZero() {
+			db = db.Where(column+" < ?", to)
+		}
+		return db
+	})
+}
+
+func inLocation(t time.Time, loc *time.Location) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// midnight returns the start of the day of t in loc, days later.
+func midnight(t time.Time, loc *time.Location, days int) time.Time {
+	t = t.In(loc)
+	// Normalizing the date keeps days of 23 or 25 hours around DST changes.
+	return time.Date(t.Year(), t.Month(), t.Day()+days, 0, 0, 0, 0, loc)
+}
+
+// Today matches the rows of the current calendar day in loc.
+func Today(column string, loc *time.Location) CriteriaOption {
+	return LastNDays(column, 1, loc)
+}
+
+// ThisWeek matches the rows of the current week in loc, starting on Monday.
+func ThisWeek(column string, loc *time.Location) CriteriaOption {
+	if loc == nil {
+		loc = time.UTC
+	}
+	now := timeNow().In(loc)
+	sinceMonday := (int(now.Weekday()) + 6) % 7
+	from := midnight(now, loc, -sinceMonday)
+	return TimeRange(column, from, midnight(now, loc, 7-sinceMonday), loc)
+}
+
+// LastNDays matches the rows of the last n calendar days in loc, today
+// included.
+func LastNDays(column string, n int, loc *time.Location) CriteriaOption {
+	if loc == nil {
+		loc = time.UTC
+	}
+	now := timeNow()
+	return TimeRange(column, midnight(now, loc, 1-n), midnight(now, loc, 1), loc)
+}
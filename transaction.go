@@ -0,0 +1,168 @@
+package gormrepo
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+var savePointSeq uint64
+
+// TxOption configures a transaction run by Transaction.
+type TxOption func(*txOptions)
+
+type txOptions struct {
+	tx            sql.TxOptions
+	recoverPanics bool
+	retries       int
+}
+
+// WithIsolation runs the transaction at the isolation level, or at the
+// nearest stronger level the database supports.
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *txOptions) {
+		o.tx.Isolation = level
+	}
+}
+
+// ReadOnly runs the transaction read only. SQL Server transactions, which
+// the driver cannot start read only, run read write.
+func ReadOnly() TxOption {
+	return func(o *txOptions) {
+		o.tx.ReadOnly = true
+	}
+}
+
+// RecoverPanics returns a panic of the callback as a *PanicError instead of
+// panicking again once the transaction is rolled back.
+func RecoverPanics() TxOption {
+	return func(o *txOptions) {
+		o.recoverPanics = true
+	}
+}
+
+// PanicError is a panic of a transaction callback, recovered with
+// RecoverPanics.
+type PanicError struct {
+	Value interface{}
+	Stack []byte // Stack of the goroutine when the panic was recovered.
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("transaction panicked: %v", e.Value)
+}
+
+// panicked returns the panic p of the rolled back callback as an error, or
+// panics again with it.
+func (o txOptions) panicked(p interface{}) error {
+	if !o.recoverPanics {
+		panic(p)
+	}
+	return &PanicError{Value: p, Stack: debug.Stack()}
+}
+
+// Transaction runs fn inside a transaction, committing when fn returns nil
+// and rolling back otherwise. When db is already a transaction fn runs in a
+// savepoint instead, so a failed nested call only rolls back its own work and
+// can be retried without aborting the enclosing transaction; the isolation
+// and read only options of the enclosing transaction apply.
+//
+// A panic of fn rolls back the transaction, or the savepoint, and panics
+// again, or is returned with RecoverPanics. Deadlocks are counted in
+// TransactionStats, and retried with RetryDeadlocks.
+func Transaction(db *gorm.DB, fn func(tx *gorm.DB) error, opts ...TxOption) error {
+	var o txOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if _, ok := db.CommonDB().(*sql.Tx); ok {
+		return nested(db, fn, o)
+	}
+	for attempt := 0; ; attempt++ {
+		err := transaction(db, fn, o)
+		if !IsDeadlock(err) {
+			return err
+		}
+		atomic.AddInt64(&txStats.Deadlocks, 1)
+		if attempt >= o.retries {
+			return err
+		}
+		atomic.AddInt64(&txStats.Retries, 1)
+		time.Sleep(deadlockBackoff(attempt))
+	}
+}
+
+func transaction(db *gorm.DB, fn func(tx *gorm.DB) error, o txOptions) (err error) {
+	if db.Error != nil {
+		// gorm would begin the transaction and leave it open.
+		return db.Error
+	}
+	txOpts := dialectTxOptions(db.Dialect().GetName(), o.tx)
+	tx := db.BeginTx(context.Background(), &txOpts)
+	if tx.Error != nil {
+		return tx.Error
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			tx.Rollback()
+			err = o.panicked(p)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit().Error
+}
+
+func nested(tx *gorm.DB, fn func(tx *gorm.DB) error, o txOptions) (err error) {
+	name := fmt.Sprintf("gormrepo_sp_%d", atomic.AddUint64(&savePointSeq, 1))
+	if err := SavePoint(tx, name); err != nil {
+		return err
+	}
+	defer func() {
+		if p := recover(); p != nil {
+			RollbackTo(tx, name)
+			err = o.panicked(p)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		if rbErr := RollbackTo(tx, name); rbErr != nil {
+			return rbErr
+		}
+		return err
+	}
+	return ReleaseSavePoint(tx, name)
+}
+
+// SavePoint creates the named savepoint in the transaction tx.
+func SavePoint(tx *gorm.DB, name string) error {
+	if tx.Dialect().GetName() == "mssql" {
+		return tx.Exec("SAVE TRANSACTION " + tx.Dialect().Quote(name)).Error
+	}
+	return tx.Exec("SAVEPOINT " + tx.Dialect().Quote(name)).Error
+}
+
+// RollbackTo rolls the transaction tx back to the named savepoint, keeping
+// the transaction usable.
+func RollbackTo(tx *gorm.DB, name string) error {
+	if tx.Dialect().GetName() == "mssql" {
+		return tx.Exec("ROLLBACK TRANSACTION " + tx.Dialect().Quote(name)).Error
+	}
+	return tx.Exec("ROLLBACK TO SAVEPOINT " + tx.Dialect().Quote(name)).Error
+}
+
+// ReleaseSavePoint releases the named savepoint, keeping its changes in the
+// transaction tx.
+func ReleaseSavePoint(tx *gorm.DB, name string) error {
+	if tx.Dialect().GetName() == "mssql" {
+		// SQL Server has no release, savepoints end with the transaction.
+		return nil
+	}
+	return tx.Exec("RELEASE SAVEPOINT " + tx.Dialect().Quote(name)).Error
+}
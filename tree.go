@@ -0,0 +1,37 @@
+package gormrepo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// TreePath returns the materialized path of the node id placed under parentPath.
+// Paths have the form "/1/4/9/", the root path is "/".
+func TreePath(parentPath string, id interface{}) string {
+	if parentPath == "" {
+		parentPath = "/"
+	}
+	return fmt.Sprintf("%s%v/", parentPath, id)
+}
+
+// TreePathIDs returns the ids stored in the materialized path, root first.
+func TreePathIDs(path string) []uint64 {
+	var ids []uint64
+	for _, part := range strings.Split(strings.Trim(path, "/"), "/") {
+		if id, err := strconv.ParseUint(part, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids
+}
+
+// TreeMovePath returns the expression replacing the oldPath prefix of column with newPath.
+func TreeMovePath(db *gorm.DB, column, oldPath, newPath string) *gorm.SqlExpr {
+	if db.Dialect().GetName() == "mysql" {
+		return gorm.Expr("CONCAT(?, SUBSTR("+column+", ?))", newPath, len(oldPath)+1)
+	}
+	return gorm.Expr("? || SUBSTR("+column+", ?)", newPath, len(oldPath)+1)
+}
@@ -0,0 +1,107 @@
+package gormrepo
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// unsafeTruncateKey is the setting of the dbs of UnsafeAllowTruncate.
+const unsafeTruncateKey = "gormrepo:unsafe_truncate"
+
+// UnsafeAllowTruncate returns db, and the transactions begun from it,
+// accepting Truncate outside of tests, such as in the scripts resetting a
+// staging database.
+func UnsafeAllowTruncate(db *gorm.DB) *gorm.DB {
+	return db.Set(unsafeTruncateKey, true)
+}
+
+// inTest reports whether the program is a test binary.
+func inTest() bool {
+	return flag.Lookup("test.v") != nil
+}
+
+// Truncate empties the tables of the models, pointers to entity types, and
+// their many to many join tables, and restarts their sequences, for tests.
+// The tables referencing the others through belongs to associations or
+// <Model>ID fields are emptied first.
+//
+// Postgres truncates the tables in one statement, with cascade also the
+// tables of other models referencing them. MySQL truncates them in order and
+// refuses the tables referenced by foreign keys, unless cascade disables the
+// foreign key checks, leaving the referencing rows. SQLite deletes their rows
+// in order.
+//
+// Outside of tests it fails with ErrUnsafeTruncate unless db is in the mode
+// of UnsafeAllowTruncate.
+func Truncate(db *gorm.DB, cascade bool, models ...interface{}) error {
+	if _, ok := db.Get(unsafeTruncateKey); !ok && !inTest() {
+		return ErrUnsafeTruncate
+	}
+	var tables []string
+	seen := map[string]bool{}
+	add := func(table string) {
+		if !seen[table] {
+			seen[table] = true
+			tables = append(tables, table)
+		}
+	}
+	ordered := seedOrder(db, models)
+	for i := len(ordered) - 1; i >= 0; i-- {
+		scope := db.NewScope(ordered[i])
+		for _, field := range scope.GetModelStruct().StructFields {
+			if rel := field.Relationship; rel != nil && rel.Kind == "many_to_many" && rel.JoinTableHandler != nil {
+				add(rel.JoinTableHandler.Table(db))
+			}
+		}
+	}
+	for i := len(ordered) - 1; i >= 0; i-- {
+		add(db.NewScope(ordered[i]).TableName())
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+	quoted := make([]string, len(tables))
+	for i, table := range tables {
+		quoted[i] = db.Dialect().Quote(table)
+	}
+	return Transaction(db, func(tx *gorm.DB) error {
+		switch tx.Dialect().GetName() {
+		case "postgres":
+			stmt := "TRUNCATE TABLE " + strings.Join(quoted, ", ") + " RESTART IDENTITY"
+			if cascade {
+				stmt += " CASCADE"
+			}
+			return tx.Exec(stmt).Error
+		case "sqlite3":
+			for i, table := range quoted {
+				if err := tx.Exec("DELETE FROM " + table).Error; err != nil {
+					return err
+				}
+				// sqlite_sequence only exists once a table has an
+				// AUTOINCREMENT key.
+				if tx.HasTable("sqlite_sequence") {
+					if err := tx.Exec("DELETE FROM sqlite_sequence WHERE name = ?", tables[i]).Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		case "mysql":
+			if cascade {
+				if err := tx.Exec("SET FOREIGN_KEY_CHECKS = 0").Error; err != nil {
+					return err
+				}
+				defer tx.Exec("SET FOREIGN_KEY_CHECKS = 1")
+			}
+		}
+		for _, table := range quoted {
+			if err := tx.Exec("TRUNCATE TABLE " + table).Error; err != nil {
+				return fmt.Errorf("truncate %s: %w", table, err)
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,87 @@
+//go:build go1.18
+
+package gormrepo
+
+import "github.com/jinzhu/gorm"
+
+// Column describes a column whose values have the Go type T, so the typed
+// criteria reject values of another type at compile time. The generated
+// <T>Columns hold the columns of each model.
+type Column[T any] struct {
+	Name string
+}
+
+func NewColumn[T any](name string) Column[T] {
+	return Column[T]{Name: name}
+}
+
+func (c Column[T]) String() string {
+	return c.Name
+}
+
+func init() {
+	for op, name := range compareOps {
+		op := op
+		RegisterCriterion(name, func(c Criterion) (CriteriaOption, error) {
+			if len(c.Args) != 1 {
+				return nil, c.invalid()
+			}
+			return compare(NewColumn[interface{}](c.Column), op, c.Args[0]), nil
+		})
+	}
+	RegisterCriterion("eq_or_null", func(c Criterion) (CriteriaOption, error) {
+		if len(c.Args) != 1 {
+			return nil, c.invalid()
+		}
+		if c.Args[0] == nil {
+			return EqOrNull[interface{}](c.Column, nil), nil
+		}
+		return EqOrNull(c.Column, &c.Args[0]), nil
+	})
+}
+
+func compare[T any](c Column[T], op string, value T) CriteriaOption {
+	return Described(Criterion{Op: compareOps[op], Column: c.Name, Args: []interface{}{value}}, func(db *gorm.DB) *gorm.DB {
+		return db.Where(c.Name+" "+op+" ?", value)
+	})
+}
+
+// Eq limits the query to rows whose column equals value.
+func Eq[T any](c Column[T], value T) CriteriaOption {
+	return compare(c, "=", value)
+}
+
+func Ne[T any](c Column[T], value T) CriteriaOption {
+	return compare(c, "<>", value)
+}
+
+func Gt[T any](c Column[T], value T) CriteriaOption {
+	return compare(c, ">", value)
+}
+
+func Gte[T any](c Column[T], value T) CriteriaOption {
+	return compare(c, ">=", value)
+}
+
+func Lt[T any](c Column[T], value T) CriteriaOption {
+	return compare(c, "<", value)
+}
+
+func Lte[T any](c Column[T], value T) CriteriaOption {
+	return compare(c, "<=", value)
+}
+
+// EqOrNull limits the query to rows whose column equals the value pointed
+// to, or is NULL when value is nil.
+func EqOrNull[T any](column string, value *T) CriteriaOption {
+	var arg interface{}
+	if value != nil {
+		arg = *value
+	}
+	return Described(Criterion{Op: "eq_or_null", Column: column, Args: []interface{}{arg}}, func(db *gorm.DB) *gorm.DB {
+		if value == nil {
+			return db.Where(column + " IS NULL")
+		}
+		return db.Where(column+" = ?", *value)
+	})
+}
@@ -0,0 +1,126 @@
+package gormrepo
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jinzhu/gorm"
+)
+
+// UniqueIndex is a unique index of a model, as declared by the unique_index
+// tags of its fields. A blank name is named by gorm after the table and
+// column.
+type UniqueIndex struct {
+	Name    string
+	Columns []string
+}
+
+// ConflictTarget is the unique index or constraint an upsert resolves
+// conflicts on, by name or by its set of columns.
+type ConflictTarget struct {
+	name    string
+	columns []string
+}
+
+// OnConstraint targets the unique index or constraint of that name.
+func OnConstraint(name string) ConflictTarget {
+	return ConflictTarget{name: name}
+}
+
+// OnColumns targets the unique index on exactly these columns, in any order.
+func OnColumns(columns ...string) ConflictTarget {
+	return ConflictTarget{columns: columns}
+}
+
+func (t ConflictTarget) String() string {
+	if t.name != "" {
+		return t.name
+	}
+	return "(" + strings.Join(t.columns, ", ") + ")"
+}
+
+// resolve returns the columns of the index matching the target among the
+// primary key and the unique indexes of the model.
+func (t ConflictTarget) resolve(scope *gorm.Scope, indexes []UniqueIndex) ([]string, error) {
+	candidates := []UniqueIndex{{Name: "primary", Columns: []string{scope.PrimaryKey()}}}
+	for _, index := range indexes {
+		if index.Name == "" {
+			index.Name = scope.Dialect().BuildKeyName("uix", scope.TableName(), index.Columns[0])
+		}
+		candidates = append(candidates, index)
+	}
+	for _, index := range candidates {
+		if t.name != "" && t.name == index.Name || t.name == "" && sameColumns(t.columns, index.Columns) {
+			return index.Columns, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownConflictTarget, t)
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) || len(a) == 0 {
+		return false
+	}
+	set := map[string]bool{}
+	for _, column := range a {
+		set[column] = true
+	}
+	for _, column := range b {
+		if !set[column] {
+			return false
+		}
+	}
+	return true
+}
+
+// Upsert inserts value or, when it conflicts on the target, updates the
+// columns of the existing row, all columns but the target, the primary key
+// and created_at when none are given. The target must be the primary key or
+// one of the unique indexes of the model, otherwise ErrUnknownConflictTarget
+// is returned. MySQL cannot target a conflict, the target is only validated.
+func Upsert(db *gorm.DB, value interface{}, target ConflictTarget, indexes []UniqueIndex, update ...string) error {
+	scope := db.NewScope(value)
+	columns, err := target.resolve(scope, indexes)
+	if err != nil {
+		return err
+	}
+	if len(update) == 0 {
+		skip := map[string]bool{"created_at": true}
+		for _, column := range columns {
+			skip[column] = true
+		}
+		for _, field := range scope.Fields() {
+			if field.IsNormal && !field.IsIgnored && !field.IsPrimaryKey && !skip[field.DBName] {
+				update = append(update, field.DBName)
+			}
+		}
+	}
+
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = scope.Quote(column)
+	}
+	sets := make([]string, len(update))
+	var option string
+	switch db.Dialect().GetName() {
+	case "mysql":
+		for i, column := range update {
+			sets[i] = scope.Quote(column) + " = VALUES(" + scope.Quote(column) + ")"
+		}
+		if len(sets) == 0 {
+			// Keep the existing row.
+			sets = []string{quoted[0] + " = " + quoted[0]}
+		}
+		option = "ON DUPLICATE KEY UPDATE " + strings.Join(sets, ", ")
+	default:
+		for i, column := range update {
+			sets[i] = scope.Quote(column) + " = excluded." + scope.Quote(column)
+		}
+		if len(sets) == 0 {
+			// DO NOTHING would return no row to the RETURNING of Postgres.
+			sets = []string{quoted[0] + " = excluded." + quoted[0]}
+		}
+		option = "ON CONFLICT (" + strings.Join(quoted, ", ") + ") DO UPDATE SET " + strings.Join(sets, ", ")
+	}
+	return db.Set("gorm:insert_option", option).Create(value).Error
+}
@@ -0,0 +1,308 @@
+// Code generated by "gormrepogen -t=Target,Delivery"; DO NOT EDIT
+
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/l-vitaly/gormrepo"
+)
+
+type deliveryBaseRepo struct {
+	*gorm.DB
+}
+
+func (r *deliveryBaseRepo) applyCriteria(criteria []gormrepo.CriteriaOption) *gorm.DB {
+	// The criteria checking their columns see the model.
+	return gormrepo.ApplyCriteria(r.DB.Model(&Delivery{}), criteria...)
+}
+
+func (r *deliveryBaseRepo) wrapError(method string, err error, criteria ...gormrepo.CriteriaOption) error {
+	return gormrepo.WrapError(err, "Delivery", method, criteria...)
+}
+
+func (r *deliveryBaseRepo) Related(claim *Delivery, related interface{}, criteria ...gormrepo.CriteriaOption) error {
+	err := r.applyCriteria(criteria).Model(claim).Related(related).Error
+	return r.wrapError("Related", err, criteria...)
+}
+
+func (r *deliveryBaseRepo) Get(id uint) (*Delivery, error) {
+	var entity Delivery
+	err := r.DB.Where(map[string]interface{}{"id": id}).Find(&entity).Error
+	return &entity, r.wrapError("Get", err)
+}
+func (r *deliveryBaseRepo) GetAll() ([]*Delivery, error) {
+	return r.GetBy()
+}
+
+func (r *deliveryBaseRepo) GetBy(criteria ...gormrepo.CriteriaOption) ([]*Delivery, error) {
+	var entities []*Delivery
+	err := r.applyCriteria(criteria).Find(&entities).Error
+	return entities, r.wrapError("GetBy", err, criteria...)
+}
+
+func (r *deliveryBaseRepo) GetByNamed(name string, args ...interface{}) ([]*Delivery, error) {
+	criteria, err := gormrepo.NamedQuery(name, args...)
+	if err != nil {
+		return nil, r.wrapError("GetByNamed", err)
+	}
+	var entities []*Delivery
+	err = r.applyCriteria(criteria).Find(&entities).Error
+	return entities, r.wrapError("GetByNamed "+name, err, criteria...)
+}
+
+func (r *deliveryBaseRepo) GetByFirst(criteria ...gormrepo.CriteriaOption) (*Delivery, error) {
+	var entity Delivery
+	err := r.applyCriteria(criteria).First(&entity).Error
+	return &entity, r.wrapError("GetByFirst", err, criteria...)
+}
+
+func (r *deliveryBaseRepo) GetByLast(criteria ...gormrepo.CriteriaOption) (*Delivery, error) {
+	var entity Delivery
+	err := r.applyCriteria(criteria).Last(&entity).Error
+	return &entity, r.wrapError("GetByLast", err, criteria...)
+}
+
+func (r *deliveryBaseRepo) Count(criteria ...gormrepo.CriteriaOption) (int, error) {
+	var count int
+	err := r.applyCriteria(criteria).Model(&Delivery{}).Count(&count).Error
+	return count, r.wrapError("Count", err, criteria...)
+}
+
+// RawScan runs the statement sql, with its arguments bound, and scans its
+// rows into dest, see gormrepo.Raw.
+func (r *deliveryBaseRepo) RawScan(dest interface{}, sql string, args ...interface{}) error {
+	raw := gormrepo.Raw(sql, args...)
+	err := r.applyCriteria([]gormrepo.CriteriaOption{raw}).Scan(dest).Error
+	return r.wrapError("RawScan", err, raw)
+}
+
+func (r *deliveryBaseRepo) CountByPeriod(column string, period gormrepo.Period, criteria ...gormrepo.CriteriaOption) (map[time.Time]int64, error) {
+	counts, err := gormrepo.CountByPeriod(r.applyCriteria(criteria).Model(&Delivery{}), column, period)
+	return counts, r.wrapError("CountByPeriod", err, criteria...)
+}
+
+func (r *deliveryBaseRepo) Percentiles(column string, ps []float64, criteria ...gormrepo.CriteriaOption) ([]float64, error) {
+	values, err := gormrepo.Percentiles(r.applyCriteria(criteria).Model(&Delivery{}), column, ps...)
+	return values, r.wrapError("Percentiles", err, criteria...)
+}
+
+func (r *deliveryBaseRepo) Histogram(column string, min, max float64, buckets int, criteria ...gormrepo.CriteriaOption) ([]gormrepo.Bucket, error) {
+	histogram, err := gormrepo.Histogram(r.applyCriteria(criteria).Model(&Delivery{}), column, min, max, buckets)
+	return histogram, r.wrapError("Histogram", err, criteria...)
+}
+
+func (r *deliveryBaseRepo) Create(entity Delivery) (*Delivery, error) {
+	if !r.DB.NewRecord(entity) {
+		return nil, r.wrapError("Create", gormrepo.ErrPrimaryNotBlank)
+	}
+	err := r.DB.Create(&entity).Error
+	if err != nil {
+		return nil, r.wrapError("Create", err)
+	}
+	return &entity, nil
+}
+
+// CopyFrom inserts the entities in bulk, see gormrepo.CopyFrom.
+func (r *deliveryBaseRepo) CopyFrom(entities []*Delivery) error {
+	return r.wrapError("CopyFrom", gormrepo.CopyFrom(r.DB, entities))
+}
+
+func (r *deliveryBaseRepo) CreateContext(ctx context.Context, entity Delivery) (*Delivery, error) {
+	key, ok := gormrepo.IdempotencyKeyFromContext(ctx)
+	if !ok {
+		return r.Create(entity)
+	}
+	table := r.DB.NewScope(&entity).TableName()
+	var created *Delivery
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		repo := &deliveryBaseRepo{tx}
+		id, found, err := gormrepo.FindIdempotencyKey(tx, key, table)
+		if err != nil {
+			return err
+		}
+		if found {
+			created, err = repo.Get(id)
+			return err
+		}
+		if created, err = repo.Create(entity); err != nil {
+			return err
+		}
+		return gormrepo.SaveIdempotencyKey(tx, key, table, uint(created.ID))
+	})
+	if err != nil {
+		return nil, r.wrapError("CreateContext", err)
+	}
+	return created, nil
+}
+
+func (r *deliveryBaseRepo) Update(entity *Delivery, fields gormrepo.Fields, criteria ...gormrepo.CriteriaOption) error {
+	err := r.applyCriteria(criteria).Model(entity).Updates(fields).Error
+	return r.wrapError("Update", err, criteria...)
+}
+
+// BulkUpdateByKey updates the fields of the rows by primary key, see
+// gormrepo.BulkUpdateByKey.
+func (r *deliveryBaseRepo) BulkUpdateByKey(pairs map[uint]gormrepo.Fields) (int64, error) {
+	n, err := gormrepo.BulkUpdateByKey(r.DB, &Delivery{}, pairs)
+	return n, r.wrapError("BulkUpdateByKey", err)
+}
+
+func (r *deliveryBaseRepo) Delete(entity *Delivery, criteria ...gormrepo.CriteriaOption) error {
+	err := r.applyCriteria(criteria).Delete(entity).Error
+	return r.wrapError("Delete", err, criteria...)
+}
+
+// DeleteAndGet deletes the rows matching the criteria and returns them, see
+// gormrepo.DeleteReturning.
+func (r *deliveryBaseRepo) DeleteAndGet(criteria ...gormrepo.CriteriaOption) ([]*Delivery, error) {
+	var entities []*Delivery
+	err := gormrepo.DeleteReturning(r.DB, &entities, criteria...)
+	return entities, r.wrapError("DeleteAndGet", err, criteria...)
+}
+
+func (r *deliveryBaseRepo) batchIDs(size int, criteria []gormrepo.CriteriaOption) (string, []interface{}, error) {
+	scope := r.DB.NewScope(&Delivery{})
+	key := scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())
+	var ids []interface{}
+	err := r.applyCriteria(criteria).Model(&Delivery{}).Limit(size).Pluck(key, &ids).Error
+	return key, ids, err
+}
+
+func (r *deliveryBaseRepo) DeleteBatch(size int, criteria ...gormrepo.CriteriaOption) (int64, error) {
+	key, ids, err := r.batchIDs(size, criteria)
+	if err != nil || len(ids) == 0 {
+		return 0, r.wrapError("DeleteBatch", err, criteria...)
+	}
+	result := r.applyCriteria(criteria).Where(key+" IN (?)", ids).Delete(&Delivery{})
+	return result.RowsAffected, r.wrapError("DeleteBatch", result.Error, criteria...)
+}
+
+func (r *deliveryBaseRepo) ArchiveBatch(table string, size int, criteria ...gormrepo.CriteriaOption) (int64, error) {
+	var archived int64
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		repo := &deliveryBaseRepo{tx}
+		key, ids, err := repo.batchIDs(size, criteria)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		insert := "INSERT INTO " + tx.Dialect().Quote(table) + " SELECT * FROM " + tx.NewScope(&Delivery{}).QuotedTableName() + " WHERE " + key + " IN (?)"
+		if err := tx.Exec(insert, ids).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where(key+" IN (?)", ids).Delete(&Delivery{})
+		archived = result.RowsAffected
+		return result.Error
+	})
+	return archived, r.wrapError("ArchiveBatch", err, criteria...)
+}
+
+func (r *deliveryBaseRepo) Archive(sink gormrepo.Sink, size int, criteria ...gormrepo.CriteriaOption) (int64, error) {
+	if size <= 0 {
+		size = gormrepo.ArchiveBatchSize
+	}
+	var archived int64
+	for {
+		var n int
+		err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+			scope := tx.NewScope(&Delivery{})
+			key := scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())
+			var entities []*Delivery
+			if err := (&deliveryBaseRepo{tx}).applyCriteria(criteria).Order(key).Limit(size).Find(&entities).Error; err != nil {
+				return err
+			}
+			n = len(entities)
+			if n == 0 {
+				return nil
+			}
+			ids := make([]interface{}, 0, n)
+			for _, entity := range entities {
+				if err := sink.Write(entity); err != nil {
+					return err
+				}
+				ids = append(ids, tx.NewScope(entity).PrimaryKeyValue())
+			}
+			if err := sink.Flush(); err != nil {
+				return err
+			}
+			return tx.Unscoped().Where(key+" IN (?)", ids).Delete(&Delivery{}).Error
+		})
+		if err != nil {
+			return archived, r.wrapError("Archive", err, criteria...)
+		}
+		archived += int64(n)
+		if n < size {
+			return archived, nil
+		}
+	}
+}
+
+// deliveryUniqueIndexes are the unique indexes declared by the tags of Delivery.
+var deliveryUniqueIndexes = []gormrepo.UniqueIndex{}
+
+// Upsert inserts the entity or updates the row it conflicts with on target,
+// the primary key or one of the unique indexes of Delivery.
+func (r *deliveryBaseRepo) Upsert(entity *Delivery, target gormrepo.ConflictTarget, update ...string) error {
+	return r.wrapError("Upsert", gormrepo.Upsert(r.DB, entity, target, deliveryUniqueIndexes, update...))
+}
+
+func (r *deliveryBaseRepo) Checksum(criteria ...gormrepo.CriteriaOption) (string, error) {
+	scope := r.DB.NewScope(&Delivery{})
+	rows, err := r.applyCriteria(criteria).Model(&Delivery{}).Order(scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())).Rows()
+	if err != nil {
+		return "", r.wrapError("Checksum", err, criteria...)
+	}
+	sum, err := gormrepo.Checksum(rows)
+	return sum, r.wrapError("Checksum", err, criteria...)
+}
+
+func (r *deliveryBaseRepo) Stats() gormrepo.Stats {
+	return gormrepo.TableStats(r.DB.NewScope(&Delivery{}).TableName())
+}
+
+// SeedDemoData inserts n demo rows, see gormrepo.Seed.
+func (r *deliveryBaseRepo) SeedDemoData(n int) error {
+	return r.wrapError("SeedDemoData", gormrepo.Seed(r.DB, n, &Delivery{}))
+}
+
+func (r *deliveryBaseRepo) AutoMigrate() error {
+	return r.wrapError("AutoMigrate", r.DB.AutoMigrate(&Delivery{}).Error)
+}
+
+// Truncate empties the table, see gormrepo.Truncate.
+func (r *deliveryBaseRepo) Truncate(cascade bool) error {
+	return r.wrapError("Truncate", gormrepo.Truncate(r.DB, cascade, &Delivery{}))
+}
+
+func (r *deliveryBaseRepo) AddUniqueIndex(name string, columns ...string) error {
+	return r.wrapError("AddUniqueIndex", r.DB.Model(&Delivery{}).AddUniqueIndex(name, columns...).Error)
+}
+
+func (r *deliveryBaseRepo) AddForeignKey(field string, dest string, onDelete string, onUpdate string) error {
+	return r.wrapError("AddForeignKey", r.DB.Model(&Delivery{}).AddForeignKey(field, dest, onDelete, onUpdate).Error)
+}
+
+func (r *deliveryBaseRepo) AddIndex(name string, columns ...string) error {
+	return r.wrapError("AddIndex", r.DB.Model(&Delivery{}).AddIndex(name, columns...).Error)
+}
+
+// DeliveryChanged describes a change of a Delivery. Old is nil for creates and New
+// for deletes, Fields holds the changed columns.
+type DeliveryChanged struct {
+	Op     gormrepo.Operation
+	Old    *Delivery
+	New    *Delivery
+	Fields []string
+}
+
+// Changed builds the change event of the entity, computing the changed columns.
+func (r *deliveryBaseRepo) Changed(op gormrepo.Operation, old, new *Delivery) DeliveryChanged {
+	return DeliveryChanged{Op: op, Old: old, New: new, Fields: gormrepo.ChangedFields(r.DB, old, new)}
+}
+
+// Debezium returns the change in the Debezium envelope format.
+func (r *deliveryBaseRepo) Debezium(changed DeliveryChanged) gormrepo.DebeziumEnvelope {
+	return gormrepo.NewDebeziumEnvelope(r.DB, changed.Op, changed.Old, changed.New)
+}
@@ -0,0 +1,42 @@
+package webhooks
+
+import (
+	"time"
+
+	"github.com/jinzhu/gorm"
+)
+
+//go:generate gormrepogen -t=Target,Delivery
+
+// Target is a registered webhook URL. An Entity of "*" matches all entities,
+// a blank Op all operations.
+type Target struct {
+	gorm.Model
+	Entity string `gorm:"index"`
+	Op     string
+	URL    string
+	Secret string
+	Active bool
+}
+
+func (Target) TableName() string {
+	return "webhook_targets"
+}
+
+// Delivery is an attempt to deliver an event to a target. Error is blank for
+// successful deliveries.
+type Delivery struct {
+	gorm.Model
+	TargetID   uint `gorm:"index"`
+	JobID      uint `gorm:"index"`
+	Event      string
+	Key        string
+	Attempt    int
+	StatusCode int
+	Duration   time.Duration
+	Error      string `gorm:"type:text"`
+}
+
+func (Delivery) TableName() string {
+	return "webhook_deliveries"
+}
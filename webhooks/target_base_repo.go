@@ -0,0 +1,308 @@
+// Code generated by "gormrepogen -t=Target,Delivery"; DO NOT EDIT
+
+package webhooks
+
+import (
+	"context"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/l-vitaly/gormrepo"
+)
+
+type targetBaseRepo struct {
+	*gorm.DB
+}
+
+func (r *targetBaseRepo) applyCriteria(criteria []gormrepo.CriteriaOption) *gorm.DB {
+	// The criteria checking their columns see the model.
+	return gormrepo.ApplyCriteria(r.DB.Model(&Target{}), criteria...)
+}
+
+func (r *targetBaseRepo) wrapError(method string, err error, criteria ...gormrepo.CriteriaOption) error {
+	return gormrepo.WrapError(err, "Target", method, criteria...)
+}
+
+func (r *targetBaseRepo) Related(claim *Target, related interface{}, criteria ...gormrepo.CriteriaOption) error {
+	err := r.applyCriteria(criteria).Model(claim).Related(related).Error
+	return r.wrapError("Related", err, criteria...)
+}
+
+func (r *targetBaseRepo) Get(id uint) (*Target, error) {
+	var entity Target
+	err := r.DB.Where(map[string]interface{}{"id": id}).Find(&entity).Error
+	return &entity, r.wrapError("Get", err)
+}
+func (r *targetBaseRepo) GetAll() ([]*Target, error) {
+	return r.GetBy()
+}
+
+func (r *targetBaseRepo) GetBy(criteria ...gormrepo.CriteriaOption) ([]*Target, error) {
+	var entities []*Target
+	err := r.applyCriteria(criteria).Find(&entities).Error
+	return entities, r.wrapError("GetBy", err, criteria...)
+}
+
+func (r *targetBaseRepo) GetByNamed(name string, args ...interface{}) ([]*Target, error) {
+	criteria, err := gormrepo.NamedQuery(name, args...)
+	if err != nil {
+		return nil, r.wrapError("GetByNamed", err)
+	}
+	var entities []*Target
+	err = r.applyCriteria(criteria).Find(&entities).Error
+	return entities, r.wrapError("GetByNamed "+name, err, criteria...)
+}
+
+func (r *targetBaseRepo) GetByFirst(criteria ...gormrepo.CriteriaOption) (*Target, error) {
+	var entity Target
+	err := r.applyCriteria(criteria).First(&entity).Error
+	return &entity, r.wrapError("GetByFirst", err, criteria...)
+}
+
+func (r *targetBaseRepo) GetByLast(criteria ...gormrepo.CriteriaOption) (*Target, error) {
+	var entity Target
+	err := r.applyCriteria(criteria).Last(&entity).Error
+	return &entity, r.wrapError("GetByLast", err, criteria...)
+}
+
+func (r *targetBaseRepo) Count(criteria ...gormrepo.CriteriaOption) (int, error) {
+	var count int
+	err := r.applyCriteria(criteria).Model(&Target{}).Count(&count).Error
+	return count, r.wrapError("Count", err, criteria...)
+}
+
+// RawScan runs the statement sql, with its arguments bound, and scans its
+// rows into dest, see gormrepo.Raw.
+func (r *targetBaseRepo) RawScan(dest interface{}, sql string, args ...interface{}) error {
+	raw := gormrepo.Raw(sql, args...)
+	err := r.applyCriteria([]gormrepo.CriteriaOption{raw}).Scan(dest).Error
+	return r.wrapError("RawScan", err, raw)
+}
+
+func (r *targetBaseRepo) CountByPeriod(column string, period gormrepo.Period, criteria ...gormrepo.CriteriaOption) (map[time.Time]int64, error) {
+	counts, err := gormrepo.CountByPeriod(r.applyCriteria(criteria).Model(&Target{}), column, period)
+	return counts, r.wrapError("CountByPeriod", err, criteria...)
+}
+
+func (r *targetBaseRepo) Percentiles(column string, ps []float64, criteria ...gormrepo.CriteriaOption) ([]float64, error) {
+	values, err := gormrepo.Percentiles(r.applyCriteria(criteria).Model(&Target{}), column, ps...)
+	return values, r.wrapError("Percentiles", err, criteria...)
+}
+
+func (r *targetBaseRepo) Histogram(column string, min, max float64, buckets int, criteria ...gormrepo.CriteriaOption) ([]gormrepo.Bucket, error) {
+	histogram, err := gormrepo.Histogram(r.applyCriteria(criteria).Model(&Target{}), column, min, max, buckets)
+	return histogram, r.wrapError("Histogram", err, criteria...)
+}
+
+func (r *targetBaseRepo) Create(entity Target) (*Target, error) {
+	if !r.DB.NewRecord(entity) {
+		return nil, r.wrapError("Create", gormrepo.ErrPrimaryNotBlank)
+	}
+	err := r.DB.Create(&entity).Error
+	if err != nil {
+		return nil, r.wrapError("Create", err)
+	}
+	return &entity, nil
+}
+
+// CopyFrom inserts the entities in bulk, see gormrepo.CopyFrom.
+func (r *targetBaseRepo) CopyFrom(entities []*Target) error {
+	return r.wrapError("CopyFrom", gormrepo.CopyFrom(r.DB, entities))
+}
+
+func (r *targetBaseRepo) CreateContext(ctx context.Context, entity Target) (*Target, error) {
+	key, ok := gormrepo.IdempotencyKeyFromContext(ctx)
+	if !ok {
+		return r.Create(entity)
+	}
+	table := r.DB.NewScope(&entity).TableName()
+	var created *Target
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		repo := &targetBaseRepo{tx}
+		id, found, err := gormrepo.FindIdempotencyKey(tx, key, table)
+		if err != nil {
+			return err
+		}
+		if found {
+			created, err = repo.Get(id)
+			return err
+		}
+		if created, err = repo.Create(entity); err != nil {
+			return err
+		}
+		return gormrepo.SaveIdempotencyKey(tx, key, table, uint(created.ID))
+	})
+	if err != nil {
+		return nil, r.wrapError("CreateContext", err)
+	}
+	return created, nil
+}
+
+func (r *targetBaseRepo) Update(entity *Target, fields gormrepo.Fields, criteria ...gormrepo.CriteriaOption) error {
+	err := r.applyCriteria(criteria).Model(entity).Updates(fields).Error
+	return r.wrapError("Update", err, criteria...)
+}
+
+// BulkUpdateByKey updates the fields of the rows by primary key, see
+// gormrepo.BulkUpdateByKey.
+func (r *targetBaseRepo) BulkUpdateByKey(pairs map[uint]gormrepo.Fields) (int64, error) {
+	n, err := gormrepo.BulkUpdateByKey(r.DB, &Target{}, pairs)
+	return n, r.wrapError("BulkUpdateByKey", err)
+}
+
+func (r *targetBaseRepo) Delete(entity *Target, criteria ...gormrepo.CriteriaOption) error {
+	err := r.applyCriteria(criteria).Delete(entity).Error
+	return r.wrapError("Delete", err, criteria...)
+}
+
+// DeleteAndGet deletes the rows matching the criteria and returns them, see
+// gormrepo.DeleteReturning.
+func (r *targetBaseRepo) DeleteAndGet(criteria ...gormrepo.CriteriaOption) ([]*Target, error) {
+	var entities []*Target
+	err := gormrepo.DeleteReturning(r.DB, &entities, criteria...)
+	return entities, r.wrapError("DeleteAndGet", err, criteria...)
+}
+
+func (r *targetBaseRepo) batchIDs(size int, criteria []gormrepo.CriteriaOption) (string, []interface{}, error) {
+	scope := r.DB.NewScope(&Target{})
+	key := scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())
+	var ids []interface{}
+	err := r.applyCriteria(criteria).Model(&Target{}).Limit(size).Pluck(key, &ids).Error
+	return key, ids, err
+}
+
+func (r *targetBaseRepo) DeleteBatch(size int, criteria ...gormrepo.CriteriaOption) (int64, error) {
+	key, ids, err := r.batchIDs(size, criteria)
+	if err != nil || len(ids) == 0 {
+		return 0, r.wrapError("DeleteBatch", err, criteria...)
+	}
+	result := r.applyCriteria(criteria).Where(key+" IN (?)", ids).Delete(&Target{})
+	return result.RowsAffected, r.wrapError("DeleteBatch", result.Error, criteria...)
+}
+
+func (r *targetBaseRepo) ArchiveBatch(table string, size int, criteria ...gormrepo.CriteriaOption) (int64, error) {
+	var archived int64
+	err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+		repo := &targetBaseRepo{tx}
+		key, ids, err := repo.batchIDs(size, criteria)
+		if err != nil || len(ids) == 0 {
+			return err
+		}
+		insert := "INSERT INTO " + tx.Dialect().Quote(table) + " SELECT * FROM " + tx.NewScope(&Target{}).QuotedTableName() + " WHERE " + key + " IN (?)"
+		if err := tx.Exec(insert, ids).Error; err != nil {
+			return err
+		}
+		result := tx.Unscoped().Where(key+" IN (?)", ids).Delete(&Target{})
+		archived = result.RowsAffected
+		return result.Error
+	})
+	return archived, r.wrapError("ArchiveBatch", err, criteria...)
+}
+
+func (r *targetBaseRepo) Archive(sink gormrepo.Sink, size int, criteria ...gormrepo.CriteriaOption) (int64, error) {
+	if size <= 0 {
+		size = gormrepo.ArchiveBatchSize
+	}
+	var archived int64
+	for {
+		var n int
+		err := gormrepo.Transaction(r.DB, func(tx *gorm.DB) error {
+			scope := tx.NewScope(&Target{})
+			key := scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())
+			var entities []*Target
+			if err := (&targetBaseRepo{tx}).applyCriteria(criteria).Order(key).Limit(size).Find(&entities).Error; err != nil {
+				return err
+			}
+			n = len(entities)
+			if n == 0 {
+				return nil
+			}
+			ids := make([]interface{}, 0, n)
+			for _, entity := range entities {
+				if err := sink.Write(entity); err != nil {
+					return err
+				}
+				ids = append(ids, tx.NewScope(entity).PrimaryKeyValue())
+			}
+			if err := sink.Flush(); err != nil {
+				return err
+			}
+			return tx.Unscoped().Where(key+" IN (?)", ids).Delete(&Target{}).Error
+		})
+		if err != nil {
+			return archived, r.wrapError("Archive", err, criteria...)
+		}
+		archived += int64(n)
+		if n < size {
+			return archived, nil
+		}
+	}
+}
+
+// targetUniqueIndexes are the unique indexes declared by the tags of Target.
+var targetUniqueIndexes = []gormrepo.UniqueIndex{}
+
+// Upsert inserts the entity or updates the row it conflicts with on target,
+// the primary key or one of the unique indexes of Target.
+func (r *targetBaseRepo) Upsert(entity *Target, target gormrepo.ConflictTarget, update ...string) error {
+	return r.wrapError("Upsert", gormrepo.Upsert(r.DB, entity, target, targetUniqueIndexes, update...))
+}
+
+func (r *targetBaseRepo) Checksum(criteria ...gormrepo.CriteriaOption) (string, error) {
+	scope := r.DB.NewScope(&Target{})
+	rows, err := r.applyCriteria(criteria).Model(&Target{}).Order(scope.QuotedTableName() + "." + scope.Quote(scope.PrimaryKey())).Rows()
+	if err != nil {
+		return "", r.wrapError("Checksum", err, criteria...)
+	}
+	sum, err := gormrepo.Checksum(rows)
+	return sum, r.wrapError("Checksum", err, criteria...)
+}
+
+func (r *targetBaseRepo) Stats() gormrepo.Stats {
+	return gormrepo.TableStats(r.DB.NewScope(&Target{}).TableName())
+}
+
+// SeedDemoData inserts n demo rows, see gormrepo.Seed.
+func (r *targetBaseRepo) SeedDemoData(n int) error {
+	return r.wrapError("SeedDemoData", gormrepo.Seed(r.DB, n, &Target{}))
+}
+
+func (r *targetBaseRepo) AutoMigrate() error {
+	return r.wrapError("AutoMigrate", r.DB.AutoMigrate(&Target{}).Error)
+}
+
+// Truncate empties the table, see gormrepo.Truncate.
+func (r *targetBaseRepo) Truncate(cascade bool) error {
+	return r.wrapError("Truncate", gormrepo.Truncate(r.DB, cascade, &Target{}))
+}
+
+func (r *targetBaseRepo) AddUniqueIndex(name string, columns ...string) error {
+	return r.wrapError("AddUniqueIndex", r.DB.Model(&Target{}).AddUniqueIndex(name, columns...).Error)
+}
+
+func (r *targetBaseRepo) AddForeignKey(field string, dest string, onDelete string, onUpdate string) error {
+	return r.wrapError("AddForeignKey", r.DB.Model(&Target{}).AddForeignKey(field, dest, onDelete, onUpdate).Error)
+}
+
+func (r *targetBaseRepo) AddIndex(name string, columns ...string) error {
+	return r.wrapError("AddIndex", r.DB.Model(&Target{}).AddIndex(name, columns...).Error)
+}
+
+// TargetChanged describes a change of a Target. Old is nil for creates and New
+// for deletes, Fields holds the changed columns.
+type TargetChanged struct {
+	Op     gormrepo.Operation
+	Old    *Target
+	New    *Target
+	Fields []string
+}
+
+// Changed builds the change event of the entity, computing the changed columns.
+func (r *targetBaseRepo) Changed(op gormrepo.Operation, old, new *Target) TargetChanged {
+	return TargetChanged{Op: op, Old: old, New: new, Fields: gormrepo.ChangedFields(r.DB, old, new)}
+}
+
+// Debezium returns the change in the Debezium envelope format.
+func (r *targetBaseRepo) Debezium(changed TargetChanged) gormrepo.DebeziumEnvelope {
+	return gormrepo.NewDebeziumEnvelope(r.DB, changed.Op, changed.Old, changed.New)
+}
@@ -0,0 +1,266 @@
+// Package webhooks delivers entity change events to registered URLs. Events
+// are enqueued as jobs of a queue, the outbox, and delivered by workers with
+// signed requests, retries and a log of every attempt.
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jinzhu/gorm"
+	"github.com/l-vitaly/gormrepo"
+	"github.com/l-vitaly/gormrepo/queue"
+)
+
+const (
+	SignatureHeader = "X-Webhook-Signature"
+	EventHeader     = "X-Webhook-Event"
+	DeliveryHeader  = "X-Webhook-Delivery"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+// Message is the body of a webhook request.
+type Message struct {
+	Event string      `json:"event"` // <Entity>.<op>
+	Key   string      `json:"key"`
+	Data  interface{} `json:"data"`
+}
+
+// job is the payload of a delivery job, the message is encoded when the
+// event is published.
+type job struct {
+	TargetID uint            `json:"target_id"`
+	Event    string          `json:"event"`
+	Key      string          `json:"key"`
+	Body     json.RawMessage `json:"body"`
+}
+
+// Webhooks is a gormrepo.Publisher enqueueing a delivery for each target
+// matching the published events.
+type Webhooks struct {
+	Client *http.Client
+
+	db         *gorm.DB
+	queue      *queue.Queue
+	targets    *targetBaseRepo
+	deliveries *deliveryBaseRepo
+}
+
+// New returns the webhooks using the named delivery queue.
+func New(db *gorm.DB, queueName string) *Webhooks {
+	return &Webhooks{
+		Client:     &http.Client{Timeout: 10 * time.Second},
+		db:         db,
+		queue:      queue.New(db, queueName),
+		targets:    &targetBaseRepo{db},
+		deliveries: &deliveryBaseRepo{db},
+	}
+}
+
+// Queue returns the delivery queue, to tune its attempts and backoff.
+func (w *Webhooks) Queue() *queue.Queue {
+	return w.queue
+}
+
+// Tx returns the webhooks enqueueing deliveries in the transaction, so they
+// are sent only if it commits.
+func (w *Webhooks) Tx(tx *gorm.DB) *Webhooks {
+	q := queue.New(tx, w.queue.Name)
+	q.MaxAttempts, q.Backoff, q.Timeout = w.queue.MaxAttempts, w.queue.Backoff, w.queue.Timeout
+	return &Webhooks{
+		Client:     w.Client,
+		db:         tx,
+		queue:      q,
+		targets:    &targetBaseRepo{tx},
+		deliveries: &deliveryBaseRepo{tx},
+	}
+}
+
+// AutoMigrate creates the target, delivery and queue tables.
+func (w *Webhooks) AutoMigrate() error {
+	if err := w.targets.AutoMigrate(); err != nil {
+		return err
+	}
+	if err := w.deliveries.AutoMigrate(); err != nil {
+		return err
+	}
+	return w.queue.AutoMigrate()
+}
+
+// Register adds an active target for the entity and operation.
+func (w *Webhooks) Register(entity, op, url, secret string) (*Target, error) {
+	return w.targets.Create(Target{Entity: entity, Op: op, URL: url, Secret: secret, Active: true})
+}
+
+// Unregister removes the target, its pending deliveries are dropped.
+func (w *Webhooks) Unregister(target *Target) error {
+	return w.targets.Delete(target)
+}
+
+// Targets returns the registered targets.
+func (w *Webhooks) Targets(criteria ...gormrepo.CriteriaOption) ([]*Target, error) {
+	return w.targets.GetBy(criteria...)
+}
+
+// Deliveries returns the delivery log.
+func (w *Webhooks) Deliveries(criteria ...gormrepo.CriteriaOption) ([]*Delivery, error) {
+	return w.deliveries.GetBy(criteria...)
+}
+
+func (w *Webhooks) Publish(ctx context.Context, events ...gormrepo.Event) error {
+	for _, event := range events {
+		targets, err := w.targets.GetBy(
+			gormrepo.And("(entity = ? OR entity = '*')", event.Entity),
+			gormrepo.And("(op = '' OR op = ?)", event.Op.String()),
+			gormrepo.And("active = ?", true),
+		)
+		if err != nil {
+			return err
+		}
+		if len(targets) == 0 {
+			continue
+		}
+		name := event.Entity + "." + event.Op.String()
+		body, err := json.Marshal(Message{Event: name, Key: event.Key, Data: event.Payload})
+		if err != nil {
+			return err
+		}
+		for _, target := range targets {
+			payload, err := json.Marshal(job{TargetID: target.ID, Event: name, Key: event.Key, Body: body})
+			if err != nil {
+				return err
+			}
+			if _, err := w.queue.Enqueue(string(payload), 0); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Work delivers the next pending webhook and reports whether there was one.
+// Failed deliveries are retried with the backoff of the queue.
+func (w *Webhooks) Work(ctx context.Context, worker string) (bool, error) {
+	j, err := w.queue.Dequeue(worker)
+	if err != nil || j == nil {
+		return false, err
+	}
+	var payload job
+	if err := json.Unmarshal([]byte(j.Payload), &payload); err != nil {
+		return true, w.queue.Fail(j, err)
+	}
+	target, err := w.targets.Get(payload.TargetID)
+	if gormrepo.IsRecordNotFound(err) {
+		return true, w.queue.Complete(j)
+	}
+	if err != nil {
+		return true, w.queue.Fail(j, err)
+	}
+
+	delivery := Delivery{TargetID: target.ID, JobID: j.ID, Event: payload.Event, Key: payload.Key, Attempt: j.Attempts}
+	start := time.Now()
+	delivery.StatusCode, err = w.send(ctx, target, j.ID, payload)
+	delivery.Duration = time.Since(start)
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	if _, logErr := w.deliveries.Create(delivery); logErr != nil && err == nil {
+		err = logErr
+	}
+	if err != nil {
+		return true, w.queue.Fail(j, err)
+	}
+	return true, w.queue.Complete(j)
+}
+
+func (w *Webhooks) send(ctx context.Context, target *Target, id uint, payload job) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, target.URL, bytes.NewReader(payload.Body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, payload.Event)
+	req.Header.Set(DeliveryHeader, strconv.FormatUint(uint64(id), 10))
+	req.Header.Set(SignatureHeader, Sign(target.Secret, time.Now(), payload.Body))
+	resp, err := w.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// Run delivers webhooks until ctx is done, polling the queue every interval
+// when it is empty, and reclaiming deliveries of dead workers.
+func (w *Webhooks) Run(ctx context.Context, worker string, interval time.Duration) error {
+	for {
+		worked, err := w.Work(ctx, worker)
+		if err != nil {
+			return err
+		}
+		if worked {
+			continue
+		}
+		if _, err := w.queue.ReclaimStale(); err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(interval):
+		}
+	}
+}
+
+// Sign returns the signature header of the body sent at t, the hex HMAC-SHA256
+// of "<unix time>.<body>" keyed by the secret of the target.
+func Sign(secret string, t time.Time, body []byte) string {
+	ts := strconv.FormatInt(t.Unix(), 10)
+	return "t=" + ts + ",v1=" + signature(secret, ts, body)
+}
+
+func signature(secret, ts string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts + "."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature header of a received body, rejecting
+// signatures older than tolerance to prevent replays.
+func Verify(secret, header string, body []byte, tolerance time.Duration) error {
+	var ts, sig string
+	for _, part := range strings.Split(header, ",") {
+		switch {
+		case strings.HasPrefix(part, "t="):
+			ts = part[2:]
+		case strings.HasPrefix(part, "v1="):
+			sig = part[3:]
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || sig == "" {
+		return ErrInvalidSignature
+	}
+	if tolerance > 0 && time.Since(time.Unix(unix, 0)) > tolerance {
+		return ErrInvalidSignature
+	}
+	if !hmac.Equal([]byte(sig), []byte(signature(secret, ts, body))) {
+		return ErrInvalidSignature
+	}
+	return nil
+}